@@ -0,0 +1,64 @@
+package goukv
+
+// BatchValidator an optional interface for providers that can validate a batch without writing it,
+// their Batch runs the same validation before writing anything
+type BatchValidator interface {
+	ValidateBatch(entries []*Entry) error
+}
+
+// ValidateEntries returns the first problem of the specified entries: a missing entry or key, or a key or a value
+// larger than the specified limits (zero means unlimited), a key may be listed more than once (the last write wins)
+func ValidateEntries(entries []*Entry, maxKeySize, maxValueSize int) error {
+	for _, e := range entries {
+		if e == nil || len(e.Key) == 0 {
+			return ErrEmptyKey
+		}
+
+		if maxKeySize > 0 && len(e.Key) > maxKeySize {
+			return ErrKeyTooLarge
+		}
+
+		if maxValueSize > 0 && len(e.Value) > maxValueSize {
+			return ErrValueTooLarge
+		}
+	}
+
+	return nil
+}
+
+// LastWrites returns the last entry of each key of the specified entries, in the order of those last entries,
+// it's used by the providers whose backends don't apply the entries of a batch in order so that the last write wins
+func LastWrites(entries []*Entry) []*Entry {
+	seen := make(map[string]bool, len(entries))
+	last := make([]*Entry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		if seen[string(entries[i].Key)] {
+			continue
+		}
+		seen[string(entries[i].Key)] = true
+		last = append(last, entries[i])
+	}
+
+	for i, j := 0, len(last)-1; i < j; i, j = i+1, j-1 {
+		last[i], last[j] = last[j], last[i]
+	}
+
+	return last
+}
+
+// BatchDetailed writes the specified entries like p.Batch and reports the error of each entry (see DetailedBatcher),
+// the batches of the providers that don't implement DetailedBatcher are all-or-nothing so every entry gets the overall error
+func BatchDetailed(p Provider, entries []*Entry) ([]error, error) {
+	if batcher, ok := p.(DetailedBatcher); ok {
+		return batcher.BatchDetailed(entries)
+	}
+
+	err := p.Batch(entries)
+
+	results := make([]error, len(entries))
+	for i := range results {
+		results[i] = err
+	}
+
+	return results, err
+}
@@ -0,0 +1,183 @@
+package goukv_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestValidateEntries(t *testing.T) {
+	cases := []struct {
+		name    string
+		entries []*goukv.Entry
+		err     error
+	}{
+		{"valid", []*goukv.Entry{{Key: []byte("k1"), Value: []byte("v")}, {Key: []byte("k2")}}, nil},
+		{"identical duplicates", []*goukv.Entry{{Key: []byte("k"), Value: []byte("v")}, {Key: []byte("k"), Value: []byte("v")}}, nil},
+		{"nil entry", []*goukv.Entry{nil}, goukv.ErrEmptyKey},
+		{"empty key", []*goukv.Entry{{Value: []byte("v")}}, goukv.ErrEmptyKey},
+		{"large key", []*goukv.Entry{{Key: bytes.Repeat([]byte("k"), 11), Value: []byte("v")}}, goukv.ErrKeyTooLarge},
+		{"large value", []*goukv.Entry{{Key: []byte("k"), Value: bytes.Repeat([]byte("v"), 101)}}, goukv.ErrValueTooLarge},
+		{"different values", []*goukv.Entry{{Key: []byte("k"), Value: []byte("v1")}, {Key: []byte("k"), Value: []byte("v2")}}, nil},
+		{"put and delete", []*goukv.Entry{{Key: []byte("k"), Value: []byte{}}, {Key: []byte("k")}}, nil},
+		{"different ttls", []*goukv.Entry{{Key: []byte("k"), Value: []byte("v")}, {Key: []byte("k"), Value: []byte("v"), TTL: time.Hour}}, nil},
+	}
+
+	for _, c := range cases {
+		if err := goukv.ValidateEntries(c.entries, 10, 100); err != c.err {
+			t.Errorf("%s: expected (%v), found (%v)", c.name, c.err, err)
+		}
+	}
+}
+
+func TestValidateBatch(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		entries := []*goukv.Entry{
+			{Key: []byte("k1"), Value: []byte("v")},
+			{Key: []byte{}, Value: []byte("v")},
+		}
+
+		if err := db.(goukv.BatchValidator).ValidateBatch(entries); err != goukv.ErrEmptyKey {
+			t.Errorf("%s: expected ErrEmptyKey, found (%v)", driver, err)
+		}
+
+		if err := db.Batch(entries); err != goukv.ErrEmptyKey {
+			t.Errorf("%s: expected Batch to run the same validation, found (%v)", driver, err)
+		}
+
+		if _, err := db.Get([]byte("k1")); err != goukv.ErrKeyNotFound {
+			t.Errorf("%s: expected nothing to be written, found (%v)", driver, err)
+		}
+	}
+
+	db, cleanup := openTempDB(t, "badgerdb", nil)
+	defer cleanup()
+
+	err := db.(goukv.BatchValidator).ValidateBatch([]*goukv.Entry{{Key: make([]byte, 65001), Value: []byte("v")}})
+	if err != goukv.ErrKeyTooLarge {
+		t.Errorf("expected ErrKeyTooLarge, found (%v)", err)
+	}
+}
+
+func TestMaxValueSize(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, map[string]interface{}{"max_value_size": 10})
+		defer cleanup()
+
+		if err := db.Put(&goukv.Entry{Key: []byte("k1"), Value: make([]byte, 10)}); err != nil {
+			t.Errorf("%s: expected a value at the limit to be written, found (%v)", driver, err)
+		}
+
+		if err := db.Put(&goukv.Entry{Key: []byte("k2"), Value: make([]byte, 11)}); err != goukv.ErrValueTooLarge {
+			t.Errorf("%s: expected ErrValueTooLarge, found (%v)", driver, err)
+		}
+
+		err := db.Batch([]*goukv.Entry{
+			{Key: []byte("k3"), Value: make([]byte, 10)},
+			{Key: []byte("k4"), Value: make([]byte, 11)},
+		})
+		if err != goukv.ErrValueTooLarge {
+			t.Errorf("%s: expected ErrValueTooLarge, found (%v)", driver, err)
+		}
+
+		for _, k := range []string{"k2", "k3", "k4"} {
+			if _, err := db.Get([]byte(k)); err != goukv.ErrKeyNotFound {
+				t.Errorf("%s: expected (%s) not to be written, found (%v)", driver, k, err)
+			}
+		}
+	}
+}
+
+func TestLastWrites(t *testing.T) {
+	entries := []*goukv.Entry{
+		{Key: []byte("a"), Value: []byte("1")},
+		{Key: []byte("b"), Value: []byte("1")},
+		{Key: []byte("a")},
+		{Key: []byte("c"), Value: []byte("1")},
+		{Key: []byte("b"), Value: []byte("2")},
+	}
+
+	last := goukv.LastWrites(entries)
+	if len(last) != 3 || last[0] != entries[2] || last[1] != entries[3] || last[2] != entries[4] {
+		t.Errorf("expected the last entry of each key in order, found (%v)", last)
+	}
+}
+
+func TestBatchLastWriteWins(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		for _, opts := range []map[string]interface{}{{}, {"enable_changelog": true, "track_timestamps": true}} {
+			db, cleanup := openTempDB(t, driver, opts)
+			defer cleanup()
+
+			err := db.Batch([]*goukv.Entry{
+				{Key: []byte("put-delete"), Value: []byte("v")},
+				{Key: []byte("delete-put")},
+				{Key: []byte("put-put"), Value: []byte("v1")},
+				{Key: []byte("put-delete")},
+				{Key: []byte("delete-put"), Value: []byte("v")},
+				{Key: []byte("put-put"), Value: []byte("v2")},
+			})
+			if err != nil {
+				t.Fatalf("%s %v: %v", driver, opts, err)
+			}
+
+			if _, err := db.Get([]byte("put-delete")); err != goukv.ErrKeyNotFound {
+				t.Errorf("%s %v: expected the key to be deleted, found (%v)", driver, opts, err)
+			}
+
+			if v, err := db.Get([]byte("delete-put")); err != nil || string(v) != "v" {
+				t.Errorf("%s %v: expected (v), found (%s) (%v)", driver, opts, v, err)
+			}
+
+			if v, err := db.Get([]byte("put-put")); err != nil || string(v) != "v2" {
+				t.Errorf("%s %v: expected (v2), found (%s) (%v)", driver, opts, v, err)
+			}
+		}
+	}
+}
+
+func TestBatchDetailed(t *testing.T) {
+	db, cleanup := openTempDB(t, "goleveldb", map[string]interface{}{"max_value_size": 4})
+	defer cleanup()
+
+	entries := []*goukv.Entry{
+		{Key: []byte("a"), Value: []byte("v")},
+		{Key: []byte("b"), Value: []byte("too large")},
+	}
+
+	results, err := goukv.BatchDetailed(db, entries)
+	if err != goukv.ErrValueTooLarge || len(results) != 2 || results[0] != err || results[1] != err {
+		t.Errorf("expected every entry of an all-or-nothing batch to fail, found (%v) (%v)", results, err)
+	}
+
+	other, otherCleanup := openTempDB(t, "goleveldb", nil)
+	defer otherCleanup()
+
+	// the "a" keys are stored in the first shard, the others in the second one (which rejects large values)
+	sharded := goukv.Shard([]goukv.Provider{other, db}, func(k []byte) int {
+		if k[0] == 'a' {
+			return 0
+		}
+		return 1
+	})
+
+	entries = []*goukv.Entry{
+		{Key: []byte("a1"), Value: []byte("v")},
+		{Key: []byte("b1"), Value: []byte("too large")},
+		{Key: []byte("b2"), Value: []byte("v")},
+	}
+
+	results, err = goukv.BatchDetailed(sharded, entries)
+	if err != goukv.ErrValueTooLarge || len(results) != 3 || results[0] != nil || results[1] != err || results[2] != err {
+		t.Errorf("expected only the entries of the failed shard to fail, found (%v) (%v)", results, err)
+	}
+
+	if _, err := other.Get([]byte("a1")); err != nil {
+		t.Errorf("expected the entry of the other shard to be written, found (%v)", err)
+	}
+}
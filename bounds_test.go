@@ -0,0 +1,60 @@
+package goukv_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestKeyBounds(t *testing.T) {
+	cases := []struct {
+		prefix      string
+		first, last string
+	}{
+		{"", "\x00z", "c"},
+		{"b", "b1", "b3"},
+		{"c", "c", "c"},
+		{"0", "", ""},
+		{"x", "", ""},
+	}
+
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, map[string]interface{}{"enable_changelog": true})
+		defer cleanup()
+
+		bounds := db.(goukv.KeyBoundsReader)
+
+		if _, err := bounds.FirstKey(nil); err != goukv.ErrKeyNotFound {
+			t.Errorf("%s: expected (%v) on an empty db, found (%v)", driver, goukv.ErrKeyNotFound, err)
+		}
+
+		// "\x00z" is sorted right after the changelog (an internal key), "0" and "b4" expire
+		for _, k := range []string{"\x00z", "a1", "b1", "b2", "b3", "c"} {
+			db.Put(&goukv.Entry{Key: []byte(k), Value: []byte("v")})
+		}
+		for _, k := range []string{"0", "b4"} {
+			db.Put(&goukv.Entry{Key: []byte(k), Value: []byte("v"), TTL: time.Hour})
+		}
+
+		if last, err := bounds.LastKey([]byte("b")); err != nil || string(last) != "b4" {
+			t.Errorf("%s: expected (b4) to be the last key before it expires, found (%s, %v)", driver, last, err)
+		}
+
+		goukv.Now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+		for _, c := range cases {
+			first, err := bounds.FirstKey([]byte(c.prefix))
+			if c.first == "" && err != goukv.ErrKeyNotFound || c.first != "" && (err != nil || string(first) != c.first) {
+				t.Errorf("%s: prefix (%s): expected the first key (%q), found (%q, %v)", driver, c.prefix, c.first, first, err)
+			}
+
+			last, err := bounds.LastKey([]byte(c.prefix))
+			if c.last == "" && err != goukv.ErrKeyNotFound || c.last != "" && (err != nil || string(last) != c.last) {
+				t.Errorf("%s: prefix (%s): expected the last key (%q), found (%q, %v)", driver, c.prefix, c.last, last, err)
+			}
+		}
+
+		goukv.Now = time.Now
+	}
+}
@@ -0,0 +1,131 @@
+package goukv_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestCompareAndDelete(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		cad := db.(goukv.CompareAndDeleter)
+
+		if deleted, err := cad.CompareAndDelete([]byte("missing"), []byte("v")); deleted || err != nil {
+			t.Errorf("%s: expected a missing key not to match, found (%v) (%v)", driver, deleted, err)
+		}
+
+		db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v1")})
+
+		var matching, mismatching int64
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			old, counter := []byte("v1"), &matching
+			if i%2 == 1 {
+				old, counter = []byte("v2"), &mismatching
+			}
+
+			wg.Add(1)
+			go (func() {
+				defer wg.Done()
+
+				deleted, err := cad.CompareAndDelete([]byte("k"), old)
+				if err != nil {
+					t.Errorf("%s: %v", driver, err)
+				}
+				if deleted {
+					atomic.AddInt64(counter, 1)
+				}
+			})()
+		}
+		wg.Wait()
+
+		if matching != 1 || mismatching != 0 {
+			t.Errorf("%s: expected a single matching delete, found (%d) matching and (%d) mismatching", driver, matching, mismatching)
+		}
+
+		if _, err := db.Get([]byte("k")); err != goukv.ErrKeyNotFound {
+			t.Errorf("%s: expected the key to be deleted, found (%v)", driver, err)
+		}
+	}
+}
+
+func TestPutVersioned(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, map[string]interface{}{"enable_versions": true})
+		defer cleanup()
+
+		vp := db.(goukv.VersionedPutter)
+		k := []byte("k")
+
+		v1, err := vp.PutVersioned(&goukv.Entry{Key: k, Value: []byte("v1")}, 0)
+		if err != nil || v1 == 0 {
+			t.Fatalf("%s: expected a missing key to match the version (0), found (%d, %v)", driver, v1, err)
+		}
+
+		if entry, err := db.(goukv.EntryGetter).GetEntry(k); err != nil || entry.Version != v1 {
+			t.Errorf("%s: expected GetEntry to report the version (%d), found (%v, %v)", driver, v1, entry, err)
+		}
+
+		if _, err := vp.PutVersioned(&goukv.Entry{Key: k, Value: []byte("v")}, 0); err != goukv.ErrConflict {
+			t.Errorf("%s: expected an existing key not to match the version (0), found (%v)", driver, err)
+		}
+
+		// the plain writes change the version too
+		db.Put(&goukv.Entry{Key: k, Value: []byte("v2")})
+		if _, err := vp.PutVersioned(&goukv.Entry{Key: k, Value: []byte("v")}, v1); err != goukv.ErrConflict {
+			t.Errorf("%s: expected a stale version to conflict, found (%v)", driver, err)
+		}
+
+		entry, _ := db.(goukv.EntryGetter).GetEntry(k)
+		current := entry.Version
+
+		var wg sync.WaitGroup
+		var won, conflicted int64
+		versions := make(chan uint64, 20)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go (func() {
+				defer wg.Done()
+
+				version, err := vp.PutVersioned(&goukv.Entry{Key: k, Value: []byte("v3")}, current)
+				switch err {
+				case nil:
+					atomic.AddInt64(&won, 1)
+					versions <- version
+				case goukv.ErrConflict:
+					atomic.AddInt64(&conflicted, 1)
+				default:
+					t.Error(err)
+				}
+			})()
+		}
+		wg.Wait()
+
+		if won != 1 || conflicted != 19 {
+			t.Fatalf("%s: expected a single update to win, found (%d) won and (%d) conflicted", driver, won, conflicted)
+		}
+
+		winner := <-versions
+		if entry, _ := db.(goukv.EntryGetter).GetEntry(k); entry.Version != winner || winner <= current {
+			t.Errorf("%s: expected the stored version to be the winner's (%d), found (%d)", driver, winner, entry.Version)
+		}
+
+		// a deleted key matches the version (0) again
+		db.Delete(k)
+		if _, err := vp.PutVersioned(&goukv.Entry{Key: k, Value: []byte("v4")}, 0); err != nil {
+			t.Errorf("%s: expected a deleted key to match the version (0), found (%v)", driver, err)
+		}
+	}
+
+	db, cleanup := openTempDB(t, "goleveldb", nil)
+	defer cleanup()
+
+	if _, err := db.(goukv.VersionedPutter).PutVersioned(&goukv.Entry{Key: []byte("k"), Value: []byte("v")}, 0); err != goukv.ErrNotSupported {
+		t.Errorf("expected goleveldb to require enable_versions, found (%v)", err)
+	}
+}
@@ -0,0 +1,66 @@
+package goukv
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+// ChangeOp the kind of a recorded mutation
+type ChangeOp uint8
+
+// available change operations
+const (
+	ChangePut ChangeOp = iota + 1
+	ChangeDelete
+)
+
+// ChangelogPrefix the reserved prefix the changelog records are stored under
+var ChangelogPrefix = []byte("\x00goukv\x00changelog\x00")
+
+// ChangelogReader an optional interface for providers that record their mutations
+type ChangelogReader interface {
+	ReadChanges(sinceSeq uint64, fn func(Change) error) error
+	TruncateChanges(beforeSeq uint64) error
+}
+
+// Change represents a single recorded mutation
+type Change struct {
+	Seq      uint64
+	Time     time.Time
+	Op       ChangeOp
+	Key      []byte
+	Value    []byte
+	TTL      time.Duration
+	ExpireAt *time.Time
+}
+
+// Bytes encodes the change to a byte array
+func (c Change) Bytes() []byte {
+	b, _ := msgpack.Marshal(c)
+	return b
+}
+
+// BytesToChange decodes the specified byte array to a Change
+func BytesToChange(b []byte) (c Change, err error) {
+	err = msgpack.Unmarshal(b, &c)
+	return
+}
+
+// ChangeKey returns the storage key of the specified sequence,
+// sequences are big-endian encoded so that the records sort by sequence
+func ChangeKey(seq uint64) []byte {
+	k := make([]byte, len(ChangelogPrefix)+8)
+	copy(k, ChangelogPrefix)
+	binary.BigEndian.PutUint64(k[len(ChangelogPrefix):], seq)
+	return k
+}
+
+// ChangeSeq extracts the sequence from the specified changelog storage key
+func ChangeSeq(k []byte) uint64 {
+	if len(k) < len(ChangelogPrefix)+8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(k[len(ChangelogPrefix):])
+}
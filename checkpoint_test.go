@@ -0,0 +1,68 @@
+package goukv_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestCheckpoint(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, map[string]interface{}{"track_timestamps": true})
+		defer cleanup()
+
+		for i := 0; i < 2500; i++ {
+			db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%04d", i)), Value: []byte(fmt.Sprintf("v%04d", i)), TTL: time.Hour})
+		}
+
+		dir, err := ioutil.TempDir("", "goukv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		ioutil.WriteFile(filepath.Join(dir, "file"), []byte("data"), 0600)
+		if err := db.(goukv.Checkpointer).Checkpoint(dir); err != goukv.ErrDirNotEmpty {
+			t.Errorf("%s: expected ErrDirNotEmpty, found (%v)", driver, err)
+		}
+
+		copyPath := filepath.Join(dir, "copy")
+		if err := db.(goukv.Checkpointer).Checkpoint(copyPath); err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+
+		// the source remains usable and the copy isn't affected by its later writes
+		if err := db.Put(&goukv.Entry{Key: []byte("k0000"), Value: []byte("changed")}); err != nil {
+			t.Errorf("%s: %v", driver, err)
+		}
+
+		copied, err := goukv.Open(driver, map[string]interface{}{"path": copyPath, "track_timestamps": true})
+		if err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+
+		if val, err := copied.Get([]byte("k0000")); err != nil || string(val) != "v0000" {
+			t.Errorf("%s: expected (v0000), found (%s, %v)", driver, val, err)
+		}
+
+		if expires, err := copied.TTL([]byte("k0001")); err != nil || expires == nil {
+			t.Errorf("%s: expected the expiration to be copied, found (%v, %v)", driver, expires, err)
+		}
+
+		db.Put(&goukv.Entry{Key: []byte("k0000"), Value: []byte("v0000"), TTL: time.Hour})
+
+		expected, _ := goukv.Fingerprint(db, nil)
+		found, err := goukv.Fingerprint(copied, nil)
+		if err != nil || !bytes.Equal(expected, found) {
+			t.Errorf("%s: expected the copy to match, found (%x) and (%x, %v)", driver, expected, found, err)
+		}
+
+		copied.Close()
+	}
+}
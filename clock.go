@@ -0,0 +1,8 @@
+package goukv
+
+import "time"
+
+// Now the clock the providers compute and check the expirations with (goleveldb and badgerdb), tests may replace it
+// with a fake one to move past a TTL without sleeping, it must be restored afterwards and must not be replaced
+// while providers are in use by other goroutines
+var Now = time.Now
@@ -0,0 +1,188 @@
+package goukv
+
+import (
+	"encoding/binary"
+	"strconv"
+	"time"
+)
+
+// KeyCodec encodes the keys before they reach the provider and decodes the scanned ones back,
+// the encoded form decides the order in which Scan returns the keys
+type KeyCodec interface {
+	EncodeKey([]byte) ([]byte, error)
+	DecodeKey([]byte) ([]byte, error)
+}
+
+// Uint64KeyCodec a KeyCodec for keys that are the decimal representation of an unsigned integer,
+// they are stored as 8 bytes big-endian so that they are scanned in numeric order.
+// prefix scans don't make sense with it, the prefix is encoded as a whole key
+type Uint64KeyCodec struct{}
+
+// EncodeKey implements KeyCodec.EncodeKey
+func (Uint64KeyCodec) EncodeKey(k []byte) ([]byte, error) {
+	n, err := strconv.ParseUint(string(k), 10, 64)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	encoded := make([]byte, 8)
+	binary.BigEndian.PutUint64(encoded, n)
+
+	return encoded, nil
+}
+
+// DecodeKey implements KeyCodec.DecodeKey
+func (Uint64KeyCodec) DecodeKey(k []byte) ([]byte, error) {
+	if len(k) != 8 {
+		return nil, ErrInvalidKey
+	}
+
+	return strconv.AppendUint(nil, binary.BigEndian.Uint64(k), 10), nil
+}
+
+// codecProvider a provider that encodes its keys using a KeyCodec
+type codecProvider struct {
+	p     Provider
+	codec KeyCodec
+}
+
+// WithKeyCodec returns a provider that encodes the keys using the specified codec before they reach p,
+// the same codec must always be used with the same data, changing it on existing data is unsafe
+// as the stored keys won't be found or decoded anymore
+func WithKeyCodec(p Provider, codec KeyCodec) Provider {
+	return &codecProvider{
+		p:     p,
+		codec: codec,
+	}
+}
+
+// entry returns a copy of the specified entry using the encoded key
+func (c *codecProvider) entry(e *Entry) (*Entry, error) {
+	k, err := c.codec.EncodeKey(e.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Entry{
+		Key:      k,
+		Value:    e.Value,
+		TTL:      e.TTL,
+		ExpireAt: e.ExpireAt,
+	}, nil
+}
+
+// Open implements goukv.Open, use goukv.Open with the "key_codec" option instead
+func (c *codecProvider) Open(map[string]interface{}) (Provider, error) {
+	return nil, ErrNotSupported
+}
+
+// Put implements goukv.Put
+func (c *codecProvider) Put(e *Entry) error {
+	e, err := c.entry(e)
+	if err != nil {
+		return err
+	}
+	return c.p.Put(e)
+}
+
+// Get implements goukv.Get
+func (c *codecProvider) Get(k []byte) ([]byte, error) {
+	k, err := c.codec.EncodeKey(k)
+	if err != nil {
+		return nil, err
+	}
+	return c.p.Get(k)
+}
+
+// TTL implements goukv.TTL
+func (c *codecProvider) TTL(k []byte) (*time.Time, error) {
+	k, err := c.codec.EncodeKey(k)
+	if err != nil {
+		return nil, err
+	}
+	return c.p.TTL(k)
+}
+
+// Delete implements goukv.Delete
+func (c *codecProvider) Delete(k []byte) error {
+	k, err := c.codec.EncodeKey(k)
+	if err != nil {
+		return err
+	}
+	return c.p.Delete(k)
+}
+
+// Batch implements goukv.Batch
+func (c *codecProvider) Batch(entries []*Entry) error {
+	encoded := make([]*Entry, len(entries))
+	for i, e := range entries {
+		e, err := c.entry(e)
+		if err != nil {
+			return err
+		}
+		encoded[i] = e
+	}
+	return c.p.Batch(encoded)
+}
+
+// Scan implements goukv.Scan, the prefix and the offset are encoded and the scanned keys are decoded
+func (c *codecProvider) Scan(opts ScanOpts) error {
+	if opts.Scanner == nil {
+		return ErrNoScanner
+	}
+
+	var err error
+	if len(opts.Prefix) > 0 {
+		if opts.Prefix, err = c.codec.EncodeKey(opts.Prefix); err != nil {
+			return err
+		}
+	}
+
+	if opts.Offset != nil {
+		if opts.Offset, err = c.codec.EncodeKey(opts.Offset); err != nil {
+			return err
+		}
+	}
+
+	scanner := opts.Scanner
+	opts.Scanner = func(k, v []byte) error {
+		k, err := c.codec.DecodeKey(k)
+		if err != nil {
+			return err
+		}
+		return scanner(k, v)
+	}
+
+	// the filters (and the sample) see the decoded keys, a key that can't be decoded is passed to the scanner to fail the scan
+	opts = opts.Sampled()
+	if keyFilter := opts.KeyFilter; keyFilter != nil {
+		opts.KeyFilter = func(k []byte) bool {
+			k, err := c.codec.DecodeKey(k)
+			return err != nil || keyFilter(k)
+		}
+	}
+
+	if filter := opts.Filter; filter != nil {
+		opts.Filter = func(k, v []byte) bool {
+			k, err := c.codec.DecodeKey(k)
+			return err != nil || filter(k, v)
+		}
+	}
+
+	// a key that can't be decoded is passed as is
+	if onError := opts.OnScannerError; onError != nil {
+		opts.OnScannerError = func(k []byte, err error) bool {
+			if decoded, decodeErr := c.codec.DecodeKey(k); decodeErr == nil {
+				k = decoded
+			}
+			return onError(k, err)
+		}
+	}
+
+	return c.p.Scan(opts)
+}
+
+// Close implements goukv.Close
+func (c *codecProvider) Close() error {
+	return c.p.Close()
+}
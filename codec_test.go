@@ -0,0 +1,44 @@
+package goukv_test
+
+import (
+	"testing"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestKeyCodec(t *testing.T) {
+	db, cleanup := openTempDB(t, "goleveldb", map[string]interface{}{
+		"key_codec": goukv.Uint64KeyCodec{},
+	})
+	defer cleanup()
+
+	for _, k := range []string{"100", "2", "10"} {
+		if err := db.Put(&goukv.Entry{Key: []byte(k), Value: []byte("v" + k)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	val, err := db.Get([]byte("10"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(val) != "v10" {
+		t.Errorf("expected (v10), found (%s)", val)
+	}
+
+	var keys []string
+	err = db.Scan(goukv.ScanOpts{Scanner: func(k, v []byte) error {
+		keys = append(keys, string(k))
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 || keys[0] != "2" || keys[1] != "10" || keys[2] != "100" {
+		t.Errorf("expected the keys in numeric order, found (%v)", keys)
+	}
+
+	if err := db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")}); err != goukv.ErrInvalidKey {
+		t.Errorf("expected ErrInvalidKey, found (%v)", err)
+	}
+}
@@ -0,0 +1,270 @@
+package goukv
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// DedupPrefix the reserved prefix the deduplicated values (and their reference counts) are stored under,
+// it's also the header of the references stored under the keys (see IsDedupRef)
+var DedupPrefix = []byte("\x00goukv\x00dedup\x00")
+
+// dedupProvider a provider storing every distinct value once, the keys hold references to them
+type dedupProvider struct {
+	p       Provider
+	minSize int
+
+	// the writes hold lock exclusively since they update the reference counts, the reads hold it shared
+	// so a value can't be collected between the read of its reference and its own
+	lock *sync.RWMutex
+}
+
+// Dedup returns a provider storing the values of at least minSize bytes once per distinct content: such a value is
+// stored under its sha256 hash (an internal key under DedupPrefix) along with the number of keys referencing it,
+// the key itself only holds the reference (DedupPrefix followed by the hash), Get and Scan resolve the references
+// transparently and a value is deleted once its last key is deleted or overwritten.
+// the entries having an expiration are stored as is since their expiry wouldn't release the reference, the writes
+// are serialized and applied using a single Batch of p so it must be atomic (i.e: goleveldb, badgerdb), and every
+// write of the deduplicated keys must go through the returned provider or the reference counts become wrong
+func Dedup(p Provider, minSize int) Provider {
+	return &dedupProvider{
+		p:       p,
+		minSize: minSize,
+		lock:    &sync.RWMutex{},
+	}
+}
+
+// IsDedupRef whether the specified value is a reference to a deduplicated value
+func IsDedupRef(v []byte) bool {
+	return len(v) == len(DedupPrefix)+sha256.Size && bytes.HasPrefix(v, DedupPrefix)
+}
+
+// dedupKey returns the key of the specified kind ('v' the value, 'r' its reference count) of the specified hash
+func dedupKey(kind byte, hash string) []byte {
+	k := make([]byte, 0, len(DedupPrefix)+1+len(hash))
+	k = append(k, DedupPrefix...)
+	k = append(k, kind)
+	return append(k, hash...)
+}
+
+// dedupBatch the writes of a batch, the reference counts are loaded once and written at the end
+type dedupBatch struct {
+	d       *dedupProvider
+	writes  []*Entry
+	refs    map[string]uint64
+	loaded  map[string]uint64
+	values  map[string][]byte
+	current map[string]string
+}
+
+// ref returns the hash the specified key currently references as seen by the batch ("" if none)
+func (b *dedupBatch) ref(k []byte) (string, error) {
+	if hash, ok := b.current[string(k)]; ok {
+		return hash, nil
+	}
+
+	v, err := b.d.p.Get(k)
+	if err == ErrKeyNotFound || (err == nil && !IsDedupRef(v)) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(v[len(DedupPrefix):]), nil
+}
+
+// count returns the reference count of the specified hash as seen by the batch
+func (b *dedupBatch) count(hash string) (uint64, error) {
+	if n, ok := b.refs[hash]; ok {
+		return n, nil
+	}
+
+	v, err := b.d.p.Get(dedupKey('r', hash))
+	if err != nil && err != ErrKeyNotFound {
+		return 0, err
+	}
+
+	var n uint64
+	if len(v) == 8 {
+		n = binary.BigEndian.Uint64(v)
+	}
+
+	b.refs[hash], b.loaded[hash] = n, n
+
+	return n, nil
+}
+
+// add applies the specified entry to the batch
+func (b *dedupBatch) add(e *Entry) error {
+	old, err := b.ref(e.Key)
+	if err != nil {
+		return err
+	}
+
+	if old != "" {
+		n, err := b.count(old)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			b.refs[old] = n - 1
+		}
+	}
+
+	if e.Value == nil || len(e.Value) < b.d.minSize || e.TTL > 0 || e.ExpireAt != nil {
+		b.writes = append(b.writes, e)
+		b.current[string(e.Key)] = ""
+		return nil
+	}
+
+	sum := sha256.Sum256(e.Value)
+	hash := string(sum[:])
+
+	n, err := b.count(hash)
+	if err != nil {
+		return err
+	}
+
+	b.refs[hash] = n + 1
+	b.values[hash] = e.Value
+	b.current[string(e.Key)] = hash
+	b.writes = append(b.writes, &Entry{Key: e.Key, Value: append(append([]byte{}, DedupPrefix...), hash...)})
+
+	return nil
+}
+
+// entries returns the writes of the batch followed by the ones of the values and of their reference counts
+func (b *dedupBatch) entries() []*Entry {
+	entries := b.writes
+	for hash, n := range b.refs {
+		loaded := b.loaded[hash]
+		switch {
+		case n == loaded:
+		case n == 0:
+			entries = append(entries, &Entry{Key: dedupKey('v', hash)}, &Entry{Key: dedupKey('r', hash)})
+		default:
+			if loaded == 0 {
+				entries = append(entries, &Entry{Key: dedupKey('v', hash), Value: b.values[hash]})
+			}
+			count := make([]byte, 8)
+			binary.BigEndian.PutUint64(count, n)
+			entries = append(entries, &Entry{Key: dedupKey('r', hash), Value: count})
+		}
+	}
+	return entries
+}
+
+// resolve returns the value the specified stored value references, or the stored value itself if it isn't a reference
+func (d *dedupProvider) resolve(v []byte) ([]byte, error) {
+	if !IsDedupRef(v) {
+		return v, nil
+	}
+
+	return d.p.Get(dedupKey('v', string(v[len(DedupPrefix):])))
+}
+
+// Open implements goukv.Open, open the provider then wrap it using Dedup instead
+func (d *dedupProvider) Open(map[string]interface{}) (Provider, error) {
+	return nil, ErrNotSupported
+}
+
+// Put implements goukv.Put
+func (d *dedupProvider) Put(e *Entry) error {
+	return d.Batch([]*Entry{e})
+}
+
+// Delete implements goukv.Delete, the value is deleted too if it was the last key referencing it
+func (d *dedupProvider) Delete(k []byte) error {
+	return d.Batch([]*Entry{{Key: k}})
+}
+
+// Batch implements goukv.Batch, the entries and the reference count updates are written in a single batch
+func (d *dedupProvider) Batch(entries []*Entry) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	b := &dedupBatch{
+		d:       d,
+		refs:    map[string]uint64{},
+		loaded:  map[string]uint64{},
+		values:  map[string][]byte{},
+		current: map[string]string{},
+	}
+
+	for _, e := range entries {
+		if len(e.Key) == 0 {
+			return ErrEmptyKey
+		}
+
+		if err := b.add(e); err != nil {
+			return err
+		}
+	}
+
+	return d.p.Batch(b.entries())
+}
+
+// Get implements goukv.Get, a reference is resolved to its value
+func (d *dedupProvider) Get(k []byte) ([]byte, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	v, err := d.p.Get(k)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.resolve(v)
+}
+
+// TTL implements goukv.TTL
+func (d *dedupProvider) TTL(k []byte) (*time.Time, error) {
+	return d.p.TTL(k)
+}
+
+// Scan implements goukv.Scan, the references are resolved to their values (Filter and MaxBytes apply to them), a key
+// whose value has been collected meanwhile (it's been concurrently overwritten or deleted) is read again
+func (d *dedupProvider) Scan(opts ScanOpts) error {
+	if opts.Scanner == nil {
+		return ErrNoScanner
+	}
+
+	// the keys are filtered (and sampled) by p
+	outer := opts
+	outer.KeyFilter, outer.SampleRate = nil, 0
+	scanner := outer.BoundedScanner()
+
+	// the errors of the resolutions abort the scan
+	opts.MaxBytes, opts.Filter, opts.OnScannerError = 0, nil, nil
+	opts.Scanner = func(k, v []byte) error {
+		if v == nil {
+			return scanner(k, v)
+		}
+
+		resolved, err := d.resolve(v)
+		if err == ErrKeyNotFound {
+			resolved, err = d.Get(k)
+			if err == ErrKeyNotFound {
+				return nil
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return scanner(k, resolved)
+	}
+
+	return d.p.Scan(opts)
+}
+
+// Close implements goukv.Close
+func (d *dedupProvider) Close() error {
+	return d.p.Close()
+}
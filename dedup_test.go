@@ -0,0 +1,116 @@
+package goukv_test
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+// dedupRefs returns the stored reference count of the specified value, zero when it isn't stored
+func dedupRefs(t *testing.T, p goukv.Provider, v []byte) uint64 {
+	sum := sha256.Sum256(v)
+
+	count, err := p.Get(append(append(append([]byte{}, goukv.DedupPrefix...), 'r'), sum[:]...))
+	if err == goukv.ErrKeyNotFound {
+		if _, err := p.Get(append(append(append([]byte{}, goukv.DedupPrefix...), 'v'), sum[:]...)); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected the unreferenced value to be deleted, found (%v)", err)
+		}
+		return 0
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return binary.BigEndian.Uint64(count)
+}
+
+func TestDedup(t *testing.T) {
+	shared, other := []byte("a shared configuration value"), []byte("another configuration value")
+
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		p, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		db := goukv.Dedup(p, 8)
+
+		for _, k := range []string{"k1", "k2", "k3"} {
+			if err := db.Put(&goukv.Entry{Key: []byte(k), Value: shared}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if n := dedupRefs(t, p, shared); n != 3 {
+			t.Errorf("%s: expected (3) references, found (%d)", driver, n)
+		}
+
+		if v, _ := p.Get([]byte("k1")); !goukv.IsDedupRef(v) {
+			t.Errorf("%s: expected the key to hold a reference, found (%q)", driver, v)
+		}
+
+		if v, err := db.Get([]byte("k2")); err != nil || string(v) != string(shared) {
+			t.Errorf("%s: expected the reference to be resolved, found (%s, %v)", driver, v, err)
+		}
+
+		// putting the same value again doesn't add a reference
+		db.Put(&goukv.Entry{Key: []byte("k1"), Value: shared})
+		if n := dedupRefs(t, p, shared); n != 3 {
+			t.Errorf("%s: expected (3) references, found (%d)", driver, n)
+		}
+
+		// an overwrite moves the reference, the batch entries apply in order
+		err := db.Batch([]*goukv.Entry{
+			{Key: []byte("k3"), Value: other},
+			{Key: []byte("k4"), Value: other},
+			{Key: []byte("k4")},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n := dedupRefs(t, p, shared); n != 2 {
+			t.Errorf("%s: expected (2) references, found (%d)", driver, n)
+		}
+		if n := dedupRefs(t, p, other); n != 1 {
+			t.Errorf("%s: expected (1) reference, found (%d)", driver, n)
+		}
+
+		values := map[string]string{}
+		db.Scan(goukv.ScanOpts{Scanner: func(k, v []byte) error {
+			values[string(k)] = string(v)
+			return nil
+		}})
+		if len(values) != 3 || values["k1"] != string(shared) || values["k3"] != string(other) {
+			t.Errorf("%s: expected the scan to resolve the references, found (%v)", driver, values)
+		}
+
+		// the last delete collects the value
+		db.Delete([]byte("k1"))
+		if n := dedupRefs(t, p, shared); n != 1 {
+			t.Errorf("%s: expected (1) reference, found (%d)", driver, n)
+		}
+
+		db.Delete([]byte("k2"))
+		db.Put(&goukv.Entry{Key: []byte("k3"), Value: []byte("small")})
+		if n := dedupRefs(t, p, shared); n != 0 {
+			t.Errorf("%s: expected the value to be collected, found (%d) references", driver, n)
+		}
+		if n := dedupRefs(t, p, other); n != 0 {
+			t.Errorf("%s: expected the overwritten value to be collected, found (%d) references", driver, n)
+		}
+
+		// the small values and the expiring ones are stored as is
+		db.Put(&goukv.Entry{Key: []byte("expiring"), Value: shared, TTL: time.Hour})
+		for _, k := range []string{"k3", "expiring"} {
+			if v, _ := p.Get([]byte(k)); goukv.IsDedupRef(v) {
+				t.Errorf("%s: expected (%s) to be stored as is", driver, k)
+			}
+		}
+		if n := dedupRefs(t, p, shared); n != 0 {
+			t.Errorf("%s: expected no reference, found (%d)", driver, n)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package goukv
+
+import "bytes"
+
+// DiffKind how a key differs between two providers
+type DiffKind uint8
+
+// available diff kinds
+const (
+	DiffOnlyInA DiffKind = iota + 1
+	DiffOnlyInB
+	DiffValueDiffers
+)
+
+// String returns the name of the diff kind
+func (k DiffKind) String() string {
+	switch k {
+	case DiffOnlyInA:
+		return "only_in_a"
+	case DiffOnlyInB:
+		return "only_in_b"
+	case DiffValueDiffers:
+		return "value_differs"
+	}
+	return "unknown"
+}
+
+// Diff calls fn with every live key under the specified prefix that differs between a and b (in key order),
+// it's a merge-join of two ordered iterations so only the current pair of each side is kept in memory.
+// the values are compared as Scan returns them (expired keys and storage wrappers aren't visible) and the internal
+// keys (see IsInternalKey) are skipped, fn may return ErrScanDone to stop early.
+// the providers implementing Iterable are iterated directly, the others through their Scan
+func Diff(a, b Provider, prefix []byte, fn func(key []byte, kind DiffKind) error) error {
+	iterA, err := newProviderIterator(a, prefix)
+	if err != nil {
+		return err
+	}
+	defer iterA.Close()
+
+	iterB, err := newProviderIterator(b, prefix)
+	if err != nil {
+		return err
+	}
+	defer iterB.Close()
+
+	okA, okB := nextExternal(iterA), nextExternal(iterB)
+	for okA || okB {
+		var key []byte
+		var kind DiffKind
+
+		cmp := 0
+		if !okA {
+			cmp = 1
+		} else if !okB {
+			cmp = -1
+		} else {
+			cmp = bytes.Compare(iterA.Key(), iterB.Key())
+		}
+
+		switch {
+		case cmp < 0:
+			key, kind = iterA.Key(), DiffOnlyInA
+			okA = nextExternal(iterA)
+		case cmp > 0:
+			key, kind = iterB.Key(), DiffOnlyInB
+			okB = nextExternal(iterB)
+		default:
+			if !bytes.Equal(iterA.Value(), iterB.Value()) {
+				key, kind = iterA.Key(), DiffValueDiffers
+			}
+			okA, okB = nextExternal(iterA), nextExternal(iterB)
+		}
+
+		if kind == 0 {
+			continue
+		}
+
+		if err := fn(key, kind); err != nil {
+			if err == ErrScanDone {
+				return nil
+			}
+			return err
+		}
+	}
+
+	if err := iterA.Error(); err != nil {
+		return err
+	}
+
+	return iterB.Error()
+}
+
+// nextExternal moves the specified iterator to its next key that isn't an internal one
+func nextExternal(it Iterator) bool {
+	for it.Next() {
+		if !IsInternalKey(it.Key()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package goukv_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestDiff(t *testing.T) {
+	a, cleanupA := openTempDB(t, "badgerdb", nil)
+	defer cleanupA()
+
+	b, cleanupB := openTempDB(t, "goleveldb", nil)
+	defer cleanupB()
+
+	a.Batch([]*goukv.Entry{
+		{Key: []byte("p/same"), Value: []byte("v")},
+		{Key: []byte("p/only-a"), Value: []byte("v")},
+		{Key: []byte("p/differs"), Value: []byte("v1")},
+		{Key: []byte("p/expired"), Value: []byte("v"), TTL: time.Second},
+		{Key: []byte("q/only-a"), Value: []byte("v")},
+	})
+
+	b.Batch([]*goukv.Entry{
+		{Key: []byte("p/same"), Value: []byte("v")},
+		{Key: []byte("p/only-b"), Value: []byte("v")},
+		{Key: []byte("p/differs"), Value: []byte("v2")},
+		{Key: []byte("p/expired"), Value: []byte("v")},
+		{Key: []byte("p/z-only-b"), Value: []byte("v")},
+	})
+
+	time.Sleep(1100 * time.Millisecond)
+
+	expected := "[p/differs:value_differs p/expired:only_in_b p/only-a:only_in_a p/only-b:only_in_b p/z-only-b:only_in_b]"
+
+	// the stats wrapper hides Iterable, so the scan based iteration is used
+	scanned, _ := goukv.WithStats(a)
+
+	for name, pair := range map[string][2]goukv.Provider{"iterable": {a, b}, "scan": {scanned, b}} {
+		var diffs []string
+		err := goukv.Diff(pair[0], pair[1], []byte("p/"), func(key []byte, kind goukv.DiffKind) error {
+			diffs = append(diffs, string(key)+":"+kind.String())
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if fmt.Sprint(diffs) != expected {
+			t.Errorf("%s: expected %s, found %v", name, expected, diffs)
+		}
+
+		diffs = nil
+		err = goukv.Diff(pair[0], pair[1], []byte("p/"), func(key []byte, kind goukv.DiffKind) error {
+			diffs = append(diffs, string(key))
+			return goukv.ErrScanDone
+		})
+		if err != nil || len(diffs) != 1 {
+			t.Errorf("%s: expected the diff to stop after the first key, found (%v) (%v)", name, diffs, err)
+		}
+	}
+}
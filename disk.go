@@ -0,0 +1,171 @@
+package goukv
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// DiskWatchdog keeps the disk usage of a provider under a limit on a best-effort basis,
+// it's configured by the "max_disk_bytes", "disk_check_interval" and "on_disk_full" options
+type DiskWatchdog struct {
+	MaxBytes int64
+	Interval time.Duration
+	OnFull   func(used int64)
+}
+
+// NewDiskWatchdog builds a DiskWatchdog from the specified provider options, nil is returned when
+// "max_disk_bytes" (int64) isn't set, "disk_check_interval" defaults to a minute
+func NewDiskWatchdog(opts map[string]interface{}) *DiskWatchdog {
+	maxBytes, ok := opts["max_disk_bytes"].(int64)
+	if !ok || maxBytes <= 0 {
+		return nil
+	}
+
+	interval, ok := opts["disk_check_interval"].(time.Duration)
+	if !ok || interval <= 0 {
+		interval = time.Minute
+	}
+
+	onFull, _ := opts["on_disk_full"].(func(used int64))
+
+	return &DiskWatchdog{
+		MaxBytes: maxBytes,
+		Interval: interval,
+		OnFull:   onFull,
+	}
+}
+
+// Run checks the size of the specified dirs every interval until stop is closed, when it exceeds
+// MaxBytes reclaim is called (e.g. a gc or a compaction) then OnFull if it's still exceeded
+func (w *DiskWatchdog) Run(stop <-chan struct{}, dirs []string, reclaim func()) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		used, err := DirSize(dirs...)
+		if err != nil || used <= w.MaxBytes {
+			continue
+		}
+
+		reclaim()
+
+		if w.OnFull == nil {
+			continue
+		}
+
+		if used, err = DirSize(dirs...); err == nil && used > w.MaxBytes {
+			w.OnFull(used)
+		}
+	}
+}
+
+// DirSize returns the total size of the files under the specified dirs, a dir listed twice is counted once
+func DirSize(dirs ...string) (int64, error) {
+	var size int64
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+
+		err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				size += info.Size()
+			}
+			return nil
+		})
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return size, nil
+}
+
+// EmptyDir returns ErrDirNotEmpty if the specified directory exists and has any file
+func EmptyDir(dir string) error {
+	f, err := os.Open(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Readdirnames(1); err != io.EOF {
+		if err == nil {
+			return ErrDirNotEmpty
+		}
+		return err
+	}
+
+	return nil
+}
+
+// CreateDir creates the specified directory and its missing parents like os.MkdirAll, when sync is set the parent of
+// every created directory is fsynced (deepest first) so that the new entries survive a crash on the filesystems that
+// don't persist them otherwise, the directories can't be synced on windows so it's skipped there
+func CreateDir(path string, perm os.FileMode, sync bool) error {
+	path = filepath.Clean(path)
+
+	// the created directories, from the deepest one
+	var created []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil || !os.IsNotExist(err) {
+			break
+		}
+
+		created = append(created, dir)
+
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(path, perm); err != nil {
+		return err
+	}
+
+	if !sync || runtime.GOOS == "windows" {
+		return nil
+	}
+
+	for _, dir := range created {
+		if err := syncDir(filepath.Dir(dir)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncDir fsyncs the specified directory
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	err = f.Sync()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,60 @@
+package goukv_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestCreateDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goukv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "a", "b", "c")
+	for _, sync := range []bool{true, false} {
+		if err := goukv.CreateDir(path, 0700, sync); err != nil {
+			t.Fatalf("sync (%v): %v", sync, err)
+		}
+
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			t.Fatalf("sync (%v): expected the directory to be created, found (%v)", sync, err)
+		}
+	}
+}
+
+func TestSyncedDirSurvivesReopen(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		dir, err := ioutil.TempDir("", "goukv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		// the parents of the db are missing too
+		path := filepath.Join(dir, "data", "db")
+
+		db, err := goukv.Open(driver, map[string]interface{}{"path": path, "sync_writes": true})
+		if err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+
+		db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+		db.Close()
+
+		db, err = goukv.Open(driver, map[string]interface{}{"path": path, "error_if_missing": true})
+		if err != nil {
+			t.Fatalf("%s: expected the db directory to exist, found (%v)", driver, err)
+		}
+
+		if v, err := db.Get([]byte("k")); err != nil || string(v) != "v" {
+			t.Errorf("%s: expected the synced write, found (%s, %v)", driver, v, err)
+		}
+		db.Close()
+	}
+}
@@ -1,10 +1,160 @@
 package goukv
 
-import "time"
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
 
 // Entry represents a key - value pair
 type Entry struct {
 	Key   []byte
 	Value []byte
 	TTL   time.Duration
+
+	// ExpireAt an absolute expiration date, when set it wins over TTL, the entries read by GetEntry
+	// have it set (and TTL set to the remaining time) when they expire (see ExpiresAt)
+	ExpireAt *time.Time
+
+	// Version the version of the stored value as reported by GetEntry (see each provider), ignored on writes
+	Version uint64
+
+	// CreatedAt and UpdatedAt the times the key has been created and last written as reported by GetEntry
+	// when the provider tracks them (the "track_timestamps" option), zero otherwise, ignored on writes
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ExpiresAt returns the absolute expiration of the entry computed from whichever of ExpireAt (it wins) and TTL is set,
+// a TTL is relative to goukv.Now at the time of the call, nil means the entry doesn't expire. the providers compute
+// the expiration of the written entries with it and report it as ExpireAt on reads (i.e: GetEntry)
+func (e *Entry) ExpiresAt() *time.Time {
+	if e.ExpireAt != nil {
+		expires := *e.ExpireAt
+		return &expires
+	}
+
+	if e.TTL > 0 {
+		expires := Now().Add(e.TTL)
+		return &expires
+	}
+
+	return nil
+}
+
+// RemainingTTL returns the time left before the entry expires (from goukv.Now), zero when it doesn't expire and
+// a negative duration when it has already expired, write ExpireAt rather than it since a TTL <= 0 means no expiration
+func (e *Entry) RemainingTTL() time.Duration {
+	if e.ExpireAt != nil {
+		return e.ExpireAt.Sub(Now())
+	}
+
+	return e.TTL
+}
+
+// NewTTLJitter builds a func that randomly extends a ttl from the "ttl_jitter" option value,
+// a time.Duration extends it by up to that duration, a float64 extends it by up to that fraction of the ttl,
+// nil is returned for any other value.
+func NewTTLJitter(v interface{}) func(time.Duration) time.Duration {
+	switch jitter := v.(type) {
+	case time.Duration:
+		if jitter <= 0 {
+			return nil
+		}
+		return func(ttl time.Duration) time.Duration {
+			return ttl + time.Duration(rand.Int63n(int64(jitter)))
+		}
+	case float64:
+		if jitter <= 0 {
+			return nil
+		}
+		return func(ttl time.Duration) time.Duration {
+			if max := int64(float64(ttl) * jitter); max > 0 {
+				return ttl + time.Duration(rand.Int63n(max))
+			}
+			return ttl
+		}
+	}
+
+	return nil
+}
+
+// PrefixTTL the default TTL of the keys having a prefix (see the "prefix_ttls" option of the providers)
+type PrefixTTL struct {
+	Prefix []byte
+
+	// TTL zero means the keys don't expire, overriding the TTL of a shorter prefix
+	TTL time.Duration
+}
+
+// NewPrefixTTLs builds a func returning the default TTL of a key from the "prefix_ttls" option value ([]PrefixTTL):
+// the TTL of the longest prefix of the key, zero when none matches. the rules are looked up by prefix length (a map
+// lookup for every distinct length the key is long enough for) so their count barely matters, the first rule of a
+// prefix wins. nil is returned for an empty list or any other value
+func NewPrefixTTLs(v interface{}) func(key []byte) time.Duration {
+	rules, ok := v.([]PrefixTTL)
+	if !ok || len(rules) == 0 {
+		return nil
+	}
+
+	ttls, known := map[string]time.Duration{}, map[int]bool{}
+	var lengths []int
+	for _, rule := range rules {
+		if _, ok := ttls[string(rule.Prefix)]; ok {
+			continue
+		}
+
+		ttls[string(rule.Prefix)] = rule.TTL
+		if !known[len(rule.Prefix)] {
+			known[len(rule.Prefix)] = true
+			lengths = append(lengths, len(rule.Prefix))
+		}
+	}
+
+	// the longest prefixes are looked up first
+	sort.Sort(sort.Reverse(sort.IntSlice(lengths)))
+
+	return func(key []byte) time.Duration {
+		for _, n := range lengths {
+			if n > len(key) {
+				continue
+			}
+
+			if ttl, ok := ttls[string(key[:n])]; ok {
+				return ttl
+			}
+		}
+
+		return 0
+	}
+}
+
+// WithPrefixTTL returns a copy of the entry having the default TTL of its key (see NewPrefixTTLs) unless its TTL or
+// ExpireAt is set (an explicit TTL always wins, a negative one opts out of the default) or it's a delete (a nil value),
+// the entry itself is returned when there is nothing to apply
+func WithPrefixTTL(e *Entry, prefixTTLs func([]byte) time.Duration) *Entry {
+	if prefixTTLs == nil || e.TTL != 0 || e.ExpireAt != nil || e.Value == nil {
+		return e
+	}
+
+	ttl := prefixTTLs(e.Key)
+	if ttl <= 0 {
+		return e
+	}
+
+	prepared := *e
+	prepared.TTL = ttl
+
+	return &prepared
+}
+
+// SameExpiry reports whether both expirations are unset or equal within a second (the coarsest provider resolution)
+func SameExpiry(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	diff := a.Sub(*b)
+
+	return diff < time.Second && diff > -time.Second
 }
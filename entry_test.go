@@ -0,0 +1,145 @@
+package goukv_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestEntryExpiresAt(t *testing.T) {
+	defer (func() { goukv.Now = time.Now })()
+
+	now := time.Now()
+	goukv.Now = func() time.Time { return now }
+
+	at := now.Add(time.Minute)
+	cases := []struct {
+		entry     goukv.Entry
+		expires   *time.Time
+		remaining time.Duration
+	}{
+		{goukv.Entry{}, nil, 0},
+		{goukv.Entry{TTL: time.Hour}, timePtr(now.Add(time.Hour)), time.Hour},
+		{goukv.Entry{TTL: time.Hour, ExpireAt: &at}, &at, time.Minute},
+		{goukv.Entry{ExpireAt: timePtr(now.Add(-time.Second))}, timePtr(now.Add(-time.Second)), -time.Second},
+	}
+
+	for i, c := range cases {
+		expires := c.entry.ExpiresAt()
+		if (expires == nil) != (c.expires == nil) || (expires != nil && !expires.Equal(*c.expires)) {
+			t.Errorf("case %d: expected the expiration (%v), found (%v)", i, c.expires, expires)
+		}
+
+		if remaining := c.entry.RemainingTTL(); remaining != c.remaining {
+			t.Errorf("case %d: expected the remaining ttl (%v), found (%v)", i, c.remaining, remaining)
+		}
+	}
+
+	// the returned expiration is a copy
+	if expires := cases[2].entry.ExpiresAt(); expires == cases[2].entry.ExpireAt {
+		t.Error("expected a copy of ExpireAt")
+	}
+}
+
+func TestGetEntryExpiresAt(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		before := time.Now()
+		db.Put(&goukv.Entry{Key: []byte("ttl"), Value: []byte("v"), TTL: time.Hour})
+		db.Put(&goukv.Entry{Key: []byte("persistent"), Value: []byte("v")})
+
+		entry, err := db.(goukv.EntryGetter).GetEntry([]byte("ttl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// badger stores the expirations with a second precision
+		expires := entry.ExpiresAt()
+		if entry.ExpireAt == nil || expires == nil || expires.Before(before.Add(time.Hour-time.Second)) || expires.After(time.Now().Add(time.Hour)) {
+			t.Errorf("%s: expected an expiration in an hour, found (%v)", driver, entry.ExpireAt)
+		}
+
+		if remaining := entry.RemainingTTL(); remaining <= time.Hour-2*time.Second || remaining > time.Hour {
+			t.Errorf("%s: expected about an hour left, found (%v)", driver, remaining)
+		}
+
+		// the read entry keeps its expiration when it's written back
+		entry.Key = []byte("copy")
+		db.Put(entry)
+		if ttl, err := db.TTL([]byte("copy")); err != nil || !goukv.SameExpiry(ttl, entry.ExpireAt) {
+			t.Errorf("%s: expected the copy to expire at (%v), found (%v, %v)", driver, entry.ExpireAt, ttl, err)
+		}
+
+		entry, err = db.(goukv.EntryGetter).GetEntry([]byte("persistent"))
+		if err != nil || entry.ExpireAt != nil || entry.ExpiresAt() != nil || entry.RemainingTTL() != 0 {
+			t.Errorf("%s: expected no expiration, found (%+v, %v)", driver, entry, err)
+		}
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestPrefixTTLs(t *testing.T) {
+	rules := []goukv.PrefixTTL{
+		{Prefix: []byte("s"), TTL: time.Hour},
+		{Prefix: []byte("session:"), TTL: 30 * time.Minute},
+		{Prefix: []byte("session:admin:"), TTL: 5 * time.Minute},
+		{Prefix: []byte("session:audit:")},
+		{Prefix: []byte("session:"), TTL: time.Minute},
+	}
+
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, map[string]interface{}{"prefix_ttls": rules})
+		defer cleanup()
+
+		start := time.Now()
+
+		db.Put(&goukv.Entry{Key: []byte("session:1"), Value: []byte("v")})
+		db.Put(&goukv.Entry{Key: []byte("session:admin:1"), Value: []byte("v")})
+		db.Put(&goukv.Entry{Key: []byte("session:explicit"), Value: []byte("v"), TTL: 2 * time.Hour})
+		db.Put(&goukv.Entry{Key: []byte("session:none"), Value: []byte("v"), TTL: -1})
+		db.Batch([]*goukv.Entry{
+			{Key: []byte("session:audit:1"), Value: []byte("v")},
+			{Key: []byte("sx"), Value: []byte("v")},
+			{Key: []byte("other"), Value: []byte("v")},
+		})
+
+		// the longest prefix wins, the first rule of a prefix wins
+		expected := map[string]time.Duration{
+			"session:1":        30 * time.Minute,
+			"session:admin:1":  5 * time.Minute,
+			"session:explicit": 2 * time.Hour,
+			"session:none":     0,
+			"session:audit:1":  0,
+			"sx":               time.Hour,
+			"other":            0,
+		}
+
+		for k, ttl := range expected {
+			expires, err := db.TTL([]byte(k))
+			if err != nil {
+				t.Fatalf("%s: %v", driver, err)
+			}
+
+			if ttl == 0 {
+				if expires != nil {
+					t.Errorf("%s: expected (%s) not to expire, found (%v)", driver, k, expires)
+				}
+				continue
+			}
+
+			if expires == nil || !goukv.SameExpiry(expires, timePtr(start.Add(ttl))) {
+				t.Errorf("%s: expected (%s) to expire at (%v), found (%v)", driver, k, start.Add(ttl), expires)
+			}
+		}
+	}
+
+	if goukv.NewPrefixTTLs(nil) != nil || goukv.NewPrefixTTLs([]goukv.PrefixTTL{}) != nil {
+		t.Error("expected no default TTLs without rules")
+	}
+}
@@ -9,4 +9,95 @@ var (
 	ErrNoScanner           = errors.New("the scanner is required")
 	ErrScanDone            = errors.New("this scan has ended")
 	ErrKeyNotFound         = errors.New("the specified key couldn't be found")
+	ErrNotSupported        = errors.New("the requested operation isn't supported")
+	ErrTxnDone             = errors.New("the transaction has already been committed or discarded")
+	ErrInvalidKey          = errors.New("the specified key can't be handled by the key codec")
+	ErrEmptyKey            = errors.New("the key is required")
+	ErrKeyTooLarge         = errors.New("the key exceeds the maximum key size")
+	ErrValueTooLarge       = errors.New("the value exceeds the maximum value size")
+	ErrDBNotFound          = errors.New("the specified database doesn't exist")
+	ErrDirNotEmpty         = errors.New("the specified directory isn't empty")
+	ErrOverlappingPrefixes = errors.New("the specified prefixes overlap")
+	ErrClosed              = errors.New("the provider is closed")
+	ErrMoveMismatch        = errors.New("the destination of the move doesn't hold the moved value")
+
+	// ErrConflict a transaction conflicts with a concurrent write, the whole transaction should be retried,
+	// every error of the ErrorConflict kind matches it (errors.Is) whatever its backend error is
+	ErrConflict = errors.New("the transaction conflicts with a concurrent write, retry it")
+
+	// ErrCorrupted the stored data is corrupted (i.e: found by the "verify_on_open" option of a provider),
+	// every error of the ErrorCorruption kind matches it (errors.Is) whatever its backend error is
+	ErrCorrupted = errors.New("the stored data is corrupted")
 )
+
+// ErrorKind the category of a backend error
+type ErrorKind uint8
+
+// available error kinds
+const (
+	// ErrorFatal the operation can't succeed as is (i.e the db is closed or misused)
+	ErrorFatal ErrorKind = iota + 1
+	// ErrorConflict the operation conflicts with a concurrent one, retrying it may succeed
+	ErrorConflict
+	// ErrorCorruption the stored data is corrupted
+	ErrorCorruption
+	// ErrorTransient the backend is temporarily unable to serve the operation, retrying it later may succeed
+	ErrorTransient
+)
+
+// String implements fmt.Stringer
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorFatal:
+		return "fatal"
+	case ErrorConflict:
+		return "conflict"
+	case ErrorCorruption:
+		return "corruption"
+	case ErrorTransient:
+		return "transient"
+	}
+	return "unknown"
+}
+
+// Error a backend error classified by a provider, the backend error is kept as is (see errors.Unwrap)
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+// WrapError wraps the specified backend error in an Error of the specified kind, nil stays nil
+// and an already classified error is returned unchanged
+func WrapError(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+
+	return &Error{Kind: kind, Err: err}
+}
+
+// IsErrorKind whether the specified error (or any error it wraps) is an Error of the specified kind
+func IsErrorKind(err error, kind ErrorKind) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Kind == kind
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	return e.Kind.String() + ": " + e.Err.Error()
+}
+
+// Is makes the errors of the ErrorConflict kind match ErrConflict and the ones of the ErrorCorruption kind match
+// ErrCorrupted (see errors.Is)
+func (e *Error) Is(target error) bool {
+	return (target == ErrConflict && e.Kind == ErrorConflict) || (target == ErrCorrupted && e.Kind == ErrorCorruption)
+}
+
+// Unwrap returns the backend error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,41 @@
+package goukv
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// Fingerprint returns a stable hash (SHA-256) of the live keys under the specified prefix and their values,
+// the pairs are scanned in key order and hashed length-prefixed, so that logically identical stores produce
+// the same fingerprint regardless of their backend (expired keys and storage wrappers aren't visible to Scan).
+// the internal keys (see IsInternalKey) aren't part of the fingerprint, use the Keyspace itself to fingerprint a keyspace
+func Fingerprint(p Provider, prefix []byte) ([]byte, error) {
+	h := sha256.New()
+	size := make([]byte, 8)
+
+	err := p.Scan(ScanOpts{
+		Prefix:     prefix,
+		Consistent: true,
+		Scanner: func(k, v []byte) error {
+			if IsInternalKey(k) {
+				return nil
+			}
+
+			binary.BigEndian.PutUint64(size, uint64(len(k)))
+			h.Write(size)
+			h.Write(k)
+
+			binary.BigEndian.PutUint64(size, uint64(len(v)))
+			h.Write(size)
+			h.Write(v)
+
+			return nil
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
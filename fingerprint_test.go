@@ -0,0 +1,42 @@
+package goukv_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestFingerprint(t *testing.T) {
+	var fingerprints [][]byte
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, map[string]interface{}{"enable_changelog": true})
+		defer cleanup()
+
+		for i := 0; i < 100; i++ {
+			db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%03d", i)), Value: []byte(fmt.Sprintf("v%03d", i)), TTL: time.Hour})
+		}
+		db.Put(&goukv.Entry{Key: []byte("expired"), Value: []byte("v"), TTL: time.Millisecond})
+		time.Sleep(10 * time.Millisecond)
+
+		fingerprint, err := goukv.Fingerprint(db, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+		fingerprints = append(fingerprints, fingerprint)
+	}
+
+	if !bytes.Equal(fingerprints[0], fingerprints[1]) {
+		t.Errorf("expected equal fingerprints, found (%x) and (%x)", fingerprints[0], fingerprints[1])
+	}
+
+	db, cleanup := openTempDB(t, "goleveldb", nil)
+	defer cleanup()
+
+	db.Put(&goukv.Entry{Key: []byte("k000"), Value: []byte("changed")})
+	if fingerprint, _ := goukv.Fingerprint(db, nil); bytes.Equal(fingerprint, fingerprints[0]) {
+		t.Error("expected different data to produce a different fingerprint")
+	}
+}
@@ -0,0 +1,36 @@
+package goukv
+
+import "math/bits"
+
+// Histogram the distribution of the key lengths and value sizes of a store,
+// the buckets are powers of two, bucket i counts the sizes in [2^(i-1), 2^i) and bucket 0 the empty ones
+type Histogram struct {
+	KeySizes   []int64
+	ValueSizes []int64
+}
+
+// Add records a key of the specified length holding a value of the specified size
+func (h *Histogram) Add(keySize, valueSize int64) {
+	h.KeySizes = addToBucket(h.KeySizes, keySize)
+	h.ValueSizes = addToBucket(h.ValueSizes, valueSize)
+}
+
+// BucketUpperBound returns the exclusive upper bound of the specified bucket
+func BucketUpperBound(i int) int64 {
+	return int64(1) << uint(i)
+}
+
+// addToBucket increments the bucket of the specified size, growing the buckets as needed
+func addToBucket(buckets []int64, size int64) []int64 {
+	if size < 0 {
+		size = 0
+	}
+
+	i := bits.Len64(uint64(size))
+	for len(buckets) <= i {
+		buckets = append(buckets, 0)
+	}
+	buckets[i]++
+
+	return buckets
+}
@@ -0,0 +1,173 @@
+package goukv
+
+import (
+	"encoding/binary"
+
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+// IndexPrefix the reserved prefix the secondary indexes are stored under
+var IndexPrefix = []byte("\x00goukv\x00index\x00")
+
+// the kinds of the keys of an index
+const (
+	indexEntryKey byte = iota + 1
+	indexFieldsKey
+)
+
+// Index a secondary index over the primary keys (ids) of a provider, each id has a value stored under the id itself
+// and fields whose values point back to it, the index keys are stored under the reserved IndexPrefix so they
+// are internal keys (see IsInternalKey), the writes are atomic so it requires a provider implementing Transactional
+type Index struct {
+	p      Provider
+	txns   Transactional
+	prefix []byte
+}
+
+// NewIndex returns the index of the specified name over p, ErrNotSupported is returned when p doesn't implement Transactional
+func NewIndex(p Provider, name string) (*Index, error) {
+	txns, ok := p.(Transactional)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	prefix := appendLengthPrefixed(append([]byte{}, IndexPrefix...), []byte(name))
+
+	return &Index{
+		p:      p,
+		txns:   txns,
+		prefix: prefix,
+	}, nil
+}
+
+// appendLengthPrefixed appends the uvarint length of b then b to dst, so that no appended value is a prefix of another one
+func appendLengthPrefixed(dst, b []byte) []byte {
+	size := make([]byte, binary.MaxVarintLen64)
+	dst = append(dst, size[:binary.PutUvarint(size, uint64(len(b)))]...)
+	return append(dst, b...)
+}
+
+// entriesPrefix returns the prefix of the index keys of the ids having the specified field value
+func (idx *Index) entriesPrefix(field, value []byte) []byte {
+	k := append(append([]byte{}, idx.prefix...), indexEntryKey)
+	k = appendLengthPrefixed(k, field)
+	return appendLengthPrefixed(k, value)
+}
+
+// fieldsKey returns the key the indexed fields of the specified id are stored under
+func (idx *Index) fieldsKey(id []byte) []byte {
+	k := append(append([]byte{}, idx.prefix...), indexFieldsKey)
+	return append(k, id...)
+}
+
+// update runs fn in a transaction then commits it, the whole transaction is retried when it conflicts
+func (idx *Index) update(fn func(txn Txn) error) error {
+	for {
+		txn, err := idx.txns.NewTxn()
+		if err != nil {
+			return err
+		}
+
+		if err := fn(txn); err != nil {
+			txn.Discard()
+			return err
+		}
+
+		err = txn.Commit()
+		if IsErrorKind(err, ErrorConflict) {
+			continue
+		}
+
+		return err
+	}
+}
+
+// unindex deletes the index keys of the fields the specified id was indexed with, it returns whether there were any
+func (idx *Index) unindex(txn Txn, id []byte) (bool, error) {
+	b, err := txn.Get(idx.fieldsKey(id))
+	if err == ErrKeyNotFound {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	var fields map[string][]byte
+	if err := msgpack.Unmarshal(b, &fields); err != nil {
+		return false, err
+	}
+
+	for field, value := range fields {
+		if err := txn.Delete(append(idx.entriesPrefix([]byte(field), value), id...)); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// IndexPut writes the value of the specified id and indexes it by the specified fields atomically,
+// the index keys of the fields it was previously indexed with are removed in the same transaction
+func (idx *Index) IndexPut(id, primaryVal []byte, fields map[string][]byte) error {
+	if len(id) == 0 {
+		return ErrEmptyKey
+	}
+
+	b, err := msgpack.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	return idx.update(func(txn Txn) error {
+		if _, err := idx.unindex(txn, id); err != nil {
+			return err
+		}
+
+		for field, value := range fields {
+			if err := txn.Put(&Entry{Key: append(idx.entriesPrefix([]byte(field), value), id...), Value: []byte{}}); err != nil {
+				return err
+			}
+		}
+
+		if err := txn.Put(&Entry{Key: idx.fieldsKey(id), Value: b}); err != nil {
+			return err
+		}
+
+		return txn.Put(&Entry{Key: id, Value: primaryVal})
+	})
+}
+
+// IndexDelete deletes the value of the specified id and its index keys atomically
+func (idx *Index) IndexDelete(id []byte) error {
+	return idx.update(func(txn Txn) error {
+		indexed, err := idx.unindex(txn, id)
+		if err != nil {
+			return err
+		}
+
+		if indexed {
+			if err := txn.Delete(idx.fieldsKey(id)); err != nil {
+				return err
+			}
+		}
+
+		return txn.Delete(id)
+	})
+}
+
+// IndexQuery returns the ids whose specified field has the specified value, in id order
+func (idx *Index) IndexQuery(field, value []byte) ([][]byte, error) {
+	prefix := idx.entriesPrefix(field, value)
+
+	var ids [][]byte
+	err := idx.p.Scan(ScanOpts{
+		Prefix: prefix,
+		Scanner: func(k, _ []byte) error {
+			ids = append(ids, append([]byte{}, k[len(prefix):]...))
+			return nil
+		},
+	})
+
+	return ids, err
+}
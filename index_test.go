@@ -0,0 +1,118 @@
+package goukv_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/alash3al/goukv"
+)
+
+// queryIndex returns the ids matching the specified field value as a string
+func queryIndex(t *testing.T, idx *goukv.Index, field, value string) string {
+	ids, err := idx.IndexQuery([]byte(field), []byte(value))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = string(id)
+	}
+
+	return fmt.Sprint(names)
+}
+
+func TestIndex(t *testing.T) {
+	db, cleanup := openTempDB(t, "goleveldb", nil)
+	defer cleanup()
+
+	idx, err := goukv.NewIndex(db, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx.IndexPut([]byte("u1"), []byte("alice"), map[string][]byte{"city": []byte("paris"), "role": []byte("admin")})
+	idx.IndexPut([]byte("u2"), []byte("bob"), map[string][]byte{"city": []byte("paris")})
+
+	if ids := queryIndex(t, idx, "city", "paris"); ids != "[u1 u2]" {
+		t.Errorf("expected [u1 u2], found %s", ids)
+	}
+
+	idx.IndexPut([]byte("u1"), []byte("alice2"), map[string][]byte{"city": []byte("berlin"), "role": []byte("admin")})
+
+	if ids := queryIndex(t, idx, "city", "paris"); ids != "[u2]" {
+		t.Errorf("expected the stale entry of u1 to be removed, found %s", ids)
+	}
+
+	if ids := queryIndex(t, idx, "city", "berlin"); ids != "[u1]" {
+		t.Errorf("expected [u1], found %s", ids)
+	}
+
+	if ids := queryIndex(t, idx, "role", "admin"); ids != "[u1]" {
+		t.Errorf("expected [u1], found %s", ids)
+	}
+
+	if val, err := db.Get([]byte("u1")); err != nil || string(val) != "alice2" {
+		t.Errorf("expected the primary value (alice2), found (%s) (%v)", val, err)
+	}
+
+	idx.IndexDelete([]byte("u2"))
+
+	if ids := queryIndex(t, idx, "city", "paris"); ids != "[]" {
+		t.Errorf("expected no ids, found %s", ids)
+	}
+
+	if _, err := db.Get([]byte("u2")); err != goukv.ErrKeyNotFound {
+		t.Errorf("expected the primary key to be deleted, found (%v)", err)
+	}
+
+	other, _ := goukv.NewIndex(db, "users2")
+	if ids := queryIndex(t, other, "city", "berlin"); ids != "[]" {
+		t.Errorf("expected the indexes to be isolated, found %s", ids)
+	}
+
+	plain, cleanupPlain := openTempDB(t, "badgerdb", nil)
+	defer cleanupPlain()
+
+	if _, err := goukv.NewIndex(plain, "users"); err != goukv.ErrNotSupported {
+		t.Errorf("expected ErrNotSupported without transactions, found (%v)", err)
+	}
+}
+
+func TestIndexConcurrentUpdates(t *testing.T) {
+	db, cleanup := openTempDB(t, "goleveldb", map[string]interface{}{"txn_isolation": "snapshot"})
+	defer cleanup()
+
+	idx, _ := goukv.NewIndex(db, "users")
+
+	cities := []string{"paris", "berlin", "rome", "madrid"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		city := cities[i%len(cities)]
+
+		wg.Add(1)
+		go (func() {
+			defer wg.Done()
+
+			if err := idx.IndexPut([]byte("u1"), []byte(city), map[string][]byte{"city": []byte(city)}); err != nil {
+				t.Error(err)
+			}
+		})()
+	}
+	wg.Wait()
+
+	val, _ := db.Get([]byte("u1"))
+
+	for _, city := range cities {
+		expected := "[]"
+		if city == string(val) {
+			expected = "[u1]"
+		}
+
+		if ids := queryIndex(t, idx, "city", city); ids != expected {
+			t.Errorf("%s: expected %s, found %s", city, expected, ids)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Package wire the protocol spoken between goukv.Server and the remote provider, every message is a frame:
+// its size (a big-endian uint32) followed by its msgpack encoding. a connection serves one request at a time,
+// every request is answered by a single response except the scans which are answered by a response per
+// scanned pair followed by a final one (Done), a client stops a scan early by closing the connection.
+package wire
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+// MaxFrameSize the maximum size of a frame, larger ones are rejected before being read (see ReadLimit)
+const MaxFrameSize = 1 << 30
+
+// ErrFrameTooLarge returned when a frame exceeds MaxFrameSize (or the limit of ReadLimit)
+var ErrFrameTooLarge = errors.New("wire: the frame exceeds the maximum frame size")
+
+// Op the operation of a request
+type Op uint8
+
+// available operations
+const (
+	OpPing Op = iota + 1
+	OpGet
+	OpPut
+	OpDelete
+	OpBatch
+	OpTTL
+	OpScan
+)
+
+// Entry a goukv.Entry, a nil value means delete within a batch
+type Entry struct {
+	Key      []byte        `msgpack:"k"`
+	Value    []byte        `msgpack:"v"`
+	TTL      time.Duration `msgpack:"t,omitempty"`
+	ExpireAt *time.Time    `msgpack:"e,omitempty"`
+}
+
+// Scan the goukv.ScanOpts of a scan (without its scanner)
+type Scan struct {
+	Prefix            []byte `msgpack:"p,omitempty"`
+	Offset            []byte `msgpack:"o,omitempty"`
+	IncludeOffset     bool   `msgpack:"io,omitempty"`
+	ReverseScan       bool   `msgpack:"r,omitempty"`
+	Consistent        bool   `msgpack:"c,omitempty"`
+	SinceVersion      uint64 `msgpack:"sv,omitempty"`
+	IncludeTombstones bool   `msgpack:"it,omitempty"`
+	MaxBytes          int64  `msgpack:"mb,omitempty"`
+	IncludeInternal   bool   `msgpack:"ii,omitempty"`
+
+	// ProgressInterval when set the progress of the scan is reported (see Response.Scanned)
+	ProgressInterval int64 `msgpack:"pi,omitempty"`
+}
+
+// Request a request sent by a client
+type Request struct {
+	Op      Op       `msgpack:"op"`
+	Key     []byte   `msgpack:"k,omitempty"`
+	Entries []*Entry `msgpack:"es,omitempty"`
+	Scan    *Scan    `msgpack:"s,omitempty"`
+}
+
+// Response the response to a request, Err is the message of the error the request failed with (if any) and ErrKind
+// its goukv.ErrorKind when it was classified, a scan is answered by a response per pair (or progress report)
+// then a Done one
+type Response struct {
+	Err     string     `msgpack:"err,omitempty"`
+	ErrKind uint8      `msgpack:"ek,omitempty"`
+	Key     []byte     `msgpack:"k,omitempty"`
+	Value   []byte     `msgpack:"v"`
+	Expires *time.Time `msgpack:"e,omitempty"`
+	Done    bool       `msgpack:"d,omitempty"`
+
+	// Scanned a progress report of a scan (the number of keys iterated so far), it carries no pair
+	Scanned int64 `msgpack:"sc,omitempty"`
+
+	// Close the server closes the connection after this response (i.e: a too large request or a panic)
+	Close bool `msgpack:"cl,omitempty"`
+}
+
+// Write writes the frame of the specified message to w
+func Write(w io.Writer, msg interface{}) error {
+	b, err := msgpack.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if len(b) > MaxFrameSize {
+		return ErrFrameTooLarge
+	}
+
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(b)))
+
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// Read reads the next frame from r into the specified message, see ReadLimit
+func Read(r *bufio.Reader, msg interface{}) error {
+	return ReadLimit(r, msg, MaxFrameSize)
+}
+
+// ReadLimit reads the next frame from r into the specified message, a frame larger than limit (or MaxFrameSize) is
+// rejected before its body is read. the body is buffered as it arrives rather than allocated from the size the peer
+// announced, so a peer can't make the reader allocate more than it actually sent
+func ReadLimit(r *bufio.Reader, msg interface{}, limit int) error {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return err
+	}
+
+	n := binary.BigEndian.Uint32(size[:])
+	if n > MaxFrameSize || int64(n) > int64(limit) {
+		return ErrFrameTooLarge
+	}
+
+	var b bytes.Buffer
+	if _, err := io.CopyN(&b, r, int64(n)); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	return msgpack.Unmarshal(b.Bytes(), msg)
+}
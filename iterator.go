@@ -0,0 +1,126 @@
+package goukv
+
+// Iterator walks the live keys of a provider in order (or in reverse order), it must be closed once done.
+// Key and Value return copies of the entry the iterator is positioned on
+type Iterator interface {
+	// Next moves to the next entry (to the first one on the first call) and reports whether there is one
+	Next() bool
+
+	// Seek moves to the first entry >= key (<= key when iterating in reverse) and reports whether there is one,
+	// the following Next calls continue from there
+	Seek(key []byte) bool
+
+	Key() []byte
+	Value() []byte
+	Error() error
+	Close() error
+}
+
+// Iterable an optional interface for providers that can expose an Iterator over the keys under a prefix,
+// the internal keys are skipped like Scan does unless the prefix is internal itself (see ScanOpts.SkipsInternal)
+type Iterable interface {
+	NewIterator(prefix []byte, reverse bool) (Iterator, error)
+}
+
+// newProviderIterator returns a forward Iterator over the keys of p under the specified prefix,
+// using Iterable when p implements it and its Scan otherwise (see scanIterator)
+func newProviderIterator(p Provider, prefix []byte) (Iterator, error) {
+	if iterable, ok := p.(Iterable); ok {
+		return iterable.NewIterator(prefix, false)
+	}
+
+	return newScanIterator(p, prefix), nil
+}
+
+// scanPair a key/value pair handed over by a scan
+type scanPair struct {
+	key, value []byte
+}
+
+// scanIterator a forward Iterator pulling the pairs of a Scan running in its own goroutine one at a time,
+// Seek isn't supported
+type scanIterator struct {
+	pairs   chan scanPair
+	errs    chan error
+	stop    chan struct{}
+	current scanPair
+	ended   bool
+	err     error
+}
+
+// newScanIterator starts scanning the keys of p under the specified prefix
+func newScanIterator(p Provider, prefix []byte) *scanIterator {
+	it := &scanIterator{
+		pairs: make(chan scanPair),
+		errs:  make(chan error, 1),
+		stop:  make(chan struct{}),
+	}
+
+	go (func() {
+		defer close(it.pairs)
+
+		it.errs <- p.Scan(ScanOpts{
+			Prefix: prefix,
+			Scanner: func(k, v []byte) error {
+				select {
+				case it.pairs <- scanPair{key: k, value: v}:
+					return nil
+				case <-it.stop:
+					return ErrScanDone
+				}
+			},
+		})
+	})()
+
+	return it
+}
+
+// Next implements Iterator.Next
+func (it *scanIterator) Next() bool {
+	if it.ended {
+		return false
+	}
+
+	pair, ok := <-it.pairs
+	if !ok {
+		if err := <-it.errs; err != nil {
+			it.err = err
+		}
+		it.current, it.ended = scanPair{}, true
+		return false
+	}
+
+	it.current = pair
+
+	return true
+}
+
+// Seek implements Iterator.Seek, it isn't supported by a scan so it stops the iteration
+func (it *scanIterator) Seek(key []byte) bool {
+	it.err = ErrNotSupported
+	return false
+}
+
+// Key implements Iterator.Key
+func (it *scanIterator) Key() []byte {
+	return append([]byte{}, it.current.key...)
+}
+
+// Value implements Iterator.Value
+func (it *scanIterator) Value() []byte {
+	return append([]byte{}, it.current.value...)
+}
+
+// Error implements Iterator.Error
+func (it *scanIterator) Error() error {
+	return it.err
+}
+
+// Close implements Iterator.Close, it stops the scan and waits for it to return
+func (it *scanIterator) Close() error {
+	close(it.stop)
+	for range it.pairs {
+	}
+
+	return nil
+}
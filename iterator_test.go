@@ -0,0 +1,103 @@
+package goukv_test
+
+import (
+	"testing"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestIteratorSeek(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		for _, k := range []string{"a", "p:a", "p:b", "p:c", "p:d", "p:e", "p:f", "q"} {
+			db.Put(&goukv.Entry{Key: []byte(k), Value: []byte("v" + k)})
+		}
+
+		expect := func(it goukv.Iterator, ok bool, key string) {
+			t.Helper()
+			if !ok {
+				t.Errorf("%s: expected (%s), found nothing", driver, key)
+				return
+			}
+			if string(it.Key()) != key || string(it.Value()) != "v"+key {
+				t.Errorf("%s: expected (%s), found (%s: %s)", driver, key, it.Key(), it.Value())
+			}
+		}
+
+		forward, err := db.(goukv.Iterable).NewIterator([]byte("p:"), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect(forward, forward.Next(), "p:a")
+		expect(forward, forward.Seek([]byte("p:d")), "p:d")
+		expect(forward, forward.Next(), "p:e")
+		expect(forward, forward.Seek([]byte("p:b")), "p:b")
+		if forward.Seek([]byte("p:z")) {
+			t.Errorf("%s: expected nothing after (p:z), found (%s)", driver, forward.Key())
+		}
+		forward.Close()
+
+		reverse, err := db.(goukv.Iterable).NewIterator([]byte("p:"), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect(reverse, reverse.Next(), "p:f")
+		expect(reverse, reverse.Seek([]byte("p:cc")), "p:c")
+		expect(reverse, reverse.Next(), "p:b")
+		expect(reverse, reverse.Seek([]byte("p:z")), "p:f")
+		if reverse.Seek([]byte("p:")) {
+			t.Errorf("%s: expected nothing before (p:), found (%s)", driver, reverse.Key())
+		}
+		reverse.Close()
+	}
+}
+
+func TestIteratorSkipsInternal(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, map[string]interface{}{"enable_changelog": true})
+		defer cleanup()
+
+		// "\x01" sorts right after the internal keys, "\x00" right before them
+		for _, k := range []string{"\x00", "\x01", "a"} {
+			db.Put(&goukv.Entry{Key: []byte(k), Value: []byte("v")})
+		}
+		db.(goukv.KeyspaceManager).Keyspace("space").Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+
+		for _, reverse := range []bool{false, true} {
+			it, err := db.(goukv.Iterable).NewIterator(nil, reverse)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var found []string
+			for ok := it.Next(); ok; ok = it.Next() {
+				if goukv.IsInternalKey(it.Key()) {
+					t.Errorf("%s: expected the internal keys to be skipped, found (%q)", driver, it.Key())
+				}
+				found = append(found, string(it.Key()))
+			}
+			if len(found) != 3 {
+				t.Errorf("%s: expected 3 keys (reverse: %v), found (%q)", driver, reverse, found)
+			}
+
+			// a seek into the internal keys lands past them
+			expected := "\x01"
+			if reverse {
+				expected = "\x00"
+			}
+			if !it.Seek(goukv.ChangelogPrefix) || string(it.Key()) != expected {
+				t.Errorf("%s: expected the seek to land on (%q) (reverse: %v)", driver, expected, reverse)
+			}
+			it.Close()
+		}
+
+		// the internal prefixes can be iterated explicitly
+		it, _ := db.(goukv.Iterable).NewIterator(goukv.ChangelogPrefix, false)
+		if !it.Next() {
+			t.Errorf("%s: expected the changelog to be iterable", driver)
+		}
+		it.Close()
+	}
+}
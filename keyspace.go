@@ -0,0 +1,135 @@
+package goukv
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// KeyspacePrefix the reserved prefix the keyspaces are stored under
+var KeyspacePrefix = []byte("\x00goukv\x00keyspace\x00")
+
+// KeyspaceManager an optional interface for providers that can host multiple logical keyspaces in a single handle
+type KeyspaceManager interface {
+	Keyspace(name string) Provider
+	DropKeyspace(name string) error
+}
+
+// KeyspaceKeyPrefix returns the prefix of the keys of the specified keyspace,
+// the name is length-prefixed so that no keyspace is a prefix of another one
+func KeyspaceKeyPrefix(name string) []byte {
+	prefix := make([]byte, len(KeyspacePrefix)+binary.MaxVarintLen64+len(name))
+	n := copy(prefix, KeyspacePrefix)
+	n += binary.PutUvarint(prefix[n:], uint64(len(name)))
+	n += copy(prefix[n:], name)
+	return prefix[:n]
+}
+
+// keyspace a provider that confines its operations to the keys of a keyspace of its parent
+type keyspace struct {
+	parent Provider
+	prefix []byte
+}
+
+// NewKeyspace returns a provider that transparently prefixes its keys with the ones of the specified keyspace,
+// it's meant to be used by the providers implementing KeyspaceManager, closing it doesn't close the parent
+func NewKeyspace(parent Provider, name string) Provider {
+	return &keyspace{
+		parent: parent,
+		prefix: KeyspaceKeyPrefix(name),
+	}
+}
+
+// key returns the parent key of the specified key
+func (ks *keyspace) key(k []byte) []byte {
+	pk := make([]byte, len(ks.prefix)+len(k))
+	copy(pk, ks.prefix)
+	copy(pk[len(ks.prefix):], k)
+	return pk
+}
+
+// entry returns a copy of the specified entry using the parent key
+func (ks *keyspace) entry(e *Entry) *Entry {
+	return &Entry{
+		Key:      ks.key(e.Key),
+		Value:    e.Value,
+		TTL:      e.TTL,
+		ExpireAt: e.ExpireAt,
+	}
+}
+
+// Open implements goukv.Open, keyspaces are obtained from their parent
+func (ks *keyspace) Open(map[string]interface{}) (Provider, error) {
+	return nil, ErrNotSupported
+}
+
+// Put implements goukv.Put
+func (ks *keyspace) Put(e *Entry) error {
+	return ks.parent.Put(ks.entry(e))
+}
+
+// Get implements goukv.Get
+func (ks *keyspace) Get(k []byte) ([]byte, error) {
+	return ks.parent.Get(ks.key(k))
+}
+
+// TTL implements goukv.TTL
+func (ks *keyspace) TTL(k []byte) (*time.Time, error) {
+	return ks.parent.TTL(ks.key(k))
+}
+
+// Delete implements goukv.Delete
+func (ks *keyspace) Delete(k []byte) error {
+	return ks.parent.Delete(ks.key(k))
+}
+
+// Batch implements goukv.Batch
+func (ks *keyspace) Batch(entries []*Entry) error {
+	prefixed := make([]*Entry, len(entries))
+	for i, e := range entries {
+		prefixed[i] = ks.entry(e)
+	}
+	return ks.parent.Batch(prefixed)
+}
+
+// Scan implements goukv.Scan, the keys passed to the scanner don't include the keyspace prefix
+func (ks *keyspace) Scan(opts ScanOpts) error {
+	if opts.Scanner == nil {
+		return ErrNoScanner
+	}
+
+	scanner := opts.Scanner
+	opts.Prefix = ks.key(opts.Prefix)
+	if opts.Offset != nil {
+		opts.Offset = ks.key(opts.Offset)
+	}
+	opts.Scanner = func(k, v []byte) error {
+		return scanner(k[len(ks.prefix):], v)
+	}
+
+	// the keys are sampled without the prefix
+	opts = opts.Sampled()
+	if keyFilter := opts.KeyFilter; keyFilter != nil {
+		opts.KeyFilter = func(k []byte) bool {
+			return keyFilter(k[len(ks.prefix):])
+		}
+	}
+
+	if filter := opts.Filter; filter != nil {
+		opts.Filter = func(k, v []byte) bool {
+			return filter(k[len(ks.prefix):], v)
+		}
+	}
+
+	if onError := opts.OnScannerError; onError != nil {
+		opts.OnScannerError = func(k []byte, err error) bool {
+			return onError(k[len(ks.prefix):], err)
+		}
+	}
+
+	return ks.parent.Scan(opts)
+}
+
+// Close implements goukv.Close, the parent is owned by the caller so it's left open
+func (ks *keyspace) Close() error {
+	return nil
+}
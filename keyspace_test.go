@@ -0,0 +1,51 @@
+package goukv_test
+
+import (
+	"testing"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestKeyspace(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		manager := db.(goukv.KeyspaceManager)
+		users, user := manager.Keyspace("users"), manager.Keyspace("user")
+
+		users.Put(&goukv.Entry{Key: []byte("k1"), Value: []byte("users")})
+		users.Put(&goukv.Entry{Key: []byte("k2"), Value: []byte("users")})
+		user.Put(&goukv.Entry{Key: []byte("k1"), Value: []byte("user")})
+		db.Put(&goukv.Entry{Key: []byte("k1"), Value: []byte("root")})
+
+		for ks, expected := range map[goukv.Provider]string{users: "users", user: "user", db: "root"} {
+			val, err := ks.Get([]byte("k1"))
+			if err != nil {
+				t.Fatalf("%s: %v", driver, err)
+			}
+			if string(val) != expected {
+				t.Errorf("%s: expected (%s), found (%s)", driver, expected, val)
+			}
+		}
+
+		var keys []string
+		users.Scan(goukv.ScanOpts{Scanner: func(k, v []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		}})
+		if len(keys) != 2 || keys[0] != "k1" || keys[1] != "k2" {
+			t.Errorf("%s: unexpected keyspace scan (%v)", driver, keys)
+		}
+
+		if err := manager.DropKeyspace("users"); err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+		if _, err := users.Get([]byte("k1")); err != goukv.ErrKeyNotFound {
+			t.Errorf("%s: expected the dropped keyspace to be empty, found (%v)", driver, err)
+		}
+		if _, err := user.Get([]byte("k1")); err != nil {
+			t.Errorf("%s: expected the other keyspaces to be kept, found (%v)", driver, err)
+		}
+	}
+}
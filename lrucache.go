@@ -0,0 +1,224 @@
+package goukv
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lruItem a cached value
+type lruItem struct {
+	key     string
+	value   []byte
+	expires *time.Time
+}
+
+// size the bytes the item accounts for
+func (item *lruItem) size() int64 {
+	return int64(len(item.key) + len(item.value))
+}
+
+// lruCache a provider caching the values of another one in memory
+type lruCache struct {
+	back       Provider
+	maxEntries int
+	maxBytes   int64
+
+	lock  *sync.Mutex
+	items map[string]*list.Element
+	order *list.List
+	bytes int64
+
+	// every write bumps generation and is pending until it's done, a value read from back (or written) is only cached
+	// when no write started meanwhile nor is still pending, so a slower concurrent write can't be hidden by it
+	generation uint64
+	pending    int
+}
+
+// NewLRUCache returns a provider caching up to maxEntries values (keys + values) of up to maxBytes (zero means
+// unbounded) of back in memory, the least recently used ones are evicted first. Get populates the cache from back on a
+// miss, the writes go to back then update the cache (Put) or drop the keys from it (Delete, Batch), Scan and TTL
+// bypass it. the cached values expire with the keys, ErrKeyNotFound isn't cached and closing it closes back
+func NewLRUCache(back Provider, maxEntries int, maxBytes int64) Provider {
+	return &lruCache{
+		back:       back,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		lock:       &sync.Mutex{},
+		items:      map[string]*list.Element{},
+		order:      list.New(),
+	}
+}
+
+// get returns the cached value of the specified key, an expired one is dropped
+func (c *lruCache) get(k []byte) ([]byte, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.items[string(k)]
+	if !ok {
+		return nil, false
+	}
+
+	item := elem.Value.(*lruItem)
+	if item.expires != nil && !Now().Before(*item.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+
+	return item.value, true
+}
+
+// add caches the specified value unless a write started since the specified generation or is still pending,
+// then evicts the least recently used values until the cache fits its bounds
+func (c *lruCache) add(generation uint64, k, v []byte, expires *time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if generation != c.generation || c.pending > 0 {
+		return
+	}
+
+	if elem, ok := c.items[string(k)]; ok {
+		c.remove(elem)
+	}
+
+	item := &lruItem{key: string(k), value: append([]byte{}, v...), expires: expires}
+	if c.maxBytes > 0 && item.size() > c.maxBytes {
+		return
+	}
+
+	c.items[item.key] = c.order.PushFront(item)
+	c.bytes += item.size()
+
+	for (c.maxEntries > 0 && c.order.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove drops the specified element, the caller must hold the lock
+func (c *lruCache) remove(elem *list.Element) {
+	item := c.order.Remove(elem).(*lruItem)
+	delete(c.items, item.key)
+	c.bytes -= item.size()
+}
+
+// startWrite marks a write as pending and returns its generation
+func (c *lruCache) startWrite() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.generation++
+	c.pending++
+
+	return c.generation
+}
+
+// endWrite marks a write as done dropping the specified keys from the cache
+func (c *lruCache) endWrite(keys ...[]byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.pending--
+
+	for _, k := range keys {
+		if elem, ok := c.items[string(k)]; ok {
+			c.remove(elem)
+		}
+	}
+}
+
+// Open implements goukv.Open, open back then wrap it using NewLRUCache instead
+func (c *lruCache) Open(map[string]interface{}) (Provider, error) {
+	return nil, ErrNotSupported
+}
+
+// Put implements goukv.Put, the written value is cached once the write is done along with the expiration read
+// from back, since back may set one the entry doesn't have (i.e: prefix_ttls, ttl_jitter)
+func (c *lruCache) Put(e *Entry) error {
+	generation := c.startWrite()
+	err := c.back.Put(e)
+	c.endWrite(e.Key)
+
+	if err != nil || e.Value == nil {
+		return err
+	}
+
+	if expires, err := c.back.TTL(e.Key); err == nil {
+		c.add(generation, e.Key, e.Value, expires)
+	}
+
+	return nil
+}
+
+// Get implements goukv.Get, a miss reads the value (and its expiration) from back and caches it,
+// a copy of the cached value is returned
+func (c *lruCache) Get(k []byte) ([]byte, error) {
+	if v, ok := c.get(k); ok {
+		return append([]byte{}, v...), nil
+	}
+
+	c.lock.Lock()
+	generation := c.generation
+	c.lock.Unlock()
+
+	var v []byte
+	var expires *time.Time
+	if getter, ok := c.back.(EntryGetter); ok {
+		entry, err := getter.GetEntry(k)
+		if err != nil {
+			return nil, err
+		}
+		v, expires = entry.Value, entry.ExpireAt
+	} else {
+		var err error
+		if v, err = c.back.Get(k); err != nil {
+			return nil, err
+		}
+		if expires, err = c.back.TTL(k); err != nil {
+			return nil, err
+		}
+	}
+
+	c.add(generation, k, v, expires)
+
+	return v, nil
+}
+
+// TTL implements goukv.TTL
+func (c *lruCache) TTL(k []byte) (*time.Time, error) {
+	return c.back.TTL(k)
+}
+
+// Delete implements goukv.Delete
+func (c *lruCache) Delete(k []byte) error {
+	c.startWrite()
+	defer c.endWrite(k)
+
+	return c.back.Delete(k)
+}
+
+// Batch implements goukv.Batch, the keys of the entries are dropped from the cache
+func (c *lruCache) Batch(entries []*Entry) error {
+	keys := make([][]byte, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+	}
+
+	c.startWrite()
+	defer c.endWrite(keys...)
+
+	return c.back.Batch(entries)
+}
+
+// Scan implements goukv.Scan, back is scanned directly
+func (c *lruCache) Scan(opts ScanOpts) error {
+	return c.back.Scan(opts)
+}
+
+// Close implements goukv.Close, it closes back
+func (c *lruCache) Close() error {
+	return c.back.Close()
+}
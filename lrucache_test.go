@@ -0,0 +1,203 @@
+package goukv_test
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestLRUCache(t *testing.T) {
+	back, cleanup := openTempDB(t, "goleveldb", nil)
+	defer cleanup()
+
+	back, stats := goukv.WithStats(back)
+	db := goukv.NewLRUCache(back, 3, 0)
+
+	for i := 1; i <= 5; i++ {
+		db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%d", i)), Value: []byte(fmt.Sprintf("v%d", i))})
+	}
+
+	// the written values are cached, k1 and k2 have been evicted
+	for _, k := range []string{"k3", "k4", "k5"} {
+		if v, err := db.Get([]byte(k)); err != nil || string(v) != "v"+k[1:] {
+			t.Errorf("expected (v%s), found (%s, %v)", k[1:], v, err)
+		}
+	}
+
+	if gets := stats.Gets(); gets != 0 {
+		t.Errorf("expected the cached keys not to be read from back, found (%d) gets", gets)
+	}
+
+	// an evicted key is read again from back, then cached evicting the least recently used one (k3)
+	if v, err := db.Get([]byte("k1")); err != nil || string(v) != "v1" {
+		t.Errorf("expected (v1), found (%s, %v)", v, err)
+	}
+
+	if gets := stats.Gets(); gets != 1 {
+		t.Errorf("expected the evicted key to be read from back, found (%d) gets", gets)
+	}
+
+	db.Get([]byte("k1"))
+	db.Get([]byte("k5"))
+	db.Get([]byte("k3"))
+
+	if gets := stats.Gets(); gets != 2 {
+		t.Errorf("expected only the least recently used key to be evicted, found (%d) gets", gets)
+	}
+
+	// the deletes and the batches drop the keys
+	db.Delete([]byte("k5"))
+	if _, err := db.Get([]byte("k5")); err != goukv.ErrKeyNotFound {
+		t.Errorf("expected the deleted key to be dropped, found (%v)", err)
+	}
+
+	db.Batch([]*goukv.Entry{{Key: []byte("k1"), Value: []byte("new")}})
+	if v, err := db.Get([]byte("k1")); err != nil || string(v) != "new" {
+		t.Errorf("expected the batch to be read, found (%s, %v)", v, err)
+	}
+
+	// the returned values are copies
+	v, _ := db.Get([]byte("k1"))
+	v[0] = 'x'
+	if v, _ := db.Get([]byte("k1")); string(v) != "new" {
+		t.Errorf("expected the cached value to be unchanged, found (%s)", v)
+	}
+
+	if _, err := db.Open(nil); err != goukv.ErrNotSupported {
+		t.Errorf("expected (%v), found (%v)", goukv.ErrNotSupported, err)
+	}
+}
+
+func TestLRUCacheMaxBytes(t *testing.T) {
+	back, cleanup := openTempDB(t, "goleveldb", nil)
+	defer cleanup()
+
+	back, stats := goukv.WithStats(back)
+
+	// 2 keys of 1 byte with values of 40 bytes
+	db := goukv.NewLRUCache(back, 0, 100)
+
+	value := bytes.Repeat([]byte("v"), 40)
+	for _, k := range []string{"a", "b", "c"} {
+		db.Put(&goukv.Entry{Key: []byte(k), Value: value})
+	}
+
+	db.Get([]byte("b"))
+	db.Get([]byte("c"))
+	if gets := stats.Gets(); gets != 0 {
+		t.Errorf("expected (b) and (c) to be cached, found (%d) gets", gets)
+	}
+
+	db.Get([]byte("a"))
+	if gets := stats.Gets(); gets != 1 {
+		t.Errorf("expected (a) to be evicted, found (%d) gets", gets)
+	}
+
+	// a value larger than the cache isn't cached
+	db.Put(&goukv.Entry{Key: []byte("large"), Value: bytes.Repeat([]byte("v"), 200)})
+	db.Get([]byte("large"))
+	if gets := stats.Gets(); gets != 2 {
+		t.Errorf("expected the large value to be read from back, found (%d) gets", gets)
+	}
+}
+
+func TestLRUCacheExpiration(t *testing.T) {
+	defer (func() { goukv.Now = time.Now })()
+
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		back, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		db := goukv.NewLRUCache(back, 10, 0)
+
+		db.Put(&goukv.Entry{Key: []byte("put"), Value: []byte("v"), TTL: time.Minute})
+		back.Put(&goukv.Entry{Key: []byte("get"), Value: []byte("v"), TTL: time.Minute})
+		db.Get([]byte("get"))
+
+		now := time.Now().Add(2 * time.Minute)
+		goukv.Now = func() time.Time { return now }
+
+		for _, k := range []string{"put", "get"} {
+			if _, err := db.Get([]byte(k)); err != goukv.ErrKeyNotFound {
+				t.Errorf("%s: expected the cached (%s) to expire with the key, found (%v)", driver, k, err)
+			}
+		}
+
+		goukv.Now = time.Now
+	}
+}
+
+func TestLRUCachePrefixTTLs(t *testing.T) {
+	defer (func() { goukv.Now = time.Now })()
+
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		back, cleanup := openTempDB(t, driver, map[string]interface{}{
+			"prefix_ttls": []goukv.PrefixTTL{{Prefix: []byte("session:"), TTL: time.Minute}},
+		})
+		defer cleanup()
+
+		db := goukv.NewLRUCache(back, 10, 0)
+
+		// the entry has no ttl, back gives it the one of its prefix
+		db.Put(&goukv.Entry{Key: []byte("session:1"), Value: []byte("v")})
+		db.Put(&goukv.Entry{Key: []byte("user:1"), Value: []byte("v")})
+
+		now := time.Now().Add(2 * time.Minute)
+		goukv.Now = func() time.Time { return now }
+
+		if _, err := db.Get([]byte("session:1")); err != goukv.ErrKeyNotFound {
+			t.Errorf("%s: expected the cached value to expire with the prefix ttl, found (%v)", driver, err)
+		}
+
+		if v, err := db.Get([]byte("user:1")); err != nil || string(v) != "v" {
+			t.Errorf("%s: expected (v), found (%s, %v)", driver, v, err)
+		}
+
+		goukv.Now = time.Now
+	}
+}
+
+func TestLRUCacheConcurrency(t *testing.T) {
+	back, cleanup := openTempDB(t, "goleveldb", nil)
+	defer cleanup()
+
+	db := goukv.NewLRUCache(back, 5, 0)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+		go (func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				k := []byte("k" + strconv.Itoa(i%8))
+				if i%5 == 0 {
+					db.Delete(k)
+				} else {
+					db.Put(&goukv.Entry{Key: k, Value: []byte(fmt.Sprintf("%d-%d", w, i))})
+				}
+			}
+		})(w)
+
+		go (func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				db.Get([]byte("k" + strconv.Itoa(i%8)))
+			}
+		})()
+	}
+	wg.Wait()
+
+	// the cache never hides the last write
+	for i := 0; i < 8; i++ {
+		k := []byte("k" + strconv.Itoa(i))
+		expected, expectedErr := back.Get(k)
+		if v, err := db.Get(k); err != expectedErr || !bytes.Equal(v, expected) {
+			t.Errorf("expected (%s, %v) for (%s), found (%s, %v)", expected, expectedErr, k, v, err)
+		}
+	}
+}
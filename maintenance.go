@@ -0,0 +1,84 @@
+package goukv
+
+import "time"
+
+// MaintenanceKind the kind of a maintenance run
+type MaintenanceKind uint8
+
+// available maintenance kinds
+const (
+	MaintenanceValueLogGC MaintenanceKind = iota + 1
+	MaintenanceCompaction
+)
+
+// String returns the name of the maintenance kind
+func (k MaintenanceKind) String() string {
+	switch k {
+	case MaintenanceValueLogGC:
+		return "value_log_gc"
+	case MaintenanceCompaction:
+		return "compaction"
+	}
+	return "unknown"
+}
+
+// MaintenanceReport describes a finished maintenance run, the sizes are the ones of the db files (see DirSize)
+// right before and after it, Err is the error the run failed with if any
+type MaintenanceReport struct {
+	Kind       MaintenanceKind
+	Started    time.Time
+	Duration   time.Duration
+	SizeBefore int64
+	SizeAfter  int64
+	Err        error
+}
+
+// Reclaimed returns the number of bytes the run reclaimed, it's negative when the db grew meanwhile
+func (r MaintenanceReport) Reclaimed() int64 {
+	return r.SizeBefore - r.SizeAfter
+}
+
+// MaintenanceHook reports the maintenance runs of a provider (i.e: its value log GCs or compactions) to a callback,
+// it's configured by the "on_maintenance" option
+type MaintenanceHook struct {
+	OnReport func(MaintenanceReport)
+}
+
+// NewMaintenanceHook builds a MaintenanceHook from the specified provider options,
+// nil is returned when "on_maintenance" (func(goukv.MaintenanceReport)) isn't set
+func NewMaintenanceHook(opts map[string]interface{}) *MaintenanceHook {
+	onReport, ok := opts["on_maintenance"].(func(MaintenanceReport))
+	if !ok || onReport == nil {
+		return nil
+	}
+
+	return &MaintenanceHook{
+		OnReport: onReport,
+	}
+}
+
+// Run calls run then reports it with the size of the specified dirs before and after it, the report is passed to
+// OnReport on its own goroutine so a slow callback doesn't block the maintenance, a nil hook only calls run
+func (h *MaintenanceHook) Run(kind MaintenanceKind, dirs []string, run func() error) error {
+	if h == nil {
+		return run()
+	}
+
+	before, _ := DirSize(dirs...)
+	started := time.Now()
+
+	err := run()
+
+	report := MaintenanceReport{
+		Kind:       kind,
+		Started:    started,
+		Duration:   time.Since(started),
+		SizeBefore: before,
+		Err:        err,
+	}
+	report.SizeAfter, _ = DirSize(dirs...)
+
+	go h.OnReport(report)
+
+	return err
+}
@@ -0,0 +1,76 @@
+package goukv_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestMaintenanceHook(t *testing.T) {
+	kinds := map[string]goukv.MaintenanceKind{
+		"goleveldb": goukv.MaintenanceCompaction,
+		"badgerdb":  goukv.MaintenanceValueLogGC,
+	}
+
+	for driver, kind := range kinds {
+		reports := make(chan goukv.MaintenanceReport, 100)
+
+		// the disk watchdog runs the maintenance as soon as the db exceeds a byte
+		db, cleanup := openTempDB(t, driver, map[string]interface{}{
+			"max_disk_bytes":      int64(1),
+			"disk_check_interval": 20 * time.Millisecond,
+			"on_maintenance": func(r goukv.MaintenanceReport) {
+				reports <- r
+			},
+		})
+		defer cleanup()
+
+		db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+
+		select {
+		case r := <-reports:
+			if r.Kind != kind || r.Err != nil {
+				t.Errorf("%s: expected a successful (%s), found (%s, %v)", driver, kind, r.Kind, r.Err)
+			}
+			if r.SizeBefore <= 0 || r.SizeAfter <= 0 || r.Started.IsZero() {
+				t.Errorf("%s: expected the sizes and start of the run, found (%+v)", driver, r)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: expected a maintenance report", driver)
+		}
+	}
+}
+
+func TestMaintenanceHookDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	hook := goukv.NewMaintenanceHook(map[string]interface{}{
+		"on_maintenance": func(goukv.MaintenanceReport) {
+			<-release
+		},
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- hook.Run(goukv.MaintenanceCompaction, nil, func() error { return nil })
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the run not to wait for the callback")
+	}
+
+	if goukv.NewMaintenanceHook(map[string]interface{}{}) != nil {
+		t.Error("expected no hook without on_maintenance")
+	}
+
+	if r := (goukv.MaintenanceReport{SizeBefore: 10, SizeAfter: 4}); r.Reclaimed() != 6 {
+		t.Errorf("expected (6) reclaimed bytes, found (%d)", r.Reclaimed())
+	}
+}
@@ -0,0 +1,216 @@
+package goukv
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// DefaultMirrorMaxPendingRepairs the number of read repairs a mirror runs at once by default
+const DefaultMirrorMaxPendingRepairs = 64
+
+// MirrorOpts the options of a mirror provider
+type MirrorOpts struct {
+	// OnSecondaryError when set the failed secondary writes are passed to it (with the name of the failed
+	// operation, i.e: "put") and ignored instead of failing the call
+	OnSecondaryError func(op string, err error)
+
+	// ReadRepair makes every successful Get backfill secondary (asynchronously) when the key is missing there
+	// or has another value, the failed repairs are passed to OnSecondaryError as "read_repair" when it's set
+	ReadRepair bool
+
+	// MaxPendingRepairs the number of read repairs queued or running at once (DefaultMirrorMaxPendingRepairs when it's
+	// zero), a key is only repaired once at a time and the Gets finding the limit reached don't repair their key
+	MaxPendingRepairs int
+}
+
+// mirrorProvider a provider that writes to two providers and reads from the first one
+type mirrorProvider struct {
+	primary   Provider
+	secondary Provider
+	opts      MirrorOpts
+
+	// the writes hold repairLock shared while the repairs hold it exclusively, so that a repair
+	// can't write a value older than the one a concurrent write is mirroring
+	repairLock *sync.RWMutex
+	repairs    *sync.WaitGroup
+
+	// pending the keys being repaired, bounded by MaxPendingRepairs
+	pending     map[string]bool
+	pendingLock *sync.Mutex
+}
+
+// Mirror returns a provider that reads from primary and writes to both primary and secondary (i.e: while migrating
+// from a backend to another one), the writes go to primary first and aren't sent to secondary when they fail there,
+// a failed secondary write fails the call (see MirrorWithErrorHandler), Get, TTL and Scan only use primary (see
+// MirrorOpts.ReadRepair)
+func Mirror(primary, secondary Provider) Provider {
+	return MirrorWithErrorHandler(primary, secondary, nil)
+}
+
+// MirrorWithErrorHandler is like Mirror but the failed secondary writes are passed to onSecondaryError
+// (with the name of the failed operation, i.e: "put") and ignored instead of failing the call
+func MirrorWithErrorHandler(primary, secondary Provider, onSecondaryError func(op string, err error)) Provider {
+	return MirrorWithOpts(primary, secondary, MirrorOpts{OnSecondaryError: onSecondaryError})
+}
+
+// MirrorWithOpts is like Mirror configured by the specified options
+func MirrorWithOpts(primary, secondary Provider, opts MirrorOpts) Provider {
+	if opts.MaxPendingRepairs <= 0 {
+		opts.MaxPendingRepairs = DefaultMirrorMaxPendingRepairs
+	}
+
+	return &mirrorProvider{
+		primary:     primary,
+		secondary:   secondary,
+		opts:        opts,
+		repairLock:  &sync.RWMutex{},
+		repairs:     &sync.WaitGroup{},
+		pending:     map[string]bool{},
+		pendingLock: &sync.Mutex{},
+	}
+}
+
+// secondaryResult returns the error of a secondary write unless it's handled by OnSecondaryError
+func (m *mirrorProvider) secondaryResult(op string, err error) error {
+	if err == nil || m.opts.OnSecondaryError == nil {
+		return err
+	}
+
+	m.opts.OnSecondaryError(op, err)
+	return nil
+}
+
+// repair copies the current primary value of the specified key to secondary unless it already has it
+func (m *mirrorProvider) repair(k []byte) error {
+	m.repairLock.Lock()
+	defer m.repairLock.Unlock()
+
+	val, err := m.primary.Get(k)
+	if err == ErrKeyNotFound {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	expires, err := m.primary.TTL(k)
+	if err == ErrKeyNotFound {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	current, err := m.secondary.Get(k)
+	if err == nil && bytes.Equal(current, val) {
+		return nil
+	}
+
+	if err != nil && err != ErrKeyNotFound {
+		return err
+	}
+
+	return m.secondary.Put(&Entry{Key: k, Value: val, ExpireAt: expires})
+}
+
+// Open implements goukv.Open, open both providers then wrap them using Mirror instead
+func (m *mirrorProvider) Open(map[string]interface{}) (Provider, error) {
+	return nil, ErrNotSupported
+}
+
+// Put implements goukv.Put
+func (m *mirrorProvider) Put(e *Entry) error {
+	m.repairLock.RLock()
+	defer m.repairLock.RUnlock()
+
+	if err := m.primary.Put(e); err != nil {
+		return err
+	}
+
+	return m.secondaryResult("put", m.secondary.Put(e))
+}
+
+// Get implements goukv.Get, a found key is repaired in the background when ReadRepair is set
+func (m *mirrorProvider) Get(k []byte) ([]byte, error) {
+	val, err := m.primary.Get(k)
+	if err != nil || !m.opts.ReadRepair {
+		return val, err
+	}
+
+	key := string(k)
+
+	// a pending repair reads the primary value once it runs, so it covers this Get too
+	m.pendingLock.Lock()
+	if m.pending[key] || len(m.pending) >= m.opts.MaxPendingRepairs {
+		m.pendingLock.Unlock()
+		return val, nil
+	}
+	m.pending[key] = true
+	m.pendingLock.Unlock()
+
+	m.repairs.Add(1)
+	go (func() {
+		defer m.repairs.Done()
+
+		err := m.repair([]byte(key))
+
+		m.pendingLock.Lock()
+		delete(m.pending, key)
+		m.pendingLock.Unlock()
+
+		if err != nil && m.opts.OnSecondaryError != nil {
+			m.opts.OnSecondaryError("read_repair", err)
+		}
+	})()
+
+	return val, nil
+}
+
+// TTL implements goukv.TTL
+func (m *mirrorProvider) TTL(k []byte) (*time.Time, error) {
+	return m.primary.TTL(k)
+}
+
+// Delete implements goukv.Delete
+func (m *mirrorProvider) Delete(k []byte) error {
+	m.repairLock.RLock()
+	defer m.repairLock.RUnlock()
+
+	if err := m.primary.Delete(k); err != nil {
+		return err
+	}
+
+	return m.secondaryResult("delete", m.secondary.Delete(k))
+}
+
+// Batch implements goukv.Batch
+func (m *mirrorProvider) Batch(entries []*Entry) error {
+	m.repairLock.RLock()
+	defer m.repairLock.RUnlock()
+
+	if err := m.primary.Batch(entries); err != nil {
+		return err
+	}
+
+	return m.secondaryResult("batch", m.secondary.Batch(entries))
+}
+
+// Scan implements goukv.Scan, only primary is scanned
+func (m *mirrorProvider) Scan(opts ScanOpts) error {
+	return m.primary.Scan(opts)
+}
+
+// Close implements goukv.Close, both providers are closed once the pending repairs are done, the first error is returned
+func (m *mirrorProvider) Close() error {
+	m.repairs.Wait()
+
+	err := m.primary.Close()
+	if err2 := m.secondary.Close(); err == nil {
+		err = err2
+	}
+
+	return err
+}
@@ -0,0 +1,215 @@
+package goukv_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestMirror(t *testing.T) {
+	primary, cleanupPrimary := openTempDB(t, "goleveldb", nil)
+	defer cleanupPrimary()
+
+	secondary, cleanupSecondary := openTempDB(t, "badgerdb", nil)
+	defer cleanupSecondary()
+
+	db := goukv.Mirror(primary, secondary)
+
+	if err := db.Put(&goukv.Entry{Key: []byte("k1"), Value: []byte("v1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := db.Batch([]*goukv.Entry{
+		{Key: []byte("k2"), Value: []byte("v2")},
+		{Key: []byte("k3"), Value: []byte("v3")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Delete([]byte("k2")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []goukv.Provider{primary, secondary} {
+		if v, err := p.Get([]byte("k1")); err != nil || string(v) != "v1" {
+			t.Errorf("expected (v1) in both backends, found (%s, %v)", v, err)
+		}
+
+		if v, err := p.Get([]byte("k3")); err != nil || string(v) != "v3" {
+			t.Errorf("expected (v3) in both backends, found (%s, %v)", v, err)
+		}
+
+		if _, err := p.Get([]byte("k2")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected (k2) to be deleted from both backends, found (%v)", err)
+		}
+	}
+
+	secondary.Put(&goukv.Entry{Key: []byte("only-secondary"), Value: []byte("v")})
+	if _, err := db.Get([]byte("only-secondary")); err != goukv.ErrKeyNotFound {
+		t.Errorf("expected the reads to only use the primary, found (%v)", err)
+	}
+}
+
+func TestMirrorSecondaryFailure(t *testing.T) {
+	primary, cleanupPrimary := openTempDB(t, "goleveldb", nil)
+	defer cleanupPrimary()
+
+	secondary, cleanupSecondary := openTempDB(t, "badgerdb", map[string]interface{}{"max_value_size": 4})
+	defer cleanupSecondary()
+
+	large := &goukv.Entry{Key: []byte("k"), Value: []byte("too large")}
+
+	if err := goukv.Mirror(primary, secondary).Put(large); err != goukv.ErrValueTooLarge {
+		t.Errorf("expected the secondary error to fail the call, found (%v)", err)
+	}
+
+	var ops []string
+	db := goukv.MirrorWithErrorHandler(primary, secondary, func(op string, err error) {
+		if err != goukv.ErrValueTooLarge {
+			t.Errorf("expected (%v) to be handled, found (%v)", goukv.ErrValueTooLarge, err)
+		}
+		ops = append(ops, op)
+	})
+
+	if err := db.Put(large); err != nil {
+		t.Errorf("expected the secondary error to be tolerated, found (%v)", err)
+	}
+
+	if err := db.Batch([]*goukv.Entry{large}); err != nil {
+		t.Errorf("expected the secondary error to be tolerated, found (%v)", err)
+	}
+
+	if len(ops) != 2 || ops[0] != "put" || ops[1] != "batch" {
+		t.Errorf("expected the handled operations (put, batch), found (%v)", ops)
+	}
+
+	if v, err := primary.Get([]byte("k")); err != nil || string(v) != "too large" {
+		t.Errorf("expected the primary to be written, found (%s, %v)", v, err)
+	}
+}
+
+func TestMirrorReadRepair(t *testing.T) {
+	primary, cleanupPrimary := openTempDB(t, "goleveldb", nil)
+	defer cleanupPrimary()
+
+	secondary, cleanupSecondary := openTempDB(t, "badgerdb", nil)
+	defer cleanupSecondary()
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	// written before mirroring started, or stale
+	primary.Put(&goukv.Entry{Key: []byte("missing"), Value: []byte("v1"), ExpireAt: &expires})
+	primary.Put(&goukv.Entry{Key: []byte("stale"), Value: []byte("new")})
+	secondary.Put(&goukv.Entry{Key: []byte("stale"), Value: []byte("old")})
+
+	repairErrs := make(chan error, 10)
+	db := goukv.MirrorWithOpts(primary, secondary, goukv.MirrorOpts{
+		ReadRepair: true,
+		OnSecondaryError: func(op string, err error) {
+			repairErrs <- err
+		},
+	})
+
+	for _, k := range []string{"missing", "stale"} {
+		if _, err := db.Get([]byte(k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := db.Get([]byte("none")); err != goukv.ErrKeyNotFound {
+		t.Errorf("expected (%v), found (%v)", goukv.ErrKeyNotFound, err)
+	}
+
+	expected := map[string]string{"missing": "v1", "stale": "new"}
+
+	// the repairs run in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for k, v := range expected {
+		for {
+			found, _ := secondary.Get([]byte(k))
+			if string(found) == v {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatalf("expected (%s) to be repaired to (%s), found (%s)", k, v, found)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if ttl, err := secondary.TTL([]byte("missing")); err != nil || ttl == nil || !ttl.Equal(expires) {
+		t.Errorf("expected the expiration to be repaired too, found (%v, %v)", ttl, err)
+	}
+
+	if _, err := secondary.Get([]byte("none")); err != goukv.ErrKeyNotFound {
+		t.Errorf("expected a missed read not to write anything, found (%v)", err)
+	}
+
+	if len(repairErrs) > 0 {
+		t.Error(<-repairErrs)
+	}
+}
+
+// blockingProvider a provider whose Gets are counted and block until released
+type blockingProvider struct {
+	goukv.Provider
+	gets    *int32
+	release chan struct{}
+}
+
+func (p blockingProvider) Get(k []byte) ([]byte, error) {
+	atomic.AddInt32(p.gets, 1)
+	<-p.release
+	return p.Provider.Get(k)
+}
+
+func TestMirrorReadRepairBound(t *testing.T) {
+	primary, cleanupPrimary := openTempDB(t, "goleveldb", nil)
+	defer cleanupPrimary()
+
+	secondary, cleanupSecondary := openTempDB(t, "goleveldb", nil)
+	defer cleanupSecondary()
+
+	for _, k := range []string{"a", "b", "c"} {
+		primary.Put(&goukv.Entry{Key: []byte(k), Value: []byte("v")})
+	}
+
+	blocking := blockingProvider{Provider: secondary, gets: new(int32), release: make(chan struct{})}
+	db := goukv.MirrorWithOpts(primary, blocking, goukv.MirrorOpts{ReadRepair: true, MaxPendingRepairs: 2})
+
+	// "a" is repaired once, "c" finds the limit reached
+	for _, k := range []string{"a", "a", "a", "b", "c"} {
+		if _, err := db.Get([]byte(k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	close(blocking.release)
+
+	// the repairs run in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if found, _ := secondary.Get([]byte("a")); string(found) == "v" {
+			if found, _ := secondary.Get([]byte("b")); string(found) == "v" {
+				break
+			}
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("expected (a) and (b) to be repaired")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if gets := atomic.LoadInt32(blocking.gets); gets != 2 {
+		t.Errorf("expected (2) repairs, found (%d)", gets)
+	}
+
+	if _, err := secondary.Get([]byte("c")); err != goukv.ErrKeyNotFound {
+		t.Errorf("expected (c) not to be repaired, found (%v)", err)
+	}
+}
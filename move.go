@@ -0,0 +1,68 @@
+package goukv
+
+import (
+	"bytes"
+	"time"
+)
+
+// MoveOpts the options of MoveWithOpts
+type MoveOpts struct {
+	// Verify reads the key back from dst after writing it and only deletes it from src when dst returns the moved
+	// value, ErrMoveMismatch is returned (and src is left as is) otherwise, it costs a read of dst per move
+	Verify bool
+}
+
+// Move moves the specified key from src to dst (i.e: demoting a key from a hot tier to a cold one): it reads the value
+// and the expiration of the key from src, writes them to dst then deletes the key from src. the move isn't atomic:
+// a failed write to dst leaves the key in src only, but a failed delete (or a crash before it) leaves it in both of
+// them, calling it again finishes the move (dst is overwritten with the same value), see MoveWithOpts to verify the
+// write before the delete. src must not be written concurrently, a write between the read and the delete is lost
+func Move(src, dst Provider, key []byte) error {
+	return MoveWithOpts(src, dst, key, MoveOpts{})
+}
+
+// MoveWithOpts is like Move configured by the specified options
+func MoveWithOpts(src, dst Provider, key []byte, opts MoveOpts) error {
+	var value []byte
+	var expires *time.Time
+	if getter, ok := src.(EntryGetter); ok {
+		entry, err := getter.GetEntry(key)
+		if err != nil {
+			return err
+		}
+		value, expires = entry.Value, entry.ExpireAt
+	} else {
+		var err error
+		if value, err = src.Get(key); err != nil {
+			return err
+		}
+		if expires, err = src.TTL(key); err != nil {
+			return err
+		}
+	}
+
+	if value == nil {
+		value = []byte{}
+	}
+
+	if err := dst.Put(&Entry{Key: key, Value: value, ExpireAt: expires}); err != nil {
+		return err
+	}
+
+	if opts.Verify {
+		stored, err := dst.Get(key)
+
+		// it may have expired meanwhile, so it's gone from src too
+		expired := err == ErrKeyNotFound && expires != nil && !Now().Before(*expires)
+
+		if err != nil && err != ErrKeyNotFound {
+			return err
+		}
+
+		if !expired && (err != nil || !bytes.Equal(stored, value)) {
+			return ErrMoveMismatch
+		}
+	}
+
+	return src.Delete(key)
+}
@@ -0,0 +1,110 @@
+package goukv_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+// faultyProvider a provider whose deletes fail, and whose puts are dropped when dropPuts is set
+type faultyProvider struct {
+	goukv.Provider
+	dropPuts bool
+}
+
+var errFaultyDelete = errors.New("faulty delete")
+
+func (p *faultyProvider) Put(e *goukv.Entry) error {
+	if p.dropPuts {
+		return nil
+	}
+	return p.Provider.Put(e)
+}
+
+func (p *faultyProvider) Delete([]byte) error {
+	return errFaultyDelete
+}
+
+func TestMove(t *testing.T) {
+	src, cleanupSrc := openTempDB(t, "badgerdb", nil)
+	defer cleanupSrc()
+
+	dst, cleanupDst := openTempDB(t, "goleveldb", nil)
+	defer cleanupDst()
+
+	src.Put(&goukv.Entry{Key: []byte("k1"), Value: []byte("v1"), TTL: time.Hour})
+	src.Put(&goukv.Entry{Key: []byte("k2"), Value: []byte("v2")})
+
+	expected, _ := src.TTL([]byte("k1"))
+
+	if err := goukv.Move(src, dst, []byte("k1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := goukv.MoveWithOpts(src, dst, []byte("k2"), goukv.MoveOpts{Verify: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range []string{"k1", "k2"} {
+		if _, err := src.Get([]byte(k)); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected (%s) to be deleted from src, found (%v)", k, err)
+		}
+
+		if v, err := dst.Get([]byte(k)); err != nil || string(v) != "v"+k[1:] {
+			t.Errorf("expected (%s) to be moved to dst, found (%s, %v)", k, v, err)
+		}
+	}
+
+	// the expiration is moved with the key
+	if expires, err := dst.TTL([]byte("k1")); err != nil || !goukv.SameExpiry(expires, expected) {
+		t.Errorf("expected (%v), found (%v, %v)", expected, expires, err)
+	}
+
+	if err := goukv.Move(src, dst, []byte("missing")); err != goukv.ErrKeyNotFound {
+		t.Errorf("expected (%v), found (%v)", goukv.ErrKeyNotFound, err)
+	}
+}
+
+func TestMovePartialFailure(t *testing.T) {
+	src, cleanupSrc := openTempDB(t, "goleveldb", nil)
+	defer cleanupSrc()
+
+	dst, cleanupDst := openTempDB(t, "goleveldb", nil)
+	defer cleanupDst()
+
+	src.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+
+	// a failed delete leaves the key in both of them, moving it again finishes the move
+	faulty := &faultyProvider{Provider: src}
+	if err := goukv.Move(faulty, dst, []byte("k")); err != errFaultyDelete {
+		t.Errorf("expected (%v), found (%v)", errFaultyDelete, err)
+	}
+
+	for _, p := range []goukv.Provider{src, dst} {
+		if v, err := p.Get([]byte("k")); err != nil || string(v) != "v" {
+			t.Errorf("expected the key in both providers, found (%s, %v)", v, err)
+		}
+	}
+
+	if err := goukv.Move(src, dst, []byte("k")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := src.Get([]byte("k")); err != goukv.ErrKeyNotFound {
+		t.Errorf("expected the key to be deleted from src, found (%v)", err)
+	}
+
+	// a write lost by dst is caught by Verify before the key is deleted from src
+	src.Put(&goukv.Entry{Key: []byte("lost"), Value: []byte("v")})
+
+	lossy := &faultyProvider{Provider: dst, dropPuts: true}
+	if err := goukv.MoveWithOpts(src, lossy, []byte("lost"), goukv.MoveOpts{Verify: true}); err != goukv.ErrMoveMismatch {
+		t.Errorf("expected (%v), found (%v)", goukv.ErrMoveMismatch, err)
+	}
+
+	if v, err := src.Get([]byte("lost")); err != nil || string(v) != "v" {
+		t.Errorf("expected the key to be kept in src, found (%s, %v)", v, err)
+	}
+}
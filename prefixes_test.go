@@ -0,0 +1,42 @@
+package goukv_test
+
+import (
+	"testing"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestListPrefixes(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, map[string]interface{}{"enable_changelog": true})
+		defer cleanup()
+
+		for _, k := range []string{"docs/a.txt", "docs/img/1.png", "docs/img/2.png", "docs/src/main.go", "docs/src/x/y.go", "music/song.mp3", "readme"} {
+			db.Put(&goukv.Entry{Key: []byte(k), Value: []byte("v")})
+		}
+
+		cases := map[string][]string{
+			"":      {"docs/", "music/"},
+			"docs/": {"docs/img/", "docs/src/"},
+			"none/": nil,
+		}
+
+		for prefix, expected := range cases {
+			found, err := db.(goukv.PrefixLister).ListPrefixes([]byte(prefix), '/')
+			if err != nil {
+				t.Fatalf("%s: %v", driver, err)
+			}
+
+			if len(found) != len(expected) {
+				t.Errorf("%s: expected (%v) under (%s), found (%q)", driver, expected, prefix, found)
+				continue
+			}
+
+			for i := range found {
+				if string(found[i]) != expected[i] {
+					t.Errorf("%s: expected (%v) under (%s), found (%q)", driver, expected, prefix, found)
+				}
+			}
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package goukv
 
 import (
+	"bytes"
+	"sort"
 	"sync"
 	"time"
 )
@@ -11,7 +13,16 @@ var (
 	providersLock = &sync.RWMutex{}
 )
 
-// Provider an interface describes a storage backend
+// InternalPrefix the prefix reserved for the keys goukv and its providers store internally (changelog, keyspaces ...)
+var InternalPrefix = []byte("\x00goukv\x00")
+
+// IsInternalKey whether the specified key is under the reserved InternalPrefix or not
+func IsInternalKey(k []byte) bool {
+	return bytes.HasPrefix(k, InternalPrefix)
+}
+
+// Provider an interface describes a storage backend, Batch applies its entries in slice order
+// so when a key is listed more than once its last entry wins
 type Provider interface {
 	Open(map[string]interface{}) (Provider, error)
 	Put(*Entry) error
@@ -23,6 +34,131 @@ type Provider interface {
 	Close() error
 }
 
+// IdempotentPutter an optional interface for providers that can skip writes that wouldn't change anything,
+// changed reports whether the entry has been written (its value or expiration differ from the stored ones)
+type IdempotentPutter interface {
+	PutIfChanged(*Entry) (changed bool, err error)
+}
+
+// CompareAndDeleter an optional interface for providers that can delete a key only if its current value is the
+// specified one, deleted reports whether the delete happened, a missing or expired key doesn't match
+type CompareAndDeleter interface {
+	CompareAndDelete(k, old []byte) (deleted bool, err error)
+}
+
+// VersionedPutter an optional interface for providers that can write an entry only if the version of the current value
+// (Entry.Version as reported by GetEntry) is the expected one, an optimistic lock (i.e: the ETags of a REST API),
+// expectedVersion 0 requires the key not to exist, ErrConflict is returned on a mismatch and newVersion is the version
+// of the written value, see each provider for its versions
+type VersionedPutter interface {
+	PutVersioned(entry *Entry, expectedVersion uint64) (newVersion uint64, err error)
+}
+
+// DetailedBatcher an optional interface for providers whose batches may partially fail, results holds the error
+// of each entry (nil when it has been written) in the order of entries and err the overall error (the first failure),
+// see BatchDetailed for the other providers
+type DetailedBatcher interface {
+	BatchDetailed(entries []*Entry) (results []error, err error)
+}
+
+// SyncWriter an optional interface for providers that can override their sync_writes option for a single write,
+// sync forces the write to be persisted (fsynced) before returning, see each provider for whether it can skip it
+type SyncWriter interface {
+	PutSync(e *Entry, sync bool) error
+	DeleteSync(k []byte, sync bool) error
+	BatchSync(entries []*Entry, sync bool) error
+}
+
+// FuncGetter an optional interface for providers that can expose a value without copying it,
+// the value is only valid inside fn and must not be modified or retained after it returns
+type FuncGetter interface {
+	GetFunc(k []byte, fn func(val []byte) error) error
+}
+
+// ExpiringScanner an optional interface for providers that can enumerate the keys expiring before a point in time,
+// keys without a TTL never expire so they are skipped
+type ExpiringScanner interface {
+	ExpiringBefore(t time.Time, fn func(key []byte, expires time.Time) error) error
+}
+
+// PrefixStatsReader an optional interface for providers that can report the size of a namespace (key prefix),
+// count is the number of live keys and bytes the space they consume, see each provider for its accuracy
+type PrefixStatsReader interface {
+	PrefixStats(prefix []byte) (count int64, bytes int64, err error)
+}
+
+// RangeSizer an optional interface for providers that can estimate the bytes their keys in [start, end) occupy
+// (nil means unbounded) from their table metadata rather than by scanning the keys, it's approximate and meant for
+// sharding and rebalancing decisions, see each provider for what it counts
+type RangeSizer interface {
+	RangeSize(start, end []byte) (int64, error)
+}
+
+// KeyGCForcer an optional interface for providers that can reclaim the space the dead versions of a key occupy
+// (i.e: a few large keys overwritten frequently), it's a best-effort hint to the storage engine which may reclaim
+// more (or less) than the versions of the key, see each provider for what it does
+type KeyGCForcer interface {
+	ForceGCKey(key []byte) error
+}
+
+// HistogramReader an optional interface for providers that can report the distribution of their key and value sizes,
+// it's a full keyspace scan (keys only where possible) so it may be expensive on large stores
+type HistogramReader interface {
+	Histogram() (Histogram, error)
+}
+
+// ExpiredPurger an optional interface for providers that can physically remove their expired keys on demand,
+// it returns the number of removed keys
+type ExpiredPurger interface {
+	PurgeExpired() (int64, error)
+}
+
+// PrefixLister an optional interface for providers that can list the distinct "directories" under a prefix,
+// each listed prefix is the specified prefix followed by a segment and the delimiter (like the S3 common prefixes),
+// keys without the delimiter after the prefix aren't listed
+type PrefixLister interface {
+	ListPrefixes(prefix []byte, delimiter byte) ([][]byte, error)
+}
+
+// KeyBoundsReader an optional interface for providers that can find their smallest and largest live keys having
+// the specified prefix (nil for all of the keys) by seeking rather than scanning, ErrKeyNotFound is returned when
+// there is none, the internal keys are skipped like Scan does (see ScanOpts.SkipsInternal)
+type KeyBoundsReader interface {
+	FirstKey(prefix []byte) ([]byte, error)
+	LastKey(prefix []byte) ([]byte, error)
+}
+
+// RawGetter an optional diagnostic interface for providers that can return a value even though it's expired,
+// expired reports whether the stored value is logically expired (Get would return ErrKeyNotFound)
+type RawGetter interface {
+	GetRaw(k []byte) (value []byte, expired bool, err error)
+}
+
+// Checkpointer an optional interface for providers that can write a consistent copy of their database
+// into an empty (or missing) directory while they keep serving, the copy opens with the same driver
+type Checkpointer interface {
+	Checkpoint(dir string) error
+}
+
+// VersionScanner an optional interface for providers that can replay their live entries in the order they were written,
+// the entries having a version >= fromVersion are passed to fn in ascending version order (which differs from the key order),
+// see each provider for the version tracking it requires, deleted and expired keys aren't reported, ErrScanDone stops it
+type VersionScanner interface {
+	ScanByVersion(fromVersion uint64, fn func(*Entry) error) error
+}
+
+// NativeAccessor an optional escape hatch for providers exposing their underlying handle (see each provider for its type),
+// using it bypasses goukv: its value wrappers, changelog, watchers, throttling and limits, so the handle must not be closed
+// and writing through it may produce values goukv can't decode, it's meant for the backend features goukv doesn't wrap
+type NativeAccessor interface {
+	Native() interface{}
+}
+
+// EntryGetter an optional interface for providers that can fetch a whole entry in a single read
+type EntryGetter interface {
+	GetEntry([]byte) (*Entry, error)
+}
+
 // Register register a new driver
 func Register(name string, provider Provider) error {
 	providersLock.Lock()
@@ -49,12 +185,51 @@ func Get(providerName string) (Provider, error) {
 	return providersMap[providerName], nil
 }
 
-// Open initialize the specified provider and returns its instance
+// Drivers returns the sorted names of the registered drivers
+func Drivers() []string {
+	providersLock.RLock()
+	defer providersLock.RUnlock()
+
+	names := make([]string, 0, len(providersMap))
+	for name := range providersMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Open initialize the specified provider and returns its instance,
+// the "key_codec" option (a KeyCodec) wraps the instance using WithKeyCodec
 func Open(providerName string, opts map[string]interface{}) (Provider, error) {
 	providerInterface, err := Get(providerName)
 	if err != nil {
 		return nil, err
 	}
 
-	return providerInterface.Open(opts)
+	db, err := providerInterface.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if codec, ok := opts["key_codec"].(KeyCodec); ok {
+		db = WithKeyCodec(db, codec)
+	}
+
+	return db, nil
+}
+
+// GetOr returns the value of the specified key from p, or fallback when the key doesn't exist (or is expired),
+// only the other errors are returned
+func GetOr(p Provider, k []byte, fallback []byte) ([]byte, error) {
+	val, err := p.Get(k)
+	if err == ErrKeyNotFound {
+		return fallback, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
 }
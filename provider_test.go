@@ -0,0 +1,53 @@
+package goukv_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alash3al/goukv"
+	_ "github.com/alash3al/goukv/providers/badgerdb"
+	_ "github.com/alash3al/goukv/providers/goleveldb"
+)
+
+// openTempDB opens the specified driver in a temporary directory, the returned func closes and removes it
+func openTempDB(t *testing.T, driver string, opts map[string]interface{}) (goukv.Provider, func()) {
+	dir, err := ioutil.TempDir("", "goukv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if opts == nil {
+		opts = map[string]interface{}{}
+	}
+	opts["path"] = filepath.Join(dir, "db")
+
+	db, err := goukv.Open(driver, opts)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGetOr(t *testing.T) {
+	db, cleanup := openTempDB(t, "goleveldb", nil)
+	defer cleanup()
+
+	db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+
+	val, err := goukv.GetOr(db, []byte("k"), []byte("fallback"))
+	if err != nil || string(val) != "v" {
+		t.Errorf("expected (v), found (%s, %v)", val, err)
+	}
+
+	val, err = goukv.GetOr(db, []byte("missing"), []byte("fallback"))
+	if err != nil || string(val) != "fallback" {
+		t.Errorf("expected (fallback), found (%s, %v)", val, err)
+	}
+}
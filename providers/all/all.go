@@ -0,0 +1,13 @@
+// Package all registers the providers of the goukv module (the pure go ones), the providers having
+// their own module (i.e: buntdb, lmdb ...) must be imported directly along with their build tag,
+// import it for its side-effect:
+//
+//	import _ "github.com/alash3al/goukv/providers/all"
+package all
+
+import (
+	// the pure go providers
+	_ "github.com/alash3al/goukv/providers/badgerdb"
+	_ "github.com/alash3al/goukv/providers/goleveldb"
+	_ "github.com/alash3al/goukv/providers/remote"
+)
@@ -0,0 +1,20 @@
+package all
+
+import (
+	"testing"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestDrivers(t *testing.T) {
+	drivers := map[string]bool{}
+	for _, name := range goukv.Drivers() {
+		drivers[name] = true
+	}
+
+	for _, name := range []string{"badgerdb", "goleveldb", "remote"} {
+		if !drivers[name] {
+			t.Errorf("expected (%s) to be registered, found (%v)", name, goukv.Drivers())
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package badgerdb
+
+import (
+	"github.com/alash3al/goukv"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/y"
+)
+
+// errorKinds the kinds of the known badger errors
+var errorKinds = map[error]goukv.ErrorKind{
+	badger.ErrConflict:       goukv.ErrorConflict,
+	y.ErrChecksumMismatch:    goukv.ErrorCorruption,
+	badger.ErrTruncateNeeded: goukv.ErrorCorruption,
+	badger.ErrInvalidDump:    goukv.ErrorCorruption,
+	badger.ErrBlockedWrites:  goukv.ErrorTransient,
+	badger.ErrRetry:          goukv.ErrorTransient,
+	badger.ErrTxnTooBig:      goukv.ErrorFatal,
+	badger.ErrDiscardedTxn:   goukv.ErrorFatal,
+	badger.ErrReadOnlyTxn:    goukv.ErrorFatal,
+	badger.ErrEmptyKey:       goukv.ErrorFatal,
+	badger.ErrInvalidKey:     goukv.ErrorFatal,
+}
+
+// classifyError wraps the known badger errors in a goukv.Error of their kind, badger wraps some of them
+// with github.com/pkg/errors so their causes are checked too, the other errors are returned unchanged
+func classifyError(err error) error {
+	for cause := err; cause != nil; {
+		if kind, ok := errorKinds[cause]; ok {
+			return goukv.WrapError(kind, err)
+		}
+
+		causer, ok := cause.(interface{ Cause() error })
+		if !ok || causer.Cause() == cause {
+			break
+		}
+		cause = causer.Cause()
+	}
+
+	return err
+}
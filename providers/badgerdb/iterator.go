@@ -0,0 +1,168 @@
+package badgerdb
+
+import (
+	"bytes"
+
+	"github.com/alash3al/goukv"
+	"github.com/dgraph-io/badger/v2"
+)
+
+// keyIterator implements goukv.Iterator, the prefix is checked here rather than by badger
+// because a reverse badger iterator can't start from the end of a prefix by itself
+type keyIterator struct {
+	txn     *badger.Txn
+	iter    *badger.Iterator
+	prefix  []byte
+	limit   []byte
+	reverse bool
+	started bool
+	err     error
+
+	// tombstones whether the tombstones are visible too (see ScanOpts.IncludeTombstones)
+	tombstones bool
+
+	// external whether the internal keys are skipped (see ScanOpts.SkipsInternal)
+	external bool
+
+	// progress when set is called for every item skipped as hidden (see ScanOpts.ProgressTicker)
+	progress func()
+}
+
+// NewIterator implements goukv.Iterable
+func (p Provider) NewIterator(prefix []byte, reverse bool) (goukv.Iterator, error) {
+	it := newKeyIterator(p.db.NewTransaction(false), prefix, reverse, true)
+	it.external = (goukv.ScanOpts{Prefix: prefix}).SkipsInternal()
+
+	return it, nil
+}
+
+// newKeyIterator returns an iterator over the keys of txn having the specified prefix, it discards txn once closed,
+// the values are only read by Value when they aren't prefetched
+func newKeyIterator(txn *badger.Txn, prefix []byte, reverse, prefetch bool) *keyIterator {
+	iterOpts := badger.DefaultIteratorOptions
+	iterOpts.Reverse = reverse
+	iterOpts.PrefetchValues = prefetch
+
+	return &keyIterator{
+		txn:     txn,
+		iter:    txn.NewIterator(iterOpts),
+		prefix:  prefix,
+		limit:   prefixLimit(prefix),
+		reverse: reverse,
+	}
+}
+
+// prefixLimit returns the first key after all of the keys having the specified prefix, nil if there is none
+func prefixLimit(prefix []byte) []byte {
+	limit := append([]byte{}, prefix...)
+	for i := len(limit) - 1; i >= 0; i-- {
+		if limit[i] < 0xff {
+			limit[i]++
+			return limit[:i+1]
+		}
+	}
+	return nil
+}
+
+// valid reports whether the iterator is positioned on a key having the prefix,
+// it moves past the keys expired according to goukv.Now (and the tombstones unless they are visible) first
+func (it *keyIterator) valid() bool {
+	// a reverse seek to the limit lands on it if it exists
+	if it.reverse && it.limit != nil && it.iter.Valid() && bytes.Compare(it.iter.Item().Key(), it.limit) >= 0 {
+		it.iter.Next()
+	}
+
+	for it.iter.Valid() {
+		item := it.iter.Item()
+		if it.external && goukv.IsInternalKey(item.Key()) {
+			it.skipInternal()
+		} else if it.hidden(item) {
+			if it.progress != nil {
+				it.progress()
+			}
+			it.iter.Next()
+		} else {
+			break
+		}
+	}
+
+	return it.iter.Valid() && bytes.HasPrefix(it.iter.Item().Key(), it.prefix)
+}
+
+// hidden whether the specified item is skipped by the iterator
+func (it *keyIterator) hidden(item *badger.Item) bool {
+	if it.tombstones {
+		return itemExpired(item)
+	}
+	return itemHidden(item)
+}
+
+// skipInternal seeks past the internal keys the iterator is positioned on, to the first key after them
+// or to the last key before them when walking backward
+func (it *keyIterator) skipInternal() {
+	if !it.reverse {
+		it.iter.Seek(prefixLimit(goukv.InternalPrefix))
+		return
+	}
+
+	// a reverse seek lands on the prefix itself if it's a key
+	it.iter.Seek(goukv.InternalPrefix)
+	if it.iter.Valid() && goukv.IsInternalKey(it.iter.Item().Key()) {
+		it.iter.Next()
+	}
+}
+
+// Next implements goukv.Iterator.Next
+func (it *keyIterator) Next() bool {
+	if !it.started {
+		if it.reverse {
+			return it.Seek(it.limit)
+		}
+		return it.Seek(it.prefix)
+	}
+
+	it.iter.Next()
+
+	return it.valid()
+}
+
+// Seek implements goukv.Iterator.Seek
+func (it *keyIterator) Seek(key []byte) bool {
+	it.started = true
+
+	if !it.reverse && bytes.Compare(key, it.prefix) < 0 {
+		key = it.prefix
+	} else if it.reverse && it.limit != nil && (len(key) == 0 || bytes.Compare(key, it.limit) > 0) {
+		key = it.limit
+	}
+
+	it.iter.Seek(key)
+
+	return it.valid()
+}
+
+// Key implements goukv.Iterator.Key
+func (it *keyIterator) Key() []byte {
+	return it.iter.Item().KeyCopy(nil)
+}
+
+// Value implements goukv.Iterator.Value, a failed read is reported by Error
+func (it *keyIterator) Value() []byte {
+	val, err := itemValueCopy(it.iter.Item())
+	if err != nil {
+		it.err = err
+	}
+	return val
+}
+
+// Error implements goukv.Iterator.Error
+func (it *keyIterator) Error() error {
+	return it.err
+}
+
+// Close implements goukv.Iterator.Close
+func (it *keyIterator) Close() error {
+	it.iter.Close()
+	it.txn.Discard()
+	return nil
+}
@@ -2,216 +2,1610 @@ package badgerdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/alash3al/goukv"
 
 	"github.com/dgraph-io/badger/v2"
 	"github.com/dgraph-io/badger/v2/options"
+	"github.com/dgraph-io/badger/v2/y"
 )
 
+// maxKeySize the maximum key size accepted by badger
+const maxKeySize = 65000
+
+// maxValueThreshold the maximum value threshold accepted by badger
+const maxValueThreshold = 1 << 20
+
+// metaTimestamps the UserMeta bit of the values prefixed with their creation and update times (track_timestamps)
+const metaTimestamps byte = 1 << 0
+
+// metaTombstone the UserMeta bit of the (empty) tombstones the deletes write in track_deletes mode
+const metaTombstone byte = 1 << 1
+
+// timestampsSize the size of the timestamps prefix, two big-endian unix nanoseconds
+const timestampsSize = 16
+
+// forceGCMaxMisses the number of consecutive value log GC runs rewriting nothing after which ForceGCKey stops
+const forceGCMaxMisses = 3
+
+// errUnchanged aborts the PutIfChanged transaction when there is nothing to write
+var errUnchanged = errors.New("the entry is unchanged")
+
+// errMismatch aborts the CompareAndDelete transaction when the current value isn't the expected one
+var errMismatch = errors.New("the value doesn't match")
+
 // Provider represents a provider
 type Provider struct {
-	db *badger.DB
+	db            *badger.DB
+	options       badger.Options
+	syncWrites    bool
+	gcStop        chan struct{}
+	gcDone        *sync.WaitGroup
+	closed        *int32
+	changelog     bool
+	changelogLock *sync.Mutex
+	changelogSeq  *uint64
+	events        *goukv.EventHub
+	ttlJitter     func(time.Duration) time.Duration
+	prefixTTLs    func([]byte) time.Duration
+	throttle      *goukv.WriteThrottle
+	maxValueSize  int
+	timestamps    bool
+	trackDeletes  bool
+	tombstoneTTL  time.Duration
+	maintenance   *goukv.MaintenanceHook
+
+	// scanSnapshotMaxDuration when set the read transaction of a longer scan is renewed (see Scan)
+	scanSnapshotMaxDuration time.Duration
+}
+
+// Open implements goukv.Open
+func (p Provider) Open(opts map[string]interface{}) (goukv.Provider, error) {
+	path, ok := opts["path"].(string)
+	if !ok {
+		return nil, errors.New("must specify path")
+	}
+
+	// badger v2.0.2 always detects the transaction conflicts, WithDetectConflicts
+	// only exists starting from v2.2007 so disabling it is refused instead of ignored
+	if detectConflicts, ok := opts["detect_conflicts"].(bool); ok && !detectConflicts {
+		return nil, errors.New("detect_conflicts can't be disabled, it requires badger >= v2.2007")
+	}
+
+	dirPerm, ok := opts["dir_perm"].(os.FileMode)
+	if !ok {
+		dirPerm = 0700
+	}
+
+	errorIfMissing, ok := opts["error_if_missing"].(bool)
+	if !ok {
+		errorIfMissing = false
+	}
+
+	// badger creates its MANIFEST with the db, so a directory without it holds no db
+	if _, err := os.Stat(filepath.Join(path, badger.ManifestFilename)); errorIfMissing && os.IsNotExist(err) {
+		return nil, goukv.ErrDBNotFound
+	}
+
+	syncWrites, ok := opts["sync_writes"].(bool)
+	if !ok {
+		syncWrites = false
+	}
+
+	// the created directories are made durable along with the writes
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := goukv.CreateDir(path, dirPerm, syncWrites); err != nil {
+			return nil, err
+		}
+	}
+
+	provider := newProvider(opts)
+
+	valueDir, ok := opts["value_dir"].(string)
+	if !ok || valueDir == "" {
+		valueDir = path
+	}
+
+	if _, err := os.Stat(valueDir); os.IsNotExist(err) {
+		if err := goukv.CreateDir(valueDir, dirPerm, syncWrites); err != nil {
+			return nil, err
+		}
+	}
+
+	compressions := map[string]options.CompressionType{
+		"none":   options.None,
+		"snappy": options.Snappy,
+		"zstd":   options.ZSTD,
+	}
+
+	compression, ok := opts["compression"].(string)
+	if !ok {
+		compression = "snappy"
+	}
+
+	compressionType, ok := compressions[compression]
+	if !ok {
+		return nil, errors.New("unknown compression: " + compression)
+	}
+
+	zstdLevel, ok := opts["zstd_level"].(int)
+	if !ok {
+		zstdLevel = 1
+	}
+
+	keepL0InMemory, ok := opts["keep_l0_in_memory"].(bool)
+	if !ok {
+		keepL0InMemory = true
+	}
+
+	loadingModes := map[string]options.FileLoadingMode{
+		"memory_map":  options.MemoryMap,
+		"file_io":     options.FileIO,
+		"load_to_ram": options.LoadToRAM,
+	}
+
+	tableLoading, ok := opts["table_loading_mode"].(string)
+	if !ok {
+		tableLoading = "memory_map"
+	}
+
+	tableLoadingMode, ok := loadingModes[tableLoading]
+	if !ok {
+		return nil, errors.New("unknown table_loading_mode: " + tableLoading)
+	}
+
+	valueLogLoading, ok := opts["value_log_loading_mode"].(string)
+	if !ok {
+		valueLogLoading = "memory_map"
+	}
+
+	// the value log files can't be loaded to RAM
+	valueLogLoadingMode, ok := loadingModes[valueLogLoading]
+	if !ok || valueLogLoadingMode == options.LoadToRAM {
+		return nil, errors.New("unknown value_log_loading_mode: " + valueLogLoading)
+	}
+
+	badgerOpts := badger.DefaultOptions(path)
+
+	// values shorter than the threshold are stored inline in the LSM tree instead of being referenced
+	// from the value log, badger refuses a threshold above 1MB so larger ones are capped to it
+	valueThreshold, ok := opts["value_threshold"].(int)
+	if !ok || valueThreshold <= 0 {
+		valueThreshold = badgerOpts.ValueThreshold
+	}
+
+	if valueThreshold > maxValueThreshold {
+		valueThreshold = maxValueThreshold
+	}
+
+	// badger keeps every table and value log file open, so larger files mean fewer descriptors
+	maxTableSize, ok := opts["max_table_size"].(int64)
+	if !ok || maxTableSize <= 0 {
+		maxTableSize = badgerOpts.MaxTableSize
+	}
+
+	valueLogFileSize, ok := opts["value_log_file_size"].(int64)
+	if !ok || valueLogFileSize <= 0 {
+		valueLogFileSize = badgerOpts.ValueLogFileSize
+	}
+
+	badgerOpts = badgerOpts.
+		WithValueDir(valueDir).
+		WithSyncWrites(syncWrites).
+		WithLogger(nil).
+		WithKeepL0InMemory(keepL0InMemory).
+		WithTableLoadingMode(tableLoadingMode).
+		WithValueLogLoadingMode(valueLogLoadingMode).
+		WithCompression(compressionType).
+		WithZSTDCompressionLevel(zstdLevel).
+		WithValueThreshold(valueThreshold).
+		WithMaxTableSize(maxTableSize).
+		WithValueLogFileSize(valueLogFileSize)
+
+	var db *badger.DB
+	err := goukv.NewOpenRetry(opts).Do(func() (err error) {
+		db, err = badger.Open(badgerOpts)
+		return err
+	})
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	// badger only verifies the blocks of the tables it reads, so a corrupted table would fail a later read
+	if verify, _ := opts["verify_on_open"].(bool); verify {
+		if err := db.VerifyChecksum(); err != nil {
+			db.Close()
+			return nil, goukv.WrapError(goukv.ErrorCorruption, err)
+		}
+	}
+
+	if err := provider.attach(db, badgerOpts); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	provider.runValueLogGC()
+
+	if watchdog := goukv.NewDiskWatchdog(opts); watchdog != nil {
+		provider.gcDone.Add(1)
+		go (func() {
+			defer provider.gcDone.Done()
+
+			watchdog.Run(provider.gcStop, []string{path, valueDir}, provider.valueLogGC)
+		})()
+	}
+
+	return provider, nil
+}
+
+// newProvider builds a provider from the options that don't configure badger itself (the changelog,
+// the timestamps, the tombstones and the write limits), the caller attaches its db
+func newProvider(opts map[string]interface{}) *Provider {
+	changelog, ok := opts["enable_changelog"].(bool)
+	if !ok {
+		changelog = false
+	}
+
+	timestamps, ok := opts["track_timestamps"].(bool)
+	if !ok {
+		timestamps = false
+	}
+
+	trackDeletes, ok := opts["track_deletes"].(bool)
+	if !ok {
+		trackDeletes = false
+	}
+
+	tombstoneTTL, ok := opts["tombstone_ttl"].(time.Duration)
+	if !ok || tombstoneTTL <= 0 {
+		tombstoneTTL = goukv.DefaultTombstoneTTL
+	}
+
+	// zero means the value log file size, which the values can't exceed anyway
+	maxValueSize, _ := opts["max_value_size"].(int)
+
+	// zero means the scans keep a single snapshot
+	scanSnapshotMaxDuration, _ := opts["scan_snapshot_max_duration"].(time.Duration)
+
+	return &Provider{
+		gcStop:        make(chan struct{}),
+		gcDone:        &sync.WaitGroup{},
+		closed:        new(int32),
+		changelog:     changelog,
+		changelogLock: &sync.Mutex{},
+		changelogSeq:  new(uint64),
+		events:        goukv.NewEventHub(),
+		ttlJitter:     goukv.NewTTLJitter(opts["ttl_jitter"]),
+		prefixTTLs:    goukv.NewPrefixTTLs(opts["prefix_ttls"]),
+		throttle:      goukv.NewWriteThrottle(opts),
+		maxValueSize:  maxValueSize,
+		timestamps:    timestamps,
+		trackDeletes:  trackDeletes,
+		tombstoneTTL:  tombstoneTTL,
+		maintenance:   goukv.NewMaintenanceHook(opts),
+
+		scanSnapshotMaxDuration: scanSnapshotMaxDuration,
+	}
+}
+
+// attach sets the db of the provider and the options it has been opened with, restoring its last changelog sequence
+func (p *Provider) attach(db *badger.DB, badgerOpts badger.Options) error {
+	p.db, p.options, p.syncWrites = db, badgerOpts, badgerOpts.SyncWrites
+
+	if p.maxValueSize <= 0 || p.maxValueSize > int(badgerOpts.ValueLogFileSize) {
+		p.maxValueSize = int(badgerOpts.ValueLogFileSize)
+	}
+
+	if !p.changelog {
+		return nil
+	}
+
+	return db.View(func(txn *badger.Txn) error {
+		iterOpts := badger.DefaultIteratorOptions
+		iterOpts.PrefetchValues = false
+		iterOpts.Reverse = true
+		iterOpts.Prefix = goukv.ChangelogPrefix
+
+		iter := txn.NewIterator(iterOpts)
+		defer iter.Close()
+
+		if iter.Seek(goukv.ChangeKey(math.MaxUint64)); iter.Valid() {
+			*p.changelogSeq = goukv.ChangeSeq(iter.Item().Key())
+		}
+
+		return nil
+	})
+}
+
+// runValueLogGC starts the goroutine running the value log GC every 5 minutes until the provider is closed
+func (p *Provider) runValueLogGC() {
+	p.gcDone.Add(1)
+	go (func() {
+		defer p.gcDone.Done()
+
+		ticker := time.NewTicker(5 * time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.gcStop:
+				return
+			case <-ticker.C:
+			}
+
+			p.valueLogGC()
+		}
+	})()
+}
+
+// valueLogGC runs the value log GC until it has nothing left to rewrite, as a single maintenance run
+func (p *Provider) valueLogGC() {
+	p.maintenance.Run(goukv.MaintenanceValueLogGC, []string{p.options.Dir, p.options.ValueDir}, func() error {
+		for p.db.RunValueLogGC(0.5) == nil {
+		}
+		return nil
+	})
+}
+
+// FromDB wraps an already opened badger db without calling Open, badgerOpts must be the options db has been opened with
+// (badger doesn't expose them, they bound max_value_size and configure Checkpoint), opts accepts the options that don't
+// configure badger itself (i.e "enable_changelog", "track_timestamps", "max_value_size") with the same defaults, the others
+// are ignored. the periodic value log GC only runs when "value_log_gc" is true, the provider takes the ownership of db:
+// closing it closes db
+func FromDB(db *badger.DB, badgerOpts badger.Options, opts map[string]interface{}) (goukv.Provider, error) {
+	provider := newProvider(opts)
+	if err := provider.attach(db, badgerOpts); err != nil {
+		return nil, err
+	}
+
+	if gc, ok := opts["value_log_gc"].(bool); ok && gc {
+		provider.runValueLogGC()
+	}
+
+	return provider, nil
+}
+
+// newBadgerEntry builds the badger entry of the specified entry, an absolute ExpireAt wins over the TTL
+func newBadgerEntry(entry *goukv.Entry) *badger.Entry {
+	badgerEntry := badger.NewEntry(entry.Key, entry.Value)
+
+	if expires := entry.ExpiresAt(); expires != nil {
+		// zero means "never expires" to badger, so dates before 1970 are clamped to an already expired one
+		badgerEntry.ExpiresAt = 1
+		if unix := expires.Unix(); unix > 1 {
+			badgerEntry.ExpiresAt = uint64(unix)
+		}
+	}
+
+	return badgerEntry
+}
+
+// withTimestamps prefixes the value of the specified badger entry with the creation time (now when zero) and the current time
+func withTimestamps(badgerEntry *badger.Entry, created time.Time) *badger.Entry {
+	now := goukv.Now()
+	if created.IsZero() {
+		created = now
+	}
+
+	val := make([]byte, timestampsSize+len(badgerEntry.Value))
+	binary.BigEndian.PutUint64(val, uint64(created.UnixNano()))
+	binary.BigEndian.PutUint64(val[8:], uint64(now.UnixNano()))
+	copy(val[timestampsSize:], badgerEntry.Value)
+
+	badgerEntry.Value = val
+	badgerEntry.UserMeta |= metaTimestamps
+
+	return badgerEntry
+}
+
+// itemValue calls fn with the value of the specified item without its timestamps prefix
+func itemValue(item *badger.Item, fn func(val []byte) error) error {
+	return item.Value(func(val []byte) error {
+		if item.UserMeta()&metaTimestamps != 0 && len(val) >= timestampsSize {
+			val = val[timestampsSize:]
+		}
+		return fn(val)
+	})
+}
+
+// itemValueCopy returns a copy of the value of the specified item without its timestamps prefix
+func itemValueCopy(item *badger.Item) ([]byte, error) {
+	var val []byte
+	err := itemValue(item, func(v []byte) error {
+		val = append([]byte{}, v...)
+		return nil
+	})
+	return val, err
+}
+
+// itemValueSize returns the size of the value of the specified item without its timestamps prefix
+func itemValueSize(item *badger.Item) int64 {
+	if item.UserMeta()&metaTimestamps != 0 {
+		return item.ValueSize() - timestampsSize
+	}
+	return item.ValueSize()
+}
+
+// itemTimestamps returns the creation and update times of the specified item, zero when they aren't tracked
+func itemTimestamps(item *badger.Item) (created, updated time.Time, err error) {
+	if item.UserMeta()&metaTimestamps == 0 {
+		return
+	}
+
+	err = item.Value(func(val []byte) error {
+		if len(val) >= timestampsSize {
+			created = time.Unix(0, int64(binary.BigEndian.Uint64(val)))
+			updated = time.Unix(0, int64(binary.BigEndian.Uint64(val[8:])))
+		}
+		return nil
+	})
+
+	return
+}
+
+// tombstoneEntry builds the tombstone of the specified deleted key, it expires after tombstone_ttl
+func (p Provider) tombstoneEntry(k []byte) *badger.Entry {
+	badgerEntry := badger.NewEntry(k, []byte{}).WithMeta(metaTombstone)
+	badgerEntry.ExpiresAt = uint64(goukv.Now().Add(p.tombstoneTTL).Unix())
+
+	return badgerEntry
+}
+
+// deleteKey deletes the specified key in txn, a tombstone is written instead in track_deletes mode
+func (p Provider) deleteKey(txn *badger.Txn, k []byte) error {
+	if p.trackDeletes {
+		return txn.SetEntry(p.tombstoneEntry(k))
+	}
+
+	return txn.Delete(k)
+}
+
+// itemTombstone whether the specified item is the tombstone of a deleted key
+func itemTombstone(item *badger.Item) bool {
+	return item.UserMeta()&metaTombstone != 0
+}
+
+// itemHidden whether the specified item isn't visible to the reads, either expired or a tombstone
+func itemHidden(item *badger.Item) bool {
+	return itemTombstone(item) || itemExpired(item)
+}
+
+// itemExpired whether the specified item is expired according to goukv.Now, badger itself only hides
+// the items expired according to the system clock
+func itemExpired(item *badger.Item) bool {
+	expiresAt := item.ExpiresAt()
+	return expiresAt > 0 && expiresAt <= uint64(goukv.Now().Unix())
+}
+
+// getItem returns the item of the specified key like txn.Get, the items expired according to goukv.Now
+// and the tombstones aren't found
+func getItem(txn *badger.Txn, k []byte) (*badger.Item, error) {
+	item, err := txn.Get(k)
+	if err == nil && itemHidden(item) {
+		return nil, badger.ErrKeyNotFound
+	}
+
+	return item, err
+}
+
+// createdAt returns the creation time of the live value of the specified key, zero if there is none
+func createdAt(txn *badger.Txn, k []byte) (time.Time, error) {
+	item, err := getItem(txn, k)
+	if err == badger.ErrKeyNotFound {
+		return time.Time{}, nil
+	}
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	created, _, err := itemTimestamps(item)
+
+	return created, err
+}
+
+// setEntry writes the specified entry in txn, keeping its creation time when track_timestamps is set
+func (p Provider) setEntry(txn *badger.Txn, entry *goukv.Entry) error {
+	badgerEntry := newBadgerEntry(entry)
+	if p.timestamps {
+		created, err := createdAt(txn, entry.Key)
+		if err != nil {
+			return err
+		}
+		withTimestamps(badgerEntry, created)
+	}
+
+	return txn.SetEntry(badgerEntry)
+}
+
+// prepareEntry applies the provider-level entry options (such as the default ttl of its prefix and the ttl jitter)
+// to a copy of the entry
+func (p Provider) prepareEntry(e *goukv.Entry) *goukv.Entry {
+	e = goukv.WithPrefixTTL(e, p.prefixTTLs)
+
+	if p.ttlJitter == nil || e.TTL <= 0 || e.ExpireAt != nil {
+		return e
+	}
+
+	prepared := *e
+	prepared.TTL = p.ttlJitter(e.TTL)
+
+	return &prepared
+}
+
+// recordChanges stamps the changes with the sequences following seq and hands their records to set
+func recordChanges(seq *uint64, changes []goukv.Change, set func(k, v []byte) error) error {
+	now := goukv.Now()
+	for _, change := range changes {
+		*seq++
+		change.Seq = *seq
+		change.Time = now
+		if err := set(goukv.ChangeKey(change.Seq), change.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// update runs fn in a read-write transaction, appending the changes to the changelog (if enabled) in the same transaction
+func (p Provider) update(changes []goukv.Change, fn func(txn *badger.Txn) error) error {
+	if !p.changelog {
+		return classifyError(p.events.Commit(changes, func() error {
+			return p.db.Update(fn)
+		}))
+	}
+
+	p.changelogLock.Lock()
+	defer p.changelogLock.Unlock()
+
+	seq := *p.changelogSeq
+	err := p.events.Commit(changes, func() error {
+		return p.db.Update(func(txn *badger.Txn) error {
+			if err := fn(txn); err != nil {
+				return err
+			}
+
+			return recordChanges(&seq, changes, txn.Set)
+		})
+	})
+
+	if err != nil {
+		return classifyError(err)
+	}
+
+	*p.changelogSeq = seq
+
+	return nil
+}
+
+// Put implements goukv.Put
+func (p Provider) Put(entry *goukv.Entry) error {
+	if len(entry.Value) > p.maxValueSize {
+		return goukv.ErrValueTooLarge
+	}
+
+	if p.throttle != nil {
+		p.throttle.Wait(entry)
+	}
+
+	entry = p.prepareEntry(entry)
+
+	changes := []goukv.Change{
+		{Op: goukv.ChangePut, Key: entry.Key, Value: entry.Value, TTL: entry.TTL, ExpireAt: entry.ExpireAt},
+	}
+
+	return p.update(changes, func(txn *badger.Txn) error {
+		return p.setEntry(txn, entry)
+	})
+}
+
+// PutIfChanged implements goukv.IdempotentPutter, the comparison and the write happen in the same transaction,
+// it's throttled like Put even when the entry turns out unchanged
+func (p Provider) PutIfChanged(entry *goukv.Entry) (bool, error) {
+	if len(entry.Value) > p.maxValueSize {
+		return false, goukv.ErrValueTooLarge
+	}
+
+	if p.throttle != nil {
+		p.throttle.Wait(entry)
+	}
+
+	entry = p.prepareEntry(entry)
+
+	changes := []goukv.Change{
+		{Op: goukv.ChangePut, Key: entry.Key, Value: entry.Value, TTL: entry.TTL, ExpireAt: entry.ExpireAt},
+	}
+
+	err := p.update(changes, func(txn *badger.Txn) error {
+		item, err := getItem(txn, entry.Key)
+		if err != nil && err != badger.ErrKeyNotFound {
+			return err
+		}
+
+		if err == nil {
+			var currentExpires *time.Time
+			if expiresAt := item.ExpiresAt(); expiresAt > 0 {
+				t := time.Unix(int64(expiresAt), 0)
+				currentExpires = &t
+			}
+			newExpires := entry.ExpiresAt()
+
+			same := false
+			err := itemValue(item, func(val []byte) error {
+				same = bytes.Equal(val, entry.Value)
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
+
+			if same && goukv.SameExpiry(currentExpires, newExpires) {
+				return errUnchanged
+			}
+		}
+
+		return p.setEntry(txn, entry)
+	})
+
+	if err == errUnchanged {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// PutVersioned implements goukv.VersionedPutter using badger's native versions (the commit timestamps of the values),
+// the version is compared and the entry written in a single transaction, which is retried when it conflicts with a
+// concurrent write of the key so that the new version is compared, it's throttled like Put (once, not per retry)
+func (p Provider) PutVersioned(entry *goukv.Entry, expectedVersion uint64) (uint64, error) {
+	if len(entry.Value) > p.maxValueSize {
+		return 0, goukv.ErrValueTooLarge
+	}
+
+	if p.throttle != nil {
+		p.throttle.Wait(entry)
+	}
+
+	entry = p.prepareEntry(entry)
+
+	changes := []goukv.Change{
+		{Op: goukv.ChangePut, Key: entry.Key, Value: entry.Value, TTL: entry.TTL, ExpireAt: entry.ExpireAt},
+	}
+
+	for {
+		var readTs uint64
+		err := p.update(changes, func(txn *badger.Txn) error {
+			readTs = txn.ReadTs()
+
+			var current uint64
+			item, err := getItem(txn, entry.Key)
+			if err == nil {
+				current = item.Version()
+			} else if err != badger.ErrKeyNotFound {
+				return err
+			}
+
+			if current != expectedVersion {
+				return errMismatch
+			}
+
+			return p.setEntry(txn, entry)
+		})
+
+		if goukv.IsErrorKind(err, goukv.ErrorConflict) {
+			continue
+		}
+
+		if err == errMismatch {
+			return 0, goukv.ErrConflict
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		return p.commitVersion(entry.Key, readTs)
+	}
+}
+
+// commitVersion returns the version the specified key has been written with by a transaction that read it at readTs,
+// badger doesn't expose the commit timestamps but a write of the key committed in between would have conflicted
+// with that transaction, so its version is the oldest one of the key newer than readTs
+func (p Provider) commitVersion(k []byte, readTs uint64) (uint64, error) {
+	var version uint64
+	err := p.db.View(func(txn *badger.Txn) error {
+		iterOpts := badger.DefaultIteratorOptions
+		iterOpts.PrefetchValues = false
+		iterOpts.AllVersions = true
+
+		iter := txn.NewIterator(iterOpts)
+		defer iter.Close()
+
+		// the versions of a key are iterated from the newest
+		for iter.Seek(k); iter.Valid() && bytes.Equal(iter.Item().Key(), k); iter.Next() {
+			if v := iter.Item().Version(); v > readTs {
+				version = v
+			}
+		}
+
+		return nil
+	})
+
+	if err == nil && version == 0 {
+		err = errors.New("the version of the written value has been discarded")
+	}
+
+	return version, classifyError(err)
+}
+
+// CompareAndDelete implements goukv.CompareAndDeleter, the value is compared and deleted in a single transaction,
+// which is retried when it conflicts with a concurrent write of the key so that the new value is compared
+func (p Provider) CompareAndDelete(k, old []byte) (bool, error) {
+	changes := []goukv.Change{
+		{Op: goukv.ChangeDelete, Key: k},
+	}
+
+	for {
+		err := p.update(changes, func(txn *badger.Txn) error {
+			item, err := getItem(txn, k)
+			if err == badger.ErrKeyNotFound {
+				return errMismatch
+			}
+
+			if err != nil {
+				return err
+			}
+
+			same := false
+			err = itemValue(item, func(val []byte) error {
+				same = bytes.Equal(val, old)
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
+
+			if !same {
+				return errMismatch
+			}
+
+			return p.deleteKey(txn, k)
+		})
+
+		if goukv.IsErrorKind(err, goukv.ErrorConflict) {
+			continue
+		}
+
+		if err == errMismatch {
+			return false, nil
+		}
+
+		return err == nil, err
+	}
+}
+
+// PutSync implements goukv.SyncWriter, badger's sync option applies to the whole db so a forced sync write
+// is followed by a sync of the db, a sync_writes db always syncs (sync=false can't skip it)
+func (p Provider) PutSync(entry *goukv.Entry, sync bool) error {
+	return p.syncAfter(sync, p.Put(entry))
+}
+
+// DeleteSync implements goukv.SyncWriter
+func (p Provider) DeleteSync(k []byte, sync bool) error {
+	return p.syncAfter(sync, p.Delete(k))
 }
 
-// Open implements goukv.Open
-func (p Provider) Open(opts map[string]interface{}) (goukv.Provider, error) {
-	path, ok := opts["path"].(string)
-	if !ok {
-		return nil, errors.New("must specify path")
+// BatchSync implements goukv.SyncWriter
+func (p Provider) BatchSync(entries []*goukv.Entry, sync bool) error {
+	return p.syncAfter(sync, p.Batch(entries))
+}
+
+// syncAfter syncs the db after a successful write when sync is forced and the db doesn't sync its writes already
+func (p Provider) syncAfter(sync bool, err error) error {
+	if err != nil || !sync || p.syncWrites {
+		return err
+	}
+
+	return classifyError(p.db.Sync())
+}
+
+// ValidateBatch implements goukv.BatchValidator using badger's limits and max_value_size
+func (p Provider) ValidateBatch(entries []*goukv.Entry) error {
+	return goukv.ValidateEntries(entries, maxKeySize, p.maxValueSize)
+}
+
+// createdTimes returns the creation times of the live keys of the specified entries, they are read before
+// the batch is written so a key created concurrently may get the time of the batch instead
+func (p Provider) createdTimes(entries []*goukv.Entry) (map[string]time.Time, error) {
+	created := map[string]time.Time{}
+	err := p.db.View(func(txn *badger.Txn) error {
+		for _, entry := range entries {
+			t, err := createdAt(txn, entry.Key)
+			if err != nil {
+				return err
+			}
+			if !t.IsZero() {
+				created[string(entry.Key)] = t
+			}
+		}
+		return nil
+	})
+
+	return created, err
+}
+
+// Batch perform multi put operation, empty value means *delete*,
+// the pending writes of a badger transaction are keyed by key, so a key written twice keeps its last entry
+func (p Provider) Batch(entries []*goukv.Entry) error {
+	if err := p.ValidateBatch(entries); err != nil {
+		return err
+	}
+
+	if p.throttle != nil {
+		p.throttle.Wait(entries...)
+	}
+
+	var created map[string]time.Time
+	if p.timestamps {
+		var err error
+		if created, err = p.createdTimes(entries); err != nil {
+			return err
+		}
+	}
+
+	batch := p.db.NewWriteBatch()
+	defer batch.Cancel()
+
+	changes := make([]goukv.Change, 0, len(entries))
+
+	for _, entry := range entries {
+		entry = p.prepareEntry(entry)
+
+		var err error
+		if entry.Value == nil && p.trackDeletes {
+			err = batch.SetEntry(p.tombstoneEntry(entry.Key))
+			changes = append(changes, goukv.Change{Op: goukv.ChangeDelete, Key: entry.Key})
+		} else if entry.Value == nil {
+			err = batch.Delete(entry.Key)
+			changes = append(changes, goukv.Change{Op: goukv.ChangeDelete, Key: entry.Key})
+		} else {
+			changes = append(changes, goukv.Change{Op: goukv.ChangePut, Key: entry.Key, Value: entry.Value, TTL: entry.TTL, ExpireAt: entry.ExpireAt})
+			badgerEntry := newBadgerEntry(entry)
+			if p.timestamps {
+				withTimestamps(badgerEntry, created[string(entry.Key)])
+			}
+			err = batch.SetEntry(badgerEntry)
+		}
+
+		if err != nil {
+			batch.Cancel()
+			return err
+		}
+	}
+
+	if !p.changelog {
+		return classifyError(p.events.Commit(changes, batch.Flush))
+	}
+
+	p.changelogLock.Lock()
+	defer p.changelogLock.Unlock()
+
+	seq := *p.changelogSeq
+	if err := recordChanges(&seq, changes, batch.Set); err != nil {
+		return err
+	}
+
+	if err := p.events.Commit(changes, batch.Flush); err != nil {
+		return classifyError(err)
+	}
+
+	*p.changelogSeq = seq
+
+	return nil
+}
+
+// Get implements goukv.Get
+func (p Provider) Get(k []byte) ([]byte, error) {
+	var data []byte
+	err := p.db.View(func(txn *badger.Txn) error {
+		item, err := getItem(txn, k)
+		if err == badger.ErrKeyNotFound {
+			return goukv.ErrKeyNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		d, err := itemValueCopy(item)
+		if err != nil {
+			return err
+		}
+
+		data = d
+
+		return err
+	})
+
+	return data, classifyError(err)
+}
+
+// GetRaw implements goukv.RawGetter, badger hides the expired keys natively
+// so expired is always false, an expired key is simply reported as not found
+func (p Provider) GetRaw(k []byte) ([]byte, bool, error) {
+	val, err := p.Get(k)
+	return val, false, err
+}
+
+// GetFunc implements goukv.FuncGetter, fn receives the value within the read transaction without copying it
+func (p Provider) GetFunc(k []byte, fn func(val []byte) error) error {
+	err := p.db.View(func(txn *badger.Txn) error {
+		item, err := getItem(txn, k)
+		if err == badger.ErrKeyNotFound {
+			return goukv.ErrKeyNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return itemValue(item, fn)
+	})
+
+	return classifyError(err)
+}
+
+// GetEntry implements goukv.EntryGetter
+func (p Provider) GetEntry(k []byte) (*goukv.Entry, error) {
+	var entry *goukv.Entry
+	err := p.db.View(func(txn *badger.Txn) error {
+		item, err := getItem(txn, k)
+		if err == badger.ErrKeyNotFound {
+			return goukv.ErrKeyNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		entry, err = itemEntry(item)
+		return err
+	})
+
+	return entry, classifyError(err)
+}
+
+// itemEntry returns the entry of the specified item
+func itemEntry(item *badger.Item) (*goukv.Entry, error) {
+	val, err := itemValueCopy(item)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &goukv.Entry{
+		Key:     item.KeyCopy(nil),
+		Value:   val,
+		Version: item.Version(),
 	}
 
-	dir := filepath.Dir(path)
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-			return nil, err
-		}
+	if entry.CreatedAt, entry.UpdatedAt, err = itemTimestamps(item); err != nil {
+		return nil, err
 	}
 
-	syncWrites, ok := opts["sync_writes"].(bool)
-	if !ok {
-		syncWrites = false
+	if expiresAt := item.ExpiresAt(); expiresAt > 0 {
+		expires := time.Unix(int64(expiresAt), 0)
+		entry.ExpireAt, entry.TTL = &expires, expires.Sub(goukv.Now())
 	}
 
-	badgerOpts := badger.DefaultOptions(path)
+	return entry, nil
+}
 
-	badgerOpts.WithSyncWrites(syncWrites)
-	badgerOpts.WithLogger(nil)
-	badgerOpts.WithKeepL0InMemory(true)
-	badgerOpts.WithCompression(options.Snappy)
+// TTL implements goukv.TTL
+func (p Provider) TTL(k []byte) (*time.Time, error) {
+	var t *time.Time
+	err := p.db.View(func(txn *badger.Txn) error {
+		item, err := getItem(txn, k)
+		if err == badger.ErrKeyNotFound {
+			return goukv.ErrKeyNotFound
+		}
 
-	db, err := badger.Open(badgerOpts)
-	if err != nil {
-		return nil, err
-	}
+		if err != nil {
+			return err
+		}
 
-	go (func() {
-		ticker := time.NewTicker(5 * time.Minute)
-		defer ticker.Stop()
+		expiresAt := item.ExpiresAt()
+		if expiresAt > 0 {
+			toUnix := time.Unix(int64(expiresAt), 0)
+			t = &toUnix
+		}
+
+		return err
+	})
 
-		for range ticker.C {
-			for {
-				err := db.RunValueLogGC(0.5)
-				if err != nil {
+	return t, classifyError(err)
+}
+
+// ExpiringBefore implements goukv.ExpiringScanner, it only reads the keys metadata
+// (values aren't loaded), keys without a TTL, tombstones and the internal keys are skipped
+func (p Provider) ExpiringBefore(t time.Time, fn func(key []byte, expires time.Time) error) error {
+	return p.db.View(func(txn *badger.Txn) error {
+		iterOpts := badger.DefaultIteratorOptions
+		iterOpts.PrefetchValues = false
+
+		iter := txn.NewIterator(iterOpts)
+		defer iter.Close()
+
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			item := iter.Item()
+			if goukv.IsInternalKey(item.Key()) {
+				if iter.Seek(prefixLimit(goukv.InternalPrefix)); !iter.Valid() {
 					break
 				}
+				item = iter.Item()
 			}
-		}
-	})()
 
-	return &Provider{
-		db: db,
-	}, nil
-}
+			expiresAt := item.ExpiresAt()
+			if expiresAt == 0 || itemTombstone(item) {
+				continue
+			}
 
-// Put implements goukv.Put
-func (p Provider) Put(entry *goukv.Entry) error {
-	return p.db.Update(func(txn *badger.Txn) error {
-		if entry.TTL > 0 {
-			badgerEntry := badger.NewEntry(entry.Key, entry.Value)
-			badgerEntry.WithTTL(entry.TTL)
-			return txn.SetEntry(badgerEntry)
+			expires := time.Unix(int64(expiresAt), 0)
+			if !expires.Before(t) {
+				continue
+			}
+
+			if err := fn(item.KeyCopy(nil), expires); err != nil {
+				if err == goukv.ErrScanDone {
+					break
+				}
+				return err
+			}
 		}
 
-		return txn.Set(entry.Key, entry.Value)
+		return nil
 	})
 }
 
-// Batch perform multi put operation, empty value means *delete*
-func (p Provider) Batch(entries []*goukv.Entry) error {
-	batch := p.db.NewWriteBatch()
-	defer batch.Cancel()
+// PrefixStats implements goukv.PrefixStatsReader, the count is exact and the bytes (keys + values sizes) are
+// read from the keys metadata without loading the values, the size of the values stored in the value log
+// is estimated by badger within a few header bytes, the internal keys aren't counted unless the prefix is internal
+// itself (see ScanOpts.SkipsInternal)
+func (p Provider) PrefixStats(prefix []byte) (int64, int64, error) {
+	external := (goukv.ScanOpts{Prefix: prefix}).SkipsInternal()
 
-	for _, entry := range entries {
-		var err error
-		if entry.Value == nil {
-			err = batch.Delete(entry.Key)
-		} else {
-			if entry.TTL > 0 {
-				badgerEntry := badger.NewEntry(entry.Key, entry.Value)
-				badgerEntry.WithTTL(entry.TTL)
+	var count, size int64
+	err := p.db.View(func(txn *badger.Txn) error {
+		iterOpts := badger.DefaultIteratorOptions
+		iterOpts.PrefetchValues = false
+		iterOpts.Prefix = prefix
 
-				err = batch.SetEntry(badgerEntry)
-			} else {
-				err = batch.Set(entry.Key, entry.Value)
+		iter := txn.NewIterator(iterOpts)
+		defer iter.Close()
+
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			item := iter.Item()
+			if itemHidden(item) || (external && goukv.IsInternalKey(item.Key())) {
+				continue
 			}
+			count++
+			size += item.KeySize() + itemValueSize(item)
 		}
 
-		if err != nil {
-			batch.Cancel()
-			return err
+		return nil
+	})
+
+	return count, size, err
+}
+
+// RangeSize implements goukv.RangeSizer, it sums the estimated sizes (keys and values, including the value log) of the
+// tables overlapping the range, the share of a table partially in the range is interpolated from its smallest and
+// largest keys so it's rough unless the range covers whole tables, the older versions still stored are counted while
+// the memtables (the writes not flushed yet) aren't
+func (p Provider) RangeSize(start, end []byte) (int64, error) {
+	var size float64
+	for _, table := range p.db.Tables(false) {
+		size += float64(table.EstimatedSz) * rangeShare(y.ParseKey(table.Left), y.ParseKey(table.Right), start, end)
+	}
+
+	return int64(size), nil
+}
+
+// rangeShare returns the estimated share (0 to 1) of the keys of a table spanning [left, right] that are in [start, end)
+func rangeShare(left, right, start, end []byte) float64 {
+	if (end != nil && bytes.Compare(left, end) >= 0) || (start != nil && bytes.Compare(right, start) < 0) {
+		return 0
+	}
+
+	lo, hi := left, right
+	if start != nil && bytes.Compare(start, lo) > 0 {
+		lo = start
+	}
+	if end != nil && bytes.Compare(end, hi) < 0 {
+		hi = end
+	}
+
+	if bytes.Equal(lo, left) && bytes.Equal(hi, right) {
+		return 1
+	}
+
+	// the keys are read as numbers from the first byte where left and right differ
+	n := 0
+	for n < len(left) && n < len(right) && left[n] == right[n] {
+		n++
+	}
+
+	number := func(k []byte) float64 {
+		var b [8]byte
+		if n < len(k) {
+			copy(b[:], k[n:])
 		}
+		return float64(binary.BigEndian.Uint64(b[:]))
 	}
 
-	return batch.Flush()
+	span := number(right) - number(left)
+	if span <= 0 {
+		return 1
+	}
+
+	return (number(hi) - number(lo)) / span
 }
 
-// Get implements goukv.Get
-func (p Provider) Get(k []byte) ([]byte, error) {
-	var data []byte
-	err := p.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(k)
+// ForceGCKey implements goukv.KeyGCForcer, badger's value log GC works on whole value log files so it can't drop the
+// versions of a single key: the live value of the key is rewritten as is (its expiration and timestamps are kept, it's
+// neither recorded in the changelog nor watched but its version changes) so that it's in the newest value log file,
+// then the value log GC runs until it misses several times in a row (as a maintenance run, see "on_maintenance"). the
+// files holding its older versions can only be reclaimed once the memtable referencing them has been flushed, so the
+// versions written since the last flush may stay until a later GC, a missing key is only GCed
+func (p Provider) ForceGCKey(key []byte) error {
+	err := p.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
 		if err == badger.ErrKeyNotFound {
-			return goukv.ErrKeyNotFound
+			return nil
 		}
 
 		if err != nil {
 			return err
 		}
 
-		d, err := item.ValueCopy(nil)
+		v, err := item.ValueCopy(nil)
 		if err != nil {
 			return err
 		}
 
-		data = d
+		entry := badger.NewEntry(key, v).WithMeta(item.UserMeta())
+		entry.ExpiresAt = item.ExpiresAt()
 
-		return err
+		return txn.SetEntry(entry)
 	})
 
-	return data, err
+	// a concurrent write already rewrote it
+	if err != nil && err != badger.ErrConflict {
+		return classifyError(err)
+	}
+
+	// badger picks the files and samples their entries randomly, so a file worth rewriting may be missed by a run
+	p.maintenance.Run(goukv.MaintenanceValueLogGC, []string{p.options.Dir, p.options.ValueDir}, func() error {
+		for misses := 0; misses < forceGCMaxMisses; {
+			if err := p.db.RunValueLogGC(0.5); err == nil {
+				misses = 0
+			} else if err == badger.ErrNoRewrite {
+				misses++
+			} else {
+				break
+			}
+		}
+		return nil
+	})
+
+	return nil
 }
 
-// TTL implements goukv.TTL
-func (p Provider) TTL(k []byte) (*time.Time, error) {
-	var t *time.Time
+// Histogram implements goukv.HistogramReader, only the keys are iterated and the value sizes are read
+// from their metadata (see PrefixStats for their accuracy), the internal keys are skipped
+func (p Provider) Histogram() (goukv.Histogram, error) {
+	var h goukv.Histogram
 	err := p.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(k)
-		if err == badger.ErrKeyNotFound {
-			return goukv.ErrKeyNotFound
-		}
+		iterOpts := badger.DefaultIteratorOptions
+		iterOpts.PrefetchValues = false
 
-		if err != nil {
-			return err
-		}
+		iter := txn.NewIterator(iterOpts)
+		defer iter.Close()
 
-		expiresAt := item.ExpiresAt()
-		if expiresAt > 0 {
-			toUnix := time.Unix(int64(expiresAt), 0)
-			t = &toUnix
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			item := iter.Item()
+			if goukv.IsInternalKey(item.Key()) || itemHidden(item) {
+				continue
+			}
+			h.Add(item.KeySize(), itemValueSize(item))
 		}
 
-		return err
+		return nil
 	})
 
-	return t, err
+	return h, err
 }
 
 // Delete implements goukv.Delete
 func (p Provider) Delete(k []byte) error {
-	return p.db.Update(func(txn *badger.Txn) error {
-		return txn.Delete(k)
+	changes := []goukv.Change{
+		{Op: goukv.ChangeDelete, Key: k},
+	}
+
+	return p.update(changes, func(txn *badger.Txn) error {
+		return p.deleteKey(txn, k)
+	})
+}
+
+// PurgeExpired implements goukv.ExpiredPurger, badger expires the keys natively and
+// drops them during its compactions so there is nothing to purge
+func (p Provider) PurgeExpired() (int64, error) {
+	return 0, nil
+}
+
+// ListPrefixes implements goukv.PrefixLister, once a prefix is found the iterator seeks past all of its keys
+func (p Provider) ListPrefixes(prefix []byte, delimiter byte) ([][]byte, error) {
+	var prefixes [][]byte
+	err := p.db.View(func(txn *badger.Txn) error {
+		iterOpts := badger.DefaultIteratorOptions
+		iterOpts.PrefetchValues = false
+		iterOpts.Prefix = prefix
+
+		iter := txn.NewIterator(iterOpts)
+		defer iter.Close()
+
+		for iter.Rewind(); iter.Valid(); {
+			k := iter.Item().Key()
+			if goukv.IsInternalKey(k) {
+				iter.Seek(prefixLimit(goukv.InternalPrefix))
+				continue
+			}
+
+			i := bytes.IndexByte(k[len(prefix):], delimiter)
+			if i < 0 || itemHidden(iter.Item()) {
+				iter.Next()
+				continue
+			}
+
+			found := append([]byte{}, k[:len(prefix)+i+1]...)
+			prefixes = append(prefixes, found)
+
+			iter.Seek(prefixLimit(found))
+		}
+
+		return nil
+	})
+
+	return prefixes, err
+}
+
+// FirstKey implements goukv.KeyBoundsReader
+func (p Provider) FirstKey(prefix []byte) ([]byte, error) {
+	return p.boundKey(prefix, false)
+}
+
+// LastKey implements goukv.KeyBoundsReader
+func (p Provider) LastKey(prefix []byte) ([]byte, error) {
+	return p.boundKey(prefix, true)
+}
+
+// boundKey returns the first (or the last when reverse) live key having the specified prefix,
+// the key iterator already moves past the expired keys and tombstones
+func (p Provider) boundKey(prefix []byte, reverse bool) ([]byte, error) {
+	it := newKeyIterator(p.db.NewTransaction(false), prefix, reverse, false)
+	it.external = (goukv.ScanOpts{Prefix: prefix}).SkipsInternal()
+	defer it.Close()
+
+	if !it.Next() {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	return it.Key(), nil
+}
+
+// ScanByVersion implements goukv.VersionScanner using badger's native versions (the commit timestamps of the values),
+// no option is needed, the keys and versions are collected and sorted first then their values are read in the same
+// read-only transaction, the keys written by the same commit share a version so they are passed in key order
+func (p Provider) ScanByVersion(fromVersion uint64, fn func(*goukv.Entry) error) error {
+	err := p.db.View(func(txn *badger.Txn) error {
+		type versionedKey struct {
+			key     []byte
+			version uint64
+		}
+
+		var keys []versionedKey
+		iterOpts := badger.DefaultIteratorOptions
+		iterOpts.PrefetchValues = false
+
+		iter := txn.NewIterator(iterOpts)
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			item := iter.Item()
+			if goukv.IsInternalKey(item.Key()) || item.Version() < fromVersion || itemHidden(item) {
+				continue
+			}
+
+			keys = append(keys, versionedKey{key: item.KeyCopy(nil), version: item.Version()})
+		}
+		iter.Close()
+
+		sort.SliceStable(keys, func(i, j int) bool {
+			return keys[i].version < keys[j].version
+		})
+
+		for _, k := range keys {
+			item, err := getItem(txn, k.key)
+			if err == badger.ErrKeyNotFound {
+				// expired since it was listed
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			entry, err := itemEntry(item)
+			if err != nil {
+				return err
+			}
+
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err == goukv.ErrScanDone {
+		return nil
+	}
+
+	return classifyError(err)
+}
+
+// Watch implements goukv.Watcher, the keyspaces dropped by DropKeyspace aren't reported
+func (p Provider) Watch(prefix []byte) (<-chan goukv.Event, func(), error) {
+	events, stop := p.events.Watch(prefix)
+	return events, stop, nil
+}
+
+// WatchWithSnapshot implements goukv.Watcher, the snapshot is read using Scan
+func (p Provider) WatchWithSnapshot(prefix []byte) (<-chan goukv.Event, func(), error) {
+	return p.events.WatchWithSnapshot(prefix, p.Scan)
+}
+
+// Checkpoint implements goukv.Checkpointer, a full backup (from a consistent read timestamp) is streamed
+// into a new db in dir, the expired and deleted keys aren't copied
+func (p Provider) Checkpoint(dir string) error {
+	if err := goukv.EmptyDir(dir); err != nil {
+		return err
+	}
+
+	dst, err := badger.Open(badger.DefaultOptions(dir).
+		WithLogger(nil).
+		WithCompression(p.options.Compression).
+		WithZSTDCompressionLevel(p.options.ZSTDCompressionLevel))
+	if err != nil {
+		return err
+	}
+
+	r, w := io.Pipe()
+	go (func() {
+		_, err := p.db.Backup(w, 0)
+		w.CloseWithError(err)
+	})()
+
+	err = dst.Load(r, 256)
+	r.CloseWithError(err)
+
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// Keyspace implements goukv.KeyspaceManager
+func (p Provider) Keyspace(name string) goukv.Provider {
+	return goukv.NewKeyspace(p, name)
+}
+
+// DropKeyspace implements goukv.KeyspaceManager using badger's DropPrefix, which blocks the writes while
+// it runs and isn't recorded in the changelog
+func (p Provider) DropKeyspace(name string) error {
+	return p.db.DropPrefix(goukv.KeyspaceKeyPrefix(name))
+}
+
+// ReadChanges implements goukv.ChangelogReader, it replays the changes recorded after sinceSeq in order
+func (p Provider) ReadChanges(sinceSeq uint64, fn func(goukv.Change) error) error {
+	return p.db.View(func(txn *badger.Txn) error {
+		iterOpts := badger.DefaultIteratorOptions
+		iterOpts.Prefix = goukv.ChangelogPrefix
+
+		iter := txn.NewIterator(iterOpts)
+		defer iter.Close()
+
+		for iter.Seek(goukv.ChangeKey(sinceSeq + 1)); iter.Valid(); iter.Next() {
+			var change goukv.Change
+			err := iter.Item().Value(func(val []byte) (err error) {
+				change, err = goukv.BytesToChange(val)
+				return err
+			})
+
+			if err != nil {
+				return err
+			}
+
+			if err := fn(change); err != nil {
+				if err == goukv.ErrScanDone {
+					break
+				}
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// TruncateChanges implements goukv.ChangelogReader, it removes the changes recorded before beforeSeq
+func (p Provider) TruncateChanges(beforeSeq uint64) error {
+	batch := p.db.NewWriteBatch()
+	defer batch.Cancel()
+
+	limit := goukv.ChangeKey(beforeSeq)
+	err := p.db.View(func(txn *badger.Txn) error {
+		iterOpts := badger.DefaultIteratorOptions
+		iterOpts.PrefetchValues = false
+		iterOpts.Prefix = goukv.ChangelogPrefix
+
+		iter := txn.NewIterator(iterOpts)
+		defer iter.Close()
+
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			key := iter.Item().KeyCopy(nil)
+			if bytes.Compare(key, limit) >= 0 {
+				break
+			}
+
+			if err := batch.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		return nil
 	})
+
+	if err != nil {
+		return err
+	}
+
+	return batch.Flush()
 }
 
-// Close implements goukv.Close
+// Native implements goukv.NativeAccessor, it returns the underlying *badger.DB, its values are prefixed with their timestamps when track_timestamps is set
+func (p Provider) Native() interface{} {
+	return p.db
+}
+
+// Close implements goukv.Close, it stops the value log GC, syncs the pending async writes and closes the db,
+// closing it again returns goukv.ErrClosed
 func (p Provider) Close() error {
-	return p.db.Close()
+	if !atomic.CompareAndSwapInt32(p.closed, 0, 1) {
+		return goukv.ErrClosed
+	}
+
+	close(p.gcStop)
+	p.gcDone.Wait()
+
+	var err error
+	if !p.syncWrites {
+		err = p.db.Sync()
+	}
+
+	if closeErr := p.db.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
 }
 
-// Scan implements goukv.Scan
+// Scan implements goukv.Scan, SinceVersion is compared with badger's native versions (the commit timestamps of the values),
+// a scan reads a single snapshot unless it runs longer than scan_snapshot_max_duration: its read transaction is then
+// renewed after the current key and the scan resumes from the next one in a new snapshot, so the versions its snapshot
+// pins can be collected but the writes committed meanwhile may be seen
 func (p Provider) Scan(opts goukv.ScanOpts) error {
 	if opts.Scanner == nil {
 		return goukv.ErrNoScanner
 	}
 
-	txn := p.db.NewTransaction(false)
-	defer txn.Commit()
+	// the keys are filtered before their values are read, which aren't prefetched then
+	opts = opts.Sampled()
+	keyFilter := opts.KeyFilter
+	opts.KeyFilter = nil
+	opts.Scanner = opts.BoundedScanner()
 
-	iterOpts := badger.DefaultIteratorOptions
-	iterOpts.Reverse = opts.ReverseScan
+	progress := opts.ProgressTicker()
 
-	if len(opts.Prefix) > 0 {
-		iterOpts.Prefix = opts.Prefix
+	// the iterator bounds the scan to the prefix in both directions, a reverse scan starts
+	// from the greatest key <= offset, or from the last key of the prefix without offset
+	newIterator := func() *keyIterator {
+		it := newKeyIterator(p.db.NewTransaction(false), opts.Prefix, opts.ReverseScan, keyFilter == nil)
+		it.tombstones = opts.IncludeTombstones
+		it.external = opts.SkipsInternal()
+		it.progress = progress
+		return it
 	}
 
-	iter := txn.NewIterator(iterOpts)
-	defer iter.Close()
+	it, started := newIterator(), time.Now()
+	defer (func() {
+		it.Close()
+	})()
+
+	// next moves to the key following the specified one, in a new snapshot once the current one is too old
+	next := func(key []byte) bool {
+		if p.scanSnapshotMaxDuration <= 0 || time.Since(started) < p.scanSnapshotMaxDuration {
+			return it.Next()
+		}
+
+		it.Close()
+		it, started = newIterator(), time.Now()
+
+		if !it.Seek(key) {
+			return false
+		}
+
+		if bytes.Equal(it.iter.Item().Key(), key) {
+			return it.Next()
+		}
+
+		return true
+	}
 
+	var ok bool
 	if opts.Offset != nil {
-		iter.Seek(opts.Offset)
+		ok = it.Seek(opts.Offset)
 	} else {
-		iter.Rewind()
+		ok = it.Next()
 	}
 
-	checked := false
-	for ; iter.Valid(); iter.Next() {
-		item := iter.Item()
+	var key []byte
+	for ; ok; ok = next(key) {
+		item := it.iter.Item()
+		progress()
 
-		key := item.KeyCopy(nil)
-		if !checked && opts.Offset != nil && !opts.IncludeOffset && bytes.Compare(key, opts.Offset) == 0 {
-			checked = true
+		key = item.KeyCopy(nil)
+		if opts.Offset != nil && !opts.IncludeOffset && bytes.Equal(key, opts.Offset) {
 			continue
 		}
-		checked = true
 
-		val, err := item.ValueCopy(nil)
-		if err != nil {
-			return err
+		if opts.SinceVersion > 0 && item.Version() <= opts.SinceVersion {
+			continue
+		}
+
+		if keyFilter != nil && !keyFilter(key) {
+			continue
+		}
+
+		var val []byte
+		if !itemTombstone(item) {
+			v, err := itemValueCopy(item)
+			if err != nil {
+				return classifyError(err)
+			}
+			val = v
 		}
 
 		if err := opts.Scanner(key, val); err != nil {
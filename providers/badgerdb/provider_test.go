@@ -1,23 +1,55 @@
 package badgerdb
 
 import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/alash3al/goukv"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/options"
 )
 
 func openDBAndDo(fn func(db goukv.Provider)) error {
+	return openDBWithOptsAndDo(map[string]interface{}{}, fn)
+}
+
+// tempDir returns a new temporary directory, the caller removes it
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "badgerdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+// openDBWithOptsAndDo opens a db in a temporary directory (unless opts has a path) then calls fn with it
+func openDBWithOptsAndDo(opts map[string]interface{}, fn func(db goukv.Provider)) error {
+	if _, ok := opts["path"]; !ok {
+		dir, err := ioutil.TempDir("", "badgerdb")
+		if err != nil {
+			return err
+		}
+		opts["path"] = filepath.Join(dir, "db")
+
+		// the db must be closed before its directory is removed, it may flush its tables on close
+		defer os.RemoveAll(dir)
+	}
+
 	p := Provider{}
-	db, err := p.Open(map[string]interface{}{
-		"path": "./db",
-	})
+
+	db, err := p.Open(opts)
 	if err != nil {
 		return err
 	}
 	defer db.Close()
-	defer os.RemoveAll("./db")
 
 	fn(db)
 
@@ -72,3 +104,1318 @@ func TestTTL(t *testing.T) {
 		t.Error(err.Error())
 	}
 }
+
+func TestGetEntry(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("k"),
+			Value: []byte("v"),
+			TTL:   time.Second * 10,
+		}
+		err := db.Put(&entry)
+		if err != nil {
+			t.Error(err)
+		}
+		found, err := db.(goukv.EntryGetter).GetEntry(entry.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(found.Key) != string(entry.Key) || string(found.Value) != string(entry.Value) {
+			t.Errorf("expected (%s => %s), found (%s => %s)", entry.Key, entry.Value, found.Key, found.Value)
+		}
+		if found.TTL <= 0 || found.TTL > entry.TTL {
+			t.Errorf("expected ttl within (0, %s], found (%s)", entry.TTL, found.TTL)
+		}
+		if _, err := db.(goukv.EntryGetter).GetEntry([]byte("missing")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected (%v), found (%v)", goukv.ErrKeyNotFound, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestChangelog(t *testing.T) {
+	opts := map[string]interface{}{
+		"enable_changelog": true,
+	}
+
+	err := openDBWithOptsAndDo(opts, func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("k1"), Value: []byte("v1")})
+		db.Batch([]*goukv.Entry{
+			{Key: []byte("k2"), Value: []byte("v2"), TTL: time.Minute},
+			{Key: []byte("k1")},
+		})
+		db.Delete([]byte("k2"))
+
+		expected := []struct {
+			op  goukv.ChangeOp
+			key string
+		}{
+			{goukv.ChangePut, "k1"},
+			{goukv.ChangePut, "k2"},
+			{goukv.ChangeDelete, "k1"},
+			{goukv.ChangeDelete, "k2"},
+		}
+
+		var changes []goukv.Change
+		err := db.(goukv.ChangelogReader).ReadChanges(0, func(change goukv.Change) error {
+			changes = append(changes, change)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != len(expected) {
+			t.Fatalf("expected (%d) changes, found (%d)", len(expected), len(changes))
+		}
+		for i, change := range changes {
+			if change.Seq != uint64(i+1) || change.Op != expected[i].op || string(change.Key) != expected[i].key {
+				t.Errorf("expected (%d %d %s), found (%d %d %s)", i+1, expected[i].op, expected[i].key, change.Seq, change.Op, change.Key)
+			}
+		}
+
+		if err := db.(goukv.ChangelogReader).TruncateChanges(3); err != nil {
+			t.Fatal(err)
+		}
+
+		changes = nil
+		db.(goukv.ChangelogReader).ReadChanges(0, func(change goukv.Change) error {
+			changes = append(changes, change)
+			return nil
+		})
+		if len(changes) != 2 || changes[0].Seq != 3 {
+			t.Errorf("expected the changes from (3) to be kept, found (%v)", changes)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestTTLJitter(t *testing.T) {
+	opts := map[string]interface{}{
+		"ttl_jitter": time.Second * 5,
+	}
+
+	err := openDBWithOptsAndDo(opts, func(db goukv.Provider) {
+		ttl := time.Second * 10
+		start := time.Now()
+
+		var entries []*goukv.Entry
+		for i := 0; i < 50; i++ {
+			entries = append(entries, &goukv.Entry{Key: []byte{byte(i)}, Value: []byte("v"), TTL: ttl})
+		}
+		if err := db.Batch(entries); err != nil {
+			t.Fatal(err)
+		}
+		end := time.Now()
+
+		seen := map[int64]bool{}
+		for _, entry := range entries {
+			expiresAt, err := db.TTL(entry.Key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expiresAt.Before(start.Add(ttl).Truncate(time.Second)) || expiresAt.After(end.Add(ttl+time.Second*5)) {
+				t.Errorf("expected expiry within [%s, %s], found (%s)", start.Add(ttl), end.Add(ttl+time.Second*5), expiresAt)
+			}
+			seen[expiresAt.Unix()] = true
+		}
+		if len(seen) < 2 {
+			t.Errorf("expected the expirations to be spread, found (%d) distinct values", len(seen))
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestExpiringBefore(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		db.Batch([]*goukv.Entry{
+			{Key: []byte("soon"), Value: []byte("v"), TTL: time.Minute},
+			{Key: []byte("later"), Value: []byte("v"), TTL: time.Hour},
+			{Key: []byte("never"), Value: []byte("v")},
+		})
+
+		// the internal keys are skipped
+		db.(goukv.KeyspaceManager).Keyspace("space").Put(&goukv.Entry{Key: []byte("soon"), Value: []byte("v"), TTL: time.Minute})
+
+		var keys []string
+		err := db.(goukv.ExpiringScanner).ExpiringBefore(time.Now().Add(time.Minute*10), func(k []byte, expires time.Time) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 1 || keys[0] != "soon" {
+			t.Errorf("expected ([soon]), found (%v)", keys)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestGetFunc(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+
+		var found string
+		err := db.(goukv.FuncGetter).GetFunc([]byte("k"), func(val []byte) error {
+			found = string(val)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found != "v" {
+			t.Errorf("expected (v), found (%s)", found)
+		}
+
+		err = db.(goukv.FuncGetter).GetFunc([]byte("missing"), func(val []byte) error {
+			t.Error("expected fn not to be called for a missing key")
+			return nil
+		})
+		if err != goukv.ErrKeyNotFound {
+			t.Errorf("expected (%v), found (%v)", goukv.ErrKeyNotFound, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestDirPerm(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	data := filepath.Join(dir, "data")
+	for _, perm := range []os.FileMode{0700, 0750} {
+		p := Provider{}
+		db, err := p.Open(map[string]interface{}{
+			"path":     filepath.Join(data, "db"),
+			"dir_perm": perm,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+
+		for _, dir := range []string{data, filepath.Join(data, "db")} {
+			info, err := os.Stat(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != perm {
+				t.Errorf("expected (%s) mode to be (%s), found (%s)", dir, perm, info.Mode().Perm())
+			}
+		}
+
+		os.RemoveAll(data)
+	}
+}
+
+func TestValueDir(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	p := Provider{}
+	db, err := p.Open(map[string]interface{}{
+		"path":      filepath.Join(dir, "lsm"),
+		"value_dir": filepath.Join(dir, "vlog"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Put(&goukv.Entry{Key: []byte("k"), Value: bytes.Repeat([]byte("v"), 1024)})
+	db.Close()
+
+	for dir, pattern := range map[string]string{filepath.Join(dir, "vlog"): "*.vlog", filepath.Join(dir, "lsm"): "*.sst"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		if len(matches) == 0 {
+			t.Errorf("expected (%s) files in (%s)", pattern, dir)
+		}
+	}
+
+	if matches, _ := filepath.Glob(filepath.Join(dir, "lsm", "*.vlog")); len(matches) != 0 {
+		t.Errorf("expected no value log files in the lsm dir, found (%v)", matches)
+	}
+}
+
+func TestPutIfChanged(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		putter := db.(goukv.IdempotentPutter)
+
+		steps := []struct {
+			entry   goukv.Entry
+			changed bool
+		}{
+			{goukv.Entry{Key: []byte("k"), Value: []byte("v1")}, true},
+			{goukv.Entry{Key: []byte("k"), Value: []byte("v1")}, false},
+			{goukv.Entry{Key: []byte("k"), Value: []byte("v2")}, true},
+			{goukv.Entry{Key: []byte("k"), Value: []byte("v2"), TTL: time.Hour}, true},
+			{goukv.Entry{Key: []byte("k"), Value: []byte("v2"), TTL: time.Hour}, false},
+			{goukv.Entry{Key: []byte("k"), Value: []byte("v2")}, true},
+		}
+
+		for i, step := range steps {
+			changed, err := putter.PutIfChanged(&step.entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != step.changed {
+				t.Errorf("step (%d): expected changed to be (%v), found (%v)", i, step.changed, changed)
+			}
+		}
+
+		if val, _ := db.Get([]byte("k")); string(val) != "v2" {
+			t.Errorf("expected (v2), found (%s)", val)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func BenchmarkPutSameValue(b *testing.B) {
+	openDBAndDo(func(db goukv.Provider) {
+		entry := goukv.Entry{Key: []byte("config"), Value: []byte("the same value over and over")}
+		for i := 0; i < b.N; i++ {
+			db.Put(&entry)
+		}
+		b.ReportMetric(1, "writes/op")
+	})
+}
+
+func BenchmarkPutIfChangedSameValue(b *testing.B) {
+	openDBAndDo(func(db goukv.Provider) {
+		entry := goukv.Entry{Key: []byte("config"), Value: []byte("the same value over and over")}
+		writes := 0
+		for i := 0; i < b.N; i++ {
+			if changed, _ := db.(goukv.IdempotentPutter).PutIfChanged(&entry); changed {
+				writes++
+			}
+		}
+		b.ReportMetric(float64(writes)/float64(b.N), "writes/op")
+	})
+}
+
+func TestConsistentScan(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		var entries []*goukv.Entry
+		for i := 0; i < 100; i++ {
+			entries = append(entries, &goukv.Entry{Key: []byte(fmt.Sprintf("k%03d", i)), Value: []byte("v")})
+		}
+		db.Batch(entries)
+
+		count := 0
+		err := db.Scan(goukv.ScanOpts{
+			Consistent: true,
+			Scanner: func(k, v []byte) error {
+				if count == 0 {
+					for i := 0; i < 100; i++ {
+						db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%03dx", i)), Value: []byte("new")})
+					}
+				}
+				count++
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != len(entries) {
+			t.Errorf("expected (%d) keys, found (%d)", len(entries), count)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestClosePersistsAsyncWrites(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	p := Provider{}
+	db, err := p.Open(map[string]interface{}{
+		"path":        filepath.Join(dir, "db"),
+		"sync_writes": false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%03d", i)), Value: []byte("v")})
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != goukv.ErrClosed {
+		t.Errorf("expected closing twice to fail with (%v), found (%v)", goukv.ErrClosed, err)
+	}
+
+	db, err = p.Open(map[string]interface{}{
+		"path": filepath.Join(dir, "db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 100; i++ {
+		if _, err := db.Get([]byte(fmt.Sprintf("k%03d", i))); err != nil {
+			t.Fatalf("expected (k%03d) to survive the close, found (%v)", i, err)
+		}
+	}
+}
+
+func TestExpireAt(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour).Truncate(time.Second)
+
+		db.Put(&goukv.Entry{Key: []byte("past"), Value: []byte("v"), ExpireAt: &past})
+		if _, err := db.Get([]byte("past")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected a past ExpireAt to expire the key immediately, found (%v)", err)
+		}
+
+		db.Put(&goukv.Entry{Key: []byte("future"), Value: []byte("v"), TTL: time.Second, ExpireAt: &future})
+		expiresAt, err := db.TTL([]byte("future"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expiresAt == nil || !expiresAt.Equal(future) {
+			t.Errorf("expected ExpireAt to win over TTL (%s), found (%v)", future, expiresAt)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestPrefixStats(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		for i := 0; i < 10; i++ {
+			db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("a:%d", i)), Value: bytes.Repeat([]byte{byte(i)}, 1024)})
+		}
+		for i := 0; i < 5; i++ {
+			db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("b:%d", i)), Value: []byte("v")})
+		}
+
+		count, size, err := db.(goukv.PrefixStatsReader).PrefixStats([]byte("a:"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 10 || size != 10*(3+1024) {
+			t.Errorf("expected (10) keys of (%d) bytes, found (%d) keys of (%d) bytes", 10*(3+1024), count, size)
+		}
+
+		if count, size, _ := db.(goukv.PrefixStatsReader).PrefixStats([]byte("b:")); count != 5 || size != 5*(3+1) {
+			t.Errorf("expected (5) keys of (%d) bytes, found (%d) keys of (%d) bytes", 5*(3+1), count, size)
+		}
+
+		// the internal keys are only counted under an internal prefix
+		db.(goukv.KeyspaceManager).Keyspace("space").Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+		if count, _, _ := db.(goukv.PrefixStatsReader).PrefixStats(nil); count != 15 {
+			t.Errorf("expected (15) keys, found (%d)", count)
+		}
+		if count, _, _ := db.(goukv.PrefixStatsReader).PrefixStats(goukv.KeyspacePrefix); count != 1 {
+			t.Errorf("expected (1) key, found (%d)", count)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		for i := 0; i < 10; i++ {
+			db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("a:%d", i)), Value: bytes.Repeat([]byte{byte(i)}, 100)})
+		}
+		db.Put(&goukv.Entry{Key: []byte("e"), Value: []byte{}})
+
+		// the internal keys are skipped
+		db.(goukv.KeyspaceManager).Keyspace("space").Put(&goukv.Entry{Key: []byte("k"), Value: bytes.Repeat([]byte{0}, 1000)})
+
+		h, err := db.(goukv.HistogramReader).Histogram()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 3 bytes keys fall in [2, 4) and 100 bytes values in [64, 128)
+		if len(h.KeySizes) != 3 || h.KeySizes[2] != 10 || h.KeySizes[1] != 1 {
+			t.Errorf("unexpected key sizes (%v)", h.KeySizes)
+		}
+		if len(h.ValueSizes) != 8 || h.ValueSizes[7] != 10 || h.ValueSizes[0] != 1 {
+			t.Errorf("unexpected value sizes (%v)", h.ValueSizes)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestSinceVersion(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("k1"), Value: []byte("v")})
+		db.Put(&goukv.Entry{Key: []byte("k2"), Value: []byte("v")})
+
+		entry, err := db.(goukv.EntryGetter).GetEntry([]byte("k2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Version == 0 {
+			t.Error("expected the version to be reported")
+		}
+
+		db.Batch([]*goukv.Entry{
+			{Key: []byte("k1"), Value: []byte("v1")},
+			{Key: []byte("k3"), Value: []byte("v")},
+		})
+
+		var keys []string
+		db.Scan(goukv.ScanOpts{SinceVersion: entry.Version, Scanner: func(k, v []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		}})
+		if len(keys) != 2 || keys[0] != "k1" || keys[1] != "k3" {
+			t.Errorf("expected the keys written after version (%d), found (%v)", entry.Version, keys)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestDiskWatchdog(t *testing.T) {
+	full := make(chan int64, 1)
+	opts := map[string]interface{}{
+		"max_disk_bytes":      int64(1024),
+		"disk_check_interval": 10 * time.Millisecond,
+		"on_disk_full": func(used int64) {
+			select {
+			case full <- used:
+			default:
+			}
+		},
+	}
+
+	err := openDBWithOptsAndDo(opts, func(db goukv.Provider) {
+		for i := 0; i < 100; i++ {
+			db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%d", i)), Value: bytes.Repeat([]byte("v"), 1024)})
+		}
+
+		select {
+		case used := <-full:
+			if used <= 1024 {
+				t.Errorf("expected the reported usage to exceed the limit, found (%d)", used)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("expected the disk full callback to be called")
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestDetectConflicts(t *testing.T) {
+	err := openDBWithOptsAndDo(map[string]interface{}{"detect_conflicts": false}, func(db goukv.Provider) {
+		t.Error("expected disabling the conflicts detection to be rejected")
+	})
+
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestTrackTimestamps(t *testing.T) {
+	err := openDBWithOptsAndDo(map[string]interface{}{"track_timestamps": true}, func(db goukv.Provider) {
+		before := time.Now()
+		db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v1")})
+
+		first, err := db.(goukv.EntryGetter).GetEntry([]byte("k"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.CreatedAt.Before(before) || !first.CreatedAt.Equal(first.UpdatedAt) {
+			t.Errorf("unexpected timestamps (%v, %v)", first.CreatedAt, first.UpdatedAt)
+		}
+		if string(first.Value) != "v1" {
+			t.Errorf("expected (v1), found (%s)", first.Value)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		db.Batch([]*goukv.Entry{{Key: []byte("k"), Value: []byte("v2")}})
+
+		second, err := db.(goukv.EntryGetter).GetEntry([]byte("k"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !second.CreatedAt.Equal(first.CreatedAt) {
+			t.Errorf("expected the creation time to be kept, found (%v) then (%v)", first.CreatedAt, second.CreatedAt)
+		}
+		if !second.UpdatedAt.After(first.UpdatedAt) {
+			t.Errorf("expected the update time to change, found (%v) then (%v)", first.UpdatedAt, second.UpdatedAt)
+		}
+
+		val, err := db.Get([]byte("k"))
+		if err != nil || string(val) != "v2" {
+			t.Errorf("expected (v2), found (%s, %v)", val, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestErrorIfMissing(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "db")
+
+	err := openDBWithOptsAndDo(map[string]interface{}{"path": path, "error_if_missing": true}, func(db goukv.Provider) {
+		t.Error("expected the missing db not to be opened")
+	})
+	if err != goukv.ErrDBNotFound {
+		t.Errorf("expected ErrDBNotFound, found (%v)", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected the missing db not to be created")
+	}
+
+	db, err := Provider{}.Open(map[string]interface{}{"path": filepath.Join(dir, "db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+	db.Close()
+
+	db, err = Provider{}.Open(map[string]interface{}{"path": filepath.Join(dir, "db"), "error_if_missing": true})
+	if err != nil {
+		t.Fatalf("expected the existing db to be opened, found (%v)", err)
+	}
+	defer db.Close()
+
+	if val, err := db.Get([]byte("k")); err != nil || string(val) != "v" {
+		t.Errorf("expected (v), found (%s, %v)", val, err)
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v"), TTL: time.Millisecond})
+
+		if purged, err := db.(goukv.ExpiredPurger).PurgeExpired(); err != nil || purged != 0 {
+			t.Errorf("expected nothing to purge, found (%d, %v)", purged, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestCompression(t *testing.T) {
+	err := openDBWithOptsAndDo(map[string]interface{}{"compression": "zstd", "zstd_level": 3}, func(db goukv.Provider) {
+		badgerOpts := db.(*Provider).options
+		if badgerOpts.Compression != options.ZSTD || badgerOpts.ZSTDCompressionLevel != 3 {
+			t.Errorf("expected zstd level (3), found (%v, %d)", badgerOpts.Compression, badgerOpts.ZSTDCompressionLevel)
+		}
+
+		db.Put(&goukv.Entry{Key: []byte("k"), Value: bytes.Repeat([]byte("v"), 1024)})
+		if val, err := db.Get([]byte("k")); err != nil || len(val) != 1024 {
+			t.Errorf("expected the value to round-trip, found (%d, %v)", len(val), err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = openDBAndDo(func(db goukv.Provider) {
+		if compression := db.(*Provider).options.Compression; compression != options.Snappy {
+			t.Errorf("expected snappy by default, found (%v)", compression)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestGetRaw(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("expired"), ExpireAt: &time.Time{}, Value: []byte("v1")})
+		db.Put(&goukv.Entry{Key: []byte("live"), Value: []byte("v2")})
+
+		if _, _, err := db.(goukv.RawGetter).GetRaw([]byte("expired")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected the expired key to be gone, found (%v)", err)
+		}
+
+		val, expired, err := db.(goukv.RawGetter).GetRaw([]byte("live"))
+		if err != nil || expired || string(val) != "v2" {
+			t.Errorf("expected the live value, found (%s, %v, %v)", val, expired, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestLoadingModes(t *testing.T) {
+	opts := map[string]interface{}{
+		"keep_l0_in_memory":      false,
+		"table_loading_mode":     "file_io",
+		"value_log_loading_mode": "file_io",
+	}
+
+	err := openDBWithOptsAndDo(opts, func(db goukv.Provider) {
+		badgerOpts := db.(*Provider).options
+		if badgerOpts.KeepL0InMemory || badgerOpts.TableLoadingMode != options.FileIO || badgerOpts.ValueLogLoadingMode != options.FileIO {
+			t.Errorf("expected file-io loading without l0 in memory, found (%v, %v, %v)", badgerOpts.KeepL0InMemory, badgerOpts.TableLoadingMode, badgerOpts.ValueLogLoadingMode)
+		}
+
+		db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+		if val, err := db.Get([]byte("k")); err != nil || string(val) != "v" {
+			t.Errorf("expected (v), found (%s, %v)", val, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = openDBAndDo(func(db goukv.Provider) {
+		badgerOpts := db.(*Provider).options
+		if !badgerOpts.KeepL0InMemory || badgerOpts.TableLoadingMode != options.MemoryMap || badgerOpts.ValueLogLoadingMode != options.MemoryMap {
+			t.Errorf("expected the memory-mapped defaults, found (%v, %v, %v)", badgerOpts.KeepL0InMemory, badgerOpts.TableLoadingMode, badgerOpts.ValueLogLoadingMode)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	if err := openDBWithOptsAndDo(map[string]interface{}{"value_log_loading_mode": "load_to_ram"}, func(goukv.Provider) {}); err == nil {
+		t.Error("expected the value log loading mode to be rejected")
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		if _, err := db.Get([]byte("missing")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected ErrKeyNotFound to be returned as is, found (%v)", err)
+		}
+
+		err := db.Put(&goukv.Entry{Key: []byte{}, Value: []byte("v")})
+
+		var classified *goukv.Error
+		if !errors.As(err, &classified) || classified.Kind != goukv.ErrorFatal || !errors.Is(err, badger.ErrEmptyKey) {
+			t.Errorf("expected a fatal error wrapping ErrEmptyKey, found (%v)", err)
+		}
+
+		if err := classifyError(badger.ErrConflict); !goukv.IsErrorKind(err, goukv.ErrorConflict) {
+			t.Errorf("expected a conflict error, found (%v)", err)
+		}
+
+		// a real conflict: both transactions read the key before the first one commits
+		native := db.(goukv.NativeAccessor).Native().(*badger.DB)
+		txn1, txn2 := native.NewTransaction(true), native.NewTransaction(true)
+		defer txn1.Discard()
+		defer txn2.Discard()
+
+		txn1.Get([]byte("conflict"))
+		txn2.Get([]byte("conflict"))
+		txn1.Set([]byte("conflict"), []byte("v1"))
+		txn2.Set([]byte("conflict"), []byte("v2"))
+
+		if err := txn1.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		err = classifyError(txn2.Commit())
+		if !errors.Is(err, goukv.ErrConflict) || !errors.Is(err, badger.ErrConflict) {
+			t.Errorf("expected (%v) wrapping (%v), found (%v)", goukv.ErrConflict, badger.ErrConflict, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestScanByVersion(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		for _, k := range []string{"b", "c", "a"} {
+			db.Put(&goukv.Entry{Key: []byte(k), Value: []byte("v" + k)})
+		}
+		db.Put(&goukv.Entry{Key: []byte("b"), Value: []byte("vb2")})
+
+		var keys []string
+		var versions []uint64
+		err := db.(goukv.VersionScanner).ScanByVersion(0, func(entry *goukv.Entry) error {
+			keys = append(keys, string(entry.Key)+"="+string(entry.Value))
+			versions = append(versions, entry.Version)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(keys) != "[c=vc a=va b=vb2]" || versions[0] >= versions[1] || versions[1] >= versions[2] {
+			t.Errorf("expected the entries in version order, found (%v) (%v)", keys, versions)
+		}
+
+		keys = nil
+		db.(goukv.VersionScanner).ScanByVersion(versions[1], func(entry *goukv.Entry) error {
+			keys = append(keys, string(entry.Key))
+			return goukv.ErrScanDone
+		})
+		if fmt.Sprint(keys) != "[a]" {
+			t.Errorf("expected the scan to start at version (%d) and stop, found (%v)", versions[1], keys)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestValueThreshold(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		if threshold := db.(*Provider).options.ValueThreshold; threshold != badger.DefaultOptions("").ValueThreshold {
+			t.Errorf("expected badger's default threshold, found (%d)", threshold)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = openDBWithOptsAndDo(map[string]interface{}{"value_threshold": 1 << 30}, func(db goukv.Provider) {
+		if threshold := db.(*Provider).options.ValueThreshold; threshold != maxValueThreshold {
+			t.Errorf("expected the threshold to be capped at (%d), found (%d)", maxValueThreshold, threshold)
+		}
+
+		value := bytes.Repeat([]byte("v"), 4096)
+		for i := 0; i < 100; i++ {
+			key := []byte(fmt.Sprintf("k%d", i))
+			db.Put(&goukv.Entry{Key: key, Value: value})
+			db.Put(&goukv.Entry{Key: key, Value: value[1:]})
+			db.Delete(key)
+		}
+
+		if err := db.(*Provider).db.RunValueLogGC(0.01); err != badger.ErrNoRewrite {
+			t.Errorf("expected the value log gc to have nothing to reclaim, found (%v)", err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestNative(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		native, ok := db.(goukv.NativeAccessor).Native().(*badger.DB)
+		if !ok {
+			t.Fatal("expected the native handle to be a *badger.DB")
+		}
+
+		db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+
+		err := native.View(func(txn *badger.Txn) error {
+			item, err := txn.Get([]byte("k"))
+			if err != nil {
+				return err
+			}
+
+			return item.Value(func(val []byte) error {
+				if string(val) != "v" {
+					t.Errorf("expected (v), found (%s)", val)
+				}
+				return nil
+			})
+		})
+
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	now := time.Now()
+	goukv.Now = func() time.Time { return now }
+	defer (func() { goukv.Now = time.Now })()
+
+	err := openDBAndDo(func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("k1"), Value: []byte("v"), TTL: time.Hour})
+		db.Put(&goukv.Entry{Key: []byte("k2"), Value: []byte("v")})
+
+		if _, err := db.Get([]byte("k1")); err != nil {
+			t.Fatalf("expected the key to be alive, found (%v)", err)
+		}
+
+		// badger stores the expirations in seconds
+		if entry, err := db.(goukv.EntryGetter).GetEntry([]byte("k1")); err != nil || entry.TTL <= time.Hour-time.Second || entry.TTL > time.Hour {
+			t.Errorf("expected a TTL of an hour on the fake clock, found (%v) (%v)", entry, err)
+		}
+
+		now = now.Add(2 * time.Hour)
+
+		if _, err := db.Get([]byte("k1")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected the key to be expired on the fake clock, found (%v)", err)
+		}
+
+		var keys []string
+		db.Scan(goukv.ScanOpts{Scanner: func(k, v []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		}})
+		if fmt.Sprint(keys) != "[k2]" {
+			t.Errorf("expected the expired key to be skipped, found (%v)", keys)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestTrackDeletes(t *testing.T) {
+	now := time.Now()
+	goukv.Now = func() time.Time { return now }
+	defer (func() { goukv.Now = time.Now })()
+
+	err := openDBWithOptsAndDo(map[string]interface{}{"track_deletes": true, "tombstone_ttl": time.Hour}, func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("k1"), Value: []byte("v")})
+		db.Put(&goukv.Entry{Key: []byte("k2"), Value: []byte("v")})
+		db.Put(&goukv.Entry{Key: []byte("k3"), Value: []byte{}})
+		db.Delete([]byte("k1"))
+		db.Batch([]*goukv.Entry{{Key: []byte("k2")}})
+
+		if _, err := db.Get([]byte("k1")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected the tombstone to be hidden, found (%v)", err)
+		}
+
+		scan := func(opts goukv.ScanOpts) string {
+			var found []string
+			opts.Scanner = func(k, v []byte) error {
+				found = append(found, fmt.Sprintf("%s=%v", k, goukv.IsTombstone(v)))
+				return nil
+			}
+			if err := db.Scan(opts); err != nil {
+				t.Fatal(err)
+			}
+			return fmt.Sprint(found)
+		}
+
+		if found := scan(goukv.ScanOpts{}); found != "[k3=false]" {
+			t.Errorf("expected the tombstones to be skipped, found (%v)", found)
+		}
+
+		if found := scan(goukv.ScanOpts{IncludeTombstones: true}); found != "[k1=true k2=true k3=false]" {
+			t.Errorf("expected the tombstones to be scanned, found (%v)", found)
+		}
+
+		now = now.Add(2 * time.Hour)
+
+		if found := scan(goukv.ScanOpts{IncludeTombstones: true}); found != "[k3=false]" {
+			t.Errorf("expected the tombstones to expire, found (%v)", found)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestFileSizes(t *testing.T) {
+	opts := map[string]interface{}{
+		"max_table_size":      int64(128 << 20),
+		"value_log_file_size": int64(256 << 20),
+	}
+
+	err := openDBWithOptsAndDo(opts, func(db goukv.Provider) {
+		badgerOpts := db.(*Provider).options
+		if badgerOpts.MaxTableSize != 128<<20 || badgerOpts.ValueLogFileSize != 256<<20 {
+			t.Errorf("expected the file sizes to be applied, found (%d) (%d)", badgerOpts.MaxTableSize, badgerOpts.ValueLogFileSize)
+		}
+
+		if size := db.(*Provider).maxValueSize; size != 256<<20 {
+			t.Errorf("expected the max value size to follow the value log file size, found (%d)", size)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = openDBAndDo(func(db goukv.Provider) {
+		defaults := badger.DefaultOptions("")
+		if badgerOpts := db.(*Provider).options; badgerOpts.MaxTableSize != defaults.MaxTableSize || badgerOpts.ValueLogFileSize != defaults.ValueLogFileSize {
+			t.Errorf("expected badger's defaults, found (%d) (%d)", badgerOpts.MaxTableSize, badgerOpts.ValueLogFileSize)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestFromDB(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	badgerOpts := badger.DefaultOptions(filepath.Join(dir, "db")).WithLogger(nil).WithValueLogFileSize(16 << 20)
+	bdb, err := badger.Open(badgerOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := FromDB(bdb, badgerOpts, map[string]interface{}{"enable_changelog": true, "max_value_size": 1 << 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := db.(*Provider).maxValueSize; size != 16<<20 {
+		t.Errorf("expected the max value size to be capped at the value log file size, found (%d)", size)
+	}
+
+	if err := db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if val, err := db.Get([]byte("k")); err != nil || string(val) != "v" {
+		t.Errorf("expected (v), found (%s) (%v)", val, err)
+	}
+
+	var changes int
+	db.(goukv.ChangelogReader).ReadChanges(0, func(goukv.Change) error {
+		changes++
+		return nil
+	})
+	if changes != 1 {
+		t.Errorf("expected the write to be recorded in the changelog, found (%d) changes", changes)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// closing the provider closes the wrapped db, so it can be opened again
+	db, err = Provider{}.Open(map[string]interface{}{"path": filepath.Join(dir, "db"), "enable_changelog": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if seq := *db.(*Provider).changelogSeq; seq != 1 {
+		t.Errorf("expected the changelog sequence to be restored, found (%d)", seq)
+	}
+}
+
+// copyCrashed copies the files of the specified open db into dst, like a crash would leave them (nothing is closed)
+func copyCrashed(t *testing.T, src, dst string) {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ioutil.ReadDir(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range files {
+		b, err := ioutil.ReadFile(filepath.Join(src, f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(dst, f.Name()), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSyncWriter(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	crashed := filepath.Join(dir, "crashed")
+
+	err := openDBWithOptsAndDo(map[string]interface{}{"sync_writes": false}, func(db goukv.Provider) {
+		writer := db.(goukv.SyncWriter)
+
+		if err := writer.PutSync(&goukv.Entry{Key: []byte("k1"), Value: []byte("v1")}, true); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := writer.BatchSync([]*goukv.Entry{{Key: []byte("k2"), Value: []byte("v2")}, {Key: []byte("k3"), Value: []byte("v3")}}, true); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := writer.DeleteSync([]byte("k3"), true); err != nil {
+			t.Fatal(err)
+		}
+
+		copyCrashed(t, db.(*Provider).options.Dir, crashed)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := Provider{}
+	db, err := p.Open(map[string]interface{}{"path": crashed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for k, expected := range map[string]string{"k1": "v1", "k2": "v2"} {
+		if v, err := db.Get([]byte(k)); err != nil || string(v) != expected {
+			t.Errorf("expected the synced (%s) to survive the crash, found (%s, %v)", k, v, err)
+		}
+	}
+
+	if _, err := db.Get([]byte("k3")); err != goukv.ErrKeyNotFound {
+		t.Errorf("expected the synced delete of (k3) to survive the crash, found (%v)", err)
+	}
+}
+
+func TestScanSnapshotMaxDuration(t *testing.T) {
+	// scan writes a key past the first scanned one while the scan is held open, then returns the scanned keys
+	scan := func(db goukv.Provider, reverse bool) []string {
+		var keys []string
+		written := false
+		db.Scan(goukv.ScanOpts{
+			ReverseScan: reverse,
+			Scanner: func(k, v []byte) error {
+				keys = append(keys, string(k))
+				if !written {
+					written = true
+					db.Put(&goukv.Entry{Key: []byte("c2"), Value: []byte("v")})
+					time.Sleep(50 * time.Millisecond)
+				}
+				return nil
+			},
+		})
+		return keys
+	}
+
+	for _, reverse := range []bool{false, true} {
+		for _, maxDuration := range []time.Duration{0, 10 * time.Millisecond} {
+			opts := map[string]interface{}{"scan_snapshot_max_duration": maxDuration}
+
+			err := openDBWithOptsAndDo(opts, func(db goukv.Provider) {
+				for _, k := range []string{"a", "b", "c", "d", "e"} {
+					db.Put(&goukv.Entry{Key: []byte(k), Value: []byte("v")})
+				}
+
+				keys := scan(db, reverse)
+
+				expected := "[a b c d e]"
+				if maxDuration > 0 {
+					// the scan resumed from the key after the first one in a new snapshot
+					expected = "[a b c c2 d e]"
+				}
+				if reverse && maxDuration > 0 {
+					expected = "[e d c2 c b a]"
+				} else if reverse {
+					expected = "[e d c b a]"
+				}
+
+				if fmt.Sprint(keys) != expected {
+					t.Errorf("expected (%s) with a (%v) max duration, found (%v)", expected, maxDuration, keys)
+				}
+			})
+
+			if err != nil {
+				t.Error(err.Error())
+			}
+		}
+	}
+}
+
+func TestRangeSize(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// small tables so that the ranges cover whole tables
+	opts := map[string]interface{}{"path": filepath.Join(dir, "db"), "max_table_size": int64(256 << 10), "value_threshold": 4096}
+
+	// the writes are flushed to the tables by closing the db
+	write := func(prefix string, from, to int) {
+		db, err := Provider{}.Open(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := from; i < to; i++ {
+			v := make([]byte, 1024)
+			rand.Read(v)
+			db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("%s:%05d", prefix, i)), Value: v})
+		}
+
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rangeSize := func(start, end []byte) int64 {
+		db, err := Provider{}.Open(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		size, err := db.(goukv.RangeSizer).RangeSize(start, end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return size
+	}
+
+	write("r", 0, 1000)
+	write("z", 0, 1000)
+
+	before := rangeSize([]byte("r:"), []byte("r;"))
+	if before < 512*1024 || before > 2*1024*1024 {
+		t.Errorf("expected about 1MiB in the range, found (%d)", before)
+	}
+
+	write("r", 1000, 2000)
+
+	after := rangeSize([]byte("r:"), []byte("r;"))
+	if ratio := float64(after) / float64(before); ratio < 1.5 || ratio > 2.5 {
+		t.Errorf("expected the estimate to double with the data, found (%d) then (%d)", before, after)
+	}
+
+	if all := rangeSize(nil, nil); all < after+512*1024 {
+		t.Errorf("expected the whole keyspace to include both ranges, found (%d)", all)
+	}
+
+	if empty := rangeSize([]byte("zz"), nil); empty != 0 {
+		t.Errorf("expected a range after the last key to be (0), found (%d)", empty)
+	}
+}
+
+func TestVerifyOnOpen(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	db, err := Provider{}.Open(map[string]interface{}{"path": filepath.Join(dir, "db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%04d", i)), Value: bytes.Repeat([]byte("v"), 16)})
+	}
+
+	// flushed to the tables
+	db.Close()
+
+	db, err = Provider{}.Open(map[string]interface{}{"path": filepath.Join(dir, "db"), "verify_on_open": true})
+	if err != nil {
+		t.Fatalf("expected an intact db to be verified, found (%v)", err)
+	}
+	db.Close()
+
+	tables, _ := filepath.Glob(filepath.Join(dir, "db", "*.sst"))
+	if len(tables) == 0 {
+		t.Fatal("expected the db to have tables")
+	}
+
+	f, err := os.OpenFile(tables[0], os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteAt(bytes.Repeat([]byte{0xff}, 64), 100)
+	f.Close()
+
+	if _, err := (Provider{}).Open(map[string]interface{}{"path": filepath.Join(dir, "db"), "verify_on_open": true}); !errors.Is(err, goukv.ErrCorrupted) {
+		t.Errorf("expected (%v), found (%v)", goukv.ErrCorrupted, err)
+	}
+
+	// the blocks are only verified when read (closing it compacts the tables, so it's checked last)
+	db, err = Provider{}.Open(map[string]interface{}{"path": filepath.Join(dir, "db")})
+	if err != nil {
+		t.Fatalf("expected the corruption to go unnoticed without verify_on_open, found (%v)", err)
+	}
+	db.Close()
+}
+
+func TestForceGCKey(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := map[string]interface{}{"path": filepath.Join(dir, "db"), "value_log_file_size": int64(1 << 20)}
+
+	db, err := Provider{}.Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the overwritten versions of a hot key, spread over several value log files
+	v := make([]byte, 64<<10)
+	for i := 0; i < 100; i++ {
+		rand.Read(v)
+		if err := db.Put(&goukv.Entry{Key: []byte("hot"), Value: v}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// flushes the memtable referencing them
+	db.Close()
+	if db, err = (Provider{}).Open(opts); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	before, _ := goukv.DirSize(filepath.Join(dir, "db"))
+
+	if err := db.(goukv.KeyGCForcer).ForceGCKey([]byte("hot")); err != nil {
+		t.Fatal(err)
+	}
+
+	after, _ := goukv.DirSize(filepath.Join(dir, "db"))
+	if after > before/2 {
+		t.Errorf("expected the older versions to be reclaimed, found (%d) bytes before and (%d) after", before, after)
+	}
+
+	if found, err := db.Get([]byte("hot")); err != nil || !bytes.Equal(found, v) {
+		t.Errorf("expected the last version to be kept, found (%v)", err)
+	}
+
+	if err := db.(goukv.KeyGCForcer).ForceGCKey([]byte("missing")); err != nil {
+		t.Errorf("expected a missing key to be ignored, found (%v)", err)
+	}
+}
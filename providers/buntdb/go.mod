@@ -0,0 +1,25 @@
+module github.com/alash3al/goukv/providers/buntdb
+
+go 1.17
+
+require (
+	github.com/alash3al/goukv v0.0.0-00010101000000-000000000000
+	github.com/tidwall/buntdb v1.3.0
+)
+
+require (
+	github.com/golang/protobuf v1.3.4 // indirect
+	github.com/tidwall/btree v1.4.2 // indirect
+	github.com/tidwall/gjson v1.14.3 // indirect
+	github.com/tidwall/grect v0.1.4 // indirect
+	github.com/tidwall/match v1.1.1 // indirect
+	github.com/tidwall/pretty v1.2.0 // indirect
+	github.com/tidwall/rtred v0.1.2 // indirect
+	github.com/tidwall/tinyqueue v0.1.1 // indirect
+	github.com/vmihailenco/msgpack/v4 v4.3.11 // indirect
+	github.com/vmihailenco/tagparser v0.1.1 // indirect
+	golang.org/x/net v0.0.0-20200301022130-244492dfa37a // indirect
+	google.golang.org/appengine v1.6.5 // indirect
+)
+
+replace github.com/alash3al/goukv => ../..
@@ -0,0 +1,14 @@
+//go:build buntdb
+// +build buntdb
+
+package buntdb
+
+import "github.com/alash3al/goukv"
+
+const (
+	name = "buntdb"
+)
+
+func init() {
+	goukv.Register(name, Provider{})
+}
@@ -0,0 +1,262 @@
+//go:build buntdb
+// +build buntdb
+
+package buntdb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alash3al/goukv"
+	"github.com/tidwall/buntdb"
+)
+
+// Provider represents a provider
+type Provider struct {
+	db *buntdb.DB
+}
+
+// Open implements goukv.Open
+func (p Provider) Open(opts map[string]interface{}) (goukv.Provider, error) {
+	path, ok := opts["path"].(string)
+	if !ok {
+		return nil, errors.New("must specify path")
+	}
+
+	dirPerm, ok := opts["dir_perm"].(os.FileMode)
+	if !ok {
+		dirPerm = 0700
+	}
+
+	if path != ":memory:" {
+		dir := filepath.Dir(path)
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			if err := os.MkdirAll(dir, dirPerm); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	syncWrites, ok := opts["sync_writes"].(bool)
+	if !ok {
+		syncWrites = false
+	}
+
+	db, err := buntdb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config buntdb.Config
+	if err := db.ReadConfig(&config); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if syncWrites {
+		config.SyncPolicy = buntdb.Always
+	} else {
+		config.SyncPolicy = buntdb.EverySecond
+	}
+
+	if err := db.SetConfig(config); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Provider{
+		db: db,
+	}, nil
+}
+
+// set writes the entry within the specified transaction, nil value means *delete*
+func set(tx *buntdb.Tx, entry *goukv.Entry) error {
+	if entry.Value == nil {
+		_, err := tx.Delete(string(entry.Key))
+		if err == buntdb.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	var setOpts *buntdb.SetOptions
+	if entry.ExpireAt != nil {
+		setOpts = &buntdb.SetOptions{Expires: true, TTL: time.Until(*entry.ExpireAt)}
+	} else if entry.TTL > 0 {
+		setOpts = &buntdb.SetOptions{Expires: true, TTL: entry.TTL}
+	}
+
+	_, _, err := tx.Set(string(entry.Key), string(entry.Value), setOpts)
+
+	return err
+}
+
+// Put implements goukv.Put
+func (p Provider) Put(entry *goukv.Entry) error {
+	if entry.Value == nil {
+		entry = &goukv.Entry{Key: entry.Key, Value: []byte{}, TTL: entry.TTL, ExpireAt: entry.ExpireAt}
+	}
+
+	return p.db.Update(func(tx *buntdb.Tx) error {
+		return set(tx, entry)
+	})
+}
+
+// Batch perform multi put operation, empty value means *delete*
+func (p Provider) Batch(entries []*goukv.Entry) error {
+	return p.db.Update(func(tx *buntdb.Tx) error {
+		for _, entry := range entries {
+			if err := set(tx, entry); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Get implements goukv.Get
+func (p Provider) Get(k []byte) ([]byte, error) {
+	var data []byte
+	err := p.db.View(func(tx *buntdb.Tx) error {
+		val, err := tx.Get(string(k))
+		if err == buntdb.ErrNotFound {
+			return goukv.ErrKeyNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		data = []byte(val)
+
+		return nil
+	})
+
+	return data, err
+}
+
+// TTL implements goukv.TTL
+func (p Provider) TTL(k []byte) (*time.Time, error) {
+	var t *time.Time
+	err := p.db.View(func(tx *buntdb.Tx) error {
+		ttl, err := tx.TTL(string(k))
+		if err == buntdb.ErrNotFound {
+			return goukv.ErrKeyNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if ttl >= 0 {
+			expires := time.Now().Add(ttl)
+			t = &expires
+		}
+
+		return nil
+	})
+
+	return t, err
+}
+
+// Delete implements goukv.Delete
+func (p Provider) Delete(k []byte) error {
+	return p.db.Update(func(tx *buntdb.Tx) error {
+		_, err := tx.Delete(string(k))
+		if err == buntdb.ErrNotFound {
+			return nil
+		}
+		return err
+	})
+}
+
+// Native implements goukv.NativeAccessor, it returns the underlying *buntdb.DB
+func (p Provider) Native() interface{} {
+	return p.db
+}
+
+// Close implements goukv.Close
+func (p Provider) Close() error {
+	return p.db.Close()
+}
+
+// Scan implements goukv.Scan
+func (p Provider) Scan(opts goukv.ScanOpts) error {
+	if opts.Scanner == nil {
+		return goukv.ErrNoScanner
+	}
+
+	opts.Scanner = opts.BoundedScanner()
+
+	prefix, offset := string(opts.Prefix), string(opts.Offset)
+	tick := opts.ProgressTicker()
+
+	var scanErr error
+	iterator := func(k, v string) bool {
+		if !strings.HasPrefix(k, prefix) {
+			return false
+		}
+
+		tick()
+
+		if opts.Offset != nil && !opts.IncludeOffset && k == offset {
+			return true
+		}
+
+		if err := opts.Scanner([]byte(k), []byte(v)); err != nil {
+			if err != goukv.ErrScanDone {
+				scanErr = err
+			}
+			return false
+		}
+
+		return true
+	}
+
+	err := p.db.View(func(tx *buntdb.Tx) error {
+		if !opts.ReverseScan {
+			pivot := prefix
+			if opts.Offset != nil && offset > pivot {
+				pivot = offset
+			}
+			return tx.AscendGreaterOrEqual("", pivot, iterator)
+		}
+
+		upper, bounded := prefixUpperBound(prefix)
+		if opts.Offset != nil && (!bounded || offset < upper) {
+			return tx.DescendLessOrEqual("", offset, iterator)
+		}
+
+		// buntdb has no exclusive descend, upper itself isn't under the prefix
+		if bounded {
+			return tx.DescendLessOrEqual("", upper, func(k, v string) bool {
+				return k == upper || iterator(k, v)
+			})
+		}
+
+		return tx.Descend("", iterator)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return scanErr
+}
+
+// prefixUpperBound returns the smallest key greater than every key having the specified prefix,
+// bounded is false when no such key exists (empty prefix or a prefix of 0xff bytes)
+func prefixUpperBound(prefix string) (upper string, bounded bool) {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1]), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,150 @@
+//go:build buntdb
+// +build buntdb
+
+package buntdb
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+func openDBAndDo(fn func(db goukv.Provider)) error {
+	p := Provider{}
+	db, err := p.Open(map[string]interface{}{
+		"path": "./db",
+	})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	defer os.RemoveAll("./db")
+
+	fn(db)
+
+	return nil
+}
+
+func TestPutGet(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("k"),
+			Value: []byte("v"),
+		}
+		err := db.Put(&entry)
+		if err != nil {
+			t.Error(err)
+		}
+		val, err := db.Get(entry.Key)
+		if err != nil {
+			t.Error(err)
+		}
+		if string(val) != string(entry.Value) {
+			t.Errorf("expected (%s), found(%s)", string(entry.Value), string(val))
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestTTL(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("k"),
+			Value: []byte("v"),
+			TTL:   time.Second * 10,
+		}
+		err := db.Put(&entry)
+		if err != nil {
+			t.Error(err)
+		}
+		expiresAt, err := db.TTL(entry.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !(expiresAt.Before(time.Now().Add(entry.TTL)) || expiresAt.Equal(time.Now().Add(entry.TTL))) {
+			t.Errorf("expected to be expires <= (%d), found (%d)", time.Now().Add(entry.TTL).Unix(), expiresAt.Unix())
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestScan(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		db.Batch([]*goukv.Entry{
+			{Key: []byte("a1"), Value: []byte("v")},
+			{Key: []byte("a2"), Value: []byte("v")},
+			{Key: []byte("a3"), Value: []byte("v")},
+			{Key: []byte("b1"), Value: []byte("v")},
+		})
+
+		cases := []struct {
+			opts     goukv.ScanOpts
+			expected string
+		}{
+			{goukv.ScanOpts{Prefix: []byte("a")}, "[a1 a2 a3]"},
+			{goukv.ScanOpts{Prefix: []byte("a"), ReverseScan: true}, "[a3 a2 a1]"},
+			{goukv.ScanOpts{Prefix: []byte("a"), Offset: []byte("a1")}, "[a2 a3]"},
+			{goukv.ScanOpts{Prefix: []byte("a"), Offset: []byte("a2"), IncludeOffset: true, ReverseScan: true}, "[a2 a1]"},
+			{goukv.ScanOpts{ReverseScan: true}, "[b1 a3 a2 a1]"},
+		}
+
+		for _, c := range cases {
+			var keys []string
+			c.opts.Scanner = func(k, v []byte) error {
+				keys = append(keys, string(k))
+				return nil
+			}
+			if err := db.Scan(c.opts); err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(keys) != c.expected {
+				t.Errorf("expected (%s), found (%v)", c.expected, keys)
+			}
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestScanProgress(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		for i := 0; i < 5; i++ {
+			db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("a%d", i)), Value: []byte("v")})
+		}
+		db.Put(&goukv.Entry{Key: []byte("b1"), Value: []byte("v")})
+
+		var reports []int64
+		err := db.Scan(goukv.ScanOpts{
+			Prefix:           []byte("a"),
+			ProgressInterval: 2,
+			Progress: func(scanned int64) {
+				reports = append(reports, scanned)
+			},
+			Scanner: func(k, v []byte) error {
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if fmt.Sprint(reports) != "[2 4]" {
+			t.Errorf("expected the keys of the prefix to be reported, found (%v)", reports)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
@@ -0,0 +1,14 @@
+//go:build consul
+// +build consul
+
+package consul
+
+import "github.com/alash3al/goukv"
+
+const (
+	name = "consul"
+)
+
+func init() {
+	goukv.Register(name, Provider{})
+}
@@ -0,0 +1,304 @@
+//go:build consul
+// +build consul
+
+package consul
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alash3al/goukv"
+	"github.com/hashicorp/consul/api"
+)
+
+// maxTxnOps the maximum number of operations of a consul transaction
+const maxTxnOps = 64
+
+// Provider represents a provider
+type Provider struct {
+	client       *api.Client
+	kv           *api.KV
+	consistent   bool
+	maxValueSize int
+}
+
+// Open implements goukv.Open
+func (p Provider) Open(opts map[string]interface{}) (goukv.Provider, error) {
+	config := api.DefaultConfig()
+
+	if address, ok := opts["address"].(string); ok {
+		config.Address = address
+	}
+
+	if token, ok := opts["token"].(string); ok {
+		config.Token = token
+	}
+
+	if datacenter, ok := opts["datacenter"].(string); ok {
+		config.Datacenter = datacenter
+	}
+
+	consistent, _ := opts["consistent_reads"].(bool)
+
+	maxValueSize, ok := opts["max_value_size"].(int)
+	if !ok || maxValueSize <= 0 {
+		maxValueSize = 512 * 1024
+	}
+
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// the agent is reachable and the cluster has a leader
+	if _, err := client.Status().Leader(); err != nil {
+		return nil, goukv.WrapError(goukv.ErrorTransient, err)
+	}
+
+	return &Provider{
+		client:       client,
+		kv:           client.KV(),
+		consistent:   consistent,
+		maxValueSize: maxValueSize,
+	}, nil
+}
+
+// queryOptions returns the options of the reads
+func (p Provider) queryOptions() *api.QueryOptions {
+	return &api.QueryOptions{RequireConsistent: p.consistent}
+}
+
+// encode returns the stored bytes of the specified entry, consul rejects the values larger than its kv_max_value_size
+func (p Provider) encode(entry *goukv.Entry) ([]byte, error) {
+	if len(entry.Key) == 0 {
+		return nil, goukv.ErrEmptyKey
+	}
+
+	b := EntryToValue(entry).Bytes()
+	if len(b) > p.maxValueSize {
+		return nil, goukv.ErrValueTooLarge
+	}
+
+	return b, nil
+}
+
+// Put implements goukv.Put
+func (p Provider) Put(entry *goukv.Entry) error {
+	b, err := p.encode(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.kv.Put(&api.KVPair{Key: string(entry.Key), Value: b}, nil)
+
+	return err
+}
+
+// Batch perform multi put operation, empty value means *delete*, the entries are applied in order using consul
+// transactions of up to 64 operations (consul's limit), so a batch is atomic up to 64 entries (its last entry of
+// each key) and a larger one is split into several transactions
+func (p Provider) Batch(entries []*goukv.Entry) error {
+	last := goukv.LastWrites(entries)
+
+	ops := make(api.TxnOps, 0, len(last))
+	for _, entry := range last {
+		if entry.Value == nil {
+			ops = append(ops, &api.TxnOp{KV: &api.KVTxnOp{Verb: api.KVDelete, Key: string(entry.Key)}})
+			continue
+		}
+
+		b, err := p.encode(entry)
+		if err != nil {
+			return err
+		}
+
+		ops = append(ops, &api.TxnOp{KV: &api.KVTxnOp{Verb: api.KVSet, Key: string(entry.Key), Value: b}})
+	}
+
+	for len(ops) > 0 {
+		n := len(ops)
+		if n > maxTxnOps {
+			n = maxTxnOps
+		}
+
+		if err := p.txn(ops[:n]); err != nil {
+			return err
+		}
+
+		ops = ops[n:]
+	}
+
+	return nil
+}
+
+// txn runs the specified operations in a single consul transaction
+func (p Provider) txn(ops api.TxnOps) error {
+	ok, res, _, err := p.client.Txn().Txn(ops, nil)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		return nil
+	}
+
+	var whats []string
+	if res != nil {
+		for _, txnErr := range res.Errors {
+			whats = append(whats, fmt.Sprintf("op %d: %s", txnErr.OpIndex, txnErr.What))
+		}
+	}
+
+	return errors.New("consul: the transaction has been rolled back: " + strings.Join(whats, ", "))
+}
+
+// get returns the live value of the specified key
+func (p Provider) get(k []byte) (Value, error) {
+	pair, _, err := p.kv.Get(string(k), p.queryOptions())
+	if err != nil {
+		return Value{}, err
+	}
+
+	if pair == nil {
+		return Value{}, goukv.ErrKeyNotFound
+	}
+
+	val := BytesToValue(pair.Value)
+	if val.IsExpired() {
+		return Value{}, goukv.ErrKeyNotFound
+	}
+
+	return val, nil
+}
+
+// Get implements goukv.Get
+func (p Provider) Get(k []byte) ([]byte, error) {
+	val, err := p.get(k)
+	if err != nil {
+		return nil, err
+	}
+
+	if val.Value == nil {
+		return []byte{}, nil
+	}
+
+	return val.Value, nil
+}
+
+// TTL implements goukv.TTL
+func (p Provider) TTL(k []byte) (*time.Time, error) {
+	val, err := p.get(k)
+	if err != nil {
+		return nil, err
+	}
+
+	return val.Expires, nil
+}
+
+// Delete implements goukv.Delete
+func (p Provider) Delete(k []byte) error {
+	_, err := p.kv.Delete(string(k), nil)
+	return err
+}
+
+// PurgeExpired implements goukv.ExpiredPurger, consul keeps the expired pairs until they are deleted, every expired
+// pair is deleted using a check-and-set on its modify index so a key written again meanwhile is kept
+func (p Provider) PurgeExpired() (int64, error) {
+	pairs, _, err := p.kv.List("", p.queryOptions())
+	if err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	for _, pair := range pairs {
+		if !BytesToValue(pair.Value).IsExpired() {
+			continue
+		}
+
+		deleted, _, err := p.kv.DeleteCAS(&api.KVPair{Key: pair.Key, ModifyIndex: pair.ModifyIndex}, nil)
+		if err != nil {
+			return purged, err
+		}
+
+		if deleted {
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+// Native implements goukv.NativeAccessor, it returns the underlying *api.Client
+func (p Provider) Native() interface{} {
+	return p.client
+}
+
+// Close implements goukv.Close, the consul client holds no connection of its own
+func (p Provider) Close() error {
+	return nil
+}
+
+// Scan implements goukv.Scan, the pairs of the prefix are listed by a single request (sorted by key) then
+// the offset, the direction and the expirations are applied client side
+func (p Provider) Scan(opts goukv.ScanOpts) error {
+	if opts.Scanner == nil {
+		return goukv.ErrNoScanner
+	}
+
+	opts.Scanner = opts.BoundedScanner()
+
+	pairs, _, err := p.kv.List(string(opts.Prefix), p.queryOptions())
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if opts.ReverseScan {
+			return pairs[i].Key > pairs[j].Key
+		}
+		return pairs[i].Key < pairs[j].Key
+	})
+
+	tick := opts.ProgressTicker()
+
+	for _, pair := range pairs {
+		k := []byte(pair.Key)
+		tick()
+
+		if opts.Offset != nil {
+			cmp := bytes.Compare(k, opts.Offset)
+			if opts.ReverseScan {
+				cmp = -cmp
+			}
+			if cmp < 0 || (cmp == 0 && !opts.IncludeOffset) {
+				continue
+			}
+		}
+
+		if opts.SkipsInternal() && goukv.IsInternalKey(k) {
+			continue
+		}
+
+		val := BytesToValue(pair.Value)
+		if val.IsExpired() {
+			continue
+		}
+
+		if val.Value == nil {
+			val.Value = []byte{}
+		}
+
+		if err := opts.Scanner(k, val.Value); err != nil {
+			if err == goukv.ErrScanDone {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+//go:build consul
+// +build consul
+
+package consul
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+// openDBAndDo opens a provider against the agent in CONSUL_HTTP_ADDR, the tests write under "goukv-test/"
+// which is deleted afterwards, they are skipped when no agent is configured
+func openDBAndDo(t *testing.T, fn func(db goukv.Provider)) {
+	address := os.Getenv("CONSUL_HTTP_ADDR")
+	if address == "" {
+		t.Skip("CONSUL_HTTP_ADDR isn't set")
+	}
+
+	p := Provider{}
+	db, err := p.Open(map[string]interface{}{
+		"address": address,
+		"token":   os.Getenv("CONSUL_HTTP_TOKEN"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer db.(*Provider).kv.DeleteTree("goukv-test/", nil)
+
+	fn(db)
+}
+
+func TestPutGet(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("goukv-test/k"),
+			Value: []byte("v"),
+		}
+		err := db.Put(&entry)
+		if err != nil {
+			t.Error(err)
+		}
+		val, err := db.Get(entry.Key)
+		if err != nil {
+			t.Error(err)
+		}
+		if string(val) != string(entry.Value) {
+			t.Errorf("expected (%s), found(%s)", string(entry.Value), string(val))
+		}
+
+		if err := db.Delete(entry.Key); err != nil {
+			t.Error(err)
+		}
+		if _, err := db.Get(entry.Key); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected ErrKeyNotFound, found (%v)", err)
+		}
+
+		large := &goukv.Entry{Key: []byte("goukv-test/large"), Value: make([]byte, 512*1024)}
+		if err := db.Put(large); err != goukv.ErrValueTooLarge {
+			t.Errorf("expected (%v), found (%v)", goukv.ErrValueTooLarge, err)
+		}
+	})
+}
+
+func TestTTL(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		defer (func() { goukv.Now = time.Now })()
+
+		entry := goukv.Entry{
+			Key:   []byte("goukv-test/ttl"),
+			Value: []byte("v"),
+			TTL:   time.Hour,
+		}
+		if err := db.Put(&entry); err != nil {
+			t.Error(err)
+		}
+
+		expires, err := db.TTL(entry.Key)
+		if err != nil {
+			t.Error(err)
+		}
+		if expires == nil || time.Until(*expires) > entry.TTL {
+			t.Errorf("unexpected expiration (%v)", expires)
+		}
+
+		now := time.Now().Add(2 * time.Hour)
+		goukv.Now = func() time.Time { return now }
+
+		if _, err := db.Get(entry.Key); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected the expired pair to be hidden, found (%v)", err)
+		}
+
+		if purged, err := db.(goukv.ExpiredPurger).PurgeExpired(); err != nil || purged < 1 {
+			t.Errorf("expected the expired pair to be purged, found (%d, %v)", purged, err)
+		}
+
+		if pair, _, err := db.(*Provider).kv.Get(string(entry.Key), nil); err != nil || pair != nil {
+			t.Errorf("expected the expired pair to be deleted, found (%v, %v)", pair, err)
+		}
+	})
+}
+
+func TestBatchScan(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entries := []*goukv.Entry{
+			{Key: []byte("goukv-test/a"), Value: []byte("v")},
+			{Key: []byte("goukv-test/scan/1"), Value: []byte("v")},
+			{Key: []byte("goukv-test/scan/2"), Value: []byte("v")},
+			{Key: []byte("goukv-test/scan/3"), Value: []byte("v")},
+			{Key: []byte("goukv-test/scan/4"), Value: []byte("v")},
+			{Key: []byte("goukv-test/scan/4")},
+		}
+
+		// more entries than a single consul transaction holds
+		for i := 0; i < 100; i++ {
+			entries = append(entries, &goukv.Entry{Key: []byte(fmt.Sprintf("goukv-test/many/%03d", i)), Value: []byte("v")})
+		}
+
+		if err := db.Batch(entries); err != nil {
+			t.Fatal(err)
+		}
+
+		scan := func(opts goukv.ScanOpts) []string {
+			var found []string
+			opts.Scanner = func(k, v []byte) error {
+				found = append(found, string(k))
+				return nil
+			}
+			if err := db.Scan(opts); err != nil {
+				t.Error(err)
+			}
+			return found
+		}
+
+		found := scan(goukv.ScanOpts{Prefix: []byte("goukv-test/scan/"), Offset: []byte("goukv-test/scan/1")})
+		if fmt.Sprint(found) != "[goukv-test/scan/2 goukv-test/scan/3]" {
+			t.Errorf("expected ([goukv-test/scan/2 goukv-test/scan/3]), found (%v)", found)
+		}
+
+		found = scan(goukv.ScanOpts{Prefix: []byte("goukv-test/scan/"), Offset: []byte("goukv-test/scan/2"), IncludeOffset: true, ReverseScan: true})
+		if fmt.Sprint(found) != "[goukv-test/scan/2 goukv-test/scan/1]" {
+			t.Errorf("expected ([goukv-test/scan/2 goukv-test/scan/1]), found (%v)", found)
+		}
+
+		if found = scan(goukv.ScanOpts{Prefix: []byte("goukv-test/many/")}); len(found) != 100 {
+			t.Errorf("expected (100) keys, found (%d)", len(found))
+		}
+	})
+}
@@ -0,0 +1,47 @@
+//go:build consul
+// +build consul
+
+package consul
+
+import (
+	"time"
+
+	"github.com/alash3al/goukv"
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+// Value represents a value with expiration date, consul has no expiration for its KV pairs
+// so the values are stored wrapped with theirs and the expired ones are hidden on read
+type Value struct {
+	Value   []byte
+	Expires *time.Time
+}
+
+// Bytes encodes the value to a byte array
+func (e Value) Bytes() []byte {
+	b, _ := msgpack.Marshal(e)
+	return b
+}
+
+// IsExpired whether the value is expired or not
+func (e Value) IsExpired() bool {
+	if e.Expires == nil {
+		return false
+	}
+	expires, now := *(e.Expires), goukv.Now()
+	return now.After(expires) || now.Equal(expires)
+}
+
+// EntryToValue build a value from entry representation
+func EntryToValue(e *goukv.Entry) Value {
+	return Value{
+		Value:   e.Value,
+		Expires: e.ExpiresAt(),
+	}
+}
+
+// BytesToValue Decodes the specified byte array to Value
+func BytesToValue(b []byte) (v Value) {
+	msgpack.Unmarshal(b, &v)
+	return
+}
@@ -0,0 +1,14 @@
+//go:build couchbase
+// +build couchbase
+
+package couchbase
+
+import "github.com/alash3al/goukv"
+
+const (
+	name = "couchbase"
+)
+
+func init() {
+	goukv.Register(name, Provider{})
+}
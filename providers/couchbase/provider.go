@@ -0,0 +1,367 @@
+//go:build couchbase
+// +build couchbase
+
+package couchbase
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alash3al/goukv"
+	"github.com/couchbase/gocb/v2"
+)
+
+// Provider represents a provider
+type Provider struct {
+	cluster    *gocb.Cluster
+	collection *gocb.Collection
+	keyspace   string
+	timeout    time.Duration
+}
+
+// transcoder stores the values as raw binary documents
+var transcoder = gocb.NewRawBinaryTranscoder()
+
+// Open implements goukv.Open
+func (p Provider) Open(opts map[string]interface{}) (goukv.Provider, error) {
+	connstr, ok := opts["connstr"].(string)
+	if !ok {
+		return nil, errors.New("must specify connstr")
+	}
+
+	bucketName, ok := opts["bucket"].(string)
+	if !ok {
+		return nil, errors.New("must specify bucket")
+	}
+
+	scopeName, ok := opts["scope"].(string)
+	if !ok {
+		scopeName = "_default"
+	}
+
+	collectionName, ok := opts["collection"].(string)
+	if !ok {
+		collectionName = "_default"
+	}
+
+	username, _ := opts["username"].(string)
+	password, _ := opts["password"].(string)
+
+	timeout, ok := opts["timeout"].(time.Duration)
+	if !ok {
+		timeout = 10 * time.Second
+	}
+
+	cluster, err := gocb.Connect(connstr, gocb.ClusterOptions{
+		Authenticator: gocb.PasswordAuthenticator{
+			Username: username,
+			Password: password,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := cluster.Bucket(bucketName)
+	if err := bucket.WaitUntilReady(timeout, nil); err != nil {
+		cluster.Close(nil)
+		return nil, err
+	}
+
+	return &Provider{
+		cluster:    cluster,
+		collection: bucket.Scope(scopeName).Collection(collectionName),
+		keyspace:   fmt.Sprintf("`%s`.`%s`.`%s`", bucketName, scopeName, collectionName),
+		timeout:    timeout,
+	}, nil
+}
+
+// expiry returns the expiry of the specified entry (0 means no expiry),
+// expired is true when its ExpireAt is already in the past
+func expiry(entry *goukv.Entry) (d time.Duration, expired bool) {
+	if entry.ExpireAt != nil {
+		d = time.Until(*entry.ExpireAt)
+		return d, d <= 0
+	}
+
+	if entry.TTL > 0 {
+		return entry.TTL, false
+	}
+
+	return 0, false
+}
+
+// Put implements goukv.Put
+func (p Provider) Put(entry *goukv.Entry) error {
+	d, expired := expiry(entry)
+	if expired {
+		return p.Delete(entry.Key)
+	}
+
+	val := entry.Value
+	if val == nil {
+		val = []byte{}
+	}
+
+	_, err := p.collection.Upsert(string(entry.Key), val, &gocb.UpsertOptions{
+		Expiry:     d,
+		Transcoder: transcoder,
+	})
+
+	return err
+}
+
+// Batch perform multi put operation, empty value means *delete*,
+// the operations are sent in bulk (concurrently, so only the last entry of each key is sent) but they aren't atomic,
+// the first failed one is returned
+func (p Provider) Batch(entries []*goukv.Entry) error {
+	_, err := p.BatchDetailed(entries)
+	return err
+}
+
+// BatchDetailed implements goukv.DetailedBatcher, the entries of a key share the result of its last entry (the one sent)
+func (p Provider) BatchDetailed(entries []*goukv.Entry) ([]error, error) {
+	last := goukv.LastWrites(entries)
+
+	ops := make([]gocb.BulkOp, 0, len(last))
+	for _, entry := range last {
+		d, expired := expiry(entry)
+		if entry.Value == nil || expired {
+			ops = append(ops, &gocb.RemoveOp{ID: string(entry.Key)})
+		} else {
+			ops = append(ops, &gocb.UpsertOp{ID: string(entry.Key), Value: entry.Value, Expiry: d})
+		}
+	}
+
+	results := make([]error, len(entries))
+
+	if err := p.collection.Do(ops, &gocb.BulkOpOptions{Timeout: p.timeout, Transcoder: transcoder}); err != nil {
+		for i := range results {
+			results[i] = err
+		}
+		return results, err
+	}
+
+	var err error
+	keyResults := make(map[string]error, len(ops))
+	for i, op := range ops {
+		var opErr error
+		switch op := op.(type) {
+		case *gocb.UpsertOp:
+			opErr = op.Err
+		case *gocb.RemoveOp:
+			if !errors.Is(op.Err, gocb.ErrDocumentNotFound) {
+				opErr = op.Err
+			}
+		}
+
+		keyResults[string(last[i].Key)] = opErr
+		if err == nil {
+			err = opErr
+		}
+	}
+
+	for i, entry := range entries {
+		results[i] = keyResults[string(entry.Key)]
+	}
+
+	return results, err
+}
+
+// Get implements goukv.Get
+func (p Provider) Get(k []byte) ([]byte, error) {
+	res, err := p.collection.Get(string(k), &gocb.GetOptions{Transcoder: transcoder})
+	if errors.Is(err, gocb.ErrDocumentNotFound) {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var val []byte
+	if err := res.Content(&val); err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+// TTL implements goukv.TTL
+func (p Provider) TTL(k []byte) (*time.Time, error) {
+	res, err := p.collection.Get(string(k), &gocb.GetOptions{WithExpiry: true, Transcoder: transcoder})
+	if errors.Is(err, gocb.ErrDocumentNotFound) {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	expires := res.ExpiryTime()
+	if expires.IsZero() {
+		return nil, nil
+	}
+
+	return &expires, nil
+}
+
+// Delete implements goukv.Delete
+func (p Provider) Delete(k []byte) error {
+	_, err := p.collection.Remove(string(k), nil)
+	if errors.Is(err, gocb.ErrDocumentNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+// Native implements goukv.NativeAccessor, it returns the underlying *gocb.Cluster
+func (p Provider) Native() interface{} {
+	return p.cluster
+}
+
+// Close implements goukv.Close
+func (p Provider) Close() error {
+	return p.cluster.Close(nil)
+}
+
+// prefixLimit returns the first key after all of the keys having the specified prefix, nil if there is none
+func prefixLimit(prefix []byte) []byte {
+	limit := append([]byte{}, prefix...)
+	for i := len(limit) - 1; i >= 0; i-- {
+		if limit[i] < 0xff {
+			limit[i]++
+			return limit[:i+1]
+		}
+	}
+	return nil
+}
+
+// Scan implements goukv.Scan, the ids are queried with N1QL in pages of 1000 (ordered by META().id,
+// bounded by the prefix range and the offset) then their values are fetched in bulk, the keyspace needs
+// a primary index (or an index covering META().id), the queries wait for the index to catch up with the writes (request_plus)
+func (p Provider) Scan(opts goukv.ScanOpts) error {
+	if opts.Scanner == nil {
+		return goukv.ErrNoScanner
+	}
+
+	opts.Scanner = opts.BoundedScanner()
+
+	order, cmp := "ASC", ">"
+	if opts.ReverseScan {
+		order, cmp = "DESC", "<"
+	}
+
+	params := map[string]interface{}{}
+	conditions := "META(d).id IS NOT MISSING"
+
+	if len(opts.Prefix) > 0 {
+		params["prefix"] = string(opts.Prefix)
+		conditions += " AND META(d).id >= $prefix"
+		if limit := prefixLimit(opts.Prefix); limit != nil {
+			params["limit"] = string(limit)
+			conditions += " AND META(d).id < $limit"
+		}
+	}
+
+	tick := opts.ProgressTicker()
+
+	// the first page starts at the offset, the following ones right after the last scanned id
+	var cursor *string
+	cursorCmp := cmp
+	if opts.Offset != nil {
+		offset := string(opts.Offset)
+		cursor = &offset
+		if opts.IncludeOffset {
+			cursorCmp += "="
+		}
+	}
+
+	for {
+		pageConditions := conditions
+		if cursor != nil {
+			params["cursor"] = *cursor
+			pageConditions += " AND META(d).id " + cursorCmp + " $cursor"
+		}
+
+		statement := "SELECT RAW META(d).id FROM " + p.keyspace + " d WHERE " + pageConditions +
+			" ORDER BY META(d).id " + order + " LIMIT 1000"
+
+		rows, err := p.cluster.Query(statement, &gocb.QueryOptions{
+			NamedParameters: params,
+			ScanConsistency: gocb.QueryScanConsistencyRequestPlus,
+			Timeout:         p.timeout,
+		})
+		if err != nil {
+			return err
+		}
+
+		var ids []string
+		for rows.Next() {
+			var id string
+			if err := rows.Row(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if len(ids) < 1 {
+			return nil
+		}
+
+		done, err := p.scanPage(ids, opts.Scanner, tick)
+		if err != nil || done || len(ids) < 1000 {
+			return err
+		}
+
+		last := ids[len(ids)-1]
+		cursor, cursorCmp = &last, cmp
+	}
+}
+
+// scanPage fetches the values of the specified ids in bulk and passes them to the scanner in order,
+// the documents removed since they were queried are skipped, tick is called for every id
+func (p Provider) scanPage(ids []string, scanner goukv.Scanner, tick func()) (bool, error) {
+	ops := make([]gocb.BulkOp, len(ids))
+	for i, id := range ids {
+		ops[i] = &gocb.GetOp{ID: id}
+	}
+
+	if err := p.collection.Do(ops, &gocb.BulkOpOptions{Timeout: p.timeout, Transcoder: transcoder}); err != nil {
+		return false, err
+	}
+
+	for _, op := range ops {
+		tick()
+
+		op := op.(*gocb.GetOp)
+		if errors.Is(op.Err, gocb.ErrDocumentNotFound) {
+			continue
+		}
+
+		if op.Err != nil {
+			return false, op.Err
+		}
+
+		var val []byte
+		if err := op.Result.Content(&val); err != nil {
+			return false, err
+		}
+
+		if err := scanner([]byte(op.ID), val); err != nil {
+			if err == goukv.ErrScanDone {
+				return true, nil
+			}
+			return false, err
+		}
+	}
+
+	return false, nil
+}
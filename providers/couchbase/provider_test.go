@@ -0,0 +1,124 @@
+//go:build couchbase
+// +build couchbase
+
+package couchbase
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+// openDBAndDo opens a provider against the cluster in COUCHBASE_CONNSTR (bucket COUCHBASE_BUCKET, defaults to "goukv",
+// credentials COUCHBASE_USERNAME/COUCHBASE_PASSWORD), the test is skipped when no cluster is configured
+func openDBAndDo(t *testing.T, fn func(db goukv.Provider)) {
+	connstr := os.Getenv("COUCHBASE_CONNSTR")
+	if connstr == "" {
+		t.Skip("COUCHBASE_CONNSTR isn't set")
+	}
+
+	bucket := os.Getenv("COUCHBASE_BUCKET")
+	if bucket == "" {
+		bucket = "goukv"
+	}
+
+	p := Provider{}
+	db, err := p.Open(map[string]interface{}{
+		"connstr":  connstr,
+		"bucket":   bucket,
+		"username": os.Getenv("COUCHBASE_USERNAME"),
+		"password": os.Getenv("COUCHBASE_PASSWORD"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fn(db)
+}
+
+func TestPutGet(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("goukv-test-k"),
+			Value: []byte("v"),
+		}
+		err := db.Put(&entry)
+		if err != nil {
+			t.Error(err)
+		}
+		val, err := db.Get(entry.Key)
+		if err != nil {
+			t.Error(err)
+		}
+		if string(val) != string(entry.Value) {
+			t.Errorf("expected (%s), found(%s)", string(entry.Value), string(val))
+		}
+
+		if err := db.Delete(entry.Key); err != nil {
+			t.Error(err)
+		}
+		if _, err := db.Get(entry.Key); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected ErrKeyNotFound, found (%v)", err)
+		}
+	})
+}
+
+func TestTTL(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("goukv-test-ttl"),
+			Value: []byte("v"),
+			TTL:   time.Second * 10,
+		}
+		if err := db.Put(&entry); err != nil {
+			t.Error(err)
+		}
+		defer db.Delete(entry.Key)
+
+		expires, err := db.TTL(entry.Key)
+		if err != nil {
+			t.Error(err)
+		}
+		if expires == nil || time.Until(*expires) > entry.TTL+time.Second || time.Until(*expires) < time.Second*5 {
+			t.Errorf("unexpected expiration (%v)", expires)
+		}
+	})
+}
+
+func TestBatchScan(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entries := []*goukv.Entry{
+			{Key: []byte("goukv-scan-1"), Value: []byte("v")},
+			{Key: []byte("goukv-scan-2"), Value: []byte("v")},
+			{Key: []byte("goukv-scan-3"), Value: []byte("v")},
+		}
+		if err := db.Batch(entries); err != nil {
+			t.Fatal(err)
+		}
+		defer db.Batch([]*goukv.Entry{
+			{Key: []byte("goukv-scan-1")},
+			{Key: []byte("goukv-scan-2")},
+			{Key: []byte("goukv-scan-3")},
+		})
+
+		var found []string
+		err := db.Scan(goukv.ScanOpts{
+			Prefix:      []byte("goukv-scan-"),
+			Offset:      []byte("goukv-scan-3"),
+			ReverseScan: true,
+			Scanner: func(k, v []byte) error {
+				found = append(found, string(k))
+				return nil
+			},
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		if len(found) != 2 || found[0] != "goukv-scan-2" || found[1] != "goukv-scan-1" {
+			t.Errorf("expected ([goukv-scan-2 goukv-scan-1]), found (%v)", found)
+		}
+	})
+}
@@ -0,0 +1,14 @@
+//go:build fdb
+// +build fdb
+
+package fdb
+
+import "github.com/alash3al/goukv"
+
+const (
+	name = "fdb"
+)
+
+func init() {
+	goukv.Register(name, Provider{})
+}
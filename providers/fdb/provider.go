@@ -0,0 +1,412 @@
+//go:build fdb
+// +build fdb
+
+package fdb
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/alash3al/goukv"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+const (
+	// maxValueSize the maximum size of a FoundationDB value
+	maxValueSize = 100000
+
+	// maxKeySize the maximum size of a FoundationDB key
+	maxKeySize = 10000
+
+	// scanChunkSize the number of pairs a scan reads per transaction
+	scanChunkSize = 1000
+)
+
+// FoundationDB error codes
+const (
+	errNotCommitted        = 1020
+	errCommitUnknownResult = 1021
+	errTransactionTooOld   = 1007
+	errTimedOut            = 1031
+	errTransactionTooLarge = 2101
+)
+
+// systemKeys the first key of the system keyspace, the scans of the whole keyspace stop before it
+var systemKeys = fdb.Key("\xff")
+
+// Provider represents a provider
+type Provider struct {
+	db            fdb.Database
+	batchMaxBytes int
+}
+
+// Open implements goukv.Open
+func (p Provider) Open(opts map[string]interface{}) (goukv.Provider, error) {
+	apiVersion, ok := opts["api_version"].(int)
+	if !ok {
+		apiVersion = 710
+	}
+
+	// it can only be set once per process, setting the same version again is a no-op
+	if err := fdb.APIVersion(apiVersion); err != nil {
+		return nil, err
+	}
+
+	// empty means the default cluster file
+	clusterFile, _ := opts["cluster_file"].(string)
+
+	timeout, ok := opts["timeout"].(time.Duration)
+	if !ok {
+		timeout = 5 * time.Second
+	}
+
+	batchMaxBytes, ok := opts["batch_max_bytes"].(int)
+	if !ok || batchMaxBytes <= 0 {
+		batchMaxBytes = 1 << 20
+	}
+
+	db, err := fdb.OpenDatabase(clusterFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Options().SetTransactionTimeout(timeout.Milliseconds()); err != nil {
+		return nil, err
+	}
+
+	// the cluster is reachable
+	_, err = db.ReadTransact(func(tr fdb.ReadTransaction) (interface{}, error) {
+		return tr.GetReadVersion().Get()
+	})
+	if err != nil {
+		return nil, goukv.WrapError(goukv.ErrorTransient, err)
+	}
+
+	return &Provider{
+		db:            db,
+		batchMaxBytes: batchMaxBytes,
+	}, nil
+}
+
+// classify wraps the FoundationDB errors with their kind, the retryable ones are retried by the
+// transactions themselves until the timeout so they're only returned once it's exceeded
+func classify(err error) error {
+	var fdbErr fdb.Error
+	if !errors.As(err, &fdbErr) {
+		return err
+	}
+
+	switch fdbErr.Code {
+	case errNotCommitted:
+		return goukv.WrapError(goukv.ErrorConflict, err)
+	case errCommitUnknownResult, errTransactionTooOld, errTimedOut:
+		return goukv.WrapError(goukv.ErrorTransient, err)
+	case errTransactionTooLarge:
+		return goukv.WrapError(goukv.ErrorFatal, err)
+	}
+
+	return err
+}
+
+// encode returns the stored bytes of the specified entry
+func encode(entry *goukv.Entry) ([]byte, error) {
+	if len(entry.Key) == 0 {
+		return nil, goukv.ErrEmptyKey
+	}
+
+	if len(entry.Key) > maxKeySize {
+		return nil, errors.New("fdb: the key exceeds the 10KB limit")
+	}
+
+	b := EntryToValue(entry).Bytes()
+	if len(b) > maxValueSize {
+		return nil, goukv.ErrValueTooLarge
+	}
+
+	return b, nil
+}
+
+// Put implements goukv.Put
+func (p Provider) Put(entry *goukv.Entry) error {
+	b, err := encode(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		tr.Set(fdb.Key(entry.Key), b)
+		return nil, nil
+	})
+
+	return classify(err)
+}
+
+// Batch perform multi put operation, empty value means *delete*, the last entry of each key is written in a single
+// transaction as long as the keys and values fit batch_max_bytes, a larger batch is split into several
+// transactions (each one is atomic) to stay under the transaction limits of FoundationDB
+func (p Provider) Batch(entries []*goukv.Entry) error {
+	last := goukv.LastWrites(entries)
+
+	type write struct {
+		key   fdb.Key
+		value []byte
+	}
+
+	writes := make([]write, 0, len(last))
+	for _, entry := range last {
+		if entry.Value == nil {
+			writes = append(writes, write{key: fdb.Key(entry.Key)})
+			continue
+		}
+
+		b, err := encode(entry)
+		if err != nil {
+			return err
+		}
+
+		writes = append(writes, write{key: fdb.Key(entry.Key), value: b})
+	}
+
+	for len(writes) > 0 {
+		n, size := 0, 0
+		for n < len(writes) && (n == 0 || size+len(writes[n].key)+len(writes[n].value) <= p.batchMaxBytes) {
+			size += len(writes[n].key) + len(writes[n].value)
+			n++
+		}
+
+		chunk := writes[:n]
+		_, err := p.db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+			for _, w := range chunk {
+				if w.value == nil {
+					tr.Clear(w.key)
+				} else {
+					tr.Set(w.key, w.value)
+				}
+			}
+			return nil, nil
+		})
+		if err != nil {
+			return classify(err)
+		}
+
+		writes = writes[n:]
+	}
+
+	return nil
+}
+
+// get returns the live value of the specified key
+func (p Provider) get(k []byte) (Value, error) {
+	b, err := p.db.ReadTransact(func(tr fdb.ReadTransaction) (interface{}, error) {
+		return tr.Get(fdb.Key(k)).Get()
+	})
+	if err != nil {
+		return Value{}, classify(err)
+	}
+
+	if b.([]byte) == nil {
+		return Value{}, goukv.ErrKeyNotFound
+	}
+
+	val := BytesToValue(b.([]byte))
+	if val.IsExpired() {
+		return Value{}, goukv.ErrKeyNotFound
+	}
+
+	return val, nil
+}
+
+// Get implements goukv.Get
+func (p Provider) Get(k []byte) ([]byte, error) {
+	val, err := p.get(k)
+	if err != nil {
+		return nil, err
+	}
+
+	if val.Value == nil {
+		return []byte{}, nil
+	}
+
+	return val.Value, nil
+}
+
+// TTL implements goukv.TTL
+func (p Provider) TTL(k []byte) (*time.Time, error) {
+	val, err := p.get(k)
+	if err != nil {
+		return nil, err
+	}
+
+	return val.Expires, nil
+}
+
+// Delete implements goukv.Delete
+func (p Provider) Delete(k []byte) error {
+	_, err := p.db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		tr.Clear(fdb.Key(k))
+		return nil, nil
+	})
+
+	return classify(err)
+}
+
+// PurgeExpired implements goukv.ExpiredPurger, FoundationDB keeps the expired keys until they are deleted, the
+// keyspace is read in chunks of 1000 keys, each one cleared of its expired keys in the transaction that read it
+// so a key written again meanwhile conflicts and is read again
+func (p Provider) PurgeExpired() (int64, error) {
+	var purged int64
+
+	begin := fdb.FirstGreaterOrEqual(fdb.Key(""))
+	for {
+		// set by the last attempt of the transaction
+		var kvs []fdb.KeyValue
+		var expired int64
+
+		_, err := p.db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+			var err error
+			kvs, err = tr.GetRange(
+				fdb.SelectorRange{Begin: begin, End: fdb.FirstGreaterOrEqual(systemKeys)},
+				fdb.RangeOptions{Limit: scanChunkSize},
+			).GetSliceWithError()
+			if err != nil {
+				return nil, err
+			}
+
+			expired = 0
+			for _, kv := range kvs {
+				if BytesToValue(kv.Value).IsExpired() {
+					tr.Clear(kv.Key)
+					expired++
+				}
+			}
+
+			return nil, nil
+		})
+		if err != nil {
+			return purged, classify(err)
+		}
+
+		purged += expired
+
+		if len(kvs) < scanChunkSize {
+			return purged, nil
+		}
+
+		begin = fdb.FirstGreaterThan(kvs[len(kvs)-1].Key)
+	}
+}
+
+// Native implements goukv.NativeAccessor, it returns the underlying fdb.Database
+func (p Provider) Native() interface{} {
+	return p.db
+}
+
+// Close implements goukv.Close, the network thread of the bindings runs for the lifetime of the process
+func (p Provider) Close() error {
+	return nil
+}
+
+// prefixRange returns the range of the keys having the specified prefix, the whole (non system) keyspace when it's empty
+func prefixRange(prefix []byte) (fdb.KeyRange, error) {
+	if len(prefix) == 0 {
+		return fdb.KeyRange{Begin: fdb.Key(""), End: systemKeys}, nil
+	}
+
+	return fdb.PrefixRange(prefix)
+}
+
+// Scan implements goukv.Scan, the range of the prefix is read with GetRange in chunks of 1000 pairs, each chunk in its
+// own read transaction so a long scan doesn't hit the 5 seconds limit, the scan isn't a snapshot across the chunks
+func (p Provider) Scan(opts goukv.ScanOpts) error {
+	if opts.Scanner == nil {
+		return goukv.ErrNoScanner
+	}
+
+	opts.Scanner = opts.BoundedScanner()
+
+	r, err := prefixRange(opts.Prefix)
+	if err != nil {
+		return err
+	}
+
+	rangeBegin, rangeEnd := r.Begin.FDBKey(), r.End.FDBKey()
+
+	var begin, end fdb.Selectable = fdb.FirstGreaterOrEqual(rangeBegin), fdb.FirstGreaterOrEqual(rangeEnd)
+	if opts.Offset != nil {
+		if !opts.ReverseScan {
+			if bytes.Compare(opts.Offset, rangeEnd) >= 0 {
+				return nil
+			}
+
+			if bytes.Compare(opts.Offset, rangeBegin) >= 0 {
+				begin = fdb.FirstGreaterThan(fdb.Key(opts.Offset))
+				if opts.IncludeOffset {
+					begin = fdb.FirstGreaterOrEqual(fdb.Key(opts.Offset))
+				}
+			}
+		} else {
+			if bytes.Compare(opts.Offset, rangeBegin) < 0 {
+				return nil
+			}
+
+			if bytes.Compare(opts.Offset, rangeEnd) < 0 {
+				end = fdb.FirstGreaterOrEqual(fdb.Key(opts.Offset))
+				if opts.IncludeOffset {
+					end = fdb.FirstGreaterThan(fdb.Key(opts.Offset))
+				}
+			}
+		}
+	}
+
+	tick := opts.ProgressTicker()
+
+	for {
+		res, err := p.db.ReadTransact(func(tr fdb.ReadTransaction) (interface{}, error) {
+			return tr.GetRange(
+				fdb.SelectorRange{Begin: begin, End: end},
+				fdb.RangeOptions{Limit: scanChunkSize, Reverse: opts.ReverseScan},
+			).GetSliceWithError()
+		})
+		if err != nil {
+			return classify(err)
+		}
+
+		kvs := res.([]fdb.KeyValue)
+		for _, kv := range kvs {
+			k := []byte(kv.Key)
+			tick()
+
+			if opts.SkipsInternal() && goukv.IsInternalKey(k) {
+				continue
+			}
+
+			val := BytesToValue(kv.Value)
+			if val.IsExpired() {
+				continue
+			}
+
+			if val.Value == nil {
+				val.Value = []byte{}
+			}
+
+			if err := opts.Scanner(k, val.Value); err != nil {
+				if err == goukv.ErrScanDone {
+					return nil
+				}
+				return err
+			}
+		}
+
+		if len(kvs) < scanChunkSize {
+			return nil
+		}
+
+		lastKey := kvs[len(kvs)-1].Key
+		if opts.ReverseScan {
+			end = fdb.FirstGreaterOrEqual(lastKey)
+		} else {
+			begin = fdb.FirstGreaterThan(lastKey)
+		}
+	}
+}
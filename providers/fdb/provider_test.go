@@ -0,0 +1,161 @@
+//go:build fdb
+// +build fdb
+
+package fdb
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// openDBAndDo opens a provider against the cluster of FDB_CLUSTER_FILE, the tests write under "goukv-test/"
+// which is cleared afterwards, they are skipped when no cluster is configured
+func openDBAndDo(t *testing.T, fn func(db goukv.Provider)) {
+	clusterFile := os.Getenv("FDB_CLUSTER_FILE")
+	if clusterFile == "" {
+		t.Skip("FDB_CLUSTER_FILE isn't set")
+	}
+
+	p := Provider{}
+	db, err := p.Open(map[string]interface{}{
+		"cluster_file":    clusterFile,
+		"batch_max_bytes": 4096,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer db.(*Provider).db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		r, _ := fdb.PrefixRange([]byte("goukv-test/"))
+		tr.ClearRange(r)
+		return nil, nil
+	})
+
+	fn(db)
+}
+
+func TestPutGet(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("goukv-test/k"),
+			Value: []byte("v"),
+		}
+		err := db.Put(&entry)
+		if err != nil {
+			t.Error(err)
+		}
+		val, err := db.Get(entry.Key)
+		if err != nil {
+			t.Error(err)
+		}
+		if string(val) != string(entry.Value) {
+			t.Errorf("expected (%s), found(%s)", string(entry.Value), string(val))
+		}
+
+		if err := db.Delete(entry.Key); err != nil {
+			t.Error(err)
+		}
+		if _, err := db.Get(entry.Key); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected ErrKeyNotFound, found (%v)", err)
+		}
+
+		large := &goukv.Entry{Key: []byte("goukv-test/large"), Value: make([]byte, maxValueSize)}
+		if err := db.Put(large); err != goukv.ErrValueTooLarge {
+			t.Errorf("expected (%v), found (%v)", goukv.ErrValueTooLarge, err)
+		}
+	})
+}
+
+func TestTTL(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		defer (func() { goukv.Now = time.Now })()
+
+		entry := goukv.Entry{
+			Key:   []byte("goukv-test/ttl"),
+			Value: []byte("v"),
+			TTL:   time.Hour,
+		}
+		if err := db.Put(&entry); err != nil {
+			t.Error(err)
+		}
+
+		expires, err := db.TTL(entry.Key)
+		if err != nil {
+			t.Error(err)
+		}
+		if expires == nil || time.Until(*expires) > entry.TTL {
+			t.Errorf("unexpected expiration (%v)", expires)
+		}
+
+		now := time.Now().Add(2 * time.Hour)
+		goukv.Now = func() time.Time { return now }
+
+		if _, err := db.Get(entry.Key); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected the expired key to be hidden, found (%v)", err)
+		}
+
+		if purged, err := db.(goukv.ExpiredPurger).PurgeExpired(); err != nil || purged < 1 {
+			t.Errorf("expected the expired key to be purged, found (%d, %v)", purged, err)
+		}
+
+		raw, err := db.(*Provider).db.ReadTransact(func(tr fdb.ReadTransaction) (interface{}, error) {
+			return tr.Get(fdb.Key(entry.Key)).Get()
+		})
+		if err != nil || raw.([]byte) != nil {
+			t.Errorf("expected the expired key to be deleted, found (%v, %v)", raw, err)
+		}
+	})
+}
+
+func TestBatchScan(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entries := []*goukv.Entry{
+			{Key: []byte("goukv-test/a"), Value: []byte("v")},
+			{Key: []byte("goukv-test/scan/1"), Value: []byte("v")},
+			{Key: []byte("goukv-test/scan/2"), Value: []byte("v")},
+			{Key: []byte("goukv-test/scan/3"), Value: []byte("v")},
+			{Key: []byte("goukv-test/scan/4"), Value: []byte("v")},
+			{Key: []byte("goukv-test/scan/4")},
+		}
+
+		// more entries than a batch transaction (batch_max_bytes) and a scan chunk hold
+		for i := 0; i < 2500; i++ {
+			entries = append(entries, &goukv.Entry{Key: []byte(fmt.Sprintf("goukv-test/many/%04d", i)), Value: []byte("v")})
+		}
+
+		if err := db.Batch(entries); err != nil {
+			t.Fatal(err)
+		}
+
+		scan := func(opts goukv.ScanOpts) []string {
+			var found []string
+			opts.Scanner = func(k, v []byte) error {
+				found = append(found, string(k))
+				return nil
+			}
+			if err := db.Scan(opts); err != nil {
+				t.Error(err)
+			}
+			return found
+		}
+
+		found := scan(goukv.ScanOpts{Prefix: []byte("goukv-test/scan/"), Offset: []byte("goukv-test/scan/1")})
+		if fmt.Sprint(found) != "[goukv-test/scan/2 goukv-test/scan/3]" {
+			t.Errorf("expected ([goukv-test/scan/2 goukv-test/scan/3]), found (%v)", found)
+		}
+
+		found = scan(goukv.ScanOpts{Prefix: []byte("goukv-test/scan/"), Offset: []byte("goukv-test/scan/2"), IncludeOffset: true, ReverseScan: true})
+		if fmt.Sprint(found) != "[goukv-test/scan/2 goukv-test/scan/1]" {
+			t.Errorf("expected ([goukv-test/scan/2 goukv-test/scan/1]), found (%v)", found)
+		}
+
+		if found = scan(goukv.ScanOpts{Prefix: []byte("goukv-test/many/")}); len(found) != 2500 {
+			t.Errorf("expected (2500) keys, found (%d)", len(found))
+		}
+	})
+}
@@ -0,0 +1,14 @@
+//go:build gcs
+// +build gcs
+
+package gcs
+
+import "github.com/alash3al/goukv"
+
+const (
+	name = "gcs"
+)
+
+func init() {
+	goukv.Register(name, Provider{})
+}
@@ -0,0 +1,400 @@
+//go:build gcs
+// +build gcs
+
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/alash3al/goukv"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// expiresKey the object metadata key the expiration of an entry is stored under (RFC3339Nano)
+const expiresKey = "goukv-expires-at"
+
+// Provider represents a provider
+type Provider struct {
+	client  *storage.Client
+	bucket  *storage.BucketHandle
+	prefix  string
+	timeout time.Duration
+}
+
+// Open implements goukv.Open
+func (p Provider) Open(opts map[string]interface{}) (goukv.Provider, error) {
+	bucketName, ok := opts["bucket"].(string)
+	if !ok {
+		return nil, errors.New("must specify bucket")
+	}
+
+	prefix, ok := opts["prefix"].(string)
+	if !ok {
+		prefix = ""
+	}
+
+	timeout, ok := opts["timeout"].(time.Duration)
+	if !ok {
+		timeout = 30 * time.Second
+	}
+
+	var clientOpts []option.ClientOption
+	if file, ok := opts["credentials_file"].(string); ok {
+		clientOpts = append(clientOpts, option.WithCredentialsFile(file))
+	}
+	if credentials, ok := opts["credentials_json"].(string); ok {
+		clientOpts = append(clientOpts, option.WithCredentialsJSON([]byte(credentials)))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client, err := storage.NewClient(ctx, clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &Provider{
+		client:  client,
+		bucket:  client.Bucket(bucketName),
+		prefix:  prefix,
+		timeout: timeout,
+	}
+
+	if lifecycle, ok := opts["lifecycle_rule"].(bool); ok && lifecycle {
+		if err := provider.ensureLifecycleRule(ctx); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	return provider, nil
+}
+
+// ensureLifecycleRule adds the rule deleting the objects of the prefix a day after their custom time (the expiration)
+// to the bucket lifecycle unless it already has it, the other rules are kept
+func (p Provider) ensureLifecycleRule(ctx context.Context) error {
+	attrs, err := p.bucket.Attrs(ctx)
+	if err != nil {
+		return err
+	}
+
+	rule := storage.LifecycleRule{
+		Action:    storage.LifecycleAction{Type: storage.DeleteAction},
+		Condition: storage.LifecycleCondition{DaysSinceCustomTime: 1},
+	}
+	if p.prefix != "" {
+		rule.Condition.MatchesPrefix = []string{p.prefix}
+	}
+
+	for _, existing := range attrs.Lifecycle.Rules {
+		if existing.Action.Type == storage.DeleteAction && existing.Condition.DaysSinceCustomTime == 1 &&
+			len(existing.Condition.MatchesPrefix) == len(rule.Condition.MatchesPrefix) &&
+			(p.prefix == "" || existing.Condition.MatchesPrefix[0] == p.prefix) {
+			return nil
+		}
+	}
+
+	lifecycle := attrs.Lifecycle
+	lifecycle.Rules = append(lifecycle.Rules, rule)
+
+	_, err = p.bucket.Update(ctx, storage.BucketAttrsToUpdate{Lifecycle: &lifecycle})
+
+	return err
+}
+
+// context returns the context of a single request, bounded by the timeout option
+func (p Provider) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), p.timeout)
+}
+
+// object returns the handle of the object the specified key is stored in
+func (p Provider) object(k []byte) *storage.ObjectHandle {
+	return p.bucket.Object(p.prefix + string(k))
+}
+
+// expiresAt returns the expiration of the specified entry (nil means no expiration)
+func expiresAt(entry *goukv.Entry) *time.Time {
+	if entry.ExpireAt != nil {
+		return entry.ExpireAt
+	}
+
+	if entry.TTL > 0 {
+		expires := time.Now().Add(entry.TTL)
+		return &expires
+	}
+
+	return nil
+}
+
+// attrsExpires returns the expiration stored in the metadata of the specified object, nil if there is none
+func attrsExpires(attrs *storage.ObjectAttrs) *time.Time {
+	v, ok := attrs.Metadata[expiresKey]
+	if !ok {
+		return nil
+	}
+
+	expires, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return nil
+	}
+
+	return &expires
+}
+
+// attrsExpired whether the specified object is expired, the lifecycle rule may not have deleted it yet
+func attrsExpired(attrs *storage.ObjectAttrs) bool {
+	expires := attrsExpires(attrs)
+	return expires != nil && !time.Now().Before(*expires)
+}
+
+// Put implements goukv.Put, the value is uploaded in a single request
+func (p Provider) Put(entry *goukv.Entry) error {
+	expires := expiresAt(entry)
+	if expires != nil && !time.Now().Before(*expires) {
+		return p.Delete(entry.Key)
+	}
+
+	ctx, cancel := p.context()
+	defer cancel()
+
+	w := p.object(entry.Key).NewWriter(ctx)
+	w.ChunkSize = 0
+	w.ContentType = "application/octet-stream"
+
+	if expires != nil {
+		w.Metadata = map[string]string{expiresKey: expires.UTC().Format(time.RFC3339Nano)}
+		w.CustomTime = *expires
+	}
+
+	if _, err := w.Write(entry.Value); err != nil {
+		cancel()
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+// Batch perform multi put operation, empty value means *delete*,
+// the objects are written one by one (only the last entry of each key), it isn't atomic, the first failure is returned
+func (p Provider) Batch(entries []*goukv.Entry) error {
+	_, err := p.BatchDetailed(entries)
+	return err
+}
+
+// BatchDetailed implements goukv.DetailedBatcher, the objects are still written after a failed one,
+// the entries of a key share the result of its last entry (the one written)
+func (p Provider) BatchDetailed(entries []*goukv.Entry) ([]error, error) {
+	var err error
+	keyResults := map[string]error{}
+	for _, entry := range goukv.LastWrites(entries) {
+		var entryErr error
+		if entry.Value == nil {
+			entryErr = p.Delete(entry.Key)
+		} else {
+			entryErr = p.Put(entry)
+		}
+
+		keyResults[string(entry.Key)] = entryErr
+		if err == nil {
+			err = entryErr
+		}
+	}
+
+	results := make([]error, len(entries))
+	for i, entry := range entries {
+		results[i] = keyResults[string(entry.Key)]
+	}
+
+	return results, err
+}
+
+// read returns the value of the specified object generation, the attrs are read first so the value
+// is read from the generation they describe
+func (p Provider) read(ctx context.Context, obj *storage.ObjectHandle, generation int64) ([]byte, error) {
+	r, err := obj.Generation(generation).NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// Get implements goukv.Get, it costs two requests (the object attrs then its value)
+func (p Provider) Get(k []byte) ([]byte, error) {
+	ctx, cancel := p.context()
+	defer cancel()
+
+	obj := p.object(k)
+	for {
+		attrs, err := obj.Attrs(ctx)
+		if err == storage.ErrObjectNotExist {
+			return nil, goukv.ErrKeyNotFound
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if attrsExpired(attrs) {
+			return nil, goukv.ErrKeyNotFound
+		}
+
+		val, err := p.read(ctx, obj, attrs.Generation)
+		if err == storage.ErrObjectNotExist {
+			// overwritten or deleted since its attrs were read
+			continue
+		}
+
+		return val, err
+	}
+}
+
+// TTL implements goukv.TTL
+func (p Provider) TTL(k []byte) (*time.Time, error) {
+	ctx, cancel := p.context()
+	defer cancel()
+
+	attrs, err := p.object(k).Attrs(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if attrsExpired(attrs) {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	return attrsExpires(attrs), nil
+}
+
+// Delete implements goukv.Delete
+func (p Provider) Delete(k []byte) error {
+	ctx, cancel := p.context()
+	defer cancel()
+
+	err := p.object(k).Delete(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil
+	}
+
+	return err
+}
+
+// ListPrefixes implements goukv.PrefixLister using the delimiter of the object listing,
+// the expiration of the objects under a listed prefix isn't checked
+func (p Provider) ListPrefixes(prefix []byte, delimiter byte) ([][]byte, error) {
+	it := p.bucket.Objects(context.Background(), &storage.Query{
+		Prefix:    p.prefix + string(prefix),
+		Delimiter: string(delimiter),
+	})
+
+	var prefixes [][]byte
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return prefixes, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		// the objects right under the prefix are listed too, only the synthetic prefixes are kept
+		if attrs.Prefix != "" {
+			prefixes = append(prefixes, []byte(attrs.Prefix[len(p.prefix):]))
+		}
+	}
+}
+
+// Native implements goukv.NativeAccessor, it returns the underlying *storage.Client
+func (p Provider) Native() interface{} {
+	return p.client
+}
+
+// Close implements goukv.Close
+func (p Provider) Close() error {
+	return p.client.Close()
+}
+
+// Scan implements goukv.Scan, the objects are listed in name order (in pages of 1000, the expiration is read
+// from the listed metadata so the expired objects are skipped without being read) then each value is read
+// with its own request, the offset is the start offset of the listing (like a page token, it needn't exist),
+// reverse scans aren't supported
+func (p Provider) Scan(opts goukv.ScanOpts) error {
+	if opts.Scanner == nil {
+		return goukv.ErrNoScanner
+	}
+
+	opts.Scanner = opts.BoundedScanner()
+
+	if opts.ReverseScan {
+		return goukv.ErrNotSupported
+	}
+
+	query := &storage.Query{Prefix: p.prefix + string(opts.Prefix)}
+	if opts.Offset != nil {
+		query.StartOffset = p.prefix + string(opts.Offset)
+	}
+
+	it := p.bucket.Objects(context.Background(), query)
+	it.PageInfo().MaxSize = 1000
+
+	tick := opts.ProgressTicker()
+
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		tick()
+
+		key := []byte(attrs.Name[len(p.prefix):])
+		if opts.Offset != nil && !opts.IncludeOffset && bytes.Equal(key, opts.Offset) {
+			continue
+		}
+
+		if attrsExpired(attrs) {
+			continue
+		}
+
+		ctx, cancel := p.context()
+		val, err := p.read(ctx, p.bucket.Object(attrs.Name), attrs.Generation)
+		cancel()
+
+		// deleted or overwritten since it was listed, its current value (if any) is read instead
+		if err == storage.ErrObjectNotExist {
+			val, err = p.Get(key)
+			if err == goukv.ErrKeyNotFound {
+				continue
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := opts.Scanner(key, val); err != nil {
+			if err == goukv.ErrScanDone {
+				return nil
+			}
+			return err
+		}
+	}
+}
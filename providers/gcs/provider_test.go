@@ -0,0 +1,127 @@
+//go:build gcs
+// +build gcs
+
+package gcs
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+// openDBAndDo opens a provider against the bucket in GCS_BUCKET (under the "goukv-test/" prefix) using the
+// application default credentials, the test is skipped when no bucket is configured
+func openDBAndDo(t *testing.T, fn func(db goukv.Provider)) {
+	bucket := os.Getenv("GCS_BUCKET")
+	if bucket == "" {
+		t.Skip("GCS_BUCKET isn't set")
+	}
+
+	p := Provider{}
+	db, err := p.Open(map[string]interface{}{
+		"bucket": bucket,
+		"prefix": "goukv-test/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fn(db)
+}
+
+func TestPutGet(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("k"),
+			Value: []byte("v"),
+		}
+		err := db.Put(&entry)
+		if err != nil {
+			t.Error(err)
+		}
+		val, err := db.Get(entry.Key)
+		if err != nil {
+			t.Error(err)
+		}
+		if string(val) != string(entry.Value) {
+			t.Errorf("expected (%s), found(%s)", string(entry.Value), string(val))
+		}
+
+		if err := db.Delete(entry.Key); err != nil {
+			t.Error(err)
+		}
+		if _, err := db.Get(entry.Key); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected ErrKeyNotFound, found (%v)", err)
+		}
+	})
+}
+
+func TestTTL(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("ttl"),
+			Value: []byte("v"),
+			TTL:   time.Second * 2,
+		}
+		if err := db.Put(&entry); err != nil {
+			t.Error(err)
+		}
+		defer db.Delete(entry.Key)
+
+		expires, err := db.TTL(entry.Key)
+		if err != nil {
+			t.Error(err)
+		}
+		if expires == nil || time.Until(*expires) > entry.TTL {
+			t.Errorf("unexpected expiration (%v)", expires)
+		}
+
+		time.Sleep(entry.TTL)
+
+		if _, err := db.Get(entry.Key); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected the expired object to be hidden, found (%v)", err)
+		}
+	})
+}
+
+func TestBatchScan(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entries := []*goukv.Entry{
+			{Key: []byte("scan/1"), Value: []byte("v")},
+			{Key: []byte("scan/2"), Value: []byte("v")},
+			{Key: []byte("scan/3"), Value: []byte("v")},
+		}
+		if err := db.Batch(entries); err != nil {
+			t.Fatal(err)
+		}
+		defer db.Batch([]*goukv.Entry{
+			{Key: []byte("scan/1")},
+			{Key: []byte("scan/2")},
+			{Key: []byte("scan/3")},
+		})
+
+		var found []string
+		err := db.Scan(goukv.ScanOpts{
+			Prefix: []byte("scan/"),
+			Offset: []byte("scan/1"),
+			Scanner: func(k, v []byte) error {
+				found = append(found, string(k))
+				return nil
+			},
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		if len(found) != 2 || found[0] != "scan/2" || found[1] != "scan/3" {
+			t.Errorf("expected ([scan/2 scan/3]), found (%v)", found)
+		}
+
+		prefixes, err := db.(goukv.PrefixLister).ListPrefixes(nil, '/')
+		if err != nil || len(prefixes) != 1 || string(prefixes[0]) != "scan/" {
+			t.Errorf("expected ([scan/]), found (%q) (%v)", prefixes, err)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package leveldb
+
+import (
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb/cache"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+// SharedBlockCache a block cache shared by several databases (see the "block_cache" option), so that their blocks
+// compete for a single capacity which bounds the memory of all of them, the capacity of every database is ignored
+type SharedBlockCache struct {
+	lru cache.Cacher
+}
+
+// NewSharedBlockCache returns an LRU block cache of the specified capacity (in bytes)
+// to be shared by several databases, opt.DefaultBlockCacheCapacity is used when it's zero
+func NewSharedBlockCache(capacity int) *SharedBlockCache {
+	if capacity <= 0 {
+		capacity = opt.DefaultBlockCacheCapacity
+	}
+
+	return &SharedBlockCache{
+		lru: cache.NewLRU(capacity),
+	}
+}
+
+// Capacity returns the capacity of the cache in bytes
+func (c *SharedBlockCache) Capacity() int {
+	return c.lru.Capacity()
+}
+
+// New implements opt.Cacher, every database gets a view of the same LRU
+func (c *SharedBlockCache) New(int) cache.Cacher {
+	return sharedCacher{c.lru}
+}
+
+// sharedCacher the view of a database on a shared LRU, the blocks are tracked by the LRU as usual but closing the
+// database doesn't flush the blocks of the others: the ones it leaves behind age out like any other block
+type sharedCacher struct {
+	cache.Cacher
+}
+
+// EvictAll implements cache.Cacher, it's only called when the database is closed
+func (sharedCacher) EvictAll() {}
+
+// Close implements cache.Cacher, the LRU outlives the databases
+func (sharedCacher) Close() error {
+	return nil
+}
+
+var (
+	blockCachesLock = &sync.Mutex{}
+	blockCaches     = map[string]*SharedBlockCache{}
+)
+
+// NamedBlockCache returns the shared block cache registered under the specified name, creating it with the specified
+// capacity if it doesn't exist yet (see the "block_cache_name" option), a registered cache is never removed
+func NamedBlockCache(name string, capacity int) *SharedBlockCache {
+	blockCachesLock.Lock()
+	defer blockCachesLock.Unlock()
+
+	c, ok := blockCaches[name]
+	if !ok {
+		c = NewSharedBlockCache(capacity)
+		blockCaches[name] = c
+	}
+
+	return c
+}
@@ -0,0 +1,24 @@
+package leveldb
+
+import (
+	"github.com/alash3al/goukv"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+)
+
+// classifyError wraps the known goleveldb errors in a goukv.Error of their kind,
+// the other errors (i.e the goukv ones and the scanners ones) are returned unchanged
+func classifyError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case err == ErrTxnConflict:
+		return goukv.WrapError(goukv.ErrorConflict, err)
+	case errors.IsCorrupted(err):
+		return goukv.WrapError(goukv.ErrorCorruption, err)
+	case err == leveldb.ErrClosed, err == leveldb.ErrReadOnly, err == leveldb.ErrSnapshotReleased, err == leveldb.ErrIterReleased:
+		return goukv.WrapError(goukv.ErrorFatal, err)
+	}
+
+	return err
+}
@@ -0,0 +1,87 @@
+package leveldb
+
+import (
+	"reflect"
+	"sync"
+)
+
+// the dbs opened by this process indexed by their absolute path, goleveldb locks its directory
+// so opening a path twice shares the first handle instead of failing
+var (
+	handles     = map[string]*handle{}
+	handlesLock = &sync.Mutex{}
+)
+
+// openOnlyOptions the options that only affect the Open call itself, a shared handle may be opened again with other ones
+var openOnlyOptions = map[string]bool{
+	"path":                true,
+	"dir_perm":            true,
+	"error_if_missing":    true,
+	"open_retry_attempts": true,
+	"open_retry_backoff":  true,
+	"verify_on_open":      true,
+}
+
+// handle a reference-counted db handle, its provider is nil while it's being opened
+type handle struct {
+	path     string
+	opts     map[string]interface{}
+	provider *Provider
+	refs     int
+
+	// opening closed once the open completes (successfully or not)
+	opening chan struct{}
+}
+
+// ref returns a new reference to the shared provider
+func (h *handle) ref() *Provider {
+	h.refs++
+
+	ref := *h.provider
+	ref.released = new(bool)
+
+	return &ref
+}
+
+// release releases the reference of the specified provider, it reports whether it was the last one,
+// the caller must hold the handles lock
+func (h *handle) release(p Provider) (last bool) {
+	*p.released = true
+
+	h.refs--
+	if h.refs > 0 {
+		return false
+	}
+
+	delete(handles, h.path)
+
+	return true
+}
+
+// sameOptions whether the specified options configure a db the same way, the funcs are compared by their code pointer
+// (they can't be compared otherwise) and the open only options are ignored
+func sameOptions(a, b map[string]interface{}) bool {
+	for _, opts := range [][2]map[string]interface{}{{a, b}, {b, a}} {
+		for k, v := range opts[0] {
+			if openOnlyOptions[k] {
+				continue
+			}
+
+			other, ok := opts[1][k]
+			if !ok {
+				return false
+			}
+
+			vf, otherf := reflect.ValueOf(v), reflect.ValueOf(other)
+			if vf.Kind() == reflect.Func && otherf.Kind() == reflect.Func {
+				if vf.Pointer() != otherf.Pointer() {
+					return false
+				}
+			} else if !reflect.DeepEqual(v, other) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,126 @@
+package leveldb
+
+import (
+	"bytes"
+
+	"github.com/alash3al/goukv"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// keyIterator implements goukv.Iterator, expired keys are skipped
+type keyIterator struct {
+	p       Provider
+	iter    iterator.Iterator
+	reverse bool
+	started bool
+	value   []byte
+
+	// external whether the internal keys are skipped (see ScanOpts.SkipsInternal)
+	external bool
+}
+
+// NewIterator implements goukv.Iterable
+func (p Provider) NewIterator(prefix []byte, reverse bool) (goukv.Iterator, error) {
+	var rng *util.Range
+	if len(prefix) > 0 {
+		rng = util.BytesPrefix(prefix)
+	}
+
+	return &keyIterator{
+		p:        p,
+		iter:     p.db.NewIterator(rng, nil),
+		reverse:  reverse,
+		external: (goukv.ScanOpts{Prefix: prefix}).SkipsInternal(),
+	}, nil
+}
+
+// step moves one entry forward (backward in reverse)
+func (it *keyIterator) step() bool {
+	if it.reverse {
+		return it.iter.Prev()
+	}
+	return it.iter.Next()
+}
+
+// skipExpired moves past the expired (and internal unless visible) entries starting from the current one
+func (it *keyIterator) skipExpired(ok bool) bool {
+	for ; ok; ok = it.step() {
+		if it.external && goukv.IsInternalKey(it.iter.Key()) {
+			if ok = it.skipInternal(); !ok {
+				break
+			}
+		}
+
+		val := it.p.decodeValue(it.iter.Value())
+		if val.IsLive() {
+			it.value = val.Value
+			return true
+		}
+	}
+
+	it.value = nil
+
+	return false
+}
+
+// skipInternal seeks past the internal keys the iterator is positioned on, to the first key after them
+// or to the last key before them when walking backward
+func (it *keyIterator) skipInternal() bool {
+	if it.reverse {
+		return it.iter.Seek(goukv.InternalPrefix) && it.iter.Prev()
+	}
+
+	return it.iter.Seek(util.BytesPrefix(goukv.InternalPrefix).Limit)
+}
+
+// Next implements goukv.Iterator.Next
+func (it *keyIterator) Next() bool {
+	if it.started {
+		return it.skipExpired(it.step())
+	}
+	it.started = true
+
+	if it.reverse {
+		return it.skipExpired(it.iter.Last())
+	}
+	return it.skipExpired(it.iter.First())
+}
+
+// Seek implements goukv.Iterator.Seek
+func (it *keyIterator) Seek(key []byte) bool {
+	it.started = true
+
+	ok := it.iter.Seek(key)
+	if it.reverse {
+		// the greatest key <= key, or the last one when key is beyond the range
+		if !ok {
+			ok = it.iter.Last()
+		} else if bytes.Compare(it.iter.Key(), key) > 0 {
+			ok = it.iter.Prev()
+		}
+	}
+
+	return it.skipExpired(ok)
+}
+
+// Key implements goukv.Iterator.Key
+func (it *keyIterator) Key() []byte {
+	return append([]byte{}, it.iter.Key()...)
+}
+
+// Value implements goukv.Iterator.Value
+func (it *keyIterator) Value() []byte {
+	return append([]byte{}, it.value...)
+}
+
+// Error implements goukv.Iterator.Error
+func (it *keyIterator) Error() error {
+	return it.iter.Error()
+}
+
+// Close implements goukv.Iterator.Close
+func (it *keyIterator) Close() error {
+	it.iter.Release()
+	return nil
+}
@@ -2,10 +2,13 @@ package leveldb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/alash3al/goukv"
@@ -16,130 +19,1409 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
+// versionKey the reserved key the last assigned version is stored under
+var versionKey = []byte("\x00goukv\x00version")
+
 // Provider represents a driver
 type Provider struct {
-	db         *leveldb.DB
-	syncWrites bool
+	db           *leveldb.DB
+	options      *opt.Options
+	syncWrites   bool
+	isolation    string
+	writeLock    *sync.RWMutex
+	changelog    bool
+	commitLock   *sync.Mutex
+	changelogSeq *uint64
+	versions     bool
+	version      *uint64
+	timestamps   bool
+	trackDeletes bool
+	tombstoneTTL time.Duration
+	ttlJitter    func(time.Duration) time.Duration
+	prefixTTLs   func([]byte) time.Duration
+	throttle     *goukv.WriteThrottle
+	noTTL        bool
+	compact      bool
+	maxValueSize int
+	events       *goukv.EventHub
+	watchdogStop chan struct{}
+	watchdogDone *sync.WaitGroup
+	handle       *handle
+	released     *bool
+	bulkReopen   *opt.Options
+	maintenance  *goukv.MaintenanceHook
+}
+
+// Open implements goukv.Open
+func (p Provider) Open(opts map[string]interface{}) (goukv.Provider, error) {
+	path, ok := opts["path"].(string)
+	if !ok {
+		return nil, errors.New("must specify path")
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	handlesLock.Lock()
+	for {
+		h, ok := handles[absPath]
+		if !ok {
+			break
+		}
+
+		// another call is opening it, the lock isn't held meanwhile so the other paths aren't blocked by its retries
+		if h.provider == nil {
+			opening := h.opening
+			handlesLock.Unlock()
+			<-opening
+			handlesLock.Lock()
+			continue
+		}
+
+		if !sameOptions(h.opts, opts) {
+			handlesLock.Unlock()
+			return nil, errors.New("the db is already open with other options: " + absPath)
+		}
+
+		ref := h.ref()
+		handlesLock.Unlock()
+
+		return ref, nil
+	}
+
+	// the placeholder of the path until it's opened
+	h := &handle{path: absPath, opts: map[string]interface{}{}, opening: make(chan struct{})}
+	for k, v := range opts {
+		h.opts[k] = v
+	}
+	handles[absPath] = h
+	handlesLock.Unlock()
+
+	provider, err := openProvider(path, opts)
+
+	handlesLock.Lock()
+	defer handlesLock.Unlock()
+
+	close(h.opening)
+	if err != nil {
+		delete(handles, absPath)
+		return nil, err
+	}
+
+	provider.handle = h
+	h.provider = provider
+
+	return h.ref(), nil
+}
+
+// openProvider opens the db of the specified path with the specified options, it isn't registered as a shared handle
+func openProvider(path string, opts map[string]interface{}) (*Provider, error) {
+	dirPerm, ok := opts["dir_perm"].(os.FileMode)
+	if !ok {
+		dirPerm = 0700
+	}
+
+	errorIfMissing, ok := opts["error_if_missing"].(bool)
+	if !ok {
+		errorIfMissing = false
+	}
+
+	// the created directories are made durable along with the writes
+	syncWrites, _ := opts["sync_writes"].(bool)
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if errorIfMissing {
+			return nil, goukv.ErrDBNotFound
+		}
+		if err := goukv.CreateDir(path, dirPerm, syncWrites); err != nil {
+			return nil, err
+		}
+	}
+
+	o := &opt.Options{
+		Filter:         filter.NewBloomFilter(10),
+		ErrorIfMissing: errorIfMissing,
+		Compression:    9,
+	}
+
+	// zero values mean goleveldb's defaults
+	o.CompactionTableSize, _ = opts["compaction_table_size"].(int)
+	o.CompactionL0Trigger, _ = opts["compaction_l0_trigger"].(int)
+	o.WriteL0SlowdownTrigger, _ = opts["write_l0_slowdown_trigger"].(int)
+	o.WriteL0PauseTrigger, _ = opts["write_l0_pause_trigger"].(int)
+
+	// the tables kept open (one descriptor each), -1 disables the cache so a table is opened on every read
+	o.OpenFilesCacheCapacity, _ = opts["open_files_cache_capacity"].(int)
+
+	// the blocks of the dbs sharing a cache are bounded by its capacity as a whole
+	if blockCache, ok := opts["block_cache"].(*SharedBlockCache); ok {
+		o.BlockCacher, o.BlockCacheCapacity = blockCache, blockCache.Capacity()
+	} else if name, ok := opts["block_cache_name"].(string); ok {
+		capacity, _ := opts["block_cache_size"].(int)
+		blockCache := NamedBlockCache(name, capacity)
+		o.BlockCacher, o.BlockCacheCapacity = blockCache, blockCache.Capacity()
+	}
+
+	bulkLoad, ok := opts["bulk_load"].(bool)
+	if !ok {
+		bulkLoad = false
+	}
+
+	// the db is reopened with the normal options to be compacted once a bulk load is closed
+	var bulkReopen *opt.Options
+	if bulkLoad {
+		bulkReopen, o = o, bulkLoadOptions(o)
+	}
+
+	provider, err := newProvider(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var db *leveldb.DB
+	err = goukv.NewOpenRetry(opts).Do(func() (err error) {
+		db, err = leveldb.OpenFile(path, o)
+		return err
+	})
+	if errorIfMissing && os.IsNotExist(err) {
+		return nil, goukv.ErrDBNotFound
+	}
+
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	// goleveldb opens the tables lazily, so a corrupted one would fail a later read
+	if verify, _ := opts["verify_on_open"].(bool); verify {
+		if err := verifyTables(db); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	provider.options, provider.bulkReopen = o, bulkReopen
+	if err := provider.attach(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if watchdog := goukv.NewDiskWatchdog(opts); watchdog != nil {
+		provider.watchdogDone.Add(1)
+		go (func() {
+			defer provider.watchdogDone.Done()
+
+			watchdog.Run(provider.watchdogStop, []string{path}, func() {
+				provider.maintenance.Run(goukv.MaintenanceCompaction, []string{path}, func() error {
+					return db.CompactRange(util.Range{})
+				})
+			})
+		})()
+	}
+
+	return provider, nil
+}
+
+// verifyTables reads the whole db (keys and values) without filling the block cache, so that the checksum of every block
+// of every table is verified, a corrupted (or missing) table fails with an error of the goukv.ErrorCorruption kind
+func verifyTables(db *leveldb.DB) error {
+	iter := db.NewIterator(nil, &opt.ReadOptions{DontFillCache: true, Strict: opt.StrictBlockChecksum})
+	for iter.Next() {
+	}
+	iter.Release()
+
+	return classifyError(iter.Error())
+}
+
+// newProvider builds a provider from the options that don't configure goleveldb itself (the write path, the value
+// wrapper and the transactions), the caller attaches its db
+func newProvider(opts map[string]interface{}) (*Provider, error) {
+	syncWrites, ok := opts["sync_writes"].(bool)
+	if !ok {
+		syncWrites = false
+	}
+
+	changelog, ok := opts["enable_changelog"].(bool)
+	if !ok {
+		changelog = false
+	}
+
+	noTTL, ok := opts["no_ttl"].(bool)
+	if !ok {
+		noTTL = false
+	}
+
+	versions, ok := opts["enable_versions"].(bool)
+	if !ok {
+		versions = false
+	}
+
+	if versions && noTTL {
+		return nil, errors.New("enable_versions requires the value wrapper, it can't be combined with no_ttl")
+	}
+
+	timestamps, ok := opts["track_timestamps"].(bool)
+	if !ok {
+		timestamps = false
+	}
+
+	if timestamps && noTTL {
+		return nil, errors.New("track_timestamps requires the value wrapper, it can't be combined with no_ttl")
+	}
+
+	trackDeletes, ok := opts["track_deletes"].(bool)
+	if !ok {
+		trackDeletes = false
+	}
+
+	if trackDeletes && noTTL {
+		return nil, errors.New("track_deletes requires the value wrapper, it can't be combined with no_ttl")
+	}
+
+	prefixTTLs := goukv.NewPrefixTTLs(opts["prefix_ttls"])
+	if prefixTTLs != nil && noTTL {
+		return nil, errors.New("prefix_ttls can't be combined with no_ttl")
+	}
+
+	tombstoneTTL, ok := opts["tombstone_ttl"].(time.Duration)
+	if !ok || tombstoneTTL <= 0 {
+		tombstoneTTL = goukv.DefaultTombstoneTTL
+	}
+
+	// zero means unlimited
+	maxValueSize, _ := opts["max_value_size"].(int)
+
+	compact, ok := opts["compact_values"].(bool)
+	if !ok {
+		compact = true
+	}
+
+	isolation, ok := opts["txn_isolation"].(string)
+	if !ok {
+		isolation = IsolationSerializable
+	}
+
+	if isolation != IsolationSerializable && isolation != IsolationSnapshot {
+		return nil, errors.New("unknown txn_isolation: " + isolation)
+	}
+
+	return &Provider{
+		syncWrites:   syncWrites,
+		isolation:    isolation,
+		writeLock:    &sync.RWMutex{},
+		changelog:    changelog,
+		commitLock:   &sync.Mutex{},
+		changelogSeq: new(uint64),
+		versions:     versions,
+		version:      new(uint64),
+		timestamps:   timestamps,
+		trackDeletes: trackDeletes,
+		tombstoneTTL: tombstoneTTL,
+		ttlJitter:    goukv.NewTTLJitter(opts["ttl_jitter"]),
+		prefixTTLs:   prefixTTLs,
+		throttle:     goukv.NewWriteThrottle(opts),
+		noTTL:        noTTL,
+		compact:      compact,
+		maxValueSize: maxValueSize,
+		events:       goukv.NewEventHub(),
+		watchdogStop: make(chan struct{}),
+		watchdogDone: &sync.WaitGroup{},
+		maintenance:  goukv.NewMaintenanceHook(opts),
+	}, nil
+}
+
+// attach sets the db of the provider, restoring its last changelog sequence and version from it
+func (p *Provider) attach(db *leveldb.DB) error {
+	p.db = db
+
+	if p.changelog {
+		iter := db.NewIterator(util.BytesPrefix(goukv.ChangelogPrefix), nil)
+		if iter.Last() {
+			*p.changelogSeq = goukv.ChangeSeq(iter.Key())
+		}
+		iter.Release()
+	}
+
+	if p.versions {
+		b, err := db.Get(versionKey, nil)
+		if err != nil && err != leveldb.ErrNotFound {
+			return err
+		}
+		if len(b) == 8 {
+			*p.version = binary.BigEndian.Uint64(b)
+		}
+	}
+
+	return nil
+}
+
+// FromDB wraps an already opened goleveldb db without calling Open, opts accepts the options that don't configure
+// goleveldb itself (i.e "enable_changelog", "enable_versions", "track_timestamps", "no_ttl", "txn_isolation") with the
+// same defaults, the db-level ones (path, compactions, disk watchdog, bulk_load, open retries) are ignored.
+// the provider takes the ownership of db: closing it closes db, and it isn't shared with the Open of the same path
+func FromDB(db *leveldb.DB, opts map[string]interface{}) (goukv.Provider, error) {
+	provider, err := newProvider(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	provider.options = &opt.Options{}
+	provider.released = new(bool)
+	if err := provider.attach(db); err != nil {
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+// bulkLoadOptions returns a copy of the specified options tuned for an import: a 32MiB write buffer, no bloom filter
+// and level-0 triggers high enough for the compactions to merge more tables at once and for the writes not to be
+// slowed down or paused while they run
+func bulkLoadOptions(o *opt.Options) *opt.Options {
+	bulk := *o
+	bulk.Filter = nil
+	bulk.WriteBuffer = 32 * opt.MiB
+	bulk.CompactionL0Trigger = 16
+	bulk.WriteL0SlowdownTrigger = 64
+	bulk.WriteL0PauseTrigger = 128
+
+	return &bulk
+}
+
+// finishBulkLoad reopens the db closed after a bulk load with its normal options then compacts it as a whole,
+// so that its tables are merged out of level-0 and rewritten with the bloom filter
+func finishBulkLoad(path string, o *opt.Options, maintenance *goukv.MaintenanceHook) error {
+	db, err := leveldb.OpenFile(path, o)
+	if err != nil {
+		return err
+	}
+
+	err = maintenance.Run(goukv.MaintenanceCompaction, []string{path}, func() error {
+		return db.CompactRange(util.Range{})
+	})
+	if err != nil {
+		db.Close()
+		return err
+	}
+
+	return db.Close()
+}
+
+// prepareEntry applies the provider-level entry options (such as the default ttl of its prefix and the ttl jitter)
+// to a copy of the entry, the values larger than max_value_size are rejected
+func (p Provider) prepareEntry(e *goukv.Entry) (*goukv.Entry, error) {
+	if p.maxValueSize > 0 && len(e.Value) > p.maxValueSize {
+		return nil, goukv.ErrValueTooLarge
+	}
+
+	if p.noTTL && (e.TTL > 0 || e.ExpireAt != nil) {
+		return nil, ErrTTLDisabled
+	}
+
+	e = goukv.WithPrefixTTL(e, p.prefixTTLs)
+
+	if p.ttlJitter == nil || e.TTL <= 0 || e.ExpireAt != nil {
+		return e, nil
+	}
+
+	prepared := *e
+	prepared.TTL = p.ttlJitter(e.TTL)
+
+	return &prepared, nil
+}
+
+// encodeValue returns the stored form of the specified value, the raw value in no_ttl mode
+func (p Provider) encodeValue(val Value) []byte {
+	if p.noTTL {
+		return val.Value
+	}
+	if !p.compact {
+		return val.MsgpackBytes()
+	}
+	return val.Bytes()
+}
+
+// createdAt returns the creation time of the live value of the specified key, nil if there is none
+func (p Provider) createdAt(k []byte) (*time.Time, error) {
+	b, err := p.db.Get(k, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	val := p.decodeValue(b)
+	if !val.IsLive() {
+		return nil, nil
+	}
+
+	return val.CreatedAt, nil
+}
+
+// decodeValue decodes the specified stored value, in no_ttl mode it's the raw value that never expires
+func (p Provider) decodeValue(b []byte) Value {
+	if p.noTTL {
+		return Value{Value: b}
+	}
+	return BytesToValue(b)
+}
+
+// write commits the specified changes holding the write lock shared,
+// transactions and atomic operations hold it exclusively so plain writes can't interleave with them
+func (p Provider) write(changes []goukv.Change) error {
+	p.writeLock.RLock()
+	defer p.writeLock.RUnlock()
+
+	return classifyError(p.commit(changes))
+}
+
+// writeDeletes removes the specified keys for good (even in track_deletes mode) holding the write lock shared
+func (p Provider) writeDeletes(changes []goukv.Change) error {
+	p.writeLock.RLock()
+	defer p.writeLock.RUnlock()
+
+	return classifyError(p.commitChanges(changes, false))
+}
+
+// commit writes the specified changes in a single batch, the deletes leave tombstones in track_deletes mode
+func (p Provider) commit(changes []goukv.Change) error {
+	return p.commitChanges(changes, p.trackDeletes)
+}
+
+// commitChanges writes the specified changes in a single batch, appending them to the changelog (if enabled)
+// and stamping the puts with the next versions and their timestamps (if enabled) atomically, the deletes write
+// (versioned) tombstones instead of removing the keys when tombstones is set, the caller must hold the write lock
+func (p Provider) commitChanges(changes []goukv.Change, tombstones bool) error {
+	wo := &opt.WriteOptions{
+		Sync: p.syncWrites,
+	}
+
+	if p.changelog || p.versions || p.timestamps {
+		p.commitLock.Lock()
+		defer p.commitLock.Unlock()
+	}
+
+	batch := new(leveldb.Batch)
+	seq, version, now := *p.changelogSeq, *p.version, goukv.Now()
+	for _, change := range changes {
+		if change.Op == goukv.ChangeDelete && tombstones {
+			expires := now.Add(p.tombstoneTTL)
+			val := Value{Expires: &expires, Tombstone: true}
+			if p.versions {
+				version++
+				val.Version = version
+			}
+			batch.Put(change.Key, p.encodeValue(val))
+		} else if change.Op == goukv.ChangeDelete {
+			batch.Delete(change.Key)
+		} else {
+			val := EntryToValue(&goukv.Entry{Key: change.Key, Value: change.Value, TTL: change.TTL, ExpireAt: change.ExpireAt})
+			if p.versions {
+				version++
+				val.Version = version
+			}
+			if p.timestamps {
+				created, err := p.createdAt(change.Key)
+				if err != nil {
+					return err
+				}
+				if created == nil {
+					created = &now
+				}
+				val.CreatedAt, val.UpdatedAt = created, &now
+			}
+			batch.Put(change.Key, p.encodeValue(val))
+		}
+
+		if p.changelog {
+			seq++
+			change.Seq = seq
+			change.Time = now
+			batch.Put(goukv.ChangeKey(change.Seq), change.Bytes())
+		}
+	}
+
+	if p.versions {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, version)
+		batch.Put(versionKey, b)
+	}
+
+	err := p.events.Commit(changes, func() error {
+		return p.db.Write(batch, wo)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	*p.changelogSeq, *p.version = seq, version
+
+	return nil
+}
+
+// Put implements goukv.Put
+func (p Provider) Put(e *goukv.Entry) error {
+	e, err := p.prepareEntry(e)
+	if err != nil {
+		return err
+	}
+
+	if p.throttle != nil {
+		p.throttle.Wait(e)
+	}
+
+	return p.write([]goukv.Change{
+		{Op: goukv.ChangePut, Key: e.Key, Value: e.Value, TTL: e.TTL, ExpireAt: e.ExpireAt},
+	})
+}
+
+// PutIfChanged implements goukv.IdempotentPutter, it holds the write lock exclusively while comparing and writing,
+// it's throttled like Put (before the lock is taken) even when the entry turns out unchanged
+func (p Provider) PutIfChanged(e *goukv.Entry) (bool, error) {
+	e, err := p.prepareEntry(e)
+	if err != nil {
+		return false, err
+	}
+	val := EntryToValue(e)
+
+	if p.throttle != nil {
+		p.throttle.Wait(e)
+	}
+
+	p.writeLock.Lock()
+	defer p.writeLock.Unlock()
+
+	b, err := p.db.Get(e.Key, nil)
+	if err != nil && err != leveldb.ErrNotFound {
+		return false, err
+	}
+
+	if err == nil {
+		current := p.decodeValue(b)
+		if current.IsLive() && bytes.Equal(current.Value, val.Value) && goukv.SameExpiry(current.Expires, val.Expires) {
+			return false, nil
+		}
+	}
+
+	err = classifyError(p.commit([]goukv.Change{
+		{Op: goukv.ChangePut, Key: e.Key, Value: e.Value, TTL: e.TTL, ExpireAt: e.ExpireAt},
+	}))
+
+	return err == nil, err
+}
+
+// PutVersioned implements goukv.VersionedPutter, it requires enable_versions (goukv.ErrNotSupported is returned
+// otherwise), the version is compared and the entry written holding the write lock exclusively, it's throttled
+// like Put (before the lock is taken) even when the version doesn't match
+func (p Provider) PutVersioned(e *goukv.Entry, expectedVersion uint64) (uint64, error) {
+	if !p.versions {
+		return 0, goukv.ErrNotSupported
+	}
+
+	e, err := p.prepareEntry(e)
+	if err != nil {
+		return 0, err
+	}
+
+	if p.throttle != nil {
+		p.throttle.Wait(e)
+	}
+
+	p.writeLock.Lock()
+	defer p.writeLock.Unlock()
+
+	b, err := p.db.Get(e.Key, nil)
+	if err != nil && err != leveldb.ErrNotFound {
+		return 0, classifyError(err)
+	}
+
+	var current uint64
+	if err == nil {
+		if val := p.decodeValue(b); val.IsLive() {
+			current = val.Version
+		}
+	}
+
+	if current != expectedVersion {
+		return 0, goukv.ErrConflict
+	}
+
+	err = p.commit([]goukv.Change{
+		{Op: goukv.ChangePut, Key: e.Key, Value: e.Value, TTL: e.TTL, ExpireAt: e.ExpireAt},
+	})
+	if err != nil {
+		return 0, classifyError(err)
+	}
+
+	// the write lock is held so no other put has been stamped since
+	return *p.version, nil
+}
+
+// CompareAndDelete implements goukv.CompareAndDeleter, the value is compared and deleted holding the write lock exclusively
+func (p Provider) CompareAndDelete(k, old []byte) (bool, error) {
+	p.writeLock.Lock()
+	defer p.writeLock.Unlock()
+
+	b, err := p.db.Get(k, nil)
+	if err == leveldb.ErrNotFound {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, classifyError(err)
+	}
+
+	current := p.decodeValue(b)
+	if !current.IsLive() || !bytes.Equal(current.Value, old) {
+		return false, nil
+	}
+
+	err = classifyError(p.commit([]goukv.Change{
+		{Op: goukv.ChangeDelete, Key: k},
+	}))
+
+	return err == nil, err
+}
+
+// PutSync implements goukv.SyncWriter, the write is done by a copy of the provider using the specified sync option
+func (p Provider) PutSync(e *goukv.Entry, sync bool) error {
+	p.syncWrites = sync
+	return p.Put(e)
+}
+
+// DeleteSync implements goukv.SyncWriter
+func (p Provider) DeleteSync(k []byte, sync bool) error {
+	p.syncWrites = sync
+	return p.Delete(k)
+}
+
+// BatchSync implements goukv.SyncWriter
+func (p Provider) BatchSync(entries []*goukv.Entry, sync bool) error {
+	p.syncWrites = sync
+	return p.Batch(entries)
+}
+
+// ValidateBatch implements goukv.BatchValidator, goleveldb has no key or value size limits but max_value_size
+func (p Provider) ValidateBatch(entries []*goukv.Entry) error {
+	return goukv.ValidateEntries(entries, 0, p.maxValueSize)
+}
+
+// Batch perform multi put operation, empty value means *delete*,
+// the entries are appended to a single leveldb batch in order, so a key written twice keeps its last entry
+func (p Provider) Batch(entries []*goukv.Entry) error {
+	if err := p.ValidateBatch(entries); err != nil {
+		return err
+	}
+
+	if p.throttle != nil {
+		p.throttle.Wait(entries...)
+	}
+
+	changes := make([]goukv.Change, 0, len(entries))
+
+	for _, entry := range entries {
+		entry, err := p.prepareEntry(entry)
+		if err != nil {
+			return err
+		}
+
+		if entry.Value == nil {
+			changes = append(changes, goukv.Change{Op: goukv.ChangeDelete, Key: entry.Key})
+		} else {
+			changes = append(changes, goukv.Change{Op: goukv.ChangePut, Key: entry.Key, Value: entry.Value, TTL: entry.TTL, ExpireAt: entry.ExpireAt})
+		}
+	}
+
+	return p.write(changes)
+}
+
+// Get implements goukv.Get
+func (p Provider) Get(k []byte) ([]byte, error) {
+	b, err := p.db.Get(k, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	val := p.decodeValue(b)
+	if !val.IsLive() {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	return val.Value, nil
+}
+
+// GetRaw implements goukv.RawGetter, the expired values are kept until they are overwritten,
+// deleted or purged by PurgeExpired, a tombstone isn't a value so it's reported as not found
+func (p Provider) GetRaw(k []byte) ([]byte, bool, error) {
+	b, err := p.db.Get(k, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, false, goukv.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, false, classifyError(err)
+	}
+
+	val := p.decodeValue(b)
+	if val.Tombstone {
+		return nil, false, goukv.ErrKeyNotFound
+	}
+
+	return val.Value, val.IsExpired(), nil
+}
+
+// GetFunc implements goukv.FuncGetter, fn receives the decoded value
+func (p Provider) GetFunc(k []byte, fn func(val []byte) error) error {
+	b, err := p.db.Get(k, nil)
+	if err == leveldb.ErrNotFound {
+		return goukv.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return classifyError(err)
+	}
+
+	val := p.decodeValue(b)
+	if !val.IsLive() {
+		return goukv.ErrKeyNotFound
+	}
+
+	return fn(val.Value)
+}
+
+// GetEntry implements goukv.EntryGetter
+func (p Provider) GetEntry(k []byte) (*goukv.Entry, error) {
+	b, err := p.db.Get(k, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	val := p.decodeValue(b)
+	if !val.IsLive() {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	return valueEntry(k, val), nil
+}
+
+// valueEntry returns the entry of the specified key and its decoded value
+func valueEntry(k []byte, val Value) *goukv.Entry {
+	entry := &goukv.Entry{
+		Key:     k,
+		Value:   val.Value,
+		Version: val.Version,
+	}
+
+	if val.CreatedAt != nil && val.UpdatedAt != nil {
+		entry.CreatedAt, entry.UpdatedAt = *val.CreatedAt, *val.UpdatedAt
+	}
+
+	if val.Expires != nil {
+		expires := *val.Expires
+		entry.ExpireAt, entry.TTL = &expires, expires.Sub(goukv.Now())
+	}
+
+	return entry
+}
+
+// TTL implements goukv.TTL
+func (p Provider) TTL(k []byte) (*time.Time, error) {
+	b, err := p.db.Get(k, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	val := p.decodeValue(b)
+	if val.Tombstone {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	return val.Expires, nil
+}
+
+// ExpiringBefore implements goukv.ExpiringScanner, keys without a TTL and the internal keys are skipped
+func (p Provider) ExpiringBefore(t time.Time, fn func(key []byte, expires time.Time) error) error {
+	iter := p.db.NewIterator(nil, nil)
+	defer iter.Release()
+
+	for next := skipInternal(iter, iter.Next, false); next(); {
+		val := p.decodeValue(iter.Value())
+		if val.Tombstone || val.Expires == nil || !val.Expires.Before(t) {
+			continue
+		}
+
+		if err := fn(append([]byte{}, iter.Key()...), *val.Expires); err != nil {
+			if err == goukv.ErrScanDone {
+				break
+			}
+			return err
+		}
+	}
+
+	return iter.Error()
+}
+
+// PrefixStats implements goukv.PrefixStatsReader, the count is exact but the bytes are approximated
+// by the on-disk (compressed) size of the prefix range, which excludes the writes not flushed to tables yet,
+// the internal keys aren't counted unless the prefix is internal itself (see ScanOpts.SkipsInternal)
+func (p Provider) PrefixStats(prefix []byte) (int64, int64, error) {
+	rng := util.BytesPrefix(prefix)
+	external := (goukv.ScanOpts{Prefix: prefix}).SkipsInternal()
+
+	iter := p.db.NewIterator(rng, nil)
+	defer iter.Release()
+
+	var count int64
+	for iter.Next() {
+		if external && goukv.IsInternalKey(iter.Key()) {
+			continue
+		}
+
+		if p.decodeValue(iter.Value()).IsLive() {
+			count++
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		return 0, 0, err
+	}
+
+	ranges := []util.Range{*rng}
+	if external && bytes.HasPrefix(goukv.InternalPrefix, prefix) {
+		ranges = append(ranges, *util.BytesPrefix(goukv.InternalPrefix))
+	}
+
+	sizes, err := p.db.SizeOf(ranges)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// the internal range is within the prefix one
+	size := sizes[0]
+	if len(sizes) > 1 {
+		size -= sizes[1]
+	}
+
+	return count, size, nil
 }
 
-// Open implements goukv.Open
-func (p Provider) Open(opts map[string]interface{}) (goukv.Provider, error) {
-	path, ok := opts["path"].(string)
-	if !ok {
-		return nil, errors.New("must specify path")
+// RangeSize implements goukv.RangeSizer, it's goleveldb's approximation of the on-disk (compressed) size of the range
+// computed from the block indexes of the tables, the writes not flushed to tables yet aren't counted
+func (p Provider) RangeSize(start, end []byte) (int64, error) {
+	// goleveldb reads a nil limit as the smallest key, the range then ends right after the last one
+	if end == nil {
+		iter := p.db.NewIterator(nil, nil)
+		if iter.Last() {
+			end = append(append([]byte{}, iter.Key()...), 0)
+		}
+		iter.Release()
+
+		if err := iter.Error(); err != nil || end == nil {
+			return 0, err
+		}
 	}
 
-	dir := filepath.Dir(path)
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-			return nil, err
+	sizes, err := p.db.SizeOf([]util.Range{{Start: start, Limit: end}})
+	if err != nil {
+		return 0, err
+	}
+
+	return sizes.Sum(), nil
+}
+
+// Histogram implements goukv.HistogramReader, every value is decoded so that the value sizes
+// exclude the expiration wrapper overhead, expired keys and the internal keys are skipped
+func (p Provider) Histogram() (goukv.Histogram, error) {
+	var h goukv.Histogram
+
+	iter := p.db.NewIterator(nil, nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if goukv.IsInternalKey(iter.Key()) {
+			continue
+		}
+
+		val := p.decodeValue(iter.Value())
+		if !val.IsLive() {
+			continue
 		}
+
+		h.Add(int64(len(iter.Key())), int64(len(val.Value)))
 	}
 
-	syncWrites, ok := opts["sync_writes"].(bool)
-	if !ok {
-		syncWrites = false
+	return h, iter.Error()
+}
+
+// Delete implements goukv.Delete
+func (p Provider) Delete(k []byte) error {
+	return p.write([]goukv.Change{
+		{Op: goukv.ChangeDelete, Key: k},
+	})
+}
+
+// PurgeExpired implements goukv.ExpiredPurger, goleveldb only hides the expired keys (and tombstones) on read so they stay on disk
+// until purged, the candidates are collected from a snapshot then re-checked and deleted in batches holding the
+// write lock exclusively so that a key written again meanwhile is kept
+func (p Provider) PurgeExpired() (int64, error) {
+	if p.noTTL {
+		return 0, nil
 	}
 
-	o := &opt.Options{
-		Filter:         filter.NewBloomFilter(10),
-		ErrorIfMissing: false,
-		Compression:    9,
-		NoSync:         syncWrites,
+	snapshot, err := p.db.GetSnapshot()
+	if err != nil {
+		return 0, err
 	}
 
-	db, err := leveldb.OpenFile(path, o)
+	iter := snapshot.NewIterator(nil, nil)
+
+	var candidates [][]byte
+	for iter.Next() {
+		if goukv.IsInternalKey(iter.Key()) {
+			continue
+		}
+		if p.decodeValue(iter.Value()).IsExpired() {
+			candidates = append(candidates, append([]byte{}, iter.Key()...))
+		}
+	}
+
+	err = iter.Error()
+	iter.Release()
+	snapshot.Release()
+
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	return &Provider{
-		db:         db,
-		syncWrites: syncWrites,
-	}, nil
+	var purged int64
+	for len(candidates) > 0 {
+		n := len(candidates)
+		if n > 1000 {
+			n = 1000
+		}
+
+		count, err := p.purge(candidates[:n])
+		purged += count
+		if err != nil {
+			return purged, err
+		}
+
+		candidates = candidates[n:]
+	}
+
+	return purged, nil
 }
 
-// Put implements goukv.Put
-func (p Provider) Put(e *goukv.Entry) error {
-	return p.db.Put(e.Key, EntryToValue(e).Bytes(), &opt.WriteOptions{
-		Sync: p.syncWrites,
-	})
+// purge deletes the specified keys that are still expired
+func (p Provider) purge(keys [][]byte) (int64, error) {
+	p.writeLock.Lock()
+	defer p.writeLock.Unlock()
+
+	var changes []goukv.Change
+	for _, k := range keys {
+		b, err := p.db.Get(k, nil)
+		if err == leveldb.ErrNotFound {
+			continue
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		if p.decodeValue(b).IsExpired() {
+			changes = append(changes, goukv.Change{Op: goukv.ChangeDelete, Key: k})
+		}
+	}
+
+	if err := p.commitChanges(changes, false); err != nil {
+		return 0, err
+	}
+
+	return int64(len(changes)), nil
 }
 
-// Batch perform multi put operation, empty value means *delete*
-func (p Provider) Batch(entries []*goukv.Entry) error {
-	batch := new(leveldb.Batch)
+// ListPrefixes implements goukv.PrefixLister, once a prefix is found the iterator seeks past all of its keys
+func (p Provider) ListPrefixes(prefix []byte, delimiter byte) ([][]byte, error) {
+	iter := p.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
 
-	for _, entry := range entries {
-		if entry.Value == nil {
-			batch.Delete(entry.Key)
-		} else {
-			batch.Put(entry.Key, EntryToValue(entry).Bytes())
+	var prefixes [][]byte
+	for ok := iter.First(); ok; {
+		k := iter.Key()
+		if goukv.IsInternalKey(k) {
+			ok = iter.Seek(util.BytesPrefix(goukv.InternalPrefix).Limit)
+			continue
+		}
+
+		i := bytes.IndexByte(k[len(prefix):], delimiter)
+		if i < 0 || !p.decodeValue(iter.Value()).IsLive() {
+			ok = iter.Next()
+			continue
 		}
+
+		found := append([]byte{}, k[:len(prefix)+i+1]...)
+		prefixes = append(prefixes, found)
+
+		ok = iter.Seek(util.BytesPrefix(found).Limit)
 	}
 
-	return p.db.Write(batch, &opt.WriteOptions{
-		Sync: p.syncWrites,
-	})
+	return prefixes, iter.Error()
 }
 
-// Get implements goukv.Get
-func (p Provider) Get(k []byte) ([]byte, error) {
-	b, err := p.db.Get(k, nil)
-	if err == leveldb.ErrNotFound {
-		return nil, goukv.ErrKeyNotFound
+// FirstKey implements goukv.KeyBoundsReader
+func (p Provider) FirstKey(prefix []byte) ([]byte, error) {
+	return p.boundKey(prefix, false)
+}
+
+// LastKey implements goukv.KeyBoundsReader
+func (p Provider) LastKey(prefix []byte) ([]byte, error) {
+	return p.boundKey(prefix, true)
+}
+
+// boundKey returns the first (or the last when reverse) live key having the specified prefix, the iterator is moved
+// from its first (or last) key past the expired keys and tombstones only
+func (p Provider) boundKey(prefix []byte, reverse bool) ([]byte, error) {
+	var iter iterator.Iterator
+	if len(prefix) > 0 {
+		iter = p.db.NewIterator(util.BytesPrefix(prefix), nil)
+	} else {
+		iter = p.db.NewIterator(nil, nil)
 	}
+	defer iter.Release()
 
-	val := BytesToValue(b)
-	if val.IsExpired() {
-		return nil, goukv.ErrKeyNotFound
+	seek, next := iter.First, iter.Next
+	if reverse {
+		seek, next = iter.Last, iter.Prev
+	}
+
+	if (goukv.ScanOpts{Prefix: prefix}).SkipsInternal() {
+		seek, next = skipInternal(iter, seek, reverse), skipInternal(iter, next, reverse)
+	}
+
+	for ok := seek(); ok; ok = next() {
+		if p.decodeValue(iter.Value()).IsLive() {
+			return append([]byte{}, iter.Key()...), nil
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		return nil, classifyError(err)
 	}
 
-	return val.Value, err
+	return nil, goukv.ErrKeyNotFound
 }
 
-// TTL implements goukv.TTL
-func (p Provider) TTL(k []byte) (*time.Time, error) {
-	b, err := p.db.Get(k, nil)
-	if err == leveldb.ErrNotFound {
-		return nil, goukv.ErrKeyNotFound
+// Watch implements goukv.Watcher, the keyspaces dropped by DropKeyspace aren't reported
+func (p Provider) Watch(prefix []byte) (<-chan goukv.Event, func(), error) {
+	events, stop := p.events.Watch(prefix)
+	return events, stop, nil
+}
+
+// WatchWithSnapshot implements goukv.Watcher, the snapshot is read using Scan
+func (p Provider) WatchWithSnapshot(prefix []byte) (<-chan goukv.Event, func(), error) {
+	return p.events.WatchWithSnapshot(prefix, p.Scan)
+}
+
+// Checkpoint implements goukv.Checkpointer, the stored records (including the expired values and the
+// reserved keys) are copied from a snapshot into a new db in dir, in batches so the writes aren't blocked
+func (p Provider) Checkpoint(dir string) error {
+	if err := goukv.EmptyDir(dir); err != nil {
+		return err
 	}
 
+	snapshot, err := p.db.GetSnapshot()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer snapshot.Release()
+
+	dst, err := leveldb.OpenFile(dir, &opt.Options{
+		Filter:      p.options.Filter,
+		Compression: p.options.Compression,
+	})
+	if err != nil {
+		return err
 	}
 
-	val := BytesToValue(b)
+	iter := snapshot.NewIterator(nil, nil)
+	defer iter.Release()
 
-	return val.Expires, nil
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		batch.Put(iter.Key(), iter.Value())
+		if batch.Len() >= 1000 {
+			if err := dst.Write(batch, nil); err != nil {
+				dst.Close()
+				return err
+			}
+			batch.Reset()
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		dst.Close()
+		return err
+	}
+
+	if err := dst.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+		dst.Close()
+		return err
+	}
+
+	return dst.Close()
 }
 
-// Delete implements goukv.Delete
-func (p Provider) Delete(k []byte) error {
-	return p.db.Delete(k, &opt.WriteOptions{
-		Sync: p.syncWrites,
+// Keyspace implements goukv.KeyspaceManager
+func (p Provider) Keyspace(name string) goukv.Provider {
+	return goukv.NewKeyspace(p, name)
+}
+
+// DropKeyspace implements goukv.KeyspaceManager, the keys are deleted in batches (recorded in the changelog if enabled,
+// no tombstones are left) then the range is compacted to reclaim its space, it isn't atomic as a whole
+func (p Provider) DropKeyspace(name string) error {
+	rng := util.BytesPrefix(goukv.KeyspaceKeyPrefix(name))
+
+	iter := p.db.NewIterator(rng, nil)
+	defer iter.Release()
+
+	var changes []goukv.Change
+	for iter.Next() {
+		changes = append(changes, goukv.Change{Op: goukv.ChangeDelete, Key: append([]byte{}, iter.Key()...)})
+		if len(changes) >= 1000 {
+			if err := p.writeDeletes(changes); err != nil {
+				return err
+			}
+			changes = nil
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	if err := p.writeDeletes(changes); err != nil {
+		return err
+	}
+
+	return p.maintenance.Run(goukv.MaintenanceCompaction, p.dirs(), func() error {
+		return p.db.CompactRange(*rng)
 	})
 }
 
-// Close implements goukv.Close
+// ForceGCKey implements goukv.KeyGCForcer using a compaction of the single key (a maintenance run, see "on_maintenance"),
+// it drops the older versions of the key from the tables overlapping it but rewrites those tables (and flushes the
+// memtable) so it may cost more than the key itself
+func (p Provider) ForceGCKey(key []byte) error {
+	return p.maintenance.Run(goukv.MaintenanceCompaction, p.dirs(), func() error {
+		return classifyError(p.db.CompactRange(util.Range{Start: key, Limit: append(append([]byte{}, key...), 0)}))
+	})
+}
+
+// dirs returns the directory of the db, none for a db wrapped by FromDB (its maintenance reports have no sizes)
+func (p Provider) dirs() []string {
+	if p.handle == nil {
+		return nil
+	}
+	return []string{p.handle.path}
+}
+
+// ReadChanges implements goukv.ChangelogReader, it replays the changes recorded after sinceSeq in order
+func (p Provider) ReadChanges(sinceSeq uint64, fn func(goukv.Change) error) error {
+	iter := p.db.NewIterator(&util.Range{Start: goukv.ChangeKey(sinceSeq + 1), Limit: util.BytesPrefix(goukv.ChangelogPrefix).Limit}, nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		change, err := goukv.BytesToChange(iter.Value())
+		if err != nil {
+			return err
+		}
+
+		if err := fn(change); err != nil {
+			if err == goukv.ErrScanDone {
+				break
+			}
+			return err
+		}
+	}
+
+	return iter.Error()
+}
+
+// TruncateChanges implements goukv.ChangelogReader, it removes the changes recorded before beforeSeq
+func (p Provider) TruncateChanges(beforeSeq uint64) error {
+	iter := p.db.NewIterator(&util.Range{Start: goukv.ChangeKey(0), Limit: goukv.ChangeKey(beforeSeq)}, nil)
+	defer iter.Release()
+
+	wo := &opt.WriteOptions{
+		Sync: p.syncWrites,
+	}
+
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		batch.Delete(append([]byte{}, iter.Key()...))
+		if batch.Len() >= 1000 {
+			if err := p.db.Write(batch, wo); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	return p.db.Write(batch, wo)
+}
+
+// Native implements goukv.NativeAccessor, it returns the underlying *leveldb.DB, the stored values are wrapped (expiration, versions ...) unless no_ttl is set, decode them with BytesToValue
+func (p Provider) Native() interface{} {
+	return p.db
+}
+
+// Close implements goukv.Close, goleveldb flushes the journal on close so async writes are persisted,
+// a db opened several times is only closed once all of its references are closed, a bulk_load one is then
+// reopened with the normal options and compacted as a whole before Close returns
 func (p Provider) Close() error {
-	return p.db.Close()
+	handlesLock.Lock()
+	defer handlesLock.Unlock()
+
+	if *p.released {
+		return classifyError(leveldb.ErrClosed)
+	}
+
+	if p.handle == nil {
+		*p.released = true
+	} else if !p.handle.release(p) {
+		return nil
+	}
+
+	close(p.watchdogStop)
+	p.watchdogDone.Wait()
+
+	if err := p.db.Close(); err != nil || p.bulkReopen == nil {
+		return err
+	}
+
+	return finishBulkLoad(p.handle.path, p.bulkReopen, p.maintenance)
 }
 
-// Scan implements goukv.Scan
+// ScanByVersion implements goukv.VersionScanner, it requires the enable_versions option, the keys and versions of a snapshot
+// are collected and sorted first then their values are read from the same snapshot, the values written before the option
+// was enabled have no version (0) so they come first
+func (p Provider) ScanByVersion(fromVersion uint64, fn func(*goukv.Entry) error) error {
+	if !p.versions {
+		return goukv.ErrNotSupported
+	}
+
+	snapshot, err := p.db.GetSnapshot()
+	if err != nil {
+		return classifyError(err)
+	}
+	defer snapshot.Release()
+
+	type versionedKey struct {
+		key     []byte
+		version uint64
+	}
+
+	var keys []versionedKey
+	iter := snapshot.NewIterator(nil, nil)
+	for iter.Next() {
+		k := iter.Key()
+		if goukv.IsInternalKey(k) {
+			continue
+		}
+
+		val := p.decodeValue(iter.Value())
+		if !val.IsLive() || val.Version < fromVersion {
+			continue
+		}
+
+		keys = append(keys, versionedKey{key: append([]byte{}, k...), version: val.Version})
+	}
+	iter.Release()
+
+	if err := iter.Error(); err != nil {
+		return classifyError(err)
+	}
+
+	// the versions are unique, the stable sort only matters for the unversioned values
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].version < keys[j].version
+	})
+
+	for _, k := range keys {
+		b, err := snapshot.Get(k.key, nil)
+		if err != nil {
+			return classifyError(err)
+		}
+
+		if err := fn(valueEntry(k.key, p.decodeValue(b))); err != nil {
+			if err == goukv.ErrScanDone {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Scan implements goukv.Scan, SinceVersion requires the enable_versions option
 func (p Provider) Scan(opts goukv.ScanOpts) error {
 	if opts.Scanner == nil {
 		return goukv.ErrNoScanner
 	}
 
+	// the keys are filtered before their values are copied and decoded
+	opts = opts.Sampled()
+	keyFilter := opts.KeyFilter
+	opts.KeyFilter = nil
+	opts.Scanner = opts.BoundedScanner()
+
+	if opts.SinceVersion > 0 && !p.versions {
+		return goukv.ErrNotSupported
+	}
+
 	var iter iterator.Iterator
 	var next func() bool
 	var seek func() bool
 
-	if opts.Prefix != nil {
-		iter = p.db.NewIterator(util.BytesPrefix(opts.Prefix), nil)
+	var reader interface {
+		NewIterator(*util.Range, *opt.ReadOptions) iterator.Iterator
+	} = p.db
+
+	if opts.Consistent {
+		snapshot, err := p.db.GetSnapshot()
+		if err != nil {
+			return err
+		}
+		defer snapshot.Release()
+
+		reader = snapshot
+	}
+
+	if len(opts.Prefix) > 0 {
+		iter = reader.NewIterator(util.BytesPrefix(opts.Prefix), nil)
 	} else {
-		iter = p.db.NewIterator(nil, nil)
+		iter = reader.NewIterator(nil, nil)
 	}
 
 	if opts.ReverseScan {
@@ -148,7 +1430,19 @@ func (p Provider) Scan(opts goukv.ScanOpts) error {
 		next = iter.Next
 	}
 
-	if opts.Offset != nil {
+	if opts.Offset != nil && opts.ReverseScan {
+		// the iterator is bounded by the prefix range, so walking backward starts
+		// at the greatest key <= offset, or at the last key of the range if the offset is beyond it
+		seek = func() bool {
+			if !iter.Seek(opts.Offset) {
+				return iter.Last()
+			}
+			if bytes.Compare(iter.Key(), opts.Offset) > 0 {
+				return iter.Prev()
+			}
+			return true
+		}
+	} else if opts.Offset != nil {
 		seek = func() bool {
 			return iter.Seek(opts.Offset)
 		}
@@ -158,10 +1452,16 @@ func (p Provider) Scan(opts goukv.ScanOpts) error {
 		seek = iter.First
 	}
 
+	if opts.SkipsInternal() {
+		seek, next = skipInternal(iter, seek, opts.ReverseScan), skipInternal(iter, next, opts.ReverseScan)
+	}
+
+	tick := opts.ProgressTicker()
+
 	defer iter.Release()
 	for ok := seek(); ok; ok = next() {
 		if err := iter.Error(); err != nil {
-			return err
+			return classifyError(err)
 		}
 
 		if !iter.Valid() {
@@ -173,21 +1473,35 @@ func (p Provider) Scan(opts goukv.ScanOpts) error {
 			break
 		}
 
+		tick()
+
 		if opts.Offset != nil && !opts.IncludeOffset && bytes.Equal(_k, opts.Offset) {
 			continue
 		}
 
+		if keyFilter != nil && !keyFilter(_k) {
+			continue
+		}
+
 		newK := make([]byte, len(_k))
 		newV := make([]byte, len(_v))
 
 		copy(newK, _k)
 		copy(newV, _v)
 
-		decodedValue := BytesToValue(newV)
-		if decodedValue.IsExpired() {
+		decodedValue := p.decodeValue(newV)
+		if decodedValue.IsExpired() || (decodedValue.Tombstone && !opts.IncludeTombstones) {
 			continue
 		}
 
+		if opts.SinceVersion > 0 && decodedValue.Version <= opts.SinceVersion {
+			continue
+		}
+
+		if decodedValue.Tombstone {
+			decodedValue.Value = nil
+		}
+
 		if err := opts.Scanner(newK, decodedValue.Value); err != nil {
 			if err == goukv.ErrScanDone {
 				break
@@ -197,3 +1511,20 @@ func (p Provider) Scan(opts goukv.ScanOpts) error {
 	}
 	return nil
 }
+
+// skipInternal returns move followed by a seek past the internal keys when it lands on one of them,
+// to the first key after them or to the last key before them when walking backward
+func skipInternal(iter iterator.Iterator, move func() bool, reverse bool) func() bool {
+	return func() bool {
+		ok := move()
+		if !ok || !goukv.IsInternalKey(iter.Key()) {
+			return ok
+		}
+
+		if reverse {
+			return iter.Seek(goukv.InternalPrefix) && iter.Prev()
+		}
+
+		return iter.Seek(util.BytesPrefix(goukv.InternalPrefix).Limit)
+	}
+}
@@ -1,19 +1,33 @@
 package leveldb
 
 import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/alash3al/goukv"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
 	// _ "github.com/alash3al/redix/providers/goleveldb"
 )
 
 func openDBAndDo(fn func(db goukv.Provider)) error {
+	return openDBWithOptsAndDo(map[string]interface{}{}, fn)
+}
+
+func openDBWithOptsAndDo(opts map[string]interface{}, fn func(db goukv.Provider)) error {
+	opts["path"] = "./db"
+
 	p := Provider{}
-	db, err := p.Open(map[string]interface{}{
-		"path": "./db",
-	})
+	db, err := p.Open(opts)
 	if err != nil {
 		return err
 	}
@@ -73,3 +87,1747 @@ func TestTTL(t *testing.T) {
 		t.Error(err.Error())
 	}
 }
+
+func TestGetEntry(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("k"),
+			Value: []byte("v"),
+			TTL:   time.Second * 10,
+		}
+		err := db.Put(&entry)
+		if err != nil {
+			t.Error(err)
+		}
+		found, err := db.(goukv.EntryGetter).GetEntry(entry.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(found.Key) != string(entry.Key) || string(found.Value) != string(entry.Value) {
+			t.Errorf("expected (%s => %s), found (%s => %s)", entry.Key, entry.Value, found.Key, found.Value)
+		}
+		if found.TTL <= 0 || found.TTL > entry.TTL {
+			t.Errorf("expected ttl within (0, %s], found (%s)", entry.TTL, found.TTL)
+		}
+		if _, err := db.(goukv.EntryGetter).GetEntry([]byte("missing")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected (%v), found (%v)", goukv.ErrKeyNotFound, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestChangelog(t *testing.T) {
+	opts := map[string]interface{}{
+		"enable_changelog": true,
+	}
+
+	err := openDBWithOptsAndDo(opts, func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("k1"), Value: []byte("v1")})
+		db.Batch([]*goukv.Entry{
+			{Key: []byte("k2"), Value: []byte("v2"), TTL: time.Minute},
+			{Key: []byte("k1")},
+		})
+		db.Delete([]byte("k2"))
+
+		expected := []struct {
+			op  goukv.ChangeOp
+			key string
+		}{
+			{goukv.ChangePut, "k1"},
+			{goukv.ChangePut, "k2"},
+			{goukv.ChangeDelete, "k1"},
+			{goukv.ChangeDelete, "k2"},
+		}
+
+		var changes []goukv.Change
+		err := db.(goukv.ChangelogReader).ReadChanges(0, func(change goukv.Change) error {
+			changes = append(changes, change)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != len(expected) {
+			t.Fatalf("expected (%d) changes, found (%d)", len(expected), len(changes))
+		}
+		for i, change := range changes {
+			if change.Seq != uint64(i+1) || change.Op != expected[i].op || string(change.Key) != expected[i].key {
+				t.Errorf("expected (%d %d %s), found (%d %d %s)", i+1, expected[i].op, expected[i].key, change.Seq, change.Op, change.Key)
+			}
+		}
+
+		if err := db.(goukv.ChangelogReader).TruncateChanges(3); err != nil {
+			t.Fatal(err)
+		}
+
+		changes = nil
+		db.(goukv.ChangelogReader).ReadChanges(0, func(change goukv.Change) error {
+			changes = append(changes, change)
+			return nil
+		})
+		if len(changes) != 2 || changes[0].Seq != 3 {
+			t.Errorf("expected the changes from (3) to be kept, found (%v)", changes)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestTTLJitter(t *testing.T) {
+	opts := map[string]interface{}{
+		"ttl_jitter": time.Second * 5,
+	}
+
+	err := openDBWithOptsAndDo(opts, func(db goukv.Provider) {
+		ttl := time.Second * 10
+		start := time.Now()
+
+		var entries []*goukv.Entry
+		for i := 0; i < 50; i++ {
+			entries = append(entries, &goukv.Entry{Key: []byte{byte(i)}, Value: []byte("v"), TTL: ttl})
+		}
+		if err := db.Batch(entries); err != nil {
+			t.Fatal(err)
+		}
+		end := time.Now()
+
+		seen := map[int64]bool{}
+		for _, entry := range entries {
+			expiresAt, err := db.TTL(entry.Key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expiresAt.Before(start.Add(ttl).Truncate(time.Second)) || expiresAt.After(end.Add(ttl+time.Second*5)) {
+				t.Errorf("expected expiry within [%s, %s], found (%s)", start.Add(ttl), end.Add(ttl+time.Second*5), expiresAt)
+			}
+			seen[expiresAt.Unix()] = true
+		}
+		if len(seen) < 2 {
+			t.Errorf("expected the expirations to be spread, found (%d) distinct values", len(seen))
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestTxnSerializable(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		txn, err := db.(goukv.Transactional).NewTxn()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		written := make(chan struct{})
+		go (func() {
+			db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("outside")})
+			close(written)
+		})()
+
+		txn.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("inside")})
+		if val, _ := txn.Get([]byte("k")); string(val) != "inside" {
+			t.Errorf("expected (inside), found (%s)", val)
+		}
+
+		select {
+		case <-written:
+			t.Fatal("expected the plain write to wait for the transaction")
+		case <-time.After(time.Millisecond * 50):
+		}
+
+		if err := txn.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		<-written
+
+		if val, _ := db.Get([]byte("k")); string(val) != "outside" {
+			t.Errorf("expected (outside), found (%s)", val)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestTxnSnapshotConflict(t *testing.T) {
+	opts := map[string]interface{}{
+		"txn_isolation": IsolationSnapshot,
+	}
+
+	err := openDBWithOptsAndDo(opts, func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v0")})
+
+		txn1, _ := db.(goukv.Transactional).NewTxn()
+		txn2, _ := db.(goukv.Transactional).NewTxn()
+
+		txn1.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v1")})
+		txn2.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v2")})
+		txn2.Put(&goukv.Entry{Key: []byte("other"), Value: []byte("v2")})
+
+		if val, _ := txn2.Get([]byte("k")); string(val) != "v2" {
+			t.Errorf("expected (v2), found (%s)", val)
+		}
+
+		if err := txn1.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if err := txn2.Commit(); !errors.Is(err, ErrTxnConflict) || !goukv.IsErrorKind(err, goukv.ErrorConflict) {
+			t.Fatalf("expected (%v), found (%v)", ErrTxnConflict, err)
+		}
+		if err := txn2.Commit(); err != goukv.ErrTxnDone {
+			t.Errorf("expected (%v), found (%v)", goukv.ErrTxnDone, err)
+		}
+
+		if val, _ := db.Get([]byte("k")); string(val) != "v1" {
+			t.Errorf("expected (v1), found (%s)", val)
+		}
+		if _, err := db.Get([]byte("other")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected the aborted transaction writes to be dropped, found (%v)", err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestTxnConcurrentConflict(t *testing.T) {
+	opts := map[string]interface{}{
+		"txn_isolation": IsolationSnapshot,
+	}
+
+	err := openDBWithOptsAndDo(opts, func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("counter"), Value: []byte("0")})
+
+		// increment reads the counter, reports it on ready, waits for write to be closed then writes the incremented value
+		increment := func(ready chan<- struct{}, write <-chan struct{}) error {
+			txn, err := db.(goukv.Transactional).NewTxn()
+			if err != nil {
+				return err
+			}
+
+			val, err := txn.Get([]byte("counter"))
+			if err != nil {
+				txn.Discard()
+				return err
+			}
+			ready <- struct{}{}
+			<-write
+
+			n, _ := strconv.Atoi(string(val))
+			txn.Put(&goukv.Entry{Key: []byte("counter"), Value: []byte(strconv.Itoa(n + 1))})
+
+			return txn.Commit()
+		}
+
+		// both transactions read the counter before any of them commits
+		ready, write := make(chan struct{}, 2), make(chan struct{})
+		first := make(chan error)
+		go (func() {
+			first <- increment(ready, write)
+		})()
+
+		second := make(chan error)
+		go (func() {
+			second <- increment(ready, write)
+		})()
+
+		<-ready
+		<-ready
+		close(write)
+
+		errs := []error{<-first, <-second}
+		conflicts := 0
+		for _, err := range errs {
+			if errors.Is(err, goukv.ErrConflict) {
+				conflicts++
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if conflicts != 1 {
+			t.Fatalf("expected exactly one of the transactions to conflict, found (%v)", errs)
+		}
+
+		// the conflicting transaction succeeds once retried
+		if err := increment(ready, write); err != nil {
+			t.Fatal(err)
+		}
+
+		if val, _ := db.Get([]byte("counter")); string(val) != "2" {
+			t.Errorf("expected (2), found (%s)", val)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestExpiringBefore(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		db.Batch([]*goukv.Entry{
+			{Key: []byte("soon"), Value: []byte("v"), TTL: time.Minute},
+			{Key: []byte("later"), Value: []byte("v"), TTL: time.Hour},
+			{Key: []byte("never"), Value: []byte("v")},
+		})
+
+		// the internal keys are skipped
+		db.(goukv.KeyspaceManager).Keyspace("space").Put(&goukv.Entry{Key: []byte("soon"), Value: []byte("v"), TTL: time.Minute})
+
+		var keys []string
+		err := db.(goukv.ExpiringScanner).ExpiringBefore(time.Now().Add(time.Minute*10), func(k []byte, expires time.Time) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 1 || keys[0] != "soon" {
+			t.Errorf("expected ([soon]), found (%v)", keys)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestReversePrefixScan(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		db.Batch([]*goukv.Entry{
+			{Key: []byte("a1"), Value: []byte("v")},
+			{Key: []byte("a2"), Value: []byte("v")},
+			{Key: []byte("a3"), Value: []byte("v")},
+			{Key: []byte("b1"), Value: []byte("v")},
+			{Key: []byte("b2"), Value: []byte("v")},
+		})
+
+		for _, prefix := range []string{"a", "b"} {
+			var keys []string
+			err := db.Scan(goukv.ScanOpts{
+				Prefix:      []byte(prefix),
+				ReverseScan: true,
+				Scanner: func(k, v []byte) error {
+					keys = append(keys, string(k))
+					return nil
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := map[string]string{"a": "[a3 a2 a1]", "b": "[b2 b1]"}[prefix]
+			if fmt.Sprint(keys) != expected {
+				t.Errorf("expected (%s), found (%v)", expected, keys)
+			}
+		}
+
+		for offset, expected := range map[string]string{"a25": "[a2 a1]", "a2": "[a1]", "a9": "[a3 a2 a1]", "0": "[]"} {
+			var keys []string
+			db.Scan(goukv.ScanOpts{
+				Prefix:      []byte("a"),
+				Offset:      []byte(offset),
+				ReverseScan: true,
+				Scanner: func(k, v []byte) error {
+					keys = append(keys, string(k))
+					return nil
+				},
+			})
+			if fmt.Sprint(keys) != expected {
+				t.Errorf("expected (%s) from offset (%s), found (%v)", expected, offset, keys)
+			}
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestGetFunc(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+
+		var found string
+		err := db.(goukv.FuncGetter).GetFunc([]byte("k"), func(val []byte) error {
+			found = string(val)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found != "v" {
+			t.Errorf("expected (v), found (%s)", found)
+		}
+
+		err = db.(goukv.FuncGetter).GetFunc([]byte("missing"), func(val []byte) error {
+			t.Error("expected fn not to be called for a missing key")
+			return nil
+		})
+		if err != goukv.ErrKeyNotFound {
+			t.Errorf("expected (%v), found (%v)", goukv.ErrKeyNotFound, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestDirPerm(t *testing.T) {
+	defer os.RemoveAll("./data")
+
+	for _, perm := range []os.FileMode{0700, 0750} {
+		p := Provider{}
+		db, err := p.Open(map[string]interface{}{
+			"path":     "./data/db",
+			"dir_perm": perm,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+
+		for _, dir := range []string{"./data", "./data/db"} {
+			info, err := os.Stat(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != perm {
+				t.Errorf("expected (%s) mode to be (%s), found (%s)", dir, perm, info.Mode().Perm())
+			}
+		}
+
+		os.RemoveAll("./data")
+	}
+}
+
+func TestPutIfChanged(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		putter := db.(goukv.IdempotentPutter)
+
+		steps := []struct {
+			entry   goukv.Entry
+			changed bool
+		}{
+			{goukv.Entry{Key: []byte("k"), Value: []byte("v1")}, true},
+			{goukv.Entry{Key: []byte("k"), Value: []byte("v1")}, false},
+			{goukv.Entry{Key: []byte("k"), Value: []byte("v2")}, true},
+			{goukv.Entry{Key: []byte("k"), Value: []byte("v2"), TTL: time.Hour}, true},
+			{goukv.Entry{Key: []byte("k"), Value: []byte("v2"), TTL: time.Hour}, false},
+			{goukv.Entry{Key: []byte("k"), Value: []byte("v2")}, true},
+		}
+
+		for i, step := range steps {
+			changed, err := putter.PutIfChanged(&step.entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != step.changed {
+				t.Errorf("step (%d): expected changed to be (%v), found (%v)", i, step.changed, changed)
+			}
+		}
+
+		if val, _ := db.Get([]byte("k")); string(val) != "v2" {
+			t.Errorf("expected (v2), found (%s)", val)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func BenchmarkPutSameValue(b *testing.B) {
+	openDBAndDo(func(db goukv.Provider) {
+		entry := goukv.Entry{Key: []byte("config"), Value: []byte("the same value over and over")}
+		for i := 0; i < b.N; i++ {
+			db.Put(&entry)
+		}
+		b.ReportMetric(1, "writes/op")
+	})
+}
+
+func BenchmarkPutIfChangedSameValue(b *testing.B) {
+	openDBAndDo(func(db goukv.Provider) {
+		entry := goukv.Entry{Key: []byte("config"), Value: []byte("the same value over and over")}
+		writes := 0
+		for i := 0; i < b.N; i++ {
+			if changed, _ := db.(goukv.IdempotentPutter).PutIfChanged(&entry); changed {
+				writes++
+			}
+		}
+		b.ReportMetric(float64(writes)/float64(b.N), "writes/op")
+	})
+}
+
+func TestConsistentScan(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		var entries []*goukv.Entry
+		for i := 0; i < 100; i++ {
+			entries = append(entries, &goukv.Entry{Key: []byte(fmt.Sprintf("k%03d", i)), Value: []byte("v")})
+		}
+		db.Batch(entries)
+
+		count := 0
+		err := db.Scan(goukv.ScanOpts{
+			Consistent: true,
+			Scanner: func(k, v []byte) error {
+				if count == 0 {
+					for i := 0; i < 100; i++ {
+						db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%03dx", i)), Value: []byte("new")})
+					}
+				}
+				count++
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != len(entries) {
+			t.Errorf("expected (%d) keys, found (%d)", len(entries), count)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestCompactionOptions(t *testing.T) {
+	opts := map[string]interface{}{
+		"compaction_table_size":     4 * opt.MiB,
+		"compaction_l0_trigger":     8,
+		"write_l0_slowdown_trigger": 16,
+		"write_l0_pause_trigger":    24,
+	}
+
+	err := openDBWithOptsAndDo(opts, func(db goukv.Provider) {
+		o := db.(*Provider).options
+		if o.GetCompactionTableSize(0) != 4*opt.MiB || o.GetCompactionL0Trigger() != 8 ||
+			o.GetWriteL0SlowdownTrigger() != 16 || o.GetWriteL0PauseTrigger() != 24 {
+			t.Errorf("expected the compaction options to be applied, found (%+v)", o)
+		}
+
+		for i := 0; i < 100; i++ {
+			db.Put(&goukv.Entry{Key: []byte{byte(i)}, Value: []byte("v")})
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = openDBAndDo(func(db goukv.Provider) {
+		o := db.(*Provider).options
+		if o.GetCompactionL0Trigger() != opt.DefaultCompactionL0Trigger || o.GetWriteL0SlowdownTrigger() != opt.DefaultWriteL0SlowdownTrigger {
+			t.Errorf("expected goleveldb's defaults, found (%+v)", o)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestClosePersistsAsyncWrites(t *testing.T) {
+	defer os.RemoveAll("./db")
+
+	p := Provider{}
+	db, err := p.Open(map[string]interface{}{
+		"path":        "./db",
+		"sync_writes": false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%03d", i)), Value: []byte("v")})
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = p.Open(map[string]interface{}{
+		"path": "./db",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 100; i++ {
+		if _, err := db.Get([]byte(fmt.Sprintf("k%03d", i))); err != nil {
+			t.Fatalf("expected (k%03d) to survive the close, found (%v)", i, err)
+		}
+	}
+}
+
+func TestExpireAt(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour).Truncate(time.Second)
+
+		db.Put(&goukv.Entry{Key: []byte("past"), Value: []byte("v"), ExpireAt: &past})
+		if _, err := db.Get([]byte("past")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected a past ExpireAt to expire the key immediately, found (%v)", err)
+		}
+
+		db.Put(&goukv.Entry{Key: []byte("future"), Value: []byte("v"), TTL: time.Second, ExpireAt: &future})
+		expiresAt, err := db.TTL([]byte("future"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expiresAt == nil || !expiresAt.Equal(future) {
+			t.Errorf("expected ExpireAt to win over TTL (%s), found (%v)", future, expiresAt)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestPrefixStats(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		for i := 0; i < 10; i++ {
+			db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("a:%d", i)), Value: bytes.Repeat([]byte{byte(i)}, 1024)})
+		}
+		for i := 0; i < 5; i++ {
+			db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("b:%d", i)), Value: []byte("v")})
+		}
+		db.(*Provider).db.CompactRange(util.Range{})
+
+		count, size, err := db.(goukv.PrefixStatsReader).PrefixStats([]byte("a:"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 10 {
+			t.Errorf("expected (10) keys, found (%d)", count)
+		}
+		if size <= 0 {
+			t.Errorf("expected the flushed size to be reported, found (%d)", size)
+		}
+
+		if count, _, _ := db.(goukv.PrefixStatsReader).PrefixStats([]byte("b:")); count != 5 {
+			t.Errorf("expected (5) keys, found (%d)", count)
+		}
+
+		// the internal keys are only counted under an internal prefix
+		db.(goukv.KeyspaceManager).Keyspace("space").Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+		if count, _, _ := db.(goukv.PrefixStatsReader).PrefixStats(nil); count != 15 {
+			t.Errorf("expected (15) keys, found (%d)", count)
+		}
+		if count, _, _ := db.(goukv.PrefixStatsReader).PrefixStats(goukv.KeyspacePrefix); count != 1 {
+			t.Errorf("expected (1) key, found (%d)", count)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		for i := 0; i < 10; i++ {
+			db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("a:%d", i)), Value: bytes.Repeat([]byte{byte(i)}, 100)})
+		}
+		db.Put(&goukv.Entry{Key: []byte("e"), Value: []byte{}})
+
+		// the internal keys are skipped
+		db.(goukv.KeyspaceManager).Keyspace("space").Put(&goukv.Entry{Key: []byte("k"), Value: bytes.Repeat([]byte{0}, 1000)})
+
+		h, err := db.(goukv.HistogramReader).Histogram()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 3 bytes keys fall in [2, 4) and 100 bytes values in [64, 128)
+		if len(h.KeySizes) != 3 || h.KeySizes[2] != 10 || h.KeySizes[1] != 1 {
+			t.Errorf("unexpected key sizes (%v)", h.KeySizes)
+		}
+		if len(h.ValueSizes) != 8 || h.ValueSizes[7] != 10 || h.ValueSizes[0] != 1 {
+			t.Errorf("unexpected value sizes (%v)", h.ValueSizes)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestNoTTL(t *testing.T) {
+	err := openDBWithOptsAndDo(map[string]interface{}{"no_ttl": true}, func(db goukv.Provider) {
+		if err := db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")}); err != nil {
+			t.Fatal(err)
+		}
+
+		raw, err := db.(*Provider).db.Get([]byte("k"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(raw) != "v" {
+			t.Errorf("expected the raw value to be stored, found (%v)", raw)
+		}
+
+		val, err := db.Get([]byte("k"))
+		if err != nil || string(val) != "v" {
+			t.Errorf("expected (v), found (%s, %v)", val, err)
+		}
+
+		if expires, err := db.TTL([]byte("k")); err != nil || expires != nil {
+			t.Errorf("expected no expiration, found (%v, %v)", expires, err)
+		}
+
+		if err := db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v"), TTL: time.Minute}); err != ErrTTLDisabled {
+			t.Errorf("expected ErrTTLDisabled, found (%v)", err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestValueWrapper(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		if err := db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v"), TTL: time.Minute}); err != nil {
+			t.Fatal(err)
+		}
+
+		raw, err := db.(*Provider).db.Get([]byte("k"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		val := BytesToValue(raw)
+		if string(val.Value) != "v" || val.Expires == nil {
+			t.Errorf("expected the value to be wrapped with its expiration, found (%v)", raw)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestSinceVersion(t *testing.T) {
+	err := openDBWithOptsAndDo(map[string]interface{}{"enable_versions": true}, func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("k1"), Value: []byte("v")})
+		db.Put(&goukv.Entry{Key: []byte("k2"), Value: []byte("v")})
+
+		entry, err := db.(goukv.EntryGetter).GetEntry([]byte("k2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Version != 2 {
+			t.Errorf("expected version (2), found (%d)", entry.Version)
+		}
+
+		db.Batch([]*goukv.Entry{
+			{Key: []byte("k1"), Value: []byte("v1")},
+			{Key: []byte("k3"), Value: []byte("v")},
+		})
+
+		var keys []string
+		db.Scan(goukv.ScanOpts{SinceVersion: entry.Version, Scanner: func(k, v []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		}})
+		if len(keys) != 2 || keys[0] != "k1" || keys[1] != "k3" {
+			t.Errorf("expected the keys written after version (2), found (%v)", keys)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = openDBAndDo(func(db goukv.Provider) {
+		err := db.Scan(goukv.ScanOpts{SinceVersion: 1, Scanner: func(k, v []byte) error { return nil }})
+		if err != goukv.ErrNotSupported {
+			t.Errorf("expected ErrNotSupported without enable_versions, found (%v)", err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestDiskWatchdog(t *testing.T) {
+	full := make(chan int64, 1)
+	opts := map[string]interface{}{
+		"max_disk_bytes":      int64(1024),
+		"disk_check_interval": 10 * time.Millisecond,
+		"on_disk_full": func(used int64) {
+			select {
+			case full <- used:
+			default:
+			}
+		},
+	}
+
+	err := openDBWithOptsAndDo(opts, func(db goukv.Provider) {
+		for i := 0; i < 100; i++ {
+			db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%d", i)), Value: bytes.Repeat([]byte("v"), 1024)})
+		}
+
+		select {
+		case used := <-full:
+			if used <= 1024 {
+				t.Errorf("expected the reported usage to exceed the limit, found (%d)", used)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("expected the disk full callback to be called")
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestOpenRetryReleasesHandles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goukv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// holds the lock of the path like another process would
+	locked := filepath.Join(dir, "locked")
+	raw, err := leveldb.OpenFile(locked, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := map[string]interface{}{"path": locked, "open_retry_attempts": 10, "open_retry_backoff": 50 * time.Millisecond}
+
+	type result struct {
+		db  goukv.Provider
+		err error
+	}
+	retrying := make(chan result, 2)
+	for i := 0; i < 2; i++ {
+		go (func() {
+			db, err := Provider{}.Open(opts)
+			retrying <- result{db, err}
+		})()
+	}
+
+	// the other paths are opened while it retries
+	time.Sleep(20 * time.Millisecond)
+	other, err := Provider{}.Open(map[string]interface{}{"path": filepath.Join(dir, "other")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.Close()
+
+	select {
+	case r := <-retrying:
+		t.Fatalf("expected the locked path to be retried, found (%v)", r.err)
+	default:
+	}
+
+	raw.Close()
+
+	// the concurrent calls share the opened db
+	first, second := <-retrying, <-retrying
+	if first.err != nil || second.err != nil {
+		t.Fatalf("expected the open to succeed once the lock is released, found (%v, %v)", first.err, second.err)
+	}
+
+	first.db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+	if val, err := second.db.Get([]byte("k")); err != nil || string(val) != "v" {
+		t.Errorf("expected the db to be shared, found (%s, %v)", val, err)
+	}
+
+	first.db.Close()
+	second.db.Close()
+}
+
+func TestSharedHandle(t *testing.T) {
+	defer os.RemoveAll("./db")
+
+	first, err := Provider{}.Open(map[string]interface{}{"path": "./db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := Provider{}.Open(map[string]interface{}{"path": "db/../db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the shared db can't be configured again
+	if _, err := (Provider{}).Open(map[string]interface{}{"path": "./db", "no_ttl": true}); err == nil {
+		t.Error("expected other options to fail")
+	}
+
+	first.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Close(); !errors.Is(err, leveldb.ErrClosed) {
+		t.Errorf("expected a released reference to be closed, found (%v)", err)
+	}
+
+	val, err := second.Get([]byte("k"))
+	if err != nil || string(val) != "v" {
+		t.Errorf("expected the shared db to stay open, found (%s, %v)", val, err)
+	}
+
+	if err := second.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Get([]byte("k")); !errors.Is(err, leveldb.ErrClosed) {
+		t.Errorf("expected the db to be closed with its last reference, found (%v)", err)
+	}
+}
+
+func TestTrackTimestamps(t *testing.T) {
+	err := openDBWithOptsAndDo(map[string]interface{}{"track_timestamps": true}, func(db goukv.Provider) {
+		before := time.Now()
+		db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v1")})
+
+		first, err := db.(goukv.EntryGetter).GetEntry([]byte("k"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.CreatedAt.Before(before) || !first.CreatedAt.Equal(first.UpdatedAt) {
+			t.Errorf("unexpected timestamps (%v, %v)", first.CreatedAt, first.UpdatedAt)
+		}
+		if string(first.Value) != "v1" {
+			t.Errorf("expected (v1), found (%s)", first.Value)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		db.Batch([]*goukv.Entry{{Key: []byte("k"), Value: []byte("v2")}})
+
+		second, err := db.(goukv.EntryGetter).GetEntry([]byte("k"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !second.CreatedAt.Equal(first.CreatedAt) {
+			t.Errorf("expected the creation time to be kept, found (%v) then (%v)", first.CreatedAt, second.CreatedAt)
+		}
+		if !second.UpdatedAt.After(first.UpdatedAt) {
+			t.Errorf("expected the update time to change, found (%v) then (%v)", first.UpdatedAt, second.UpdatedAt)
+		}
+
+		val, err := db.Get([]byte("k"))
+		if err != nil || string(val) != "v2" {
+			t.Errorf("expected (v2), found (%s, %v)", val, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestErrorIfMissing(t *testing.T) {
+	err := openDBWithOptsAndDo(map[string]interface{}{"error_if_missing": true}, func(db goukv.Provider) {
+		t.Error("expected the missing db not to be opened")
+	})
+	if err != goukv.ErrDBNotFound {
+		t.Errorf("expected ErrDBNotFound, found (%v)", err)
+	}
+	if _, err := os.Stat("./db"); !os.IsNotExist(err) {
+		t.Error("expected the missing db not to be created")
+	}
+
+	defer os.RemoveAll("./db")
+
+	db, err := Provider{}.Open(map[string]interface{}{"path": "./db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+	db.Close()
+
+	db, err = Provider{}.Open(map[string]interface{}{"path": "./db", "error_if_missing": true})
+	if err != nil {
+		t.Fatalf("expected the existing db to be opened, found (%v)", err)
+	}
+	defer db.Close()
+
+	if val, err := db.Get([]byte("k")); err != nil || string(val) != "v" {
+		t.Errorf("expected (v), found (%s, %v)", val, err)
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		for i := 0; i < 10; i++ {
+			db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("short:%d", i)), Value: []byte("v"), TTL: time.Millisecond})
+		}
+		db.Put(&goukv.Entry{Key: []byte("long"), Value: []byte("v"), TTL: time.Hour})
+		db.Put(&goukv.Entry{Key: []byte("forever"), Value: []byte("v")})
+
+		time.Sleep(10 * time.Millisecond)
+
+		purged, err := db.(goukv.ExpiredPurger).PurgeExpired()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if purged != 10 {
+			t.Errorf("expected (10) purged keys, found (%d)", purged)
+		}
+
+		if _, err := db.(*Provider).db.Get([]byte("short:0"), nil); err != leveldb.ErrNotFound {
+			t.Errorf("expected the expired key to be physically deleted, found (%v)", err)
+		}
+
+		for _, k := range []string{"long", "forever"} {
+			if _, err := db.Get([]byte(k)); err != nil {
+				t.Errorf("expected (%s) to be kept, found (%v)", k, err)
+			}
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestCompactValues(t *testing.T) {
+	expires, createdAt, updatedAt := time.Now().Add(time.Hour), time.Now().Add(-time.Hour), time.Now()
+	values := []Value{
+		{Value: []byte("v")},
+		{Value: []byte{}},
+		{Value: []byte("v"), Expires: &expires},
+		{Value: []byte("v"), Expires: &expires, Version: 300, CreatedAt: &createdAt, UpdatedAt: &updatedAt},
+	}
+
+	for _, val := range values {
+		for _, b := range [][]byte{val.Bytes(), val.MsgpackBytes()} {
+			decoded := BytesToValue(b)
+			if string(decoded.Value) != string(val.Value) || decoded.Version != val.Version {
+				t.Errorf("expected (%v), found (%v)", val, decoded)
+			}
+			if (val.Expires == nil) != (decoded.Expires == nil) || (val.Expires != nil && !val.Expires.Equal(*decoded.Expires)) {
+				t.Errorf("expected expiration (%v), found (%v)", val.Expires, decoded.Expires)
+			}
+			if (val.CreatedAt == nil) != (decoded.CreatedAt == nil) || (val.CreatedAt != nil && (!val.CreatedAt.Equal(*decoded.CreatedAt) || !val.UpdatedAt.Equal(*decoded.UpdatedAt))) {
+				t.Errorf("expected timestamps (%v, %v), found (%v, %v)", val.CreatedAt, val.UpdatedAt, decoded.CreatedAt, decoded.UpdatedAt)
+			}
+		}
+	}
+
+	if size := len(Value{Value: []byte("value")}.Bytes()); size != len("value")+1 {
+		t.Errorf("expected a value without expiration to cost a single byte, found (%d) bytes", size)
+	}
+}
+
+func TestCompactValuesFarFuture(t *testing.T) {
+	expires := time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	decoded := BytesToValue(Value{Value: []byte("v"), Expires: &expires}.Bytes())
+	if decoded.IsExpired() {
+		t.Fatalf("expected a far-future expiration not to wrap to the past, found (%v)", decoded.Expires)
+	}
+
+	// it's clamped to the last expiration the encoding can hold
+	if !decoded.Expires.Equal(maxUnixNano) {
+		t.Errorf("expected (%v), found (%v)", maxUnixNano, decoded.Expires)
+	}
+
+	err := openDBAndDo(func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v"), ExpireAt: &expires})
+
+		if val, err := db.Get([]byte("k")); err != nil || string(val) != "v" {
+			t.Errorf("expected (v), found (%s, %v)", val, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestCompactValuesBackwardCompatibility(t *testing.T) {
+	err := openDBWithOptsAndDo(map[string]interface{}{"compact_values": false}, func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("old"), Value: []byte("v1"), TTL: time.Hour})
+
+		raw, _ := db.(*Provider).db.Get([]byte("old"), nil)
+		if len(raw) < 1 || raw[0]&0xF0 != 0x80 {
+			t.Fatalf("expected a msgpack encoded value, found (%v)", raw)
+		}
+
+		// the same db written by a provider using the compact encoding
+		compact := *db.(*Provider)
+		compact.compact = true
+		compact.Put(&goukv.Entry{Key: []byte("new"), Value: []byte("v2")})
+
+		raw, _ = compact.db.Get([]byte("new"), nil)
+		if string(raw) != "\xe0v2" {
+			t.Fatalf("expected a compact encoded value, found (%v)", raw)
+		}
+
+		for k, v := range map[string]string{"old": "v1", "new": "v2"} {
+			val, err := compact.Get([]byte(k))
+			if err != nil || string(val) != v {
+				t.Errorf("expected (%s), found (%s, %v)", v, val, err)
+			}
+		}
+
+		if expires, err := compact.TTL([]byte("old")); err != nil || expires == nil {
+			t.Errorf("expected the expiration of the msgpack encoded value, found (%v, %v)", expires, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestGetRaw(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("expired"), Value: []byte("v1"), TTL: time.Millisecond})
+		db.Put(&goukv.Entry{Key: []byte("live"), Value: []byte("v2"), TTL: time.Hour})
+
+		time.Sleep(10 * time.Millisecond)
+
+		if _, err := db.Get([]byte("expired")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected ErrKeyNotFound, found (%v)", err)
+		}
+
+		val, expired, err := db.(goukv.RawGetter).GetRaw([]byte("expired"))
+		if err != nil || !expired || string(val) != "v1" {
+			t.Errorf("expected the expired value, found (%s, %v, %v)", val, expired, err)
+		}
+
+		val, expired, err = db.(goukv.RawGetter).GetRaw([]byte("live"))
+		if err != nil || expired || string(val) != "v2" {
+			t.Errorf("expected the live value, found (%s, %v, %v)", val, expired, err)
+		}
+
+		if _, _, err := db.(goukv.RawGetter).GetRaw([]byte("missing")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected ErrKeyNotFound, found (%v)", err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		if err := db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := db.Get([]byte("missing")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected ErrKeyNotFound to be returned as is, found (%v)", err)
+		}
+
+		db.(*Provider).db.Close()
+
+		_, err := db.Get([]byte("k"))
+
+		var classified *goukv.Error
+		if !errors.As(err, &classified) || classified.Kind != goukv.ErrorFatal || errors.Unwrap(err) != leveldb.ErrClosed {
+			t.Errorf("expected a fatal error wrapping ErrClosed, found (%v)", err)
+		}
+
+		if err := db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")}); !goukv.IsErrorKind(err, goukv.ErrorFatal) {
+			t.Errorf("expected a fatal error, found (%v)", err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestScanByVersion(t *testing.T) {
+	err := openDBWithOptsAndDo(map[string]interface{}{"enable_versions": true}, func(db goukv.Provider) {
+		for _, k := range []string{"b", "c", "a"} {
+			db.Put(&goukv.Entry{Key: []byte(k), Value: []byte("v" + k)})
+		}
+		db.Put(&goukv.Entry{Key: []byte("b"), Value: []byte("vb2")})
+		db.Put(&goukv.Entry{Key: []byte("d"), Value: []byte("vd"), TTL: time.Millisecond})
+		time.Sleep(5 * time.Millisecond)
+
+		var keys []string
+		var versions []uint64
+		err := db.(goukv.VersionScanner).ScanByVersion(0, func(entry *goukv.Entry) error {
+			keys = append(keys, string(entry.Key)+"="+string(entry.Value))
+			versions = append(versions, entry.Version)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(keys) != "[c=vc a=va b=vb2]" || fmt.Sprint(versions) != "[2 3 4]" {
+			t.Errorf("expected the entries in version order, found (%v) (%v)", keys, versions)
+		}
+
+		keys = nil
+		db.(goukv.VersionScanner).ScanByVersion(3, func(entry *goukv.Entry) error {
+			keys = append(keys, string(entry.Key))
+			return goukv.ErrScanDone
+		})
+		if fmt.Sprint(keys) != "[a]" {
+			t.Errorf("expected the scan to start at version (3) and stop, found (%v)", keys)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = openDBAndDo(func(db goukv.Provider) {
+		err := db.(goukv.VersionScanner).ScanByVersion(0, func(*goukv.Entry) error { return nil })
+		if err != goukv.ErrNotSupported {
+			t.Errorf("expected ErrNotSupported without enable_versions, found (%v)", err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestNative(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		native, ok := db.(goukv.NativeAccessor).Native().(*leveldb.DB)
+		if !ok {
+			t.Fatal("expected the native handle to be a *leveldb.DB")
+		}
+
+		db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+
+		raw, err := native.Get([]byte("k"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(raw) == "v" || string(BytesToValue(raw).Value) != "v" {
+			t.Errorf("expected the native handle to expose the wrapped value, found (%q)", raw)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	now := time.Now()
+	goukv.Now = func() time.Time { return now }
+	defer (func() { goukv.Now = time.Now })()
+
+	err := openDBAndDo(func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("k1"), Value: []byte("v"), TTL: time.Hour})
+		db.Put(&goukv.Entry{Key: []byte("k2"), Value: []byte("v")})
+
+		if _, err := db.Get([]byte("k1")); err != nil {
+			t.Fatalf("expected the key to be alive, found (%v)", err)
+		}
+
+		if entry, err := db.(goukv.EntryGetter).GetEntry([]byte("k1")); err != nil || entry.TTL != time.Hour {
+			t.Errorf("expected a TTL of an hour on the fake clock, found (%v) (%v)", entry, err)
+		}
+
+		now = now.Add(2 * time.Hour)
+
+		if _, err := db.Get([]byte("k1")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected the key to be expired on the fake clock, found (%v)", err)
+		}
+
+		var keys []string
+		db.Scan(goukv.ScanOpts{Scanner: func(k, v []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		}})
+		if fmt.Sprint(keys) != "[k2]" {
+			t.Errorf("expected the expired key to be skipped, found (%v)", keys)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestTrackDeletes(t *testing.T) {
+	now := time.Now()
+	goukv.Now = func() time.Time { return now }
+	defer (func() { goukv.Now = time.Now })()
+
+	err := openDBWithOptsAndDo(map[string]interface{}{"track_deletes": true, "tombstone_ttl": time.Hour}, func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("k1"), Value: []byte("v")})
+		db.Put(&goukv.Entry{Key: []byte("k2"), Value: []byte("v")})
+		db.Put(&goukv.Entry{Key: []byte("k3"), Value: []byte{}})
+		db.Delete([]byte("k1"))
+		db.Batch([]*goukv.Entry{{Key: []byte("k2")}})
+
+		if _, err := db.Get([]byte("k1")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected the tombstone to be hidden, found (%v)", err)
+		}
+
+		scan := func(opts goukv.ScanOpts) string {
+			var found []string
+			opts.Scanner = func(k, v []byte) error {
+				found = append(found, fmt.Sprintf("%s=%v", k, goukv.IsTombstone(v)))
+				return nil
+			}
+			if err := db.Scan(opts); err != nil {
+				t.Fatal(err)
+			}
+			return fmt.Sprint(found)
+		}
+
+		if found := scan(goukv.ScanOpts{}); found != "[k3=false]" {
+			t.Errorf("expected the tombstones to be skipped, found (%v)", found)
+		}
+
+		if found := scan(goukv.ScanOpts{IncludeTombstones: true}); found != "[k1=true k2=true k3=false]" {
+			t.Errorf("expected the tombstones to be scanned, found (%v)", found)
+		}
+
+		now = now.Add(2 * time.Hour)
+
+		if found := scan(goukv.ScanOpts{IncludeTombstones: true}); found != "[k3=false]" {
+			t.Errorf("expected the tombstones to expire, found (%v)", found)
+		}
+
+		if purged, err := db.(goukv.ExpiredPurger).PurgeExpired(); err != nil || purged != 2 {
+			t.Errorf("expected the expired tombstones to be purged, found (%d) (%v)", purged, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestOpenFilesCacheCapacity(t *testing.T) {
+	err := openDBWithOptsAndDo(map[string]interface{}{"open_files_cache_capacity": 16}, func(db goukv.Provider) {
+		if capacity := db.(*Provider).options.GetOpenFilesCacheCapacity(); capacity != 16 {
+			t.Errorf("expected an open files cache of (16), found (%d)", capacity)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	err = openDBAndDo(func(db goukv.Provider) {
+		if capacity := db.(*Provider).options.GetOpenFilesCacheCapacity(); capacity != opt.DefaultOpenFilesCacheCapacity {
+			t.Errorf("expected goleveldb's default, found (%d)", capacity)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestBulkLoad(t *testing.T) {
+	defer os.RemoveAll("./db")
+
+	p := Provider{}
+	db, err := p.Open(map[string]interface{}{"path": "./db", "bulk_load": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := db.(*Provider).options
+	if o.GetFilter() != nil || o.GetWriteBuffer() != 32*opt.MiB || o.GetWriteL0PauseTrigger() != 128 {
+		t.Errorf("expected the bulk load options, found (%+v)", o)
+	}
+
+	for i := 0; i < 1000; i++ {
+		db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%04d", i)), Value: []byte("v")})
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = p.Open(map[string]interface{}{"path": "./db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if o := db.(*Provider).options; o.GetFilter() == nil {
+		t.Error("expected the bloom filter once reopened without bulk_load")
+	}
+
+	if files, _ := db.(*Provider).db.GetProperty("leveldb.num-files-at-level0"); files != "0" {
+		t.Errorf("expected the bulk load to be compacted out of level-0, found (%s) files", files)
+	}
+
+	if val, err := db.Get([]byte("k0999")); err != nil || string(val) != "v" {
+		t.Errorf("expected the imported value, found (%s) (%v)", val, err)
+	}
+}
+
+// BenchmarkImport imports 1M random keys per op in batches of 1000 then closes the db, import-ns/op excludes
+// the close (and so the final compaction of a bulk load)
+func BenchmarkImport(b *testing.B) {
+	for _, bulkLoad := range []bool{false, true} {
+		b.Run(fmt.Sprintf("bulk_load=%v", bulkLoad), func(b *testing.B) {
+			var imported time.Duration
+			value := bytes.Repeat([]byte("v"), 100)
+			for i := 0; i < b.N; i++ {
+				os.RemoveAll("./db")
+
+				db, err := Provider{}.Open(map[string]interface{}{"path": "./db", "bulk_load": bulkLoad})
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				start := time.Now()
+				for j := 0; j < 1000; j++ {
+					entries := make([]*goukv.Entry, 1000)
+					for k := range entries {
+						entries[k] = &goukv.Entry{Key: []byte(fmt.Sprintf("%08x", (j*1000+k)*2654435761%(1<<32))), Value: value}
+					}
+					if err := db.Batch(entries); err != nil {
+						b.Fatal(err)
+					}
+				}
+				imported += time.Since(start)
+
+				if err := db.Close(); err != nil {
+					b.Fatal(err)
+				}
+			}
+			os.RemoveAll("./db")
+
+			b.ReportMetric(float64(imported.Nanoseconds())/float64(b.N), "import-ns/op")
+		})
+	}
+}
+
+func TestFromDB(t *testing.T) {
+	defer os.RemoveAll("./db")
+
+	ldb, err := leveldb.OpenFile("./db", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := FromDB(ldb, map[string]interface{}{"enable_versions": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Put(&goukv.Entry{Key: []byte("k1"), Value: []byte("v")})
+	db.Put(&goukv.Entry{Key: []byte("k2"), Value: []byte("v")})
+
+	if entry, err := db.(goukv.EntryGetter).GetEntry([]byte("k2")); err != nil || entry.Version != 2 {
+		t.Errorf("expected the version (2), found (%v) (%v)", entry, err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ldb.Get([]byte("k1"), nil); err != leveldb.ErrClosed {
+		t.Errorf("expected the wrapped db to be closed, found (%v)", err)
+	}
+
+	if err := db.Close(); !goukv.IsErrorKind(err, goukv.ErrorFatal) {
+		t.Errorf("expected closing twice to fail, found (%v)", err)
+	}
+
+	if _, err := FromDB(ldb, map[string]interface{}{"txn_isolation": "unknown"}); err == nil {
+		t.Error("expected the unknown txn_isolation to be rejected")
+	}
+}
+
+// copyCrashed copies the files of the specified open db into dst, like a crash would leave them (nothing is closed)
+func copyCrashed(t *testing.T, src, dst string) {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ioutil.ReadDir(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range files {
+		b, err := ioutil.ReadFile(filepath.Join(src, f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(dst, f.Name()), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSyncWriter(t *testing.T) {
+	defer os.RemoveAll("./db-crashed")
+
+	err := openDBWithOptsAndDo(map[string]interface{}{"sync_writes": false}, func(db goukv.Provider) {
+		writer := db.(goukv.SyncWriter)
+
+		if err := writer.PutSync(&goukv.Entry{Key: []byte("k1"), Value: []byte("v1")}, true); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := writer.BatchSync([]*goukv.Entry{{Key: []byte("k2"), Value: []byte("v2")}, {Key: []byte("k3"), Value: []byte("v3")}}, true); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := writer.DeleteSync([]byte("k3"), true); err != nil {
+			t.Fatal(err)
+		}
+
+		copyCrashed(t, "./db", "./db-crashed")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := Provider{}
+	db, err := p.Open(map[string]interface{}{"path": "./db-crashed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for k, expected := range map[string]string{"k1": "v1", "k2": "v2"} {
+		if v, err := db.Get([]byte(k)); err != nil || string(v) != expected {
+			t.Errorf("expected the synced (%s) to survive the crash, found (%s, %v)", k, v, err)
+		}
+	}
+
+	if _, err := db.Get([]byte("k3")); err != goukv.ErrKeyNotFound {
+		t.Errorf("expected the synced delete of (k3) to survive the crash, found (%v)", err)
+	}
+}
+
+func TestSharedBlockCache(t *testing.T) {
+	shared := NewSharedBlockCache(1 << 20)
+
+	first, err := Provider{}.Open(map[string]interface{}{"path": "./db", "block_cache": shared})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./db")
+
+	second, err := Provider{}.Open(map[string]interface{}{"path": "./db2", "block_cache": shared})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	defer os.RemoveAll("./db2")
+
+	for _, db := range []goukv.Provider{first, second} {
+		if cacher := db.(*Provider).options.BlockCacher; cacher != shared {
+			t.Errorf("expected both dbs to use the shared cache, found (%v)", cacher)
+		}
+
+		if capacity := db.(*Provider).options.GetBlockCacheCapacity(); capacity != 1<<20 {
+			t.Errorf("expected the capacity of the shared cache, found (%d)", capacity)
+		}
+
+		for i := 0; i < 100; i++ {
+			db.Put(&goukv.Entry{Key: []byte(strconv.Itoa(i)), Value: []byte("v")})
+		}
+
+		// flushed to the tables so the reads go through the block cache
+		if err := db.(*Provider).db.CompactRange(util.Range{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := db.Get([]byte("1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := second.Get([]byte("2")); err != nil || string(v) != "v" {
+		t.Errorf("expected the other db to still read through the cache, found (%s, %v)", v, err)
+	}
+
+	named := NamedBlockCache("TestSharedBlockCache", 1<<20)
+	err = openDBWithOptsAndDo(map[string]interface{}{"block_cache_name": "TestSharedBlockCache"}, func(db goukv.Provider) {
+		if cacher := db.(*Provider).options.BlockCacher; cacher != named {
+			t.Errorf("expected the named cache, found (%v)", cacher)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestRangeSize(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		sizer := db.(goukv.RangeSizer)
+
+		// incompressible values so the sizes follow the written bytes
+		write := func(prefix string, from, to int) {
+			for i := from; i < to; i++ {
+				v := make([]byte, 1024)
+				rand.Read(v)
+				db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("%s:%05d", prefix, i)), Value: v})
+			}
+
+			if err := db.(*Provider).db.CompactRange(util.Range{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		write("r", 0, 1000)
+		write("z", 0, 1000)
+
+		before, err := sizer.RangeSize([]byte("r:"), []byte("r;"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if before < 512*1024 || before > 2*1024*1024 {
+			t.Errorf("expected about 1MiB in the range, found (%d)", before)
+		}
+
+		write("r", 1000, 2000)
+
+		after, _ := sizer.RangeSize([]byte("r:"), []byte("r;"))
+		if ratio := float64(after) / float64(before); ratio < 1.5 || ratio > 2.5 {
+			t.Errorf("expected the estimate to double with the data, found (%d) then (%d)", before, after)
+		}
+
+		if all, _ := sizer.RangeSize(nil, nil); all < after+512*1024 {
+			t.Errorf("expected the whole keyspace to include both ranges, found (%d)", all)
+		}
+
+		if empty, _ := sizer.RangeSize([]byte("a"), []byte("b")); empty != 0 {
+			t.Errorf("expected an empty range to be (0), found (%d)", empty)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestVerifyOnOpen(t *testing.T) {
+	defer os.RemoveAll("./db")
+
+	db, err := Provider{}.Open(map[string]interface{}{"path": "./db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%04d", i)), Value: bytes.Repeat([]byte("v"), 100)})
+	}
+
+	// flushed to the tables
+	if err := db.(*Provider).db.CompactRange(util.Range{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	db, err = Provider{}.Open(map[string]interface{}{"path": "./db", "verify_on_open": true})
+	if err != nil {
+		t.Fatalf("expected an intact db to be verified, found (%v)", err)
+	}
+	db.Close()
+
+	tables, _ := filepath.Glob("./db/*.ldb")
+	if len(tables) == 0 {
+		t.Fatal("expected the db to have tables")
+	}
+
+	f, err := os.OpenFile(tables[0], os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteAt(bytes.Repeat([]byte{0xff}, 64), 100)
+	f.Close()
+
+	// the tables are opened lazily
+	db, err = Provider{}.Open(map[string]interface{}{"path": "./db"})
+	if err != nil {
+		t.Fatalf("expected the corruption to go unnoticed without verify_on_open, found (%v)", err)
+	}
+	db.Close()
+
+	if _, err := (Provider{}).Open(map[string]interface{}{"path": "./db", "verify_on_open": true}); !errors.Is(err, goukv.ErrCorrupted) {
+		t.Errorf("expected (%v), found (%v)", goukv.ErrCorrupted, err)
+	}
+}
+
+func TestVerifyOnOpenSharedHandle(t *testing.T) {
+	defer os.RemoveAll("./db")
+
+	first, err := Provider{}.Open(map[string]interface{}{"path": "./db", "verify_on_open": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+
+	// it only affects the open call, the db is shared whatever its value
+	second, err := Provider{}.Open(map[string]interface{}{"path": "./db", "verify_on_open": false})
+	if err != nil {
+		t.Fatalf("expected the db to be shared, found (%v)", err)
+	}
+	defer second.Close()
+
+	first.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+	if val, err := second.Get([]byte("k")); err != nil || string(val) != "v" {
+		t.Errorf("expected the db to be shared, found (%s, %v)", val, err)
+	}
+}
+
+func TestForceGCKey(t *testing.T) {
+	err := openDBWithOptsAndDo(map[string]interface{}{}, func(db goukv.Provider) {
+		// the overwritten versions of a hot key
+		v := make([]byte, 10<<10)
+		for i := 0; i < 200; i++ {
+			rand.Read(v)
+			if err := db.Put(&goukv.Entry{Key: []byte("hot"), Value: v}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		before, _ := goukv.DirSize("./db")
+
+		if err := db.(goukv.KeyGCForcer).ForceGCKey([]byte("hot")); err != nil {
+			t.Fatal(err)
+		}
+
+		after, _ := goukv.DirSize("./db")
+		if after > before/10 {
+			t.Errorf("expected the older versions to be reclaimed, found (%d) bytes before and (%d) after", before, after)
+		}
+
+		if found, err := db.Get([]byte("hot")); err != nil || !bytes.Equal(found, v) {
+			t.Errorf("expected the last version to be kept, found (%v)", err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
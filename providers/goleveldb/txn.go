@@ -0,0 +1,195 @@
+package leveldb
+
+import (
+	"bytes"
+
+	"github.com/alash3al/goukv"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// available transaction isolation levels
+const (
+	// IsolationSerializable transactions hold the provider write lock from start to end, so they never conflict
+	IsolationSerializable = "serializable"
+
+	// IsolationSnapshot transactions read from a snapshot and only lock on commit,
+	// where a write-write conflict with a concurrently committed write aborts the commit
+	IsolationSnapshot = "snapshot"
+)
+
+// ErrTxnConflict returned by Commit when a key written by a snapshot transaction has been changed since it started,
+// it's goukv.ErrConflict itself
+var ErrTxnConflict = goukv.ErrConflict
+
+// Txn implements goukv.Txn
+type Txn struct {
+	p        Provider
+	snapshot *leveldb.Snapshot
+	writes   map[string]*goukv.Entry
+	keys     []string
+	done     bool
+}
+
+// NewTxn implements goukv.Transactional
+func (p Provider) NewTxn() (goukv.Txn, error) {
+	txn := &Txn{
+		p:      p,
+		writes: map[string]*goukv.Entry{},
+	}
+
+	if p.isolation == IsolationSnapshot {
+		snapshot, err := p.db.GetSnapshot()
+		if err != nil {
+			return nil, err
+		}
+		txn.snapshot = snapshot
+	} else {
+		p.writeLock.Lock()
+	}
+
+	return txn, nil
+}
+
+// read returns the raw stored bytes of the specified key as seen by the transaction
+func (txn *Txn) read(k []byte) ([]byte, error) {
+	if txn.snapshot != nil {
+		return txn.snapshot.Get(k, nil)
+	}
+	return txn.p.db.Get(k, nil)
+}
+
+// Get implements goukv.Txn.Get, the pending writes of the transaction are visible to it
+func (txn *Txn) Get(k []byte) ([]byte, error) {
+	if txn.done {
+		return nil, goukv.ErrTxnDone
+	}
+
+	if entry, ok := txn.writes[string(k)]; ok {
+		if entry.Value == nil {
+			return nil, goukv.ErrKeyNotFound
+		}
+		return entry.Value, nil
+	}
+
+	b, err := txn.read(k)
+	if err == leveldb.ErrNotFound {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	val := txn.p.decodeValue(b)
+	if !val.IsLive() {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	return val.Value, nil
+}
+
+// Put implements goukv.Txn.Put
+func (txn *Txn) Put(e *goukv.Entry) error {
+	if txn.done {
+		return goukv.ErrTxnDone
+	}
+
+	e, err := txn.p.prepareEntry(e)
+	if err != nil {
+		return err
+	}
+
+	if e.Value == nil {
+		e = &goukv.Entry{Key: e.Key, Value: []byte{}, TTL: e.TTL, ExpireAt: e.ExpireAt}
+	}
+
+	txn.set(e)
+
+	return nil
+}
+
+// Delete implements goukv.Txn.Delete
+func (txn *Txn) Delete(k []byte) error {
+	if txn.done {
+		return goukv.ErrTxnDone
+	}
+
+	txn.set(&goukv.Entry{Key: k})
+
+	return nil
+}
+
+// set records a pending write, nil value means *delete*
+func (txn *Txn) set(e *goukv.Entry) {
+	k := string(e.Key)
+	if _, ok := txn.writes[k]; !ok {
+		txn.keys = append(txn.keys, k)
+	}
+	txn.writes[k] = e
+}
+
+// Commit implements goukv.Txn.Commit
+func (txn *Txn) Commit() error {
+	if txn.done {
+		return goukv.ErrTxnDone
+	}
+	defer txn.Discard()
+
+	if txn.snapshot != nil {
+		txn.p.writeLock.Lock()
+		defer txn.p.writeLock.Unlock()
+
+		if err := txn.checkConflicts(); err != nil {
+			return classifyError(err)
+		}
+	}
+
+	changes := make([]goukv.Change, 0, len(txn.keys))
+
+	for _, k := range txn.keys {
+		entry := txn.writes[k]
+		if entry.Value == nil {
+			changes = append(changes, goukv.Change{Op: goukv.ChangeDelete, Key: entry.Key})
+		} else {
+			changes = append(changes, goukv.Change{Op: goukv.ChangePut, Key: entry.Key, Value: entry.Value, TTL: entry.TTL, ExpireAt: entry.ExpireAt})
+		}
+	}
+
+	return classifyError(txn.p.commit(changes))
+}
+
+// checkConflicts compares the current stored bytes of every written key with the ones in the snapshot,
+// the caller must hold the write lock
+func (txn *Txn) checkConflicts() error {
+	for _, k := range txn.keys {
+		before, err := txn.snapshot.Get([]byte(k), nil)
+		if err != nil && err != leveldb.ErrNotFound {
+			return err
+		}
+
+		current, err := txn.p.db.Get([]byte(k), nil)
+		if err != nil && err != leveldb.ErrNotFound {
+			return err
+		}
+
+		if !bytes.Equal(before, current) {
+			return ErrTxnConflict
+		}
+	}
+
+	return nil
+}
+
+// Discard implements goukv.Txn.Discard, it releases the transaction resources without writing anything
+func (txn *Txn) Discard() {
+	if txn.done {
+		return
+	}
+	txn.done = true
+
+	if txn.snapshot != nil {
+		txn.snapshot.Release()
+	} else {
+		txn.p.writeLock.Unlock()
+	}
+}
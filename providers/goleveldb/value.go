@@ -1,20 +1,74 @@
 package leveldb
 
 import (
+	"encoding/binary"
+	"errors"
+	"math"
 	"time"
 
 	"github.com/alash3al/goukv"
 	"github.com/vmihailenco/msgpack/v4"
 )
 
-// Value represents a value with expiration date
+// ErrTTLDisabled returned when an entry with an expiration is written in no_ttl mode
+var ErrTTLDisabled = errors.New("expirations aren't supported in no_ttl mode")
+
+// Value represents a value with expiration date, its version and timestamps are only set when enabled,
+// a tombstone is the (valueless) marker a delete leaves behind in track_deletes mode
 type Value struct {
-	Value   []byte
-	Expires *time.Time
+	Value     []byte
+	Expires   *time.Time
+	Version   uint64     `msgpack:",omitempty"`
+	CreatedAt *time.Time `msgpack:",omitempty"`
+	UpdatedAt *time.Time `msgpack:",omitempty"`
+	Tombstone bool       `msgpack:",omitempty"`
 }
 
-// Bytes encodes the value to a byte array
+// the compact encoding starts with a flag byte (compactMagic|flags) telling which fields follow as varints,
+// the raw value comes last, the msgpack encoding of a Value always starts with a map header (0x80-0x8f, 0xde or 0xdf)
+// so both encodings can be told apart
+const (
+	compactMagic      = 0xE0
+	compactMagicMask  = 0xF0
+	compactExpires    = 0x01
+	compactVersion    = 0x02
+	compactTimestamps = 0x04
+	compactTombstone  = 0x08
+)
+
+// Bytes encodes the value to a byte array using the compact encoding,
+// a value without expiration, version and timestamps only costs a single extra byte
 func (e Value) Bytes() []byte {
+	b := make([]byte, 1, 1+len(e.Value))
+	flags := byte(compactMagic)
+
+	if e.Expires != nil {
+		flags |= compactExpires
+		b = appendVarint(b, unixNano(*e.Expires))
+	}
+
+	if e.Version > 0 {
+		flags |= compactVersion
+		b = appendUvarint(b, e.Version)
+	}
+
+	if e.CreatedAt != nil && e.UpdatedAt != nil {
+		flags |= compactTimestamps
+		b = appendVarint(b, unixNano(*e.CreatedAt))
+		b = appendVarint(b, unixNano(*e.UpdatedAt))
+	}
+
+	if e.Tombstone {
+		flags |= compactTombstone
+	}
+
+	b[0] = flags
+
+	return append(b, e.Value...)
+}
+
+// MsgpackBytes encodes the value to a byte array using the msgpack encoding used before the compact one
+func (e Value) MsgpackBytes() []byte {
 	b, _ := msgpack.Marshal(e)
 	return b
 }
@@ -24,27 +78,100 @@ func (e Value) IsExpired() bool {
 	if e.Expires == nil {
 		return false
 	}
-	expires := *(e.Expires)
-	return time.Now().After(expires) || time.Now().Equal(expires)
+	expires, now := *(e.Expires), goukv.Now()
+	return now.After(expires) || now.Equal(expires)
+}
+
+// IsLive whether the value is visible to the reads, a tombstone never is
+func (e Value) IsLive() bool {
+	return !e.Tombstone && !e.IsExpired()
 }
 
 // EntryToValue build a value from entry representation
 func EntryToValue(e *goukv.Entry) Value {
-	val := Value{
+	return Value{
 		Value:   e.Value,
-		Expires: nil,
+		Expires: e.ExpiresAt(),
 	}
+}
 
-	if e.TTL > 0 {
-		expires := time.Now().Add(e.TTL)
-		val.Expires = &expires
+// BytesToValue Decodes the specified byte array to Value, it accepts both the compact and the msgpack encodings
+func BytesToValue(b []byte) (v Value) {
+	if len(b) < 1 || b[0]&compactMagicMask != compactMagic {
+		msgpack.Unmarshal(b, &v)
+		return
 	}
 
-	return val
-}
+	flags, b := b[0], b[1:]
+
+	if flags&compactExpires != 0 {
+		var expires int64
+		expires, b = readVarint(b)
+		t := time.Unix(0, expires)
+		v.Expires = &t
+	}
+
+	if flags&compactVersion != 0 {
+		v.Version, b = readUvarint(b)
+	}
+
+	if flags&compactTimestamps != 0 {
+		var createdAt, updatedAt int64
+		createdAt, b = readVarint(b)
+		updatedAt, b = readVarint(b)
+		c, u := time.Unix(0, createdAt), time.Unix(0, updatedAt)
+		v.CreatedAt, v.UpdatedAt = &c, &u
+	}
+
+	v.Tombstone = flags&compactTombstone != 0
+
+	// the specified byte array may be reused by the caller (i.e an iterator)
+	v.Value = append([]byte{}, b...)
 
-// BytesToValue Decodes the specified byte array to Value
-func BytesToValue(b []byte) (v Value) {
-	msgpack.Unmarshal(b, &v)
 	return
 }
+
+// the times the compact encoding can hold, the nanoseconds since the epoch within the int64 range
+var minUnixNano, maxUnixNano = time.Unix(0, math.MinInt64), time.Unix(0, math.MaxInt64)
+
+// unixNano returns t as nanoseconds since the epoch clamped to the int64 range (the years 1677 to 2262), since
+// UnixNano overflows outside of it, so a far-future expiration is stored as the last one it can hold
+func unixNano(t time.Time) int64 {
+	if t.After(maxUnixNano) {
+		return math.MaxInt64
+	}
+
+	if t.Before(minUnixNano) {
+		return math.MinInt64
+	}
+
+	return t.UnixNano()
+}
+
+func appendVarint(b []byte, x int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutVarint(buf[:], x)]...)
+}
+
+func appendUvarint(b []byte, x uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], x)]...)
+}
+
+// readVarint reads a varint from the specified byte array and returns the remaining bytes, a corrupted varint reads as 0
+func readVarint(b []byte) (int64, []byte) {
+	x, n := binary.Varint(b)
+	if n <= 0 {
+		return 0, nil
+	}
+	return x, b[n:]
+}
+
+// readUvarint reads an uvarint from the specified byte array and returns the remaining bytes, a corrupted uvarint reads as 0
+func readUvarint(b []byte) (uint64, []byte) {
+	x, n := binary.Uvarint(b)
+	if n <= 0 {
+		return 0, nil
+	}
+	return x, b[n:]
+}
@@ -0,0 +1,14 @@
+//go:build immudb
+// +build immudb
+
+package immudb
+
+import "github.com/alash3al/goukv"
+
+const (
+	name = "immudb"
+)
+
+func init() {
+	goukv.Register(name, Provider{})
+}
@@ -0,0 +1,278 @@
+//go:build immudb
+// +build immudb
+
+package immudb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alash3al/goukv"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/client"
+)
+
+// scanPageSize the number of entries fetched by each scan request
+const scanPageSize = 1000
+
+// Provider represents a provider
+type Provider struct {
+	client   client.ImmuClient
+	verified bool
+}
+
+// Open implements goukv.Open
+func (p Provider) Open(opts map[string]interface{}) (goukv.Provider, error) {
+	addr, ok := opts["addr"].(string)
+	if !ok {
+		addr = "127.0.0.1:3322"
+	}
+
+	username, ok := opts["username"].(string)
+	if !ok {
+		return nil, errors.New("must specify username")
+	}
+
+	password, ok := opts["password"].(string)
+	if !ok {
+		return nil, errors.New("must specify password")
+	}
+
+	database, ok := opts["database"].(string)
+	if !ok {
+		database = "defaultdb"
+	}
+
+	verified, ok := opts["verified"].(bool)
+	if !ok {
+		verified = false
+	}
+
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := client.NewClient().WithOptions(client.DefaultOptions().WithAddress(host).WithPort(port))
+	if err := c.OpenSession(context.Background(), []byte(username), []byte(password), database); err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		client:   c,
+		verified: verified,
+	}, nil
+}
+
+// isNotFound whether the specified (grpc) error reports a missing key or not
+func isNotFound(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "key not found")
+}
+
+// metadata returns the metadata holding the expiration of the specified entry, nil if it doesn't expire
+func metadata(entry *goukv.Entry) *schema.KVMetadata {
+	var expires time.Time
+	if entry.ExpireAt != nil {
+		expires = *entry.ExpireAt
+	} else if entry.TTL > 0 {
+		expires = time.Now().Add(entry.TTL)
+	} else {
+		return nil
+	}
+
+	return &schema.KVMetadata{
+		Expiration: &schema.Expiration{ExpiresAt: expires.Unix()},
+	}
+}
+
+// errVerifiedExpiration VerifiedSet can't set the expiration metadata, only SetAll can
+var errVerifiedExpiration = errors.New("immudb: the verified writes can't expire, remove the ttl or disable verified")
+
+// Put implements goukv.Put, with the verified option the write is verified against the server state,
+// an expiring entry can't be verified so it's rejected then
+func (p Provider) Put(entry *goukv.Entry) error {
+	ctx := context.Background()
+
+	if entry.Value == nil {
+		entry = &goukv.Entry{Key: entry.Key, Value: []byte{}, TTL: entry.TTL, ExpireAt: entry.ExpireAt}
+	}
+
+	if md := metadata(entry); md != nil {
+		if p.verified {
+			return errVerifiedExpiration
+		}
+
+		_, err := p.client.SetAll(ctx, &schema.SetRequest{
+			KVs: []*schema.KeyValue{{Key: entry.Key, Value: entry.Value, Metadata: md}},
+		})
+		return err
+	}
+
+	if p.verified {
+		_, err := p.client.VerifiedSet(ctx, entry.Key, entry.Value)
+		return err
+	}
+
+	_, err := p.client.Set(ctx, entry.Key, entry.Value)
+
+	return err
+}
+
+// Batch perform multi put operation, empty value means *delete*,
+// the puts are written atomically using SetAll then the deletes are applied, so only the last entry of each key is sent,
+// with the verified option the SetAll transaction is verified against the server state and expiring entries are rejected
+func (p Provider) Batch(entries []*goukv.Entry) error {
+	ctx := context.Background()
+
+	var kvs []*schema.KeyValue
+	var deletes [][]byte
+	for _, entry := range goukv.LastWrites(entries) {
+		if entry.Value == nil {
+			deletes = append(deletes, entry.Key)
+			continue
+		}
+
+		md := metadata(entry)
+		if md != nil && p.verified {
+			return errVerifiedExpiration
+		}
+		kvs = append(kvs, &schema.KeyValue{Key: entry.Key, Value: entry.Value, Metadata: md})
+	}
+
+	if len(kvs) > 0 {
+		hdr, err := p.client.SetAll(ctx, &schema.SetRequest{KVs: kvs})
+		if err != nil {
+			return err
+		}
+
+		if p.verified {
+			if _, err := p.client.VerifiedTxByID(ctx, hdr.Id); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(deletes) > 0 {
+		if _, err := p.client.Delete(ctx, &schema.DeleteKeysRequest{Keys: deletes}); err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// get fetches the entry of the specified key, verified if the verified option is set
+func (p Provider) get(k []byte) (*schema.Entry, error) {
+	var entry *schema.Entry
+	var err error
+	if p.verified {
+		entry, err = p.client.VerifiedGet(context.Background(), k)
+	} else {
+		entry, err = p.client.Get(context.Background(), k)
+	}
+
+	if isNotFound(err) {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	return entry, err
+}
+
+// Get implements goukv.Get
+func (p Provider) Get(k []byte) ([]byte, error) {
+	entry, err := p.get(k)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.Value, nil
+}
+
+// TTL implements goukv.TTL
+func (p Provider) TTL(k []byte) (*time.Time, error) {
+	entry, err := p.get(k)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Metadata == nil || entry.Metadata.Expiration == nil {
+		return nil, nil
+	}
+
+	expires := time.Unix(entry.Metadata.Expiration.ExpiresAt, 0)
+
+	return &expires, nil
+}
+
+// Delete implements goukv.Delete, immudb keeps the history so the key is only logically deleted
+func (p Provider) Delete(k []byte) error {
+	_, err := p.client.Delete(context.Background(), &schema.DeleteKeysRequest{Keys: [][]byte{k}})
+	if isNotFound(err) {
+		return nil
+	}
+
+	return err
+}
+
+// Native implements goukv.NativeAccessor, it returns the underlying client.ImmuClient
+func (p Provider) Native() interface{} {
+	return p.client
+}
+
+// Close implements goukv.Close
+func (p Provider) Close() error {
+	return p.client.CloseSession(context.Background())
+}
+
+// Scan implements goukv.Scan, the keys are fetched in pages resuming after the last received key
+func (p Provider) Scan(opts goukv.ScanOpts) error {
+	if opts.Scanner == nil {
+		return goukv.ErrNoScanner
+	}
+
+	opts.Scanner = opts.BoundedScanner()
+
+	req := &schema.ScanRequest{
+		Prefix:        opts.Prefix,
+		SeekKey:       opts.Offset,
+		InclusiveSeek: opts.Offset != nil && opts.IncludeOffset,
+		Desc:          opts.ReverseScan,
+		Limit:         scanPageSize,
+	}
+
+	tick := opts.ProgressTicker()
+
+	for {
+		entries, err := p.client.Scan(context.Background(), req)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries.Entries {
+			tick()
+
+			if err := opts.Scanner(entry.Key, entry.Value); err != nil {
+				if err == goukv.ErrScanDone {
+					return nil
+				}
+				return err
+			}
+		}
+
+		if len(entries.Entries) < scanPageSize {
+			return nil
+		}
+
+		req.SeekKey = entries.Entries[len(entries.Entries)-1].Key
+		req.InclusiveSeek = false
+	}
+}
@@ -0,0 +1,123 @@
+//go:build immudb
+// +build immudb
+
+package immudb
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+// openDBAndDo opens a provider against the server in IMMUDB_ADDR using the default credentials,
+// the test is skipped when no server is configured
+func openDBAndDo(t *testing.T, opts map[string]interface{}, fn func(db goukv.Provider)) {
+	addr := os.Getenv("IMMUDB_ADDR")
+	if addr == "" {
+		t.Skip("IMMUDB_ADDR isn't set")
+	}
+
+	opts["addr"] = addr
+	opts["username"] = "immudb"
+	opts["password"] = "immudb"
+
+	p := Provider{}
+	db, err := p.Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fn(db)
+}
+
+func TestPutGet(t *testing.T) {
+	for _, verified := range []bool{false, true} {
+		openDBAndDo(t, map[string]interface{}{"verified": verified}, func(db goukv.Provider) {
+			entry := goukv.Entry{
+				Key:   []byte("goukv-test-k"),
+				Value: []byte("v"),
+			}
+			if err := db.Put(&entry); err != nil {
+				t.Fatal(err)
+			}
+
+			val, err := db.Get(entry.Key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(val) != string(entry.Value) {
+				t.Errorf("expected (%s), found(%s)", string(entry.Value), string(val))
+			}
+
+			if err := db.Delete(entry.Key); err != nil {
+				t.Error(err)
+			}
+			if _, err := db.Get(entry.Key); err != goukv.ErrKeyNotFound {
+				t.Errorf("expected ErrKeyNotFound, found (%v)", err)
+			}
+		})
+	}
+}
+
+func TestTTL(t *testing.T) {
+	openDBAndDo(t, map[string]interface{}{}, func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("goukv-test-ttl"),
+			Value: []byte("v"),
+			TTL:   time.Minute,
+		}
+		if err := db.Put(&entry); err != nil {
+			t.Fatal(err)
+		}
+
+		expires, err := db.TTL(entry.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expires == nil || time.Until(*expires) > entry.TTL {
+			t.Errorf("unexpected expiration (%v)", expires)
+		}
+	})
+}
+
+func TestVerifiedTTL(t *testing.T) {
+	// the expiring entries are rejected before anything is sent, no server is needed
+	db := Provider{verified: true}
+
+	entry := &goukv.Entry{Key: []byte("goukv-test-k"), Value: []byte("v"), TTL: time.Minute}
+	if err := db.Put(entry); err != errVerifiedExpiration {
+		t.Errorf("expected (%v), found (%v)", errVerifiedExpiration, err)
+	}
+
+	if err := db.Batch([]*goukv.Entry{entry}); err != errVerifiedExpiration {
+		t.Errorf("expected (%v), found (%v)", errVerifiedExpiration, err)
+	}
+}
+
+func TestScanPrefix(t *testing.T) {
+	openDBAndDo(t, map[string]interface{}{}, func(db goukv.Provider) {
+		db.Batch([]*goukv.Entry{
+			{Key: []byte("goukv-scan-1"), Value: []byte("v")},
+			{Key: []byte("goukv-scan-2"), Value: []byte("v")},
+		})
+
+		var keys []string
+		err := db.Scan(goukv.ScanOpts{
+			Prefix:      []byte("goukv-scan-"),
+			ReverseScan: true,
+			Scanner: func(k, v []byte) error {
+				keys = append(keys, string(k))
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 2 || keys[0] != "goukv-scan-2" {
+			t.Errorf("unexpected keys (%v)", keys)
+		}
+	})
+}
@@ -0,0 +1,18 @@
+module github.com/alash3al/goukv/providers/lmdb
+
+go 1.17
+
+require (
+	github.com/alash3al/goukv v0.0.0-00010101000000-000000000000
+	github.com/bmatsuo/lmdb-go v1.8.0
+	github.com/vmihailenco/msgpack/v4 v4.3.11
+)
+
+require (
+	github.com/golang/protobuf v1.3.4 // indirect
+	github.com/vmihailenco/tagparser v0.1.1 // indirect
+	golang.org/x/net v0.0.0-20200301022130-244492dfa37a // indirect
+	google.golang.org/appengine v1.6.5 // indirect
+)
+
+replace github.com/alash3al/goukv => ../..
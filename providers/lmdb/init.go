@@ -0,0 +1,14 @@
+//go:build lmdb
+// +build lmdb
+
+package lmdb
+
+import "github.com/alash3al/goukv"
+
+const (
+	name = "lmdb"
+)
+
+func init() {
+	goukv.Register(name, Provider{})
+}
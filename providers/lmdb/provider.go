@@ -0,0 +1,289 @@
+//go:build lmdb
+// +build lmdb
+
+package lmdb
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/alash3al/goukv"
+	"github.com/bmatsuo/lmdb-go/lmdb"
+)
+
+// Provider represents a provider
+type Provider struct {
+	env *lmdb.Env
+	dbi lmdb.DBI
+}
+
+// Open implements goukv.Open
+func (p Provider) Open(opts map[string]interface{}) (goukv.Provider, error) {
+	path, ok := opts["path"].(string)
+	if !ok {
+		return nil, errors.New("must specify path")
+	}
+
+	dirPerm, ok := opts["dir_perm"].(os.FileMode)
+	if !ok {
+		dirPerm = 0700
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(path, dirPerm); err != nil {
+			return nil, err
+		}
+	}
+
+	syncWrites, ok := opts["sync_writes"].(bool)
+	if !ok {
+		syncWrites = false
+	}
+
+	mapSize, ok := opts["map_size"].(int64)
+	if !ok {
+		mapSize = 1 << 30
+	}
+
+	env, err := lmdb.NewEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := env.SetMapSize(mapSize); err != nil {
+		env.Close()
+		return nil, err
+	}
+
+	var flags uint
+	if !syncWrites {
+		flags |= lmdb.NoSync | lmdb.NoMetaSync
+	}
+
+	if err := env.Open(path, flags, 0600); err != nil {
+		env.Close()
+		return nil, err
+	}
+
+	var dbi lmdb.DBI
+	err = env.Update(func(txn *lmdb.Txn) (err error) {
+		dbi, err = txn.OpenRoot(0)
+		return err
+	})
+
+	if err != nil {
+		env.Close()
+		return nil, err
+	}
+
+	return &Provider{
+		env: env,
+		dbi: dbi,
+	}, nil
+}
+
+// Put implements goukv.Put
+func (p Provider) Put(entry *goukv.Entry) error {
+	return p.env.Update(func(txn *lmdb.Txn) error {
+		return txn.Put(p.dbi, entry.Key, EntryToValue(entry).Bytes(), 0)
+	})
+}
+
+// Batch perform multi put operation, empty value means *delete*
+func (p Provider) Batch(entries []*goukv.Entry) error {
+	return p.env.Update(func(txn *lmdb.Txn) error {
+		for _, entry := range entries {
+			var err error
+			if entry.Value == nil {
+				err = txn.Del(p.dbi, entry.Key, nil)
+				if lmdb.IsNotFound(err) {
+					err = nil
+				}
+			} else {
+				err = txn.Put(p.dbi, entry.Key, EntryToValue(entry).Bytes(), 0)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// get reads and decodes the value of the specified key
+func (p Provider) get(k []byte) (val Value, err error) {
+	err = p.env.View(func(txn *lmdb.Txn) error {
+		b, err := txn.Get(p.dbi, k)
+		if lmdb.IsNotFound(err) {
+			return goukv.ErrKeyNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		val = BytesToValue(b)
+
+		return nil
+	})
+
+	return val, err
+}
+
+// Get implements goukv.Get
+func (p Provider) Get(k []byte) ([]byte, error) {
+	val, err := p.get(k)
+	if err != nil {
+		return nil, err
+	}
+
+	if val.IsExpired() {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	return val.Value, nil
+}
+
+// TTL implements goukv.TTL
+func (p Provider) TTL(k []byte) (*time.Time, error) {
+	val, err := p.get(k)
+	if err != nil {
+		return nil, err
+	}
+
+	return val.Expires, nil
+}
+
+// Delete implements goukv.Delete
+func (p Provider) Delete(k []byte) error {
+	return p.env.Update(func(txn *lmdb.Txn) error {
+		err := txn.Del(p.dbi, k, nil)
+		if lmdb.IsNotFound(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+// Native implements goukv.NativeAccessor, it returns the underlying *lmdb.Env, the stored values are wrapped (expiration), decode them with BytesToValue
+func (p Provider) Native() interface{} {
+	return p.env
+}
+
+// Close implements goukv.Close
+func (p Provider) Close() error {
+	return p.env.Close()
+}
+
+// Scan implements goukv.Scan
+func (p Provider) Scan(opts goukv.ScanOpts) error {
+	if opts.Scanner == nil {
+		return goukv.ErrNoScanner
+	}
+
+	opts.Scanner = opts.BoundedScanner()
+
+	return p.env.View(func(txn *lmdb.Txn) error {
+		cur, err := txn.OpenCursor(p.dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+
+		tick := opts.ProgressTicker()
+
+		k, v, err := seek(cur, opts)
+		for ; err == nil; k, v, err = next(cur, opts.ReverseScan) {
+			if !bytes.HasPrefix(k, opts.Prefix) {
+				break
+			}
+
+			tick()
+
+			if opts.Offset != nil && !opts.IncludeOffset && bytes.Equal(k, opts.Offset) {
+				continue
+			}
+
+			decodedValue := BytesToValue(v)
+			if decodedValue.IsExpired() {
+				continue
+			}
+
+			if err := opts.Scanner(k, decodedValue.Value); err != nil {
+				if err == goukv.ErrScanDone {
+					return nil
+				}
+				return err
+			}
+		}
+
+		if lmdb.IsNotFound(err) {
+			return nil
+		}
+
+		return err
+	})
+}
+
+// seek positions the cursor at the first key of the scan, the greatest key <= offset
+// (or the last key of the prefix range) in reverse scans
+func seek(cur *lmdb.Cursor, opts goukv.ScanOpts) ([]byte, []byte, error) {
+	if !opts.ReverseScan {
+		start := opts.Prefix
+		if opts.Offset != nil && bytes.Compare(opts.Offset, start) > 0 {
+			start = opts.Offset
+		}
+
+		if len(start) == 0 {
+			return cur.Get(nil, nil, lmdb.First)
+		}
+
+		return cur.Get(start, nil, lmdb.SetRange)
+	}
+
+	upper, bounded := prefixUpperBound(opts.Prefix)
+	start, inclusive := upper, false
+	if opts.Offset != nil && (!bounded || bytes.Compare(opts.Offset, upper) < 0) {
+		start, bounded, inclusive = opts.Offset, true, true
+	}
+
+	if !bounded {
+		return cur.Get(nil, nil, lmdb.Last)
+	}
+
+	k, v, err := cur.Get(start, nil, lmdb.SetRange)
+	if lmdb.IsNotFound(err) {
+		return cur.Get(nil, nil, lmdb.Last)
+	}
+
+	if err != nil || (inclusive && bytes.Equal(k, start)) {
+		return k, v, err
+	}
+
+	return cur.Get(nil, nil, lmdb.Prev)
+}
+
+// next moves the cursor in the scan direction
+func next(cur *lmdb.Cursor, reverse bool) ([]byte, []byte, error) {
+	if reverse {
+		return cur.Get(nil, nil, lmdb.Prev)
+	}
+	return cur.Get(nil, nil, lmdb.Next)
+}
+
+// prefixUpperBound returns the smallest key greater than every key having the specified prefix,
+// bounded is false when no such key exists (empty prefix or a prefix of 0xff bytes)
+func prefixUpperBound(prefix []byte) (upper []byte, bounded bool) {
+	upper = append([]byte{}, prefix...)
+	for i := len(upper) - 1; i >= 0; i-- {
+		if upper[i] < 0xff {
+			upper[i]++
+			return upper[:i+1], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,14 @@
+//go:build mongo
+// +build mongo
+
+package mongo
+
+import "github.com/alash3al/goukv"
+
+const (
+	name = "mongo"
+)
+
+func init() {
+	goukv.Register(name, Provider{})
+}
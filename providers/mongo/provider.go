@@ -0,0 +1,331 @@
+//go:build mongo
+// +build mongo
+
+package mongo
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/alash3al/goukv"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Provider represents a provider
+type Provider struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+// document the document an entry is stored in, the expired documents are removed by the TTL index on expires
+type document struct {
+	Key     string     `bson:"_id"`
+	Value   []byte     `bson:"value"`
+	Expires *time.Time `bson:"expires,omitempty"`
+}
+
+// Open implements goukv.Open
+func (p Provider) Open(opts map[string]interface{}) (goukv.Provider, error) {
+	uri, ok := opts["uri"].(string)
+	if !ok {
+		return nil, errors.New("must specify uri")
+	}
+
+	database, ok := opts["database"].(string)
+	if !ok {
+		database = "goukv"
+	}
+
+	collection, ok := opts["collection"].(string)
+	if !ok {
+		collection = "kv"
+	}
+
+	timeout, ok := opts["timeout"].(time.Duration)
+	if !ok {
+		timeout = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+
+	coll := client.Database(database).Collection(collection)
+
+	// mongo removes the documents once their expires date is passed (its TTL monitor runs every minute)
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+
+	return &Provider{
+		client:     client,
+		collection: coll,
+		timeout:    timeout,
+	}, nil
+}
+
+// context returns the context of a single request, bounded by the timeout option
+func (p Provider) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), p.timeout)
+}
+
+// expiresAt returns the expiration of the specified entry (nil means no expiration)
+func expiresAt(entry *goukv.Entry) *time.Time {
+	if entry.ExpireAt != nil {
+		return entry.ExpireAt
+	}
+
+	if entry.TTL > 0 {
+		expires := time.Now().Add(entry.TTL)
+		return &expires
+	}
+
+	return nil
+}
+
+// expired whether the specified expiration is passed
+func expired(expires *time.Time) bool {
+	return expires != nil && !time.Now().Before(*expires)
+}
+
+// liveFilter adds the condition excluding the expired documents to the specified filter,
+// the TTL monitor removes them periodically so they may still be stored
+func liveFilter(filter bson.M) bson.M {
+	filter["$or"] = bson.A{
+		bson.M{"expires": nil},
+		bson.M{"expires": bson.M{"$gt": time.Now()}},
+	}
+	return filter
+}
+
+// writeModel returns the write of the specified entry, an upsert or a delete (nil values and passed expirations)
+func writeModel(entry *goukv.Entry) mongo.WriteModel {
+	expires := expiresAt(entry)
+	if entry.Value == nil || expired(expires) {
+		return mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": string(entry.Key)})
+	}
+
+	return mongo.NewReplaceOneModel().
+		SetFilter(bson.M{"_id": string(entry.Key)}).
+		SetReplacement(document{Key: string(entry.Key), Value: entry.Value, Expires: expires}).
+		SetUpsert(true)
+}
+
+// Put implements goukv.Put, the document is upserted
+func (p Provider) Put(entry *goukv.Entry) error {
+	expires := expiresAt(entry)
+	if expired(expires) {
+		return p.Delete(entry.Key)
+	}
+
+	ctx, cancel := p.context()
+	defer cancel()
+
+	_, err := p.collection.ReplaceOne(
+		ctx,
+		bson.M{"_id": string(entry.Key)},
+		document{Key: string(entry.Key), Value: entry.Value, Expires: expires},
+		options.Replace().SetUpsert(true),
+	)
+
+	return err
+}
+
+// Batch perform multi put operation, empty value means *delete*,
+// it's a single unordered bulk write of the last entry of each key, it isn't atomic, the first failure is returned
+func (p Provider) Batch(entries []*goukv.Entry) error {
+	_, err := p.BatchDetailed(entries)
+	return err
+}
+
+// BatchDetailed implements goukv.DetailedBatcher, the bulk write is unordered so the documents are still written
+// after a failed one, the entries of a key share the result of its last entry (the one written)
+func (p Provider) BatchDetailed(entries []*goukv.Entry) ([]error, error) {
+	results := make([]error, len(entries))
+
+	last := goukv.LastWrites(entries)
+	if len(last) == 0 {
+		return results, nil
+	}
+
+	models := make([]mongo.WriteModel, len(last))
+	for i, entry := range last {
+		models[i] = writeModel(entry)
+	}
+
+	ctx, cancel := p.context()
+	defer cancel()
+
+	_, err := p.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err == nil {
+		return results, nil
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+		for i := range results {
+			results[i] = err
+		}
+		return results, err
+	}
+
+	keyResults := map[string]error{}
+	for _, writeErr := range bulkErr.WriteErrors {
+		keyResults[string(last[writeErr.Index].Key)] = writeErr
+	}
+
+	for i, entry := range entries {
+		results[i] = keyResults[string(entry.Key)]
+	}
+
+	return results, err
+}
+
+// find returns the live document of the specified key
+func (p Provider) find(k []byte) (*document, error) {
+	ctx, cancel := p.context()
+	defer cancel()
+
+	var doc document
+	err := p.collection.FindOne(ctx, liveFilter(bson.M{"_id": string(k)})).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+// Get implements goukv.Get, the expired documents are filtered since the TTL monitor may not have removed them yet
+func (p Provider) Get(k []byte) ([]byte, error) {
+	doc, err := p.find(k)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc.Value, nil
+}
+
+// TTL implements goukv.TTL, mongo stores the dates with a millisecond precision
+func (p Provider) TTL(k []byte) (*time.Time, error) {
+	doc, err := p.find(k)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc.Expires, nil
+}
+
+// Delete implements goukv.Delete
+func (p Provider) Delete(k []byte) error {
+	ctx, cancel := p.context()
+	defer cancel()
+
+	_, err := p.collection.DeleteOne(ctx, bson.M{"_id": string(k)})
+
+	return err
+}
+
+// Native implements goukv.NativeAccessor, it returns the underlying *mongo.Collection
+func (p Provider) Native() interface{} {
+	return p.collection
+}
+
+// Close implements goukv.Close
+func (p Provider) Close() error {
+	ctx, cancel := p.context()
+	defer cancel()
+
+	return p.client.Disconnect(ctx)
+}
+
+// Scan implements goukv.Scan, it's a single find sorted by _id (the key) read by the cursor in batches of 1000,
+// the prefix is an anchored regex on _id (which uses its index) and the offset a range condition on it
+func (p Provider) Scan(opts goukv.ScanOpts) error {
+	if opts.Scanner == nil {
+		return goukv.ErrNoScanner
+	}
+
+	opts.Scanner = opts.BoundedScanner()
+
+	id := bson.M{}
+	if len(opts.Prefix) > 0 {
+		id["$regex"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(string(opts.Prefix))}
+	}
+
+	if opts.Offset != nil {
+		op := "$gt"
+		if opts.ReverseScan {
+			op = "$lt"
+		}
+		if opts.IncludeOffset {
+			op += "e"
+		}
+		id[op] = string(opts.Offset)
+	}
+
+	filter := bson.M{}
+	if len(id) > 0 {
+		filter["_id"] = id
+	}
+
+	order := 1
+	if opts.ReverseScan {
+		order = -1
+	}
+
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: order}}).
+		SetBatchSize(1000)
+
+	ctx := context.Background()
+
+	cursor, err := p.collection.Find(ctx, liveFilter(filter), findOpts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	tick := opts.ProgressTicker()
+
+	for cursor.Next(ctx) {
+		tick()
+
+		var doc document
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+
+		if err := opts.Scanner([]byte(doc.Key), doc.Value); err != nil {
+			if err == goukv.ErrScanDone {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
@@ -0,0 +1,128 @@
+//go:build mongo
+// +build mongo
+
+package mongo
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+// openDBAndDo opens a provider against the server in MONGO_URI (in the "goukv_test" collection),
+// the collection is dropped afterwards, the test is skipped when no server is configured
+func openDBAndDo(t *testing.T, fn func(db goukv.Provider)) {
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		t.Skip("MONGO_URI isn't set")
+	}
+
+	p := Provider{}
+	db, err := p.Open(map[string]interface{}{
+		"uri":        uri,
+		"collection": "goukv_test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer db.(*Provider).collection.Drop(context.Background())
+
+	fn(db)
+}
+
+func TestPutGet(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("k"),
+			Value: []byte("v"),
+		}
+		err := db.Put(&entry)
+		if err != nil {
+			t.Error(err)
+		}
+		val, err := db.Get(entry.Key)
+		if err != nil {
+			t.Error(err)
+		}
+		if string(val) != string(entry.Value) {
+			t.Errorf("expected (%s), found(%s)", string(entry.Value), string(val))
+		}
+
+		if err := db.Delete(entry.Key); err != nil {
+			t.Error(err)
+		}
+		if _, err := db.Get(entry.Key); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected ErrKeyNotFound, found (%v)", err)
+		}
+	})
+}
+
+func TestTTL(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("ttl"),
+			Value: []byte("v"),
+			TTL:   time.Second * 2,
+		}
+		if err := db.Put(&entry); err != nil {
+			t.Error(err)
+		}
+
+		expires, err := db.TTL(entry.Key)
+		if err != nil {
+			t.Error(err)
+		}
+		if expires == nil || time.Until(*expires) > entry.TTL {
+			t.Errorf("unexpected expiration (%v)", expires)
+		}
+
+		// the TTL monitor runs every minute, the expired document is filtered until then
+		time.Sleep(entry.TTL)
+
+		if _, err := db.Get(entry.Key); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected the expired document to be hidden, found (%v)", err)
+		}
+	})
+}
+
+func TestBatchScan(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entries := []*goukv.Entry{
+			{Key: []byte("a"), Value: []byte("v")},
+			{Key: []byte("scan/1"), Value: []byte("v")},
+			{Key: []byte("scan/2"), Value: []byte("v")},
+			{Key: []byte("scan/3"), Value: []byte("v")},
+			{Key: []byte("scan/4"), Value: []byte("v")},
+			{Key: []byte("scan/4")},
+		}
+		if err := db.Batch(entries); err != nil {
+			t.Fatal(err)
+		}
+
+		scan := func(opts goukv.ScanOpts) []string {
+			var found []string
+			opts.Scanner = func(k, v []byte) error {
+				found = append(found, string(k))
+				return nil
+			}
+			if err := db.Scan(opts); err != nil {
+				t.Error(err)
+			}
+			return found
+		}
+
+		found := scan(goukv.ScanOpts{Prefix: []byte("scan/"), Offset: []byte("scan/1")})
+		if len(found) != 2 || found[0] != "scan/2" || found[1] != "scan/3" {
+			t.Errorf("expected ([scan/2 scan/3]), found (%v)", found)
+		}
+
+		found = scan(goukv.ScanOpts{Prefix: []byte("scan/"), Offset: []byte("scan/2"), IncludeOffset: true, ReverseScan: true})
+		if len(found) != 2 || found[0] != "scan/2" || found[1] != "scan/1" {
+			t.Errorf("expected ([scan/2 scan/1]), found (%v)", found)
+		}
+	})
+}
@@ -0,0 +1,14 @@
+//go:build rediscluster
+// +build rediscluster
+
+package rediscluster
+
+import "github.com/alash3al/goukv"
+
+const (
+	name = "rediscluster"
+)
+
+func init() {
+	goukv.Register(name, Provider{})
+}
@@ -0,0 +1,301 @@
+//go:build rediscluster
+// +build rediscluster
+
+package rediscluster
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alash3al/goukv"
+	"github.com/go-redis/redis/v8"
+)
+
+// Provider represents a provider
+type Provider struct {
+	client *redis.ClusterClient
+}
+
+// Open implements goukv.Open
+func (p Provider) Open(opts map[string]interface{}) (goukv.Provider, error) {
+	var addrs []string
+	switch v := opts["addrs"].(type) {
+	case []string:
+		addrs = v
+	case string:
+		addrs = strings.Split(v, ",")
+	}
+
+	if len(addrs) < 1 {
+		return nil, errors.New("must specify addrs")
+	}
+
+	username, _ := opts["username"].(string)
+	password, _ := opts["password"].(string)
+
+	client := redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs:    addrs,
+		Username: username,
+		Password: password,
+	})
+
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &Provider{
+		client: client,
+	}, nil
+}
+
+// expiration returns the expiration of the specified entry (0 means no expiration),
+// expired is true when its ExpireAt is already in the past
+func expiration(entry *goukv.Entry) (ttl time.Duration, expired bool) {
+	if entry.ExpireAt != nil {
+		ttl = time.Until(*entry.ExpireAt)
+		return ttl, ttl <= 0
+	}
+
+	if entry.TTL > 0 {
+		return entry.TTL, false
+	}
+
+	return 0, false
+}
+
+// write queues the command that writes the specified entry and returns it, nil value means *delete*
+func write(ctx context.Context, pipe redis.Pipeliner, entry *goukv.Entry) redis.Cmder {
+	ttl, expired := expiration(entry)
+	if entry.Value == nil || expired {
+		return pipe.Del(ctx, string(entry.Key))
+	}
+
+	return pipe.Set(ctx, string(entry.Key), entry.Value, ttl)
+}
+
+// Put implements goukv.Put
+func (p Provider) Put(entry *goukv.Entry) error {
+	ctx := context.Background()
+
+	ttl, expired := expiration(entry)
+	if expired {
+		return p.client.Del(ctx, string(entry.Key)).Err()
+	}
+
+	val := entry.Value
+	if val == nil {
+		val = []byte{}
+	}
+
+	return p.client.Set(ctx, string(entry.Key), val, ttl).Err()
+}
+
+// Batch perform multi put operation, empty value means *delete*,
+// the entries are grouped by their hash slot and each slot is written in a MULTI/EXEC
+// pipelined to the node owning it, so a batch is only atomic within a single slot
+func (p Provider) Batch(entries []*goukv.Entry) error {
+	_, err := p.BatchDetailed(entries)
+	return err
+}
+
+// BatchDetailed implements goukv.DetailedBatcher, each entry gets the error of its own command,
+// so the entries of a failed slot report its failure while the other slots may have been written
+func (p Provider) BatchDetailed(entries []*goukv.Entry) ([]error, error) {
+	slots := map[int][]int{}
+	for i, entry := range entries {
+		slot := Slot(entry.Key)
+		slots[slot] = append(slots[slot], i)
+	}
+
+	ctx := context.Background()
+	cmds := make([]redis.Cmder, len(entries))
+	_, err := p.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, group := range slots {
+			for _, i := range group {
+				cmds[i] = write(ctx, pipe, entries[i])
+			}
+		}
+		return nil
+	})
+
+	results := make([]error, len(entries))
+	for i, cmd := range cmds {
+		results[i] = cmd.Err()
+	}
+
+	return results, err
+}
+
+// Get implements goukv.Get
+func (p Provider) Get(k []byte) ([]byte, error) {
+	val, err := p.client.Get(context.Background(), string(k)).Bytes()
+	if err == redis.Nil {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+// TTL implements goukv.TTL
+func (p Provider) TTL(k []byte) (*time.Time, error) {
+	ttl, err := p.client.PTTL(context.Background(), string(k)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	// -2 means that the key doesn't exist and -1 means that it has no expiration
+	if ttl == -2 {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	if ttl < 0 {
+		return nil, nil
+	}
+
+	expires := time.Now().Add(ttl)
+
+	return &expires, nil
+}
+
+// Delete implements goukv.Delete
+func (p Provider) Delete(k []byte) error {
+	return p.client.Del(context.Background(), string(k)).Err()
+}
+
+// Native implements goukv.NativeAccessor, it returns the underlying *redis.ClusterClient
+func (p Provider) Native() interface{} {
+	return p.client
+}
+
+// Close implements goukv.Close
+func (p Provider) Close() error {
+	return p.client.Close()
+}
+
+// keys returns the sorted keys matching the specified prefix, SCAN is run on every primary
+func (p Provider) keys(ctx context.Context, prefix []byte) ([][]byte, error) {
+	var keys [][]byte
+	var keysLock sync.Mutex
+
+	match := escapeGlob(prefix) + "*"
+	err := p.client.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		iter := node.Scan(ctx, 0, match, 1000).Iterator()
+		for iter.Next(ctx) {
+			keysLock.Lock()
+			keys = append(keys, []byte(iter.Val()))
+			keysLock.Unlock()
+		}
+		return iter.Err()
+	})
+
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	})
+
+	return keys, err
+}
+
+// Scan implements goukv.Scan, the keys of all the primaries are collected and sorted
+// before their values are fetched in pipelined pages, so a scan holds all the matched keys in memory
+func (p Provider) Scan(opts goukv.ScanOpts) error {
+	if opts.Scanner == nil {
+		return goukv.ErrNoScanner
+	}
+
+	opts.Scanner = opts.BoundedScanner()
+
+	ctx := context.Background()
+
+	keys, err := p.keys(ctx, opts.Prefix)
+	if err != nil {
+		return err
+	}
+
+	if opts.ReverseScan {
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+
+	if opts.Offset != nil {
+		start := len(keys)
+		for i, k := range keys {
+			cmp := bytes.Compare(k, opts.Offset)
+			if opts.ReverseScan {
+				cmp = -cmp
+			}
+			if cmp > 0 || (cmp == 0 && opts.IncludeOffset) {
+				start = i
+				break
+			}
+		}
+		keys = keys[start:]
+	}
+
+	tick := opts.ProgressTicker()
+
+	for len(keys) > 0 {
+		page := keys
+		if len(page) > 1000 {
+			page = page[:1000]
+		}
+		keys = keys[len(page):]
+
+		cmds := make([]*redis.StringCmd, len(page))
+		_, err := p.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, k := range page {
+				cmds[i] = pipe.Get(ctx, string(k))
+			}
+			return nil
+		})
+		if err != nil && err != redis.Nil {
+			return err
+		}
+
+		for i, k := range page {
+			tick()
+
+			// the key may have expired or been deleted since it was listed
+			val, err := cmds[i].Bytes()
+			if err == redis.Nil {
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if err := opts.Scanner(k, val); err != nil {
+				if err == goukv.ErrScanDone {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// escapeGlob escapes the glob special characters of the specified prefix for SCAN MATCH
+func escapeGlob(prefix []byte) string {
+	var b strings.Builder
+	for _, c := range prefix {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
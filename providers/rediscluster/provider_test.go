@@ -0,0 +1,141 @@
+//go:build rediscluster
+// +build rediscluster
+
+package rediscluster
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+	"github.com/go-redis/redis/v8"
+)
+
+// openDBAndDo opens a provider against the cluster in REDIS_CLUSTER_ADDRS,
+// the test is skipped when no cluster is configured
+func openDBAndDo(t *testing.T, fn func(db goukv.Provider)) {
+	addrs := os.Getenv("REDIS_CLUSTER_ADDRS")
+	if addrs == "" {
+		t.Skip("REDIS_CLUSTER_ADDRS isn't set")
+	}
+
+	p := Provider{}
+	db, err := p.Open(map[string]interface{}{
+		"addrs": addrs,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer db.(*Provider).client.ForEachMaster(context.Background(), func(ctx context.Context, node *redis.Client) error {
+		return node.FlushDB(ctx).Err()
+	})
+
+	fn(db)
+}
+
+func TestSlot(t *testing.T) {
+	cases := map[string]int{
+		"foo":                  12182,
+		"somekey":              11058,
+		"{user1000}.following": 3443,
+		"{user1000}.followers": 3443,
+		"foo{}{bar}":           8363,
+	}
+
+	for k, expected := range cases {
+		if slot := Slot([]byte(k)); slot != expected {
+			t.Errorf("expected slot (%d) for (%s), found (%d)", expected, k, slot)
+		}
+	}
+
+	if Slot(HashTag("user1000", []byte("a"))) != Slot(HashTag("user1000", []byte("b"))) {
+		t.Error("expected keys with the same hash tag to share the slot")
+	}
+}
+
+func TestPutGet(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("k"),
+			Value: []byte("v"),
+		}
+		err := db.Put(&entry)
+		if err != nil {
+			t.Error(err)
+		}
+		val, err := db.Get(entry.Key)
+		if err != nil {
+			t.Error(err)
+		}
+		if string(val) != string(entry.Value) {
+			t.Errorf("expected (%s), found(%s)", string(entry.Value), string(val))
+		}
+
+		if err := db.Delete(entry.Key); err != nil {
+			t.Error(err)
+		}
+		if _, err := db.Get(entry.Key); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected ErrKeyNotFound, found (%v)", err)
+		}
+	})
+}
+
+func TestTTL(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("k"),
+			Value: []byte("v"),
+			TTL:   time.Second * 10,
+		}
+		if err := db.Put(&entry); err != nil {
+			t.Error(err)
+		}
+
+		expires, err := db.TTL(entry.Key)
+		if err != nil {
+			t.Error(err)
+		}
+		if expires == nil || time.Until(*expires) > entry.TTL || time.Until(*expires) < time.Second*5 {
+			t.Errorf("unexpected expiration (%v)", expires)
+		}
+
+		if _, err := db.TTL([]byte("missing")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected ErrKeyNotFound, found (%v)", err)
+		}
+	})
+}
+
+func TestBatchScan(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		// the keys are spread over several slots (and nodes)
+		err := db.Batch([]*goukv.Entry{
+			{Key: []byte("a1"), Value: []byte("v")},
+			{Key: []byte("a2"), Value: []byte("v")},
+			{Key: []byte("a3"), Value: []byte("v")},
+			{Key: []byte("b1"), Value: []byte("v")},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var found []string
+		err = db.Scan(goukv.ScanOpts{
+			Prefix:      []byte("a"),
+			Offset:      []byte("a3"),
+			ReverseScan: true,
+			Scanner: func(k, v []byte) error {
+				found = append(found, string(k))
+				return nil
+			},
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		if len(found) != 2 || found[0] != "a2" || found[1] != "a1" {
+			t.Errorf("expected ([a2 a1]), found (%v)", found)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+//go:build rediscluster
+// +build rediscluster
+
+package rediscluster
+
+import "bytes"
+
+// SlotCount the number of hash slots of a redis cluster
+const SlotCount = 16384
+
+// HashTag returns the specified key prefixed with the {tag} hash tag,
+// keys sharing the same tag are stored in the same slot
+func HashTag(tag string, key []byte) []byte {
+	k := make([]byte, 0, len(tag)+len(key)+2)
+	k = append(k, '{')
+	k = append(k, tag...)
+	k = append(k, '}')
+	return append(k, key...)
+}
+
+// Slot returns the hash slot of the specified key, only the hash tag is hashed if the key has a non-empty one
+func Slot(key []byte) int {
+	if start := bytes.IndexByte(key, '{'); start >= 0 {
+		if end := bytes.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+
+	return int(crc16(key)) % SlotCount
+}
+
+// crc16 the CRC16-CCITT (XMODEM) checksum used by redis cluster
+func crc16(b []byte) uint16 {
+	var crc uint16
+	for _, c := range b {
+		crc ^= uint16(c) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,11 @@
+package remote
+
+import "github.com/alash3al/goukv"
+
+const (
+	name = "remote"
+)
+
+func init() {
+	goukv.Register(name, Provider{})
+}
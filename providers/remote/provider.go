@@ -0,0 +1,338 @@
+package remote
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/alash3al/goukv"
+	"github.com/alash3al/goukv/internal/wire"
+)
+
+// knownErrors the goukv errors a server may respond with, they are returned as is so they can be compared
+var knownErrors = []error{
+	goukv.ErrKeyNotFound,
+	goukv.ErrNoScanner,
+	goukv.ErrNotSupported,
+	goukv.ErrInvalidKey,
+	goukv.ErrEmptyKey,
+	goukv.ErrKeyTooLarge,
+	goukv.ErrValueTooLarge,
+	goukv.ErrClosed,
+	goukv.ErrConflict,
+}
+
+// Provider represents a provider
+type Provider struct {
+	addr      string
+	tlsConfig *tls.Config
+	timeout   time.Duration
+
+	// idle the pool of the idle connections
+	idle   chan *conn
+	closed *int32
+}
+
+// conn a connection to the server
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// Open implements goukv.Open
+func (p Provider) Open(opts map[string]interface{}) (goukv.Provider, error) {
+	addr, ok := opts["addr"].(string)
+	if !ok {
+		return nil, errors.New("must specify addr")
+	}
+
+	tlsConfig, _ := opts["tls_config"].(*tls.Config)
+
+	timeout, ok := opts["timeout"].(time.Duration)
+	if !ok {
+		timeout = 30 * time.Second
+	}
+
+	poolSize, ok := opts["pool_size"].(int)
+	if !ok || poolSize < 1 {
+		poolSize = 8
+	}
+
+	db := &Provider{
+		addr:      addr,
+		tlsConfig: tlsConfig,
+		timeout:   timeout,
+		idle:      make(chan *conn, poolSize),
+		closed:    new(int32),
+	}
+
+	if _, err := db.call(&wire.Request{Op: wire.OpPing}); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// dial opens a new connection to the server
+func (p Provider) dial() (*conn, error) {
+	dialer := &net.Dialer{Timeout: p.timeout}
+
+	var c net.Conn
+	var err error
+
+	if p.tlsConfig != nil {
+		c, err = tls.DialWithDialer(dialer, "tcp", p.addr, p.tlsConfig)
+	} else {
+		c, err = dialer.Dial("tcp", p.addr)
+	}
+
+	if err != nil {
+		return nil, goukv.WrapError(goukv.ErrorTransient, err)
+	}
+
+	return &conn{Conn: c, r: bufio.NewReader(c), w: bufio.NewWriter(c)}, nil
+}
+
+// acquire returns an idle connection or a new one
+func (p Provider) acquire() (*conn, error) {
+	if atomic.LoadInt32(p.closed) == 1 {
+		return nil, goukv.ErrClosed
+	}
+
+	select {
+	case c := <-p.idle:
+		return c, nil
+	default:
+		return p.dial()
+	}
+}
+
+// release puts the specified connection back into the pool, it's closed when the pool is full or the provider closed
+func (p Provider) release(c *conn) {
+	if atomic.LoadInt32(p.closed) == 1 {
+		c.Close()
+		return
+	}
+
+	select {
+	case p.idle <- c:
+	default:
+		c.Close()
+	}
+}
+
+// done releases the specified connection once its last response is read, unless the server closes it
+func (p Provider) done(c *conn, res *wire.Response) {
+	if res.Close {
+		c.Close()
+		return
+	}
+
+	p.release(c)
+}
+
+// send writes the specified request to the specified connection
+func (p Provider) send(c *conn, req *wire.Request) error {
+	if err := wire.Write(c.w, req); err != nil {
+		return err
+	}
+
+	return c.w.Flush()
+}
+
+// call sends the specified request and reads its response within the timeout, a broken connection is discarded
+// and its error is classified as transient, the error of the response is returned as an error
+func (p Provider) call(req *wire.Request) (*wire.Response, error) {
+	c, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	c.SetDeadline(time.Now().Add(p.timeout))
+
+	var res wire.Response
+	if err := p.send(c, req); err != nil {
+		c.Close()
+		return nil, goukv.WrapError(goukv.ErrorTransient, err)
+	}
+
+	if err := wire.Read(c.r, &res); err != nil {
+		c.Close()
+		return nil, goukv.WrapError(goukv.ErrorTransient, err)
+	}
+
+	c.SetDeadline(time.Time{})
+	p.done(c, &res)
+
+	return &res, responseError(&res)
+}
+
+// responseError returns the error of the specified response, the known goukv errors are returned as is
+func responseError(res *wire.Response) error {
+	if res.Err == "" {
+		return nil
+	}
+
+	var err error
+	for _, known := range knownErrors {
+		if known.Error() == res.Err {
+			err = known
+			break
+		}
+	}
+
+	if err == nil {
+		err = errors.New(res.Err)
+	}
+
+	if res.ErrKind > 0 {
+		return goukv.WrapError(goukv.ErrorKind(res.ErrKind), err)
+	}
+
+	return err
+}
+
+// toWire returns the wire entry of the specified entry
+func toWire(entry *goukv.Entry) *wire.Entry {
+	return &wire.Entry{
+		Key:      entry.Key,
+		Value:    entry.Value,
+		TTL:      entry.TTL,
+		ExpireAt: entry.ExpireAt,
+	}
+}
+
+// Put implements goukv.Put
+func (p Provider) Put(entry *goukv.Entry) error {
+	_, err := p.call(&wire.Request{Op: wire.OpPut, Entries: []*wire.Entry{toWire(entry)}})
+	return err
+}
+
+// Batch perform multi put operation, empty value means *delete*, it's applied by the served provider as is
+func (p Provider) Batch(entries []*goukv.Entry) error {
+	req := &wire.Request{Op: wire.OpBatch, Entries: make([]*wire.Entry, len(entries))}
+	for i, entry := range entries {
+		req.Entries[i] = toWire(entry)
+	}
+
+	_, err := p.call(req)
+	return err
+}
+
+// Get implements goukv.Get
+func (p Provider) Get(k []byte) ([]byte, error) {
+	res, err := p.call(&wire.Request{Op: wire.OpGet, Key: k})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Value, nil
+}
+
+// TTL implements goukv.TTL
+func (p Provider) TTL(k []byte) (*time.Time, error) {
+	res, err := p.call(&wire.Request{Op: wire.OpTTL, Key: k})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Expires, nil
+}
+
+// Delete implements goukv.Delete
+func (p Provider) Delete(k []byte) error {
+	_, err := p.call(&wire.Request{Op: wire.OpDelete, Key: k})
+	return err
+}
+
+// Close implements goukv.Close, the idle connections are closed and the busy ones once released
+func (p Provider) Close() error {
+	atomic.StoreInt32(p.closed, 1)
+
+	for {
+		select {
+		case c := <-p.idle:
+			c.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// Scan implements goukv.Scan, the server streams the scanned pairs over a dedicated connection (no timeout applies)
+// as the scanner consumes them, stopping the scan early closes that connection so the server stops scanning
+func (p Provider) Scan(opts goukv.ScanOpts) error {
+	if opts.Scanner == nil {
+		return goukv.ErrNoScanner
+	}
+
+	// the filters, the sample and the scanner error policy run on the client, so MaxBytes must be enforced after them
+	opts = opts.Sampled()
+	maxBytes := opts.MaxBytes
+	if opts.KeyFilter != nil || opts.Filter != nil || opts.OnScannerError != nil {
+		opts.Scanner, maxBytes = opts.BoundedScanner(), 0
+	}
+
+	c, err := p.acquire()
+	if err != nil {
+		return err
+	}
+
+	req := &wire.Request{
+		Op: wire.OpScan,
+		Scan: &wire.Scan{
+			Prefix:            opts.Prefix,
+			Offset:            opts.Offset,
+			IncludeOffset:     opts.IncludeOffset,
+			ReverseScan:       opts.ReverseScan,
+			Consistent:        opts.Consistent,
+			SinceVersion:      opts.SinceVersion,
+			IncludeTombstones: opts.IncludeTombstones,
+			MaxBytes:          maxBytes,
+			IncludeInternal:   opts.IncludeInternal,
+		},
+	}
+
+	if opts.Progress != nil {
+		req.Scan.ProgressInterval = opts.ProgressInterval
+		if req.Scan.ProgressInterval <= 0 {
+			req.Scan.ProgressInterval = goukv.DefaultProgressInterval
+		}
+	}
+
+	if err := p.send(c, req); err != nil {
+		c.Close()
+		return goukv.WrapError(goukv.ErrorTransient, err)
+	}
+
+	for {
+		var res wire.Response
+		if err := wire.Read(c.r, &res); err != nil {
+			c.Close()
+			return goukv.WrapError(goukv.ErrorTransient, err)
+		}
+
+		if res.Done {
+			p.done(c, &res)
+			return responseError(&res)
+		}
+
+		if res.Scanned > 0 {
+			opts.Progress(res.Scanned)
+			continue
+		}
+
+		if err := opts.Scanner(res.Key, res.Value); err != nil {
+			c.Close()
+			if err == goukv.ErrScanDone {
+				return nil
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+	"github.com/alash3al/goukv/internal/wire"
+	leveldb "github.com/alash3al/goukv/providers/goleveldb"
+)
+
+// openDBWithOptsAndDo serves a goleveldb db (opened with the specified options) on a local port then opens a client of it
+func openDBWithOptsAndDo(opts map[string]interface{}, fn func(db goukv.Provider)) error {
+	opts["path"] = "./db"
+
+	local, err := leveldb.Provider{}.Open(opts)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll("./db")
+	defer local.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+
+	server := goukv.NewServer(local)
+	defer server.Close()
+	go server.Serve(l)
+
+	db, err := Provider{}.Open(map[string]interface{}{"addr": l.Addr().String(), "pool_size": 2})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	fn(db)
+
+	return nil
+}
+
+func openDBAndDo(fn func(db goukv.Provider)) error {
+	return openDBWithOptsAndDo(map[string]interface{}{}, fn)
+}
+
+func TestPutGetDelete(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		if err := db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")}); err != nil {
+			t.Fatal(err)
+		}
+
+		if v, err := db.Get([]byte("k")); err != nil || string(v) != "v" {
+			t.Errorf("expected (v), found (%s, %v)", v, err)
+		}
+
+		if err := db.Put(&goukv.Entry{Key: []byte("empty"), Value: []byte{}}); err != nil {
+			t.Fatal(err)
+		}
+
+		if v, err := db.Get([]byte("empty")); err != nil || v == nil || len(v) != 0 {
+			t.Errorf("expected an empty value, found (%v, %v)", v, err)
+		}
+
+		if err := db.Delete([]byte("k")); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := db.Get([]byte("k")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected (%v), found (%v)", goukv.ErrKeyNotFound, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		expires := time.Now().Add(time.Hour)
+
+		if err := db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v"), ExpireAt: &expires}); err != nil {
+			t.Fatal(err)
+		}
+
+		if ttl, err := db.TTL([]byte("k")); err != nil || ttl == nil || !goukv.SameExpiry(ttl, &expires) {
+			t.Errorf("expected (%v), found (%v, %v)", expires, ttl, err)
+		}
+
+		db.Put(&goukv.Entry{Key: []byte("persistent"), Value: []byte("v")})
+		if ttl, err := db.TTL([]byte("persistent")); err != nil || ttl != nil {
+			t.Errorf("expected no expiration, found (%v, %v)", ttl, err)
+		}
+
+		db.Put(&goukv.Entry{Key: []byte("short"), Value: []byte("v"), TTL: time.Millisecond})
+		time.Sleep(5 * time.Millisecond)
+		if _, err := db.Get([]byte("short")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected (%v), found (%v)", goukv.ErrKeyNotFound, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		db.Put(&goukv.Entry{Key: []byte("deleted"), Value: []byte("v")})
+
+		err := db.Batch([]*goukv.Entry{
+			{Key: []byte("k1"), Value: []byte("v1")},
+			{Key: []byte("k2"), Value: []byte("v2")},
+			{Key: []byte("deleted")},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for k, v := range map[string]string{"k1": "v1", "k2": "v2"} {
+			if found, err := db.Get([]byte(k)); err != nil || string(found) != v {
+				t.Errorf("expected (%s), found (%s, %v)", v, found, err)
+			}
+		}
+
+		if _, err := db.Get([]byte("deleted")); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected a nil value to delete the key, found (%v)", err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		for i := 0; i < 100; i++ {
+			db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%03d", i)), Value: []byte(fmt.Sprintf("v%03d", i))})
+		}
+		db.Put(&goukv.Entry{Key: []byte("other"), Value: []byte("v")})
+
+		var keys []string
+		err := db.Scan(goukv.ScanOpts{
+			Prefix:      []byte("k"),
+			ReverseScan: true,
+			Scanner: func(k, v []byte) error {
+				if string(v) != "v"+string(k[1:]) {
+					t.Errorf("unexpected value (%s) of (%s)", v, k)
+				}
+				keys = append(keys, string(k))
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(keys) != 100 || keys[0] != "k099" || keys[99] != "k000" {
+			t.Errorf("expected the 100 keys in reverse, found (%d) keys", len(keys))
+		}
+
+		// stopping a scan early discards its connection, the next calls use new ones
+		for i := 0; i < 5; i++ {
+			count := 0
+			err := db.Scan(goukv.ScanOpts{
+				Scanner: func(k, v []byte) error {
+					if count++; count == 10 {
+						return goukv.ErrScanDone
+					}
+					return nil
+				},
+			})
+			if err != nil || count != 10 {
+				t.Errorf("expected the scan to stop after (10) keys, found (%d, %v)", count, err)
+			}
+		}
+
+		var reports []int64
+		db.Scan(goukv.ScanOpts{
+			ProgressInterval: 40,
+			Progress: func(scanned int64) {
+				reports = append(reports, scanned)
+			},
+			Scanner: func(k, v []byte) error {
+				return nil
+			},
+		})
+		if fmt.Sprint(reports) != "[40 80]" {
+			t.Errorf("expected the progress to be reported, found (%v)", reports)
+		}
+
+		if v, err := db.Get([]byte("other")); err != nil || string(v) != "v" {
+			t.Errorf("expected (v), found (%s, %v)", v, err)
+		}
+
+		// the filters run on the client, before MaxBytes is enforced
+		var filtered []string
+		db.Scan(goukv.ScanOpts{
+			Prefix:   []byte("k"),
+			MaxBytes: 8,
+			Filter: func(k, v []byte) bool {
+				return k[len(k)-1] == '7'
+			},
+			Scanner: func(k, v []byte) error {
+				filtered = append(filtered, string(k))
+				return nil
+			},
+		})
+		if fmt.Sprint(filtered) != "[k007 k017]" {
+			t.Errorf("expected ([k007 k017]), found (%v)", filtered)
+		}
+
+		// the scanner error policy runs on the client too
+		var delivered, failed []string
+		err = db.Scan(goukv.ScanOpts{
+			Prefix: []byte("k00"),
+			OnScannerError: func(k []byte, err error) bool {
+				failed = append(failed, string(k))
+				return true
+			},
+			Scanner: func(k, v []byte) error {
+				if k[len(k)-1] == '5' {
+					return errors.New("bad record")
+				}
+				delivered = append(delivered, string(k))
+				return nil
+			},
+		})
+		if err != nil || len(delivered) != 9 || fmt.Sprint(failed) != "[k005]" {
+			t.Errorf("expected the bad record to be skipped, found (%v, %v, %v)", delivered, failed, err)
+		}
+
+		if err := db.Scan(goukv.ScanOpts{}); err != goukv.ErrNoScanner {
+			t.Errorf("expected (%v), found (%v)", goukv.ErrNoScanner, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	err := openDBWithOptsAndDo(map[string]interface{}{"max_value_size": 4}, func(db goukv.Provider) {
+		if err := db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("too large")}); err != goukv.ErrValueTooLarge {
+			t.Errorf("expected (%v), found (%v)", goukv.ErrValueTooLarge, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, err := (Provider{}).Open(map[string]interface{}{"addr": "127.0.0.1:1", "timeout": time.Second}); !goukv.IsErrorKind(err, goukv.ErrorTransient) {
+		t.Errorf("expected an unreachable server to be a transient error, found (%v)", err)
+	}
+}
+
+func TestClosed(t *testing.T) {
+	err := openDBAndDo(func(db goukv.Provider) {
+		db.Close()
+
+		if _, err := db.Get([]byte("k")); err != goukv.ErrClosed {
+			t.Errorf("expected (%v), found (%v)", goukv.ErrClosed, err)
+		}
+	})
+
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// panickyProvider a provider whose Get panics
+type panickyProvider struct {
+	goukv.Provider
+}
+
+func (panickyProvider) Get([]byte) ([]byte, error) {
+	panic("get")
+}
+
+// lockedBuffer a buffer safe for concurrent use
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func TestServerLimits(t *testing.T) {
+	local, err := leveldb.Provider{}.Open(map[string]interface{}{"path": "./db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./db")
+	defer local.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errorLog := &lockedBuffer{}
+
+	server := goukv.NewServer(panickyProvider{local})
+	server.MaxRequestSize = 1 << 10
+	server.ErrorLog = log.New(errorLog, "", 0)
+	defer server.Close()
+	go server.Serve(l)
+
+	// call sends the specified request on a new connection, it returns its response
+	call := func(req interface{}) (*wire.Response, error) {
+		c, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+
+		if err := wire.Write(c, req); err != nil {
+			return nil, err
+		}
+
+		var res wire.Response
+		err = wire.Read(bufio.NewReader(c), &res)
+		return &res, err
+	}
+
+	for _, op := range []wire.Op{wire.OpPut, wire.OpBatch} {
+		res, err := call(&wire.Request{Op: op, Entries: []*wire.Entry{nil}})
+		if err != nil || res.Err == "" {
+			t.Errorf("expected the nil entry to be rejected, found (%v, %v)", res, err)
+		}
+	}
+
+	res, err := call(&wire.Request{Op: wire.OpPut, Entries: []*wire.Entry{{Key: []byte("k"), Value: make([]byte, 2<<10)}}})
+	if err != nil || res.Err != wire.ErrFrameTooLarge.Error() {
+		t.Errorf("expected the large request to be rejected, found (%v, %v)", res, err)
+	}
+
+	// a frame announcing more than it sends isn't allocated upfront
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Write([]byte{0x3f, 0xff, 0xff, 0xff})
+	var header wire.Response
+	if err := wire.Read(bufio.NewReader(c), &header); err != nil || header.Err == "" {
+		t.Errorf("expected the announced size to be rejected, found (%v, %v)", header, err)
+	}
+	c.Close()
+
+	// the server still serves the other requests
+	db, err := Provider{}.Open(map[string]interface{}{"addr": l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// a panic is answered by an error, logged, then only its connection is closed
+	if _, err := db.Get([]byte("k")); err == nil || !strings.Contains(err.Error(), "panicked") {
+		t.Errorf("expected the panic to be answered by an error, found (%v)", err)
+	}
+
+	if logged := errorLog.String(); !strings.Contains(logged, "get") || !strings.Contains(logged, "panickyProvider") {
+		t.Errorf("expected the panic value and its stack to be logged, found (%s)", logged)
+	}
+
+	if err := db.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")}); err != nil {
+		t.Error(err)
+	}
+}
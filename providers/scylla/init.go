@@ -0,0 +1,14 @@
+//go:build scylla
+// +build scylla
+
+package scylla
+
+import "github.com/alash3al/goukv"
+
+const (
+	name = "scylla"
+)
+
+func init() {
+	goukv.Register(name, Provider{})
+}
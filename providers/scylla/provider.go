@@ -0,0 +1,238 @@
+//go:build scylla
+// +build scylla
+
+package scylla
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/alash3al/goukv"
+	"github.com/gocql/gocql"
+)
+
+// Provider represents a provider
+type Provider struct {
+	session   *gocql.Session
+	table     string
+	batchType gocql.BatchType
+}
+
+// Open implements goukv.Open
+func (p Provider) Open(opts map[string]interface{}) (goukv.Provider, error) {
+	var hosts []string
+	switch v := opts["hosts"].(type) {
+	case []string:
+		hosts = v
+	case string:
+		hosts = strings.Split(v, ",")
+	}
+
+	if len(hosts) < 1 {
+		return nil, errors.New("must specify hosts")
+	}
+
+	keyspace, ok := opts["keyspace"].(string)
+	if !ok {
+		return nil, errors.New("must specify keyspace")
+	}
+
+	table, ok := opts["table"].(string)
+	if !ok {
+		table = "kv"
+	}
+
+	consistency, ok := opts["consistency"].(string)
+	if !ok {
+		consistency = "QUORUM"
+	}
+
+	batchType := gocql.LoggedBatch
+	if logged, ok := opts["logged_batch"].(bool); ok && !logged {
+		batchType = gocql.UnloggedBatch
+	}
+
+	cluster := gocql.NewCluster(hosts...)
+	cluster.Keyspace = keyspace
+
+	c, err := gocql.ParseConsistencyWrapper(consistency)
+	if err != nil {
+		return nil, err
+	}
+	cluster.Consistency = c
+
+	session, err := cluster.CreateSession()
+	if err != nil {
+		return nil, err
+	}
+
+	err = session.Query("CREATE TABLE IF NOT EXISTS " + table + " (key blob PRIMARY KEY, value blob)").Exec()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	return &Provider{
+		session:   session,
+		table:     table,
+		batchType: batchType,
+	}, nil
+}
+
+// ttlSeconds returns the cassandra TTL of the specified entry (0 means no TTL),
+// expired is true when its ExpireAt is already in the past
+func ttlSeconds(entry *goukv.Entry) (ttl int, expired bool) {
+	d := entry.TTL
+	if entry.ExpireAt != nil {
+		d = time.Until(*entry.ExpireAt)
+		if d <= 0 {
+			return 0, true
+		}
+	}
+
+	if d <= 0 {
+		return 0, false
+	}
+
+	ttl = int(d / time.Second)
+	if d%time.Second > 0 {
+		ttl++
+	}
+
+	return ttl, false
+}
+
+// statement returns the statement and its args that writes the specified entry, nil value means *delete*
+func (p Provider) statement(entry *goukv.Entry) (string, []interface{}) {
+	ttl, expired := ttlSeconds(entry)
+	if entry.Value == nil || expired {
+		return "DELETE FROM " + p.table + " WHERE key = ?", []interface{}{entry.Key}
+	}
+
+	return "INSERT INTO " + p.table + " (key, value) VALUES (?, ?) USING TTL ?", []interface{}{entry.Key, entry.Value, ttl}
+}
+
+// Put implements goukv.Put
+func (p Provider) Put(entry *goukv.Entry) error {
+	if entry.Value == nil {
+		entry = &goukv.Entry{Key: entry.Key, Value: []byte{}, TTL: entry.TTL, ExpireAt: entry.ExpireAt}
+	}
+
+	stmt, args := p.statement(entry)
+
+	return p.session.Query(stmt, args...).Exec()
+}
+
+// Batch perform multi put operation, empty value means *delete*,
+// the statements of a batch share a timestamp so only the last entry of each key is sent
+func (p Provider) Batch(entries []*goukv.Entry) error {
+	batch := p.session.NewBatch(p.batchType)
+	for _, entry := range goukv.LastWrites(entries) {
+		stmt, args := p.statement(entry)
+		batch.Query(stmt, args...)
+	}
+
+	return p.session.ExecuteBatch(batch)
+}
+
+// Get implements goukv.Get
+func (p Provider) Get(k []byte) ([]byte, error) {
+	var val []byte
+	err := p.session.Query("SELECT value FROM "+p.table+" WHERE key = ?", k).Scan(&val)
+	if err == gocql.ErrNotFound {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+// TTL implements goukv.TTL
+func (p Provider) TTL(k []byte) (*time.Time, error) {
+	var ttl *int
+	err := p.session.Query("SELECT TTL(value) FROM "+p.table+" WHERE key = ?", k).Scan(&ttl)
+	if err == gocql.ErrNotFound {
+		return nil, goukv.ErrKeyNotFound
+	}
+
+	if err != nil || ttl == nil {
+		return nil, err
+	}
+
+	expires := time.Now().Add(time.Duration(*ttl) * time.Second)
+
+	return &expires, nil
+}
+
+// Delete implements goukv.Delete
+func (p Provider) Delete(k []byte) error {
+	return p.session.Query("DELETE FROM "+p.table+" WHERE key = ?", k).Exec()
+}
+
+// Native implements goukv.NativeAccessor, it returns the underlying *gocql.Session
+func (p Provider) Native() interface{} {
+	return p.session
+}
+
+// Close implements goukv.Close
+func (p Provider) Close() error {
+	p.session.Close()
+	return nil
+}
+
+// Scan implements goukv.Scan, the rows are partitioned by key so they are iterated
+// in token order (not key order), the prefix is filtered client side and the offset
+// resumes the iteration after the token of the offset key, reverse scans aren't supported
+func (p Provider) Scan(opts goukv.ScanOpts) error {
+	if opts.Scanner == nil {
+		return goukv.ErrNoScanner
+	}
+
+	opts.Scanner = opts.BoundedScanner()
+
+	if opts.ReverseScan {
+		return goukv.ErrNotSupported
+	}
+
+	var query *gocql.Query
+	if opts.Offset != nil {
+		op := ">"
+		if opts.IncludeOffset {
+			op = ">="
+		}
+		query = p.session.Query("SELECT key, value FROM "+p.table+" WHERE token(key) "+op+" token(?)", opts.Offset)
+	} else {
+		query = p.session.Query("SELECT key, value FROM " + p.table)
+	}
+
+	tick := opts.ProgressTicker()
+
+	iter := query.PageSize(1000).Iter()
+	for {
+		var k, v []byte
+		if !iter.Scan(&k, &v) {
+			break
+		}
+
+		tick()
+
+		if !bytes.HasPrefix(k, opts.Prefix) {
+			continue
+		}
+
+		if err := opts.Scanner(k, v); err != nil {
+			iter.Close()
+			if err == goukv.ErrScanDone {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return iter.Close()
+}
@@ -0,0 +1,121 @@
+//go:build scylla
+// +build scylla
+
+package scylla
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+// openDBAndDo opens a provider against the cluster in SCYLLA_HOSTS (keyspace SCYLLA_KEYSPACE, defaults to "goukv"),
+// the test is skipped when no cluster is configured
+func openDBAndDo(t *testing.T, fn func(db goukv.Provider)) {
+	hosts := os.Getenv("SCYLLA_HOSTS")
+	if hosts == "" {
+		t.Skip("SCYLLA_HOSTS isn't set")
+	}
+
+	keyspace := os.Getenv("SCYLLA_KEYSPACE")
+	if keyspace == "" {
+		keyspace = "goukv"
+	}
+
+	p := Provider{}
+	db, err := p.Open(map[string]interface{}{
+		"hosts":    hosts,
+		"keyspace": keyspace,
+		"table":    "goukv_test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	defer db.(*Provider).session.Query("TRUNCATE goukv_test").Exec()
+
+	fn(db)
+}
+
+func TestPutGet(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("k"),
+			Value: []byte("v"),
+		}
+		err := db.Put(&entry)
+		if err != nil {
+			t.Error(err)
+		}
+		val, err := db.Get(entry.Key)
+		if err != nil {
+			t.Error(err)
+		}
+		if string(val) != string(entry.Value) {
+			t.Errorf("expected (%s), found(%s)", string(entry.Value), string(val))
+		}
+
+		if err := db.Delete(entry.Key); err != nil {
+			t.Error(err)
+		}
+		if _, err := db.Get(entry.Key); err != goukv.ErrKeyNotFound {
+			t.Errorf("expected ErrKeyNotFound, found (%v)", err)
+		}
+	})
+}
+
+func TestTTL(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		entry := goukv.Entry{
+			Key:   []byte("k"),
+			Value: []byte("v"),
+			TTL:   time.Second * 10,
+		}
+		if err := db.Put(&entry); err != nil {
+			t.Error(err)
+		}
+
+		expires, err := db.TTL(entry.Key)
+		if err != nil {
+			t.Error(err)
+		}
+		if expires == nil || time.Until(*expires) > entry.TTL || time.Until(*expires) < time.Second*5 {
+			t.Errorf("unexpected expiration (%v)", expires)
+		}
+	})
+}
+
+func TestScanPrefix(t *testing.T) {
+	openDBAndDo(t, func(db goukv.Provider) {
+		err := db.Batch([]*goukv.Entry{
+			{Key: []byte("a1"), Value: []byte("v")},
+			{Key: []byte("a2"), Value: []byte("v")},
+			{Key: []byte("b1"), Value: []byte("v")},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		found := 0
+		err = db.Scan(goukv.ScanOpts{
+			Prefix: []byte("a"),
+			Scanner: func(k, v []byte) error {
+				found++
+				return nil
+			},
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		if found != 2 {
+			t.Errorf("expected (2) keys, found (%d)", found)
+		}
+
+		err = db.Scan(goukv.ScanOpts{ReverseScan: true, Scanner: func(k, v []byte) error { return nil }})
+		if err != goukv.ErrNotSupported {
+			t.Errorf("expected ErrNotSupported, found (%v)", err)
+		}
+	})
+}
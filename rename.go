@@ -0,0 +1,139 @@
+package goukv
+
+import "bytes"
+
+// DefaultRenameBatchSize the number of keys RenamePrefix moves per batch by default
+const DefaultRenameBatchSize = 1000
+
+// RenameOpts the options of RenamePrefix
+type RenameOpts struct {
+	// BatchSize the number of keys moved per batch, DefaultRenameBatchSize when it's zero
+	BatchSize int
+}
+
+// RenamePrefix moves every live key under oldPrefix to newPrefix (the rest of the key is kept) with its value and
+// expiration, it returns the number of moved keys. the keys are read in pages of BatchSize then each page is written
+// with a single Batch holding the new keys and the deletes of the old ones, so a page is atomic on the providers whose
+// batches are (i.e goleveldb, badgerdb) but the rename as a whole isn't: a failure leaves the pages before it moved,
+// and calling it again resumes it. an existing key under newPrefix is overwritten, and ErrOverlappingPrefixes is
+// returned when a prefix is a prefix of the other one (including an empty one) since the moved keys would be moved again
+func RenamePrefix(p Provider, oldPrefix, newPrefix []byte, opts RenameOpts) (int64, error) {
+	if bytes.HasPrefix(oldPrefix, newPrefix) || bytes.HasPrefix(newPrefix, oldPrefix) {
+		return 0, ErrOverlappingPrefixes
+	}
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultRenameBatchSize
+	}
+
+	var moved int64
+	var offset []byte
+	for {
+		var keys, values [][]byte
+		err := p.Scan(ScanOpts{
+			Prefix: oldPrefix,
+			Offset: offset,
+			Scanner: func(k, v []byte) error {
+				keys, values = append(keys, k), append(values, v)
+				if len(keys) == opts.BatchSize {
+					return ErrScanDone
+				}
+				return nil
+			},
+		})
+		if err != nil {
+			return moved, err
+		}
+
+		if len(keys) == 0 {
+			return moved, nil
+		}
+
+		entries := make([]*Entry, 0, len(keys)*2)
+		for i, k := range keys {
+			expires, err := p.TTL(k)
+			if err == ErrKeyNotFound {
+				// deleted or expired since it was scanned
+				continue
+			}
+
+			if err != nil {
+				return moved, err
+			}
+
+			newKey := append(append([]byte{}, newPrefix...), k[len(oldPrefix):]...)
+			entries = append(entries, &Entry{Key: newKey, Value: values[i], ExpireAt: expires}, &Entry{Key: k})
+		}
+
+		if err := p.Batch(entries); err != nil {
+			return moved, err
+		}
+
+		moved += int64(len(entries) / 2)
+
+		if len(keys) < opts.BatchSize {
+			return moved, nil
+		}
+
+		offset = keys[len(keys)-1]
+	}
+}
+
+// SwapPrefix replaces the keys under livePrefix by the ones under stagingPrefix (i.e: an index rebuilt under a
+// staging prefix then published): every key under livePrefix is deleted and every live key under stagingPrefix is
+// moved to livePrefix (the rest of the key is kept) with its value and expiration. the deletes and the moves are
+// written by a single Batch, so the readers of a provider whose batches are atomic (i.e goleveldb, badgerdb) see either
+// the old keys or the new ones but never a mix, the staged keys are held in memory meanwhile and stagingPrefix must
+// not be written concurrently. ErrOverlappingPrefixes is returned when a prefix is a prefix of the other one
+func SwapPrefix(p Provider, livePrefix, stagingPrefix []byte) error {
+	if bytes.HasPrefix(livePrefix, stagingPrefix) || bytes.HasPrefix(stagingPrefix, livePrefix) {
+		return ErrOverlappingPrefixes
+	}
+
+	var entries []*Entry
+	moved := map[string]bool{}
+
+	err := p.Scan(ScanOpts{
+		Prefix: stagingPrefix,
+		Scanner: func(k, v []byte) error {
+			expires, err := p.TTL(k)
+			if err == ErrKeyNotFound {
+				// expired since it was scanned
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			liveKey := append(append([]byte{}, livePrefix...), k[len(stagingPrefix):]...)
+			moved[string(liveKey)] = true
+			entries = append(entries, &Entry{Key: liveKey, Value: v, ExpireAt: expires}, &Entry{Key: k})
+
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// the live keys that aren't overwritten by a staged one are deleted
+	err = p.Scan(ScanOpts{
+		Prefix: livePrefix,
+		Scanner: func(k, v []byte) error {
+			if !moved[string(k)] {
+				entries = append(entries, &Entry{Key: k})
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	return p.Batch(entries)
+}
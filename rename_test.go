@@ -0,0 +1,182 @@
+package goukv_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestRenamePrefix(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		expires := time.Now().Add(time.Hour).Truncate(time.Second)
+
+		var entries []*goukv.Entry
+		for i := 0; i < 2500; i++ {
+			entry := &goukv.Entry{Key: []byte(fmt.Sprintf("v1:%05d", i)), Value: []byte(fmt.Sprintf("val%d", i))}
+			if i%10 == 0 {
+				entry.ExpireAt = &expires
+			}
+			entries = append(entries, entry)
+		}
+		entries = append(entries, &goukv.Entry{Key: []byte("v3:other"), Value: []byte("v")})
+
+		if err := db.Batch(entries); err != nil {
+			t.Fatal(err)
+		}
+
+		moved, err := goukv.RenamePrefix(db, []byte("v1:"), []byte("v2:"), goukv.RenameOpts{BatchSize: 300})
+		if err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+
+		if moved != 2500 {
+			t.Errorf("%s: expected (2500) moved keys, found (%d)", driver, moved)
+		}
+
+		count := func(prefix string) int {
+			n := 0
+			db.Scan(goukv.ScanOpts{Prefix: []byte(prefix), Scanner: func(k, v []byte) error {
+				n++
+				return nil
+			}})
+			return n
+		}
+
+		if n := count("v1:"); n != 0 {
+			t.Errorf("%s: expected the old keys to be deleted, found (%d)", driver, n)
+		}
+
+		if n := count("v2:"); n != 2500 {
+			t.Errorf("%s: expected (2500) new keys, found (%d)", driver, n)
+		}
+
+		if n := count("v3:"); n != 1 {
+			t.Errorf("%s: expected the other keys to be kept, found (%d)", driver, n)
+		}
+
+		for _, i := range []int{0, 7, 2490, 2499} {
+			k := []byte(fmt.Sprintf("v2:%05d", i))
+			if v, err := db.Get(k); err != nil || string(v) != fmt.Sprintf("val%d", i) {
+				t.Errorf("%s: expected the value of (%s) to be kept, found (%s, %v)", driver, k, v, err)
+			}
+
+			ttl, err := db.TTL(k)
+			if err != nil {
+				t.Fatalf("%s: %v", driver, err)
+			}
+
+			if i%10 == 0 && (ttl == nil || !ttl.Equal(expires)) {
+				t.Errorf("%s: expected the expiration of (%s) to be kept, found (%v)", driver, k, ttl)
+			} else if i%10 != 0 && ttl != nil {
+				t.Errorf("%s: expected (%s) not to expire, found (%v)", driver, k, ttl)
+			}
+		}
+
+		if moved, err := goukv.RenamePrefix(db, []byte("v1:"), []byte("v2:"), goukv.RenameOpts{}); err != nil || moved != 0 {
+			t.Errorf("%s: expected nothing left to move, found (%d, %v)", driver, moved, err)
+		}
+
+		for _, prefixes := range [][2]string{{"v2:", "v2:x"}, {"v2:x", "v2:"}, {"", "v4:"}} {
+			if _, err := goukv.RenamePrefix(db, []byte(prefixes[0]), []byte(prefixes[1]), goukv.RenameOpts{}); err != goukv.ErrOverlappingPrefixes {
+				t.Errorf("%s: expected (%v) renaming (%s) to (%s), found (%v)", driver, goukv.ErrOverlappingPrefixes, prefixes[0], prefixes[1], err)
+			}
+		}
+	}
+}
+
+func TestSwapPrefix(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		// the live index has 100 keys of the old version, the staged one 80 keys of the new version
+		var entries []*goukv.Entry
+		for i := 0; i < 100; i++ {
+			entries = append(entries, &goukv.Entry{Key: []byte(fmt.Sprintf("live:%03d", i)), Value: []byte("old")})
+		}
+		expires := time.Now().Add(time.Hour).Truncate(time.Second)
+		for i := 0; i < 80; i++ {
+			entries = append(entries, &goukv.Entry{Key: []byte(fmt.Sprintf("staging:%03d", i*2)), Value: []byte("new"), ExpireAt: &expires})
+		}
+		if err := db.Batch(entries); err != nil {
+			t.Fatal(err)
+		}
+
+		// readers scan the live index until it's swapped and checked a few more times
+		var wg sync.WaitGroup
+		swapped := make(chan struct{})
+		inconsistent := make(chan string, 4)
+		for r := 0; r < 4; r++ {
+			wg.Add(1)
+			go (func() {
+				defer wg.Done()
+
+				for after := 0; after < 5; {
+					counts := map[string]int{}
+					db.Scan(goukv.ScanOpts{
+						Prefix: []byte("live:"),
+						Scanner: func(k, v []byte) error {
+							counts[string(v)]++
+							return nil
+						},
+					})
+
+					if len(counts) != 1 || (counts["old"] != 100 && counts["new"] != 80) {
+						inconsistent <- fmt.Sprint(counts)
+						return
+					}
+
+					select {
+					case <-swapped:
+						after++
+					default:
+					}
+				}
+			})()
+		}
+
+		if err := goukv.SwapPrefix(db, []byte("live:"), []byte("staging:")); err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+		close(swapped)
+		wg.Wait()
+
+		if len(inconsistent) > 0 {
+			t.Errorf("%s: expected the readers to see either index, found (%v)", driver, <-inconsistent)
+		}
+
+		live := 0
+		db.Scan(goukv.ScanOpts{Prefix: []byte("live:"), Scanner: func(k, v []byte) error {
+			live++
+			return nil
+		}})
+		if live != 80 {
+			t.Errorf("%s: expected (80) live keys, found (%d)", driver, live)
+		}
+
+		if v, err := db.Get([]byte("live:002")); err != nil || string(v) != "new" {
+			t.Errorf("%s: expected (new), found (%s, %v)", driver, v, err)
+		}
+		if _, err := db.Get([]byte("live:001")); err != goukv.ErrKeyNotFound {
+			t.Errorf("%s: expected the keys missing from the staged index to be deleted, found (%v)", driver, err)
+		}
+		if ttl, err := db.TTL([]byte("live:002")); err != nil || ttl == nil || !ttl.Equal(expires) {
+			t.Errorf("%s: expected the expiration to be moved, found (%v, %v)", driver, ttl, err)
+		}
+
+		db.Scan(goukv.ScanOpts{Prefix: []byte("staging:"), Scanner: func(k, v []byte) error {
+			t.Errorf("%s: expected the staged keys to be moved, found (%s)", driver, k)
+			return goukv.ErrScanDone
+		}})
+
+		if err := goukv.SwapPrefix(db, []byte("live:"), []byte("live:v2:")); err != goukv.ErrOverlappingPrefixes {
+			t.Errorf("%s: expected (%v), found (%v)", driver, goukv.ErrOverlappingPrefixes, err)
+		}
+	}
+}
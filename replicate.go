@@ -0,0 +1,276 @@
+package goukv
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// ReplicationPrefix the reserved prefix the replication checkpoints are stored under (in the destinations)
+var ReplicationPrefix = []byte("\x00goukv\x00replication\x00")
+
+// ErrReplicationGap returned by Replicate when the changes following its checkpoint have been truncated from the
+// changelog of the source, the destination can't catch up anymore and must be synced again from scratch
+var ErrReplicationGap = errors.New("the changelog has been truncated past the replication checkpoint")
+
+// replicatedPrefixes the internal prefixes holding data, the other internal keys (the changelog, the counters of the
+// providers ...) belong to the source itself and aren't replicated
+var replicatedPrefixes = [][]byte{KeyspacePrefix, IndexPrefix, DedupPrefix, StreamPrefix}
+
+const (
+	// DefaultReplicateBatchSize the number of keys (or changes) Replicate writes per batch by default
+	DefaultReplicateBatchSize = 1000
+
+	// DefaultReplicatePollInterval the time Replicate waits for new changes once it caught up by default
+	DefaultReplicatePollInterval = 100 * time.Millisecond
+)
+
+// ReplicateOpts the options of Replicate
+type ReplicateOpts struct {
+	// Name the name of the checkpoint in the destination, so that several sources can be replicated to the same
+	// destination, "default" when it's empty
+	Name string
+
+	// BatchSize the number of keys (or changes) written per batch, DefaultReplicateBatchSize when it's zero
+	BatchSize int
+
+	// PollInterval the time to wait for new changes once caught up, DefaultReplicatePollInterval when it's zero
+	PollInterval time.Duration
+}
+
+// replicationKey returns the key of the checkpoint of the specified replication
+func replicationKey(name string) []byte {
+	if name == "" {
+		name = "default"
+	}
+
+	return append(append([]byte{}, ReplicationPrefix...), name...)
+}
+
+// ReplicationCheckpoint returns the sequence of the last change of its source the specified replication (see
+// ReplicateOpts.Name) wrote to dst, the source may truncate its changelog up to it (see ChangelogReader), false is
+// returned when the replication hasn't completed its full sync yet
+func ReplicationCheckpoint(dst Provider, name string) (uint64, bool, error) {
+	b, err := dst.Get(replicationKey(name))
+	if err == ErrKeyNotFound {
+		return 0, false, nil
+	}
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	if len(b) != 8 {
+		return 0, false, errors.New("invalid replication checkpoint")
+	}
+
+	return binary.BigEndian.Uint64(b), true, nil
+}
+
+// isReplicated whether the specified key of the source is replicated
+func isReplicated(k []byte) bool {
+	if !IsInternalKey(k) {
+		return true
+	}
+
+	for _, prefix := range replicatedPrefixes {
+		if bytes.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Replicate copies src to dst then streams the changes of src to dst until ctx is done, it returns ctx.Err() then.
+// src must record its changes (i.e: the "enable_changelog" option of goleveldb and badgerdb, see ChangelogReader),
+// ErrNotSupported is returned otherwise (a provider whose changelog is disabled records nothing, so only its full sync
+// would be replicated). the replication runs in two phases:
+// - the full sync: the last sequence of the changelog is read, then every live key of src is copied (with its
+// expiration) in batches of BatchSize, the keys of dst missing from src aren't deleted so dst should be empty.
+// - the tailing: the changes following that sequence are read every PollInterval and written in batches of BatchSize,
+// each batch writing the checkpoint (the sequence of its last change) too under ReplicationPrefix in dst.
+// calling it again resumes the tailing from the checkpoint (an interrupted full sync starts over), the delivery is
+// at-least-once: the changes committed during the full sync are written again by the tailing, and when the batches
+// of dst aren't atomic a crash may leave the changes of a batch written but not its checkpoint so they're written
+// again on resume, replaying them in order converges to the same state. ErrReplicationGap is returned when src
+// truncated the changes following the checkpoint (see ReplicationCheckpoint)
+func Replicate(ctx context.Context, src, dst Provider, opts ReplicateOpts) error {
+	changelog, ok := src.(ChangelogReader)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultReplicateBatchSize
+	}
+
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultReplicatePollInterval
+	}
+
+	checkpoint := replicationKey(opts.Name)
+
+	seq, synced, err := ReplicationCheckpoint(dst, opts.Name)
+	if err != nil {
+		return err
+	}
+
+	if !synced {
+		if seq, err = fullSync(ctx, changelog, src, dst, checkpoint, opts.BatchSize); err != nil {
+			return err
+		}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		entries := make([]*Entry, 0, opts.BatchSize+1)
+		last := seq
+		err := changelog.ReadChanges(seq, func(c Change) error {
+			// seq is zero when the changelog was empty at the full sync, a truncated one can't be detected then
+			if seq > 0 && last == seq && c.Seq > seq+1 {
+				return ErrReplicationGap
+			}
+
+			last = c.Seq
+
+			if isReplicated(c.Key) {
+				entries = append(entries, changeEntry(c))
+			}
+
+			if int(last-seq) == opts.BatchSize {
+				return ErrScanDone
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if last > seq {
+			if err := dst.Batch(append(entries, checkpointEntry(checkpoint, last))); err != nil {
+				return err
+			}
+
+			// a full batch, the changelog may hold more changes
+			full := int(last-seq) == opts.BatchSize
+			if seq = last; full {
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(opts.PollInterval):
+		}
+	}
+}
+
+// fullSync copies the replicated keys of src to dst then writes the checkpoint, it returns the sequence of the last
+// change recorded before the copy
+func fullSync(ctx context.Context, changelog ChangelogReader, src, dst Provider, checkpoint []byte, batchSize int) (uint64, error) {
+	var seq uint64
+	err := changelog.ReadChanges(0, func(c Change) error {
+		seq = c.Seq
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var entries []*Entry
+	flush := func() error {
+		if len(entries) == 0 {
+			return nil
+		}
+
+		err := dst.Batch(entries)
+		entries = nil
+
+		return err
+	}
+
+	copyPrefix := func(prefix []byte) error {
+		return src.Scan(ScanOpts{
+			Prefix: prefix,
+			Scanner: func(k, v []byte) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+
+				if !isReplicated(k) {
+					return nil
+				}
+
+				expires, err := src.TTL(k)
+				if err == ErrKeyNotFound {
+					// deleted or expired since it was scanned
+					return nil
+				}
+
+				if err != nil {
+					return err
+				}
+
+				entry := &Entry{Key: append([]byte{}, k...), Value: append([]byte{}, v...), ExpireAt: expires}
+				if entries = append(entries, entry); len(entries) == batchSize {
+					return flush()
+				}
+
+				return nil
+			},
+		})
+	}
+
+	// the scans of the whole keyspace skip the internal keys
+	for _, prefix := range append([][]byte{nil}, replicatedPrefixes...) {
+		if err := copyPrefix(prefix); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := flush(); err != nil {
+		return 0, err
+	}
+
+	if err := dst.Batch([]*Entry{checkpointEntry(checkpoint, seq)}); err != nil {
+		return 0, err
+	}
+
+	return seq, nil
+}
+
+// changeEntry returns the entry replaying the specified change, a nil value for the deletes
+func changeEntry(c Change) *Entry {
+	if c.Op == ChangeDelete {
+		return &Entry{Key: c.Key}
+	}
+
+	entry := &Entry{Key: c.Key, Value: c.Value, ExpireAt: c.ExpireAt}
+	if entry.Value == nil {
+		entry.Value = []byte{}
+	}
+
+	// the TTL is relative to the time of the change
+	if entry.ExpireAt == nil && c.TTL > 0 {
+		expires := c.Time.Add(c.TTL)
+		entry.ExpireAt = &expires
+	}
+
+	return entry
+}
+
+// checkpointEntry returns the entry writing the specified checkpoint
+func checkpointEntry(checkpoint []byte, seq uint64) *Entry {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, seq)
+
+	return &Entry{Key: checkpoint, Value: b}
+}
@@ -0,0 +1,114 @@
+package goukv_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+// replicated waits until dst holds the specified values (an empty one means the key must be missing)
+func replicated(t *testing.T, dst goukv.Provider, expected map[string]string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for k, v := range expected {
+		for {
+			found, err := dst.Get([]byte(k))
+			if (v == "" && err == goukv.ErrKeyNotFound) || (v != "" && string(found) == v) {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatalf("expected (%s) to be replicated as (%s), found (%s, %v)", k, v, found, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestReplicate(t *testing.T) {
+	src, cleanupSrc := openTempDB(t, "goleveldb", map[string]interface{}{"enable_changelog": true})
+	defer cleanupSrc()
+
+	dst, cleanupDst := openTempDB(t, "badgerdb", nil)
+	defer cleanupDst()
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	for i := 0; i < 10; i++ {
+		src.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%d", i)), Value: []byte("v")})
+	}
+	src.Put(&goukv.Entry{Key: []byte("expiring"), Value: []byte("v"), ExpireAt: &expires})
+	src.(goukv.KeyspaceManager).Keyspace("space").Put(&goukv.Entry{Key: []byte("k"), Value: []byte("space")})
+
+	opts := goukv.ReplicateOpts{BatchSize: 3, PollInterval: 10 * time.Millisecond}
+
+	// run starts a replication, the returned func stops it and returns its error
+	run := func() func() error {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go (func() {
+			done <- goukv.Replicate(ctx, src, dst, opts)
+		})()
+
+		return func() error {
+			cancel()
+			return <-done
+		}
+	}
+
+	stop := run()
+
+	// the full sync
+	replicated(t, dst, map[string]string{"k0": "v", "k9": "v", "expiring": "v"})
+	replicated(t, dst.(goukv.KeyspaceManager).Keyspace("space"), map[string]string{"k": "space"})
+
+	if ttl, err := dst.TTL([]byte("expiring")); err != nil || ttl == nil || !ttl.Equal(expires) {
+		t.Errorf("expected the expiration to be replicated, found (%v, %v)", ttl, err)
+	}
+
+	// the tailing
+	for i := 0; i < 10; i++ {
+		src.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%d", i)), Value: []byte("new")})
+	}
+	src.Delete([]byte("k0"))
+	src.Batch([]*goukv.Entry{{Key: []byte("batched"), Value: []byte("v")}, {Key: []byte("k1")}})
+
+	replicated(t, dst, map[string]string{"k0": "", "k1": "", "k2": "new", "k9": "new", "batched": "v"})
+
+	if err := stop(); err != context.Canceled {
+		t.Errorf("expected (%v), found (%v)", context.Canceled, err)
+	}
+
+	// the sequence of the last change
+	seq, synced, err := goukv.ReplicationCheckpoint(dst, "")
+	if err != nil || !synced || seq != 25 {
+		t.Errorf("expected the checkpoint to be (25), found (%d, %v, %v)", seq, synced, err)
+	}
+
+	// it resumes from the checkpoint, the replicated changes can be truncated
+	src.Put(&goukv.Entry{Key: []byte("resumed"), Value: []byte("v")})
+	if err := src.(goukv.ChangelogReader).TruncateChanges(seq + 1); err != nil {
+		t.Fatal(err)
+	}
+
+	stop = run()
+	replicated(t, dst, map[string]string{"resumed": "v"})
+	stop()
+
+	// the changes truncated before being replicated can't be caught up
+	src.Put(&goukv.Entry{Key: []byte("lost"), Value: []byte("v")})
+	src.Put(&goukv.Entry{Key: []byte("next"), Value: []byte("v")})
+	if err := src.(goukv.ChangelogReader).TruncateChanges(seq + 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := goukv.Replicate(context.Background(), src, dst, opts); err != goukv.ErrReplicationGap {
+		t.Errorf("expected (%v), found (%v)", goukv.ErrReplicationGap, err)
+	}
+
+	// the changelog is required
+	if err := goukv.Replicate(context.Background(), goukv.NewKeyspace(src, "space"), dst, opts); err != goukv.ErrNotSupported {
+		t.Errorf("expected (%v), found (%v)", goukv.ErrNotSupported, err)
+	}
+}
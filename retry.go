@@ -0,0 +1,83 @@
+package goukv
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxOpenRetryBackoff the maximum wait between two open attempts
+const maxOpenRetryBackoff = 5 * time.Second
+
+// OpenRetry retries opening a provider while its directory is locked by another process (i.e: a restarted
+// container whose previous process hasn't released it yet), it's configured by the "open_retry_attempts"
+// and "open_retry_backoff" options
+type OpenRetry struct {
+	Attempts int
+	Backoff  time.Duration
+}
+
+// NewOpenRetry builds an OpenRetry from the specified provider options, nil is returned when "open_retry_attempts" (int)
+// isn't set, "open_retry_backoff" (time.Duration) is the first wait (100ms by default), it's doubled after each attempt up to 5s
+func NewOpenRetry(opts map[string]interface{}) *OpenRetry {
+	attempts, ok := opts["open_retry_attempts"].(int)
+	if !ok || attempts <= 0 {
+		return nil
+	}
+
+	backoff, ok := opts["open_retry_backoff"].(time.Duration)
+	if !ok || backoff <= 0 {
+		backoff = 100 * time.Millisecond
+	}
+
+	return &OpenRetry{
+		Attempts: attempts,
+		Backoff:  backoff,
+	}
+}
+
+// Do calls open then retries it up to Attempts more times while it fails with a lock error (see IsLockError),
+// any other error is returned right away, a nil OpenRetry calls open once
+func (r *OpenRetry) Do(open func() error) error {
+	err := open()
+	if r == nil {
+		return err
+	}
+
+	backoff := r.Backoff
+	for i := 0; i < r.Attempts && IsLockError(err); i++ {
+		time.Sleep(backoff)
+
+		if backoff *= 2; backoff > maxOpenRetryBackoff {
+			backoff = maxOpenRetryBackoff
+		}
+
+		err = open()
+	}
+
+	return err
+}
+
+// IsLockError whether the specified error is a failure to acquire a (non-blocking) file lock held by someone else,
+// both the wrapped (errors.Unwrap) and the github.com/pkg/errors (Cause) chains are followed
+func IsLockError(err error) bool {
+	for err != nil {
+		var errno syscall.Errno
+		if errors.As(err, &errno) && (errno == syscall.EAGAIN || errno == syscall.EWOULDBLOCK) {
+			return true
+		}
+
+		if strings.Contains(err.Error(), "resource temporarily unavailable") {
+			return true
+		}
+
+		causer, ok := err.(interface{ Cause() error })
+		if !ok || causer.Cause() == err {
+			return false
+		}
+		err = causer.Cause()
+	}
+
+	return false
+}
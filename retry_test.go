@@ -0,0 +1,100 @@
+//go:build !windows
+// +build !windows
+
+package goukv_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+// holdLock locks the specified db directory the way another process of the driver would, until release is called,
+// goleveldb locks its LOCK file while badger locks the directory itself
+func holdLock(t *testing.T, driver, path string) (release func()) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	name, flag := path, os.O_RDONLY
+	if driver == "goleveldb" {
+		name, flag = filepath.Join(path, "LOCK"), os.O_RDWR|os.O_CREATE
+	}
+
+	f, err := os.OpenFile(name, flag, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		t.Fatal(err)
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}
+}
+
+func TestOpenRetry(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		dir, err := ioutil.TempDir("", "goukv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "db")
+		release := holdLock(t, driver, path)
+
+		db, err := goukv.Open(driver, map[string]interface{}{"path": path})
+		if !goukv.IsLockError(err) {
+			t.Fatalf("%s: expected a lock error without retries, found (%v)", driver, err)
+		}
+
+		time.AfterFunc(300*time.Millisecond, release)
+
+		db, err = goukv.Open(driver, map[string]interface{}{
+			"path":                path,
+			"open_retry_attempts": 10,
+			"open_retry_backoff":  50 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("%s: expected the open to succeed once the lock is released, found (%v)", driver, err)
+		}
+		db.Close()
+	}
+}
+
+func TestOpenRetryOtherErrors(t *testing.T) {
+	attempts := 0
+	retry := &goukv.OpenRetry{Attempts: 3, Backoff: time.Millisecond}
+
+	err := retry.Do(func() error {
+		attempts++
+		return errors.New("corrupted")
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("expected a non lock error to fail right away, found (%v) after (%d) attempts", err, attempts)
+	}
+
+	attempts = 0
+	err = retry.Do(func() error {
+		attempts++
+		return syscall.EWOULDBLOCK
+	})
+	if err != syscall.EWOULDBLOCK || attempts != 4 {
+		t.Errorf("expected the lock error after (4) attempts, found (%v) after (%d) attempts", err, attempts)
+	}
+
+	if goukv.NewOpenRetry(map[string]interface{}{}) != nil {
+		t.Error("expected no retries without open_retry_attempts")
+	}
+}
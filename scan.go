@@ -1,13 +1,229 @@
 package goukv
 
+import "hash/fnv"
+
 // ScanOpts scanner options
 type ScanOpts struct {
+	// Prefix bounds the scan to the keys having it, a nil or an empty prefix scans all of the keys
 	Prefix        []byte
 	Offset        []byte
 	Scanner       Scanner
 	IncludeOffset bool
 	ReverseScan   bool
+
+	// Consistent makes the scan see a point-in-time view of the db, writes committed
+	// while scanning aren't observed (badger scans always run in a read transaction so they already are, unless they
+	// outlive its scan_snapshot_max_duration option)
+	Consistent bool
+
+	// SinceVersion when set only the entries written with a newer version are scanned,
+	// deleted keys aren't reported (use the changelog for them)
+	SinceVersion uint64
+
+	// IncludeTombstones also scans the tombstones the deletes leave in track_deletes mode (until they expire),
+	// the scanner receives them with a nil value (see IsTombstone), the live values are never nil
+	IncludeTombstones bool
+
+	// MaxBytes when set the scan stops (as if the scanner returned ErrScanDone) before the value that would make the
+	// delivered values exceed it, the first value is always delivered even if it's larger (see BoundedScanner)
+	MaxBytes int64
+
+	// IncludeInternal also scans the internal keys (see IsInternalKey) for diagnostics, they are skipped
+	// by default unless the prefix is itself an internal key (i.e: the scans of a keyspace)
+	IncludeInternal bool
+
+	// ReuseBuffers makes ScanBatched pass the same slice (holding the next batch) to every call of its fn,
+	// so fn must not retain it, the batches are freshly allocated otherwise
+	ReuseBuffers bool
+
+	// Progress when set is called with the number of keys iterated so far every ProgressInterval keys
+	// (DefaultProgressInterval when zero), the skipped keys (expired, tombstones, older versions, the excluded
+	// offset ...) are counted too so it keeps firing while nothing is delivered, its calls are never concurrent
+	Progress         func(scanned int64)
+	ProgressInterval int64
+
+	// KeyFilter and Filter when set skip the keys (or the key/value pairs) they return false for before the
+	// scanner is called, so the skipped ones aren't delivered and don't count toward MaxBytes, KeyFilter runs before
+	// the value is read (badgerdb doesn't prefetch the values then so the filtered out ones aren't loaded from its value
+	// log, goleveldb doesn't copy nor decode them) so it's the cheaper one (see BoundedScanner), they must not retain
+	// the passed slices
+	KeyFilter func(key []byte) bool
+	Filter    func(key, value []byte) bool
+
+	// OnScannerError when set is passed the errors the scanner returns (other than ErrScanDone) with their key,
+	// returning true skips the pair and continues the scan while false aborts it with the error like it does
+	// without it (i.e: to log the bad records of a large export and go on), the errors of the provider itself
+	// (i.e: a failed read) aren't passed to it, it must not retain the key
+	OnScannerError func(key []byte, err error) bool
+
+	// SampleRate when in (0, 1) only scans that fraction of the keys (roughly), a key is part of the sample when a hash of
+	// it is under the rate (see InSample) so the same keys are sampled by every scan and every run, the order of the
+	// sampled keys is preserved and the other ones are skipped by the KeyFilter Sampled folds the rate into, so their
+	// values aren't loaded either (see KeyFilter), zero (or >= 1) scans all of the keys
+	SampleRate float64
+}
+
+// DefaultProgressInterval the number of keys iterated between two calls of ScanOpts.Progress by default
+const DefaultProgressInterval = 10000
+
+// KV a key/value pair
+type KV struct {
+	Key   []byte
+	Value []byte
+}
+
+// SkipsInternal whether the scan skips the internal keys, the providers storing internal keys check it
+func (opts ScanOpts) SkipsInternal() bool {
+	return !opts.IncludeInternal && !IsInternalKey(opts.Prefix)
+}
+
+// InSample whether the specified key is part of the samples of the specified rate (see ScanOpts.SampleRate), a key part
+// of the sample of a rate is part of the samples of the greater rates too
+func InSample(k []byte, rate float64) bool {
+	if rate <= 0 || rate >= 1 {
+		return true
+	}
+
+	h := fnv.New64a()
+	h.Write(k)
+
+	// FNV spreads similar keys poorly over the high bits, they're mixed with the splitmix64 finalizer
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return float64(x) < rate*(1<<64)
+}
+
+// Sampled returns opts with its SampleRate applied by its KeyFilter, the providers and the wrappers
+// translating KeyFilter call it first so that the keys are sampled like they're filtered
+func (opts ScanOpts) Sampled() ScanOpts {
+	if opts.SampleRate <= 0 || opts.SampleRate >= 1 {
+		opts.SampleRate = 0
+		return opts
+	}
+
+	rate, keyFilter := opts.SampleRate, opts.KeyFilter
+	opts.SampleRate = 0
+	opts.KeyFilter = func(k []byte) bool {
+		return InSample(k, rate) && (keyFilter == nil || keyFilter(k))
+	}
+
+	return opts
+}
+
+// BoundedScanner returns the scanner of opts skipping the pairs out of its sample or rejected by its KeyFilter and Filter
+// then enforcing its MaxBytes: it returns ErrScanDone instead of passing a value that would make the passed values exceed
+// MaxBytes (unless it's the first one), the errors of the scanner are then passed to its OnScannerError, the providers
+// scan through it (a provider applying KeyFilter itself clears it first, see Sampled), it's opts.Scanner itself when none
+// of them is set
+func (opts ScanOpts) BoundedScanner() Scanner {
+	opts = opts.Sampled()
+	scanner := opts.Scanner
+
+	if opts.OnScannerError != nil {
+		failing := scanner
+		scanner = func(k, v []byte) error {
+			err := failing(k, v)
+			if err != nil && err != ErrScanDone && opts.OnScannerError(k, err) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	if opts.MaxBytes > 0 {
+		bounded, delivered, total := scanner, false, int64(0)
+		scanner = func(k, v []byte) error {
+			if delivered && total+int64(len(v)) > opts.MaxBytes {
+				return ErrScanDone
+			}
+
+			delivered, total = true, total+int64(len(v))
+
+			return bounded(k, v)
+		}
+	}
+
+	if opts.KeyFilter == nil && opts.Filter == nil {
+		return scanner
+	}
+
+	filtered := scanner
+	return func(k, v []byte) error {
+		if opts.KeyFilter != nil && !opts.KeyFilter(k) {
+			return nil
+		}
+
+		if opts.Filter != nil && !opts.Filter(k, v) {
+			return nil
+		}
+
+		return filtered(k, v)
+	}
+}
+
+// ProgressTicker returns the func the providers call for every key they iterate (delivered or skipped),
+// it calls opts.Progress every ProgressInterval keys, it's a no-op when Progress isn't set
+func (opts ScanOpts) ProgressTicker() func() {
+	if opts.Progress == nil {
+		return func() {}
+	}
+
+	interval := opts.ProgressInterval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+
+	progress, scanned := opts.Progress, int64(0)
+	return func() {
+		if scanned++; scanned%interval == 0 {
+			progress(scanned)
+		}
+	}
 }
 
 // Scanner a function that performs the scanning/filterig
 type Scanner func([]byte, []byte) error
+
+// ScanBatched scans p like Scan but passes the scanned pairs to fn in batches of batchSize pairs (the last one may be
+// smaller), which saves a call per pair for bulk processing, opts.Scanner is ignored and fn may return ErrScanDone
+// to stop the scan, the keys and values themselves are never reused
+func ScanBatched(p Provider, opts ScanOpts, batchSize int, fn func([]KV) error) error {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	batch := make([]KV, 0, batchSize)
+	flush := func() error {
+		err := fn(batch)
+		if opts.ReuseBuffers {
+			batch = batch[:0]
+		} else {
+			batch = make([]KV, 0, batchSize)
+		}
+		return err
+	}
+
+	opts.Scanner = func(k, v []byte) error {
+		batch = append(batch, KV{Key: k, Value: v})
+		if len(batch) < batchSize {
+			return nil
+		}
+		return flush()
+	}
+
+	// a stopped scan has nothing left to pass since its last batch was flushed
+	if err := p.Scan(opts); err != nil || len(batch) == 0 {
+		return err
+	}
+
+	if err := flush(); err != ErrScanDone {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,574 @@
+package goukv_test
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestScanPrefixOffset(t *testing.T) {
+	cases := []struct {
+		opts     goukv.ScanOpts
+		expected string
+	}{
+		{goukv.ScanOpts{Prefix: []byte("b"), ReverseScan: true}, "b3 b2 b1"},
+		{goukv.ScanOpts{Prefix: []byte("b"), ReverseScan: true, Offset: []byte("b2")}, "b1"},
+		{goukv.ScanOpts{Prefix: []byte("b"), ReverseScan: true, Offset: []byte("b2"), IncludeOffset: true}, "b2 b1"},
+		{goukv.ScanOpts{Prefix: []byte("b"), ReverseScan: true, Offset: []byte("b25")}, "b2 b1"},
+		{goukv.ScanOpts{Prefix: []byte("b"), ReverseScan: true, Offset: []byte("b3")}, "b2 b1"},
+		{goukv.ScanOpts{Prefix: []byte("b"), ReverseScan: true, Offset: []byte("b3"), IncludeOffset: true}, "b3 b2 b1"},
+		{goukv.ScanOpts{Prefix: []byte("b"), ReverseScan: true, Offset: []byte("c")}, "b3 b2 b1"},
+		{goukv.ScanOpts{Prefix: []byte("b"), ReverseScan: true, Offset: []byte("a9")}, ""},
+		{goukv.ScanOpts{Prefix: []byte("b"), ReverseScan: true, Offset: []byte("b1")}, ""},
+		{goukv.ScanOpts{Prefix: []byte("c"), ReverseScan: true}, "c1 c"},
+		{goukv.ScanOpts{Prefix: []byte("b"), Offset: []byte("b2")}, "b3"},
+		{goukv.ScanOpts{Prefix: []byte("b"), Offset: []byte("b2"), IncludeOffset: true}, "b2 b3"},
+		{goukv.ScanOpts{Prefix: []byte("b"), Offset: []byte("a")}, "b1 b2 b3"},
+		{goukv.ScanOpts{ReverseScan: true, Offset: []byte("b2"), IncludeOffset: true}, "b2 b1 a2 a1"},
+	}
+
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		// "c" is the first key after the "b" prefix, a reverse scan of "b" must start right before it
+		for _, k := range []string{"a1", "a2", "b1", "b2", "b3", "c", "c1"} {
+			db.Put(&goukv.Entry{Key: []byte(k), Value: []byte("v")})
+		}
+
+		for _, c := range cases {
+			var found []string
+			opts := c.opts
+			opts.Scanner = func(k, v []byte) error {
+				found = append(found, string(k))
+				return nil
+			}
+
+			if err := db.Scan(opts); err != nil {
+				t.Fatalf("%s: %v", driver, err)
+			}
+
+			if strings.Join(found, " ") != c.expected {
+				t.Errorf("%s: prefix (%s), offset (%s), include offset (%v), reverse (%v): expected (%s), found (%s)",
+					driver, c.opts.Prefix, c.opts.Offset, c.opts.IncludeOffset, c.opts.ReverseScan, c.expected, strings.Join(found, " "))
+			}
+		}
+	}
+}
+
+func TestScanEmptyPrefix(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		for _, k := range []string{"a", "b", "c"} {
+			db.Put(&goukv.Entry{Key: []byte(k), Value: []byte("v")})
+		}
+
+		for _, reverse := range []bool{false, true} {
+			scan := func(prefix []byte) string {
+				var found []string
+				err := db.Scan(goukv.ScanOpts{Prefix: prefix, ReverseScan: reverse, Scanner: func(k, v []byte) error {
+					found = append(found, string(k))
+					return nil
+				}})
+				if err != nil {
+					t.Fatalf("%s: %v", driver, err)
+				}
+				return strings.Join(found, " ")
+			}
+
+			expected := "a b c"
+			if reverse {
+				expected = "c b a"
+			}
+
+			if found := scan(nil); found != expected {
+				t.Errorf("%s: nil prefix, reverse (%v): expected (%s), found (%s)", driver, reverse, expected, found)
+			}
+
+			if found := scan([]byte{}); found != expected {
+				t.Errorf("%s: empty prefix, reverse (%v): expected (%s), found (%s)", driver, reverse, expected, found)
+			}
+		}
+	}
+}
+
+func TestScanMaxBytes(t *testing.T) {
+	cases := []struct {
+		opts     goukv.ScanOpts
+		expected string
+	}{
+		{goukv.ScanOpts{MaxBytes: 10}, "a b"},
+		{goukv.ScanOpts{MaxBytes: 9}, "a"},
+		{goukv.ScanOpts{MaxBytes: 1}, "a"},
+		{goukv.ScanOpts{MaxBytes: 22}, "a b c"},
+		{goukv.ScanOpts{MaxBytes: 100}, "a b c d"},
+		{goukv.ScanOpts{MaxBytes: 5, Offset: []byte("c"), IncludeOffset: true}, "c"},
+		{goukv.ScanOpts{MaxBytes: 15, ReverseScan: true}, "d c"},
+	}
+
+	// the values of a, b, c and d are 2, 8, 12 and 1 bytes long
+	values := map[string]int{"a": 2, "b": 8, "c": 12, "d": 1}
+
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		for k, size := range values {
+			db.Put(&goukv.Entry{Key: []byte(k), Value: []byte(strings.Repeat("v", size))})
+		}
+
+		sharded := goukv.Shard([]goukv.Provider{db}, nil)
+
+		for _, p := range []goukv.Provider{db, sharded} {
+			for _, c := range cases {
+				var found []string
+				opts := c.opts
+				opts.Scanner = func(k, v []byte) error {
+					found = append(found, string(k))
+					return nil
+				}
+
+				if err := p.Scan(opts); err != nil {
+					t.Fatalf("%s: %v", driver, err)
+				}
+
+				if strings.Join(found, " ") != c.expected {
+					t.Errorf("%s: max bytes (%d), offset (%s), reverse (%v): expected (%s), found (%s)",
+						driver, c.opts.MaxBytes, c.opts.Offset, c.opts.ReverseScan, c.expected, strings.Join(found, " "))
+				}
+			}
+		}
+	}
+}
+
+func TestScanInternalKeys(t *testing.T) {
+	cases := []struct {
+		opts     goukv.ScanOpts
+		expected string
+	}{
+		{goukv.ScanOpts{}, "\x00a \x00z b"},
+		{goukv.ScanOpts{ReverseScan: true}, "b \x00z \x00a"},
+		{goukv.ScanOpts{Prefix: []byte("\x00")}, "\x00a \x00z"},
+		{goukv.ScanOpts{Prefix: []byte("\x00"), ReverseScan: true}, "\x00z \x00a"},
+		{goukv.ScanOpts{Offset: []byte("\x00goukv\x00"), IncludeOffset: true}, "\x00z b"},
+		{goukv.ScanOpts{Offset: []byte("\x00goukv\x00"), ReverseScan: true}, "\x00a"},
+	}
+
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		// the changelog and the keyspace are stored under the internal prefix,
+		// "\x00a" and "\x00z" are sorted right before and after them
+		db, cleanup := openTempDB(t, driver, map[string]interface{}{"enable_changelog": true})
+		defer cleanup()
+
+		for _, k := range []string{"\x00a", "\x00z", "b"} {
+			db.Put(&goukv.Entry{Key: []byte(k), Value: []byte("v")})
+		}
+
+		ks := db.(goukv.KeyspaceManager).Keyspace("ks")
+		ks.Put(&goukv.Entry{Key: []byte("k"), Value: []byte("v")})
+
+		for _, c := range cases {
+			var found []string
+			opts := c.opts
+			opts.Scanner = func(k, v []byte) error {
+				found = append(found, string(k))
+				return nil
+			}
+
+			if err := db.Scan(opts); err != nil {
+				t.Fatalf("%s: %v", driver, err)
+			}
+
+			if strings.Join(found, " ") != c.expected {
+				t.Errorf("%s: prefix (%q), offset (%q), reverse (%v): expected (%q), found (%q)",
+					driver, c.opts.Prefix, c.opts.Offset, c.opts.ReverseScan, c.expected, strings.Join(found, " "))
+			}
+		}
+
+		internal := 0
+		err := db.Scan(goukv.ScanOpts{IncludeInternal: true, Scanner: func(k, v []byte) error {
+			if goukv.IsInternalKey(k) {
+				internal++
+			}
+			return nil
+		}})
+		if err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+
+		if internal == 0 {
+			t.Errorf("%s: expected IncludeInternal to scan the internal keys", driver)
+		}
+
+		var found []string
+		err = ks.Scan(goukv.ScanOpts{Scanner: func(k, v []byte) error {
+			found = append(found, string(k))
+			return nil
+		}})
+		if err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+
+		if strings.Join(found, " ") != "k" {
+			t.Errorf("%s: expected the keyspace to still scan its keys, found (%q)", driver, found)
+		}
+	}
+}
+
+func TestScanBatched(t *testing.T) {
+	cases := []struct {
+		keys      int
+		batchSize int
+		reuse     bool
+		expected  []int
+	}{
+		{9, 3, false, []int{3, 3, 3}},
+		{10, 3, false, []int{3, 3, 3, 1}},
+		{10, 3, true, []int{3, 3, 3, 1}},
+		{2, 5, false, []int{2}},
+		{0, 5, false, nil},
+	}
+
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		for _, c := range cases {
+			db, cleanup := openTempDB(t, driver, nil)
+
+			for i := 0; i < c.keys; i++ {
+				db.Put(&goukv.Entry{Key: []byte{'k', byte('a' + i)}, Value: []byte{byte('a' + i)}})
+			}
+
+			var sizes []int
+			var batches [][]goukv.KV
+			var keys []string
+			err := goukv.ScanBatched(db, goukv.ScanOpts{ReuseBuffers: c.reuse}, c.batchSize, func(batch []goukv.KV) error {
+				sizes = append(sizes, len(batch))
+				batches = append(batches, batch)
+				for _, kv := range batch {
+					if kv.Value[0] != kv.Key[1] {
+						t.Errorf("%s: unexpected value (%s) of (%s)", driver, kv.Value, kv.Key)
+					}
+					keys = append(keys, string(kv.Key))
+				}
+				return nil
+			})
+			cleanup()
+
+			if err != nil {
+				t.Fatalf("%s: %v", driver, err)
+			}
+
+			if fmt.Sprint(sizes) != fmt.Sprint(c.expected) || len(keys) != c.keys {
+				t.Errorf("%s: (%d) keys in batches of (%d): expected the batch sizes (%v), found (%v)", driver, c.keys, c.batchSize, c.expected, sizes)
+			}
+
+			// the batches share their array only when reused
+			if len(batches) > 1 && (&batches[0][:1][0] == &batches[1][:1][0]) != c.reuse {
+				t.Errorf("%s: reuse (%v): unexpected sharing of the batches", driver, c.reuse)
+			}
+		}
+	}
+
+	db, cleanup := openTempDB(t, "goleveldb", nil)
+	defer cleanup()
+
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		db.Put(&goukv.Entry{Key: []byte(k), Value: []byte("v")})
+	}
+
+	calls := 0
+	err := goukv.ScanBatched(db, goukv.ScanOpts{}, 2, func(batch []goukv.KV) error {
+		calls++
+		return goukv.ErrScanDone
+	})
+	if err != nil || calls != 1 {
+		t.Errorf("expected ErrScanDone to stop after a batch, found (%d) calls (%v)", calls, err)
+	}
+}
+
+func TestScanProgress(t *testing.T) {
+	defer (func() { goukv.Now = time.Now })()
+
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		goukv.Now = time.Now
+
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		// 200 live keys followed by 100 keys that expire
+		for i := 0; i < 300; i++ {
+			e := &goukv.Entry{Key: []byte(fmt.Sprintf("k%03d", i)), Value: []byte("v")}
+			if i >= 200 {
+				e.TTL = time.Hour
+			}
+			db.Put(e)
+		}
+
+		now := time.Now().Add(2 * time.Hour)
+		goukv.Now = func() time.Time { return now }
+
+		var reports []int64
+		delivered := 0
+		err := db.Scan(goukv.ScanOpts{
+			Prefix:           []byte("k"),
+			ProgressInterval: 100,
+			Progress: func(scanned int64) {
+				reports = append(reports, scanned)
+			},
+			Scanner: func(k, v []byte) error {
+				delivered++
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if delivered != 200 {
+			t.Errorf("%s: expected (200) keys to be delivered, found (%d)", driver, delivered)
+		}
+
+		if fmt.Sprint(reports) != "[100 200 300]" {
+			t.Errorf("%s: expected the expired keys to be counted too, found the reports (%v)", driver, reports)
+		}
+	}
+}
+
+func TestScanFilter(t *testing.T) {
+	// scan returns the keys delivered by a scan stopping after limit keys
+	scan := func(db goukv.Provider, opts goukv.ScanOpts, limit int) []string {
+		var keys []string
+		opts.Scanner = func(k, v []byte) error {
+			if string(v) != "even" {
+				t.Errorf("expected the filtered out values to be skipped, found (%s: %s)", k, v)
+			}
+			if keys = append(keys, string(k)); len(keys) == limit {
+				return goukv.ErrScanDone
+			}
+			return nil
+		}
+		if err := db.Scan(opts); err != nil {
+			t.Fatal(err)
+		}
+		return keys
+	}
+
+	even := func(k, v []byte) bool {
+		return string(v) == "even"
+	}
+
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		space := goukv.NewKeyspace(db, "space")
+		for i := 0; i < 100; i++ {
+			v := "odd"
+			if i%2 == 0 {
+				v = "even"
+			}
+			db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%02d", i)), Value: []byte(v)})
+			space.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%02d", i)), Value: []byte(v)})
+		}
+
+		// the skipped pairs don't count toward the limit
+		if keys := scan(db, goukv.ScanOpts{Filter: even}, 3); fmt.Sprint(keys) != "[k00 k02 k04]" {
+			t.Errorf("%s: expected ([k00 k02 k04]), found (%v)", driver, keys)
+		}
+
+		if keys := scan(db, goukv.ScanOpts{Filter: even, ReverseScan: true}, 2); fmt.Sprint(keys) != "[k98 k96]" {
+			t.Errorf("%s: expected ([k98 k96]), found (%v)", driver, keys)
+		}
+
+		// nor toward MaxBytes, 3 values of 4 bytes
+		if keys := scan(db, goukv.ScanOpts{Filter: even, MaxBytes: 12}, 0); fmt.Sprint(keys) != "[k00 k02 k04]" {
+			t.Errorf("%s: expected ([k00 k02 k04]), found (%v)", driver, keys)
+		}
+
+		keyFilter := func(k []byte) bool {
+			return k[len(k)-1] == '0'
+		}
+		if keys := scan(db, goukv.ScanOpts{KeyFilter: keyFilter, Filter: even}, 4); fmt.Sprint(keys) != "[k00 k10 k20 k30]" {
+			t.Errorf("%s: expected ([k00 k10 k20 k30]), found (%v)", driver, keys)
+		}
+
+		// the filters of a keyspace see its own keys
+		keyFilter = func(k []byte) bool {
+			return string(k) >= "k90"
+		}
+		if keys := scan(space, goukv.ScanOpts{KeyFilter: keyFilter, Filter: even}, 0); fmt.Sprint(keys) != "[k90 k92 k94 k96 k98]" {
+			t.Errorf("%s: expected ([k90 k92 k94 k96 k98]), found (%v)", driver, keys)
+		}
+	}
+
+	var shards []goukv.Provider
+	for i := 0; i < 3; i++ {
+		shard, cleanup := openTempDB(t, "goleveldb", nil)
+		defer cleanup()
+		shards = append(shards, shard)
+	}
+
+	db := goukv.Shard(shards, nil)
+	for i := 0; i < 20; i++ {
+		v := "odd"
+		if i%2 == 0 {
+			v = "even"
+		}
+		db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%02d", i)), Value: []byte(v)})
+	}
+
+	if keys := scan(db, goukv.ScanOpts{Filter: even, MaxBytes: 16}, 0); fmt.Sprint(keys) != "[k00 k02 k04 k06]" {
+		t.Errorf("expected the merged scan to be filtered, found (%v)", keys)
+	}
+}
+
+func TestScanOnScannerError(t *testing.T) {
+	errBadRecord := errors.New("bad record")
+
+	// scan returns the delivered keys, the keys passed to OnScannerError and the error of the scan,
+	// the scanner fails on the keys ending with 3
+	scan := func(db goukv.Provider, opts goukv.ScanOpts, tolerate bool) ([]string, []string, error) {
+		var keys, failed []string
+		opts.Scanner = func(k, v []byte) error {
+			if k[len(k)-1] == '3' {
+				return errBadRecord
+			}
+			keys = append(keys, string(k))
+			return nil
+		}
+		opts.OnScannerError = func(k []byte, err error) bool {
+			if err != errBadRecord {
+				t.Errorf("expected (%v), found (%v)", errBadRecord, err)
+			}
+			failed = append(failed, string(k))
+			return tolerate
+		}
+		err := db.Scan(opts)
+		return keys, failed, err
+	}
+
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		space := goukv.NewKeyspace(db, "space")
+		for i := 0; i < 20; i++ {
+			db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%02d", i)), Value: []byte("v")})
+			space.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%02d", i)), Value: []byte("v")})
+		}
+
+		keys, failed, err := scan(db, goukv.ScanOpts{Prefix: []byte("k0")}, true)
+		if err != nil || len(keys) != 9 || fmt.Sprint(failed) != "[k03]" {
+			t.Errorf("%s: expected the bad record to be skipped, found (%v, %v, %v)", driver, keys, failed, err)
+		}
+
+		keys, failed, err = scan(db, goukv.ScanOpts{Prefix: []byte("k")}, false)
+		if err != errBadRecord || fmt.Sprint(keys) != "[k00 k01 k02]" || fmt.Sprint(failed) != "[k03]" {
+			t.Errorf("%s: expected the scan to abort on the bad record, found (%v, %v, %v)", driver, keys, failed, err)
+		}
+
+		// the policy of a keyspace sees its own keys
+		keys, failed, err = scan(space, goukv.ScanOpts{}, true)
+		if err != nil || len(keys) != 18 || fmt.Sprint(failed) != "[k03 k13]" {
+			t.Errorf("%s: expected the bad records of the keyspace to be skipped, found (%v, %v, %v)", driver, keys, failed, err)
+		}
+	}
+
+	// without a policy the scan aborts
+	db, cleanup := openTempDB(t, "goleveldb", nil)
+	defer cleanup()
+
+	db.Put(&goukv.Entry{Key: []byte("k3"), Value: []byte("v")})
+	err := db.Scan(goukv.ScanOpts{Scanner: func(k, v []byte) error {
+		return errBadRecord
+	}})
+	if err != errBadRecord {
+		t.Errorf("expected the scan to abort by default, found (%v)", err)
+	}
+}
+
+func TestScanSampleRate(t *testing.T) {
+	// scan returns the scanned keys
+	scan := func(db goukv.Provider, opts goukv.ScanOpts) []string {
+		var keys []string
+		opts.Scanner = func(k, v []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		}
+		if err := db.Scan(opts); err != nil {
+			t.Fatal(err)
+		}
+		return keys
+	}
+
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		space := goukv.NewKeyspace(db, "space")
+
+		entries := make([]*goukv.Entry, 0, 5000)
+		for i := 0; i < 5000; i++ {
+			entries = append(entries, &goukv.Entry{Key: []byte(fmt.Sprintf("k%05d", i)), Value: []byte("v")})
+		}
+		db.Batch(entries)
+		space.Batch(entries[:1000])
+
+		keys := scan(db, goukv.ScanOpts{Prefix: []byte("k"), SampleRate: 0.1})
+		if len(keys) < 400 || len(keys) > 600 {
+			t.Errorf("%s: expected roughly (500) sampled keys, found (%d)", driver, len(keys))
+		}
+
+		if !sort.StringsAreSorted(keys) {
+			t.Errorf("%s: expected the sampled keys to be ordered", driver)
+		}
+
+		for _, k := range keys {
+			if !goukv.InSample([]byte(k), 0.1) {
+				t.Errorf("%s: expected (%s) to be part of the sample", driver, k)
+			}
+		}
+
+		// the sample is stable and grows with the rate
+		if again := scan(db, goukv.ScanOpts{Prefix: []byte("k"), SampleRate: 0.1}); fmt.Sprint(again) != fmt.Sprint(keys) {
+			t.Errorf("%s: expected the same keys to be sampled again", driver)
+		}
+
+		larger := map[string]bool{}
+		for _, k := range scan(db, goukv.ScanOpts{Prefix: []byte("k"), SampleRate: 0.5}) {
+			larger[k] = true
+		}
+		for _, k := range keys {
+			if !larger[k] {
+				t.Errorf("%s: expected (%s) to be part of the larger sample", driver, k)
+			}
+		}
+
+		// the excluded keys don't reach KeyFilter
+		filtered := 0
+		scan(db, goukv.ScanOpts{Prefix: []byte("k"), SampleRate: 0.1, KeyFilter: func(k []byte) bool {
+			filtered++
+			return true
+		}})
+		if filtered != len(keys) {
+			t.Errorf("%s: expected (%d) keys to be filtered, found (%d)", driver, len(keys), filtered)
+		}
+
+		// a keyspace samples its own keys
+		for _, k := range scan(space, goukv.ScanOpts{SampleRate: 0.1}) {
+			if !goukv.InSample([]byte(k), 0.1) {
+				t.Errorf("%s: expected (%s) of the keyspace to be part of the sample", driver, k)
+			}
+		}
+
+		if all := scan(db, goukv.ScanOpts{Prefix: []byte("k")}); len(all) != 5000 {
+			t.Errorf("%s: expected no sampling without a rate, found (%d) keys", driver, len(all))
+		}
+	}
+}
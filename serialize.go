@@ -0,0 +1,150 @@
+package goukv
+
+import (
+	"sync"
+	"time"
+)
+
+// serializedProvider a provider running the operations of its callers one at a time on a single worker goroutine
+type serializedProvider struct {
+	p        Provider
+	requests chan func()
+
+	// the callers hold closeLock shared while they enqueue, Close holds it exclusively to mark it closed
+	closeLock *sync.RWMutex
+	closed    bool
+}
+
+// Serialize returns a provider queueing its operations (up to queueSize of them, 0 means unbuffered) to be run one at a
+// time by a single worker goroutine, so p is never accessed concurrently, the callers block until their operation has
+// run and, while the queue is full, until it has room for theirs (backpressure). the scanners of Scan run on the worker
+// so they must not call the returned provider (it would deadlock), the operations following Close return ErrClosed
+func Serialize(p Provider, queueSize int) Provider {
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	s := &serializedProvider{
+		p:         p,
+		requests:  make(chan func(), queueSize),
+		closeLock: &sync.RWMutex{},
+	}
+
+	go (func() {
+		for fn := range s.requests {
+			fn()
+		}
+	})()
+
+	return s
+}
+
+// do runs fn on the worker and waits for it
+func (s *serializedProvider) do(fn func()) error {
+	done := make(chan struct{})
+
+	s.closeLock.RLock()
+	if s.closed {
+		s.closeLock.RUnlock()
+		return ErrClosed
+	}
+	s.requests <- func() {
+		fn()
+		close(done)
+	}
+	s.closeLock.RUnlock()
+
+	<-done
+
+	return nil
+}
+
+// QueueLen returns the number of operations waiting for the worker, to monitor the backpressure
+func (s *serializedProvider) QueueLen() int {
+	return len(s.requests)
+}
+
+// Open implements goukv.Open, open the provider then wrap it using Serialize instead
+func (s *serializedProvider) Open(map[string]interface{}) (Provider, error) {
+	return nil, ErrNotSupported
+}
+
+// Put implements goukv.Put
+func (s *serializedProvider) Put(e *Entry) error {
+	var err error
+	if doErr := s.do(func() { err = s.p.Put(e) }); doErr != nil {
+		return doErr
+	}
+	return err
+}
+
+// Get implements goukv.Get
+func (s *serializedProvider) Get(k []byte) ([]byte, error) {
+	var val []byte
+	var err error
+	if doErr := s.do(func() { val, err = s.p.Get(k) }); doErr != nil {
+		return nil, doErr
+	}
+	return val, err
+}
+
+// TTL implements goukv.TTL
+func (s *serializedProvider) TTL(k []byte) (*time.Time, error) {
+	var expires *time.Time
+	var err error
+	if doErr := s.do(func() { expires, err = s.p.TTL(k) }); doErr != nil {
+		return nil, doErr
+	}
+	return expires, err
+}
+
+// Delete implements goukv.Delete
+func (s *serializedProvider) Delete(k []byte) error {
+	var err error
+	if doErr := s.do(func() { err = s.p.Delete(k) }); doErr != nil {
+		return doErr
+	}
+	return err
+}
+
+// Batch implements goukv.Batch
+func (s *serializedProvider) Batch(entries []*Entry) error {
+	var err error
+	if doErr := s.do(func() { err = s.p.Batch(entries) }); doErr != nil {
+		return doErr
+	}
+	return err
+}
+
+// Scan implements goukv.Scan, the whole scan (including the scanner) runs on the worker
+func (s *serializedProvider) Scan(opts ScanOpts) error {
+	var err error
+	if doErr := s.do(func() { err = s.p.Scan(opts) }); doErr != nil {
+		return doErr
+	}
+	return err
+}
+
+// Close implements goukv.Close, the operations queued before it run first then p is closed and the worker stops
+func (s *serializedProvider) Close() error {
+	s.closeLock.Lock()
+	if s.closed {
+		s.closeLock.Unlock()
+		return ErrClosed
+	}
+	s.closed = true
+	s.closeLock.Unlock()
+
+	// nothing can be queued anymore
+	var err error
+	done := make(chan struct{})
+	s.requests <- func() {
+		err = s.p.Close()
+		close(done)
+	}
+	close(s.requests)
+
+	<-done
+
+	return err
+}
@@ -0,0 +1,122 @@
+package goukv_test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+// trackingProvider a provider recording how many of its puts run at once, the puts wait for release when it's set
+type trackingProvider struct {
+	goukv.Provider
+	active, maxActive int32
+	started           chan string
+	release           chan struct{}
+}
+
+func (p *trackingProvider) Put(e *goukv.Entry) error {
+	active := atomic.AddInt32(&p.active, 1)
+	defer atomic.AddInt32(&p.active, -1)
+
+	for {
+		max := atomic.LoadInt32(&p.maxActive)
+		if active <= max || atomic.CompareAndSwapInt32(&p.maxActive, max, active) {
+			break
+		}
+	}
+
+	if p.started != nil {
+		p.started <- string(e.Key)
+	}
+
+	if p.release != nil {
+		<-p.release
+	} else {
+		time.Sleep(time.Millisecond)
+	}
+
+	return p.Provider.Put(e)
+}
+
+func TestSerialize(t *testing.T) {
+	db, cleanup := openTempDB(t, "goleveldb", nil)
+	defer cleanup()
+
+	tracking := &trackingProvider{Provider: db}
+	serialized := goukv.Serialize(tracking, 4)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go (func(i int) {
+			defer wg.Done()
+			if err := serialized.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%d", i)), Value: []byte("v")}); err != nil {
+				t.Error(err)
+			}
+		})(i)
+	}
+	wg.Wait()
+
+	if tracking.maxActive != 1 {
+		t.Errorf("expected a single put at a time, found (%d)", tracking.maxActive)
+	}
+
+	for i := 0; i < 20; i++ {
+		if _, err := serialized.Get([]byte(fmt.Sprintf("k%d", i))); err != nil {
+			t.Errorf("expected (k%d) to be written, found (%v)", i, err)
+		}
+	}
+}
+
+func TestSerializeBackpressure(t *testing.T) {
+	db, cleanup := openTempDB(t, "goleveldb", nil)
+	defer cleanup()
+
+	tracking := &trackingProvider{Provider: db, started: make(chan string, 3), release: make(chan struct{})}
+	serialized := goukv.Serialize(tracking, 1)
+	queue := serialized.(interface{ QueueLen() int })
+
+	put := func(k string) chan error {
+		result := make(chan error, 1)
+		go (func() {
+			result <- serialized.Put(&goukv.Entry{Key: []byte(k), Value: []byte("v")})
+		})()
+		return result
+	}
+
+	// k1 holds the worker, k2 fills the queue and k3 waits for room
+	first := put("k1")
+	<-tracking.started
+
+	second := put("k2")
+	for queue.QueueLen() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	third := put("k3")
+	time.Sleep(50 * time.Millisecond)
+
+	if n := queue.QueueLen(); n != 1 {
+		t.Errorf("expected the queue to stay bounded to (1), found (%d)", n)
+	}
+
+	close(tracking.release)
+
+	for _, result := range []chan error{first, second, third} {
+		if err := <-result; err != nil {
+			t.Error(err)
+		}
+	}
+
+	if err := serialized.Close(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := serialized.Get([]byte("k1")); err != goukv.ErrClosed {
+		t.Errorf("expected (%v) after Close, found (%v)", goukv.ErrClosed, err)
+	}
+}
@@ -0,0 +1,316 @@
+package goukv
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"runtime/debug"
+	"sync"
+
+	"github.com/alash3al/goukv/internal/wire"
+)
+
+// DefaultMaxRequestSize the maximum size of a request (its msgpack encoding) a Server accepts by default
+const DefaultMaxRequestSize = 8 << 20
+
+// Server serves a provider to the remote clients (see the remote provider) over a length-prefixed
+// msgpack protocol, every connection is served on its own goroutine one request at a time
+type Server struct {
+	p Provider
+
+	// MaxRequestSize the maximum size of a request (its msgpack encoding, i.e: the keys and values of a batch),
+	// a larger one is answered by an error then its connection is closed, DefaultMaxRequestSize by default,
+	// it must be set before serving
+	MaxRequestSize int
+
+	// ErrorLog logs the panics of the requests (their value and stack), the standard logger when nil,
+	// it must be set before serving
+	ErrorLog *log.Logger
+
+	mu        sync.Mutex
+	closed    bool
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	handlers  sync.WaitGroup
+}
+
+// NewServer returns a server of the specified provider, the provider is owned by the caller so closing the server
+// doesn't close it
+func NewServer(p Provider) *Server {
+	return &Server{
+		p:              p,
+		MaxRequestSize: DefaultMaxRequestSize,
+		listeners:      map[net.Listener]struct{}{},
+		conns:          map[net.Conn]struct{}{},
+	}
+}
+
+// ListenAndServe listens on the specified tcp address (using tls when tlsConfig is set) then serves it, see Serve
+func (s *Server) ListenAndServe(addr string, tlsConfig *tls.Config) error {
+	var l net.Listener
+	var err error
+
+	if tlsConfig != nil {
+		l, err = tls.Listen("tcp", addr, tlsConfig)
+	} else {
+		l, err = net.Listen("tcp", addr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(l)
+}
+
+// Serve accepts the connections of the specified listener until it fails or the server is closed,
+// ErrClosed is returned once the server is closed, the listener is closed either way
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	defer (func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+		l.Close()
+	})()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+
+			if closed {
+				return ErrClosed
+			}
+
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return ErrClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.handlers.Add(1)
+		s.mu.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+// Close stops the listeners, closes the connections and waits for their handlers, the provider isn't closed
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.handlers.Wait()
+
+	return nil
+}
+
+// handle serves the requests of the specified connection until it's closed or broken
+func (s *Server) handle(conn net.Conn) {
+	defer (func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+		s.handlers.Done()
+	})()
+
+	maxRequestSize := s.MaxRequestSize
+	if maxRequestSize <= 0 {
+		maxRequestSize = DefaultMaxRequestSize
+	}
+
+	r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+
+	for {
+		var req wire.Request
+		if err := wire.ReadLimit(r, &req, maxRequestSize); err != nil {
+			// the rest of the request can't be skipped, the client is told why its connection is closed
+			if err == wire.ErrFrameTooLarge && wire.Write(w, &wire.Response{Err: err.Error(), Close: true}) == nil {
+				w.Flush()
+			}
+			return
+		}
+
+		if err := s.serve(conn, w, &req); err != nil || w.Flush() != nil {
+			return
+		}
+	}
+}
+
+// errPanicked returned to the clients whose requests panicked
+var errPanicked = errors.New("goukv: the server panicked while serving the request")
+
+// serve serves the specified request of the specified connection, a panic (i.e: in the provider) is logged
+// then answered by an error response (the final one of a scan) and errPanicked is returned so that the
+// connection is closed, the state of the request is unknown
+func (s *Server) serve(conn net.Conn, w *bufio.Writer, req *wire.Request) (err error) {
+	defer (func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+
+		s.logf("goukv: panic serving %v: %v\n%s", conn.RemoteAddr(), v, debug.Stack())
+
+		if wire.Write(w, &wire.Response{Err: errPanicked.Error(), Done: req.Op == wire.OpScan, Close: true}) == nil {
+			w.Flush()
+		}
+		err = errPanicked
+	})()
+
+	if req.Op == wire.OpScan {
+		return s.scan(w, req)
+	}
+
+	return wire.Write(w, s.do(req))
+}
+
+// logf logs to ErrorLog, or the standard logger when it's nil
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+		return
+	}
+
+	log.Printf(format, args...)
+}
+
+// errNilEntry returned to the clients whose batches hold a nil entry
+var errNilEntry = errors.New("goukv: a batch can't hold a nil entry")
+
+// do runs the specified request except the scans
+func (s *Server) do(req *wire.Request) *wire.Response {
+	res := &wire.Response{}
+
+	var err error
+	switch req.Op {
+	case wire.OpPing:
+	case wire.OpGet:
+		res.Value, err = s.p.Get(req.Key)
+	case wire.OpTTL:
+		res.Expires, err = s.p.TTL(req.Key)
+	case wire.OpDelete:
+		err = s.p.Delete(req.Key)
+	case wire.OpPut:
+		if len(req.Entries) != 1 || req.Entries[0] == nil {
+			err = errors.New("goukv: a put requires a single entry")
+			break
+		}
+		err = s.p.Put(entryFromWire(req.Entries[0]))
+	case wire.OpBatch:
+		entries := make([]*Entry, len(req.Entries))
+		for i, e := range req.Entries {
+			if e == nil {
+				err = errNilEntry
+				break
+			}
+			entries[i] = entryFromWire(e)
+		}
+		if err == nil {
+			err = s.p.Batch(entries)
+		}
+	default:
+		err = ErrNotSupported
+	}
+
+	setResponseError(res, err)
+
+	return res
+}
+
+// scan streams the pairs of the specified scan request, a response per pair then a Done one,
+// a failed write (i.e: the client closed the connection to stop the scan) stops it
+func (s *Server) scan(w *bufio.Writer, req *wire.Request) error {
+	opts := ScanOpts{}
+	if req.Scan != nil {
+		opts = ScanOpts{
+			Prefix:            req.Scan.Prefix,
+			Offset:            req.Scan.Offset,
+			IncludeOffset:     req.Scan.IncludeOffset,
+			ReverseScan:       req.Scan.ReverseScan,
+			Consistent:        req.Scan.Consistent,
+			SinceVersion:      req.Scan.SinceVersion,
+			IncludeTombstones: req.Scan.IncludeTombstones,
+			MaxBytes:          req.Scan.MaxBytes,
+			IncludeInternal:   req.Scan.IncludeInternal,
+			ProgressInterval:  req.Scan.ProgressInterval,
+		}
+	}
+
+	var writeErr error
+	opts.Scanner = func(k, v []byte) error {
+		if writeErr = wire.Write(w, &wire.Response{Key: k, Value: v}); writeErr != nil {
+			return ErrScanDone
+		}
+		return nil
+	}
+
+	// a failed report is detected by the next pair
+	if opts.ProgressInterval > 0 {
+		opts.Progress = func(scanned int64) {
+			if writeErr == nil {
+				writeErr = wire.Write(w, &wire.Response{Scanned: scanned})
+			}
+		}
+	}
+
+	err := s.p.Scan(opts)
+	if writeErr != nil {
+		return writeErr
+	}
+
+	res := &wire.Response{Done: true}
+	setResponseError(res, err)
+
+	return wire.Write(w, res)
+}
+
+// entryFromWire returns the entry of the specified wire entry
+func entryFromWire(e *wire.Entry) *Entry {
+	return &Entry{
+		Key:      e.Key,
+		Value:    e.Value,
+		TTL:      e.TTL,
+		ExpireAt: e.ExpireAt,
+	}
+}
+
+// setResponseError sets the error of the specified response, with its kind when it's classified
+func setResponseError(res *wire.Response, err error) {
+	if err == nil {
+		return
+	}
+
+	var classified *Error
+	if errors.As(err, &classified) {
+		res.ErrKind = uint8(classified.Kind)
+		err = classified.Err
+	}
+
+	res.Err = err.Error()
+}
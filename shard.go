@@ -0,0 +1,284 @@
+package goukv
+
+import (
+	"bytes"
+	"container/heap"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// sharded a provider that spreads the keys across multiple providers
+type sharded struct {
+	shards []Provider
+	hash   func([]byte) int
+}
+
+// Shard returns a provider that routes each key to one of the specified providers,
+// hash maps a key to its shard index (reduced modulo the shards count), when nil
+// a jump consistent hash of the key is used so that adding a shard moves as few keys as possible.
+// single-key operations hit the owning shard only, Batch and Scan fan out to all of the shards.
+// it panics without providers since no key could be routed
+func Shard(providers []Provider, hash func([]byte) int) Provider {
+	if len(providers) == 0 {
+		panic("goukv: Shard requires at least one provider")
+	}
+
+	if hash == nil {
+		hash = func(k []byte) int {
+			return JumpHash(k, len(providers))
+		}
+	}
+
+	return &sharded{
+		shards: providers,
+		hash:   hash,
+	}
+}
+
+// JumpHash maps the specified key to one of n buckets using the jump consistent hash
+func JumpHash(k []byte, n int) int {
+	h := fnv.New64a()
+	h.Write(k)
+
+	key, b, j := h.Sum64(), int64(-1), int64(0)
+	for j < int64(n) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+
+	return int(b)
+}
+
+// shardIndex returns the index of the shard owning the specified key
+func (s *sharded) shardIndex(k []byte) int {
+	i := s.hash(k) % len(s.shards)
+	if i < 0 {
+		i += len(s.shards)
+	}
+	return i
+}
+
+// shardOf returns the provider owning the specified key
+func (s *sharded) shardOf(k []byte) Provider {
+	return s.shards[s.shardIndex(k)]
+}
+
+// Open implements goukv.Open, the shards must be opened by the caller
+func (s *sharded) Open(map[string]interface{}) (Provider, error) {
+	return nil, ErrNotSupported
+}
+
+// Put implements goukv.Put
+func (s *sharded) Put(e *Entry) error {
+	return s.shardOf(e.Key).Put(e)
+}
+
+// Get implements goukv.Get
+func (s *sharded) Get(k []byte) ([]byte, error) {
+	return s.shardOf(k).Get(k)
+}
+
+// TTL implements goukv.TTL
+func (s *sharded) TTL(k []byte) (*time.Time, error) {
+	return s.shardOf(k).TTL(k)
+}
+
+// Delete implements goukv.Delete
+func (s *sharded) Delete(k []byte) error {
+	return s.shardOf(k).Delete(k)
+}
+
+// Batch implements goukv.Batch, the entries are grouped by shard and written concurrently,
+// each shard applies its group atomically but the batch as a whole isn't atomic
+func (s *sharded) Batch(entries []*Entry) error {
+	_, err := s.BatchDetailed(entries)
+	return err
+}
+
+// BatchDetailed implements goukv.DetailedBatcher, the entries of a group share the result of their shard
+// (or get their own when the shard implements DetailedBatcher too)
+func (s *sharded) BatchDetailed(entries []*Entry) ([]error, error) {
+	groups, positions := map[int][]*Entry{}, map[int][]int{}
+	for pos, entry := range entries {
+		i := s.shardIndex(entry.Key)
+		groups[i] = append(groups[i], entry)
+		positions[i] = append(positions[i], pos)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]error, len(entries))
+	errs := make(chan error, len(groups))
+	for i, group := range groups {
+		wg.Add(1)
+		go (func(shard Provider, group []*Entry, positions []int) {
+			defer wg.Done()
+
+			// the groups fill disjoint positions of results
+			groupResults, err := BatchDetailed(shard, group)
+			for j, pos := range positions {
+				results[pos] = groupResults[j]
+			}
+
+			errs <- err
+		})(s.shards[i], group, positions[i])
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
+// Scan implements goukv.Scan, every shard is scanned with the same options and the
+// ordered streams are merged using a k-way merge so that the global order is preserved
+func (s *sharded) Scan(opts ScanOpts) error {
+	if opts.Scanner == nil {
+		return ErrNoScanner
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	defer (func() {
+		close(done)
+		wg.Wait()
+	})()
+
+	// the shards report every key to a shared counter so Progress sees the total of the merged scan
+	var progress func(int64)
+	if opts.Progress != nil {
+		var progressLock sync.Mutex
+		tick := opts.ProgressTicker()
+		progress = func(int64) {
+			progressLock.Lock()
+			tick()
+			progressLock.Unlock()
+		}
+	}
+
+	streams := make([]chan shardItem, len(s.shards))
+	errs := make([]error, len(s.shards))
+	for i, shard := range s.shards {
+		streams[i] = make(chan shardItem, 64)
+
+		wg.Add(1)
+		go (func(i int, shard Provider) {
+			defer wg.Done()
+			defer close(streams[i])
+
+			// the merged scan enforces MaxBytes, a shard stopping early would leave a gap in it
+			shardOpts := opts
+			shardOpts.MaxBytes = 0
+			shardOpts.Progress, shardOpts.ProgressInterval = progress, 1
+			shardOpts.Scanner = func(k, v []byte) error {
+				select {
+				case streams[i] <- shardItem{key: k, value: v}:
+					return nil
+				case <-done:
+					return ErrScanDone
+				}
+			}
+
+			errs[i] = shard.Scan(shardOpts)
+		})(i, shard)
+	}
+
+	// the shards apply the filters and the sample
+	merged := opts
+	merged.KeyFilter, merged.Filter, merged.SampleRate = nil, nil, 0
+	scanner := merged.BoundedScanner()
+
+	h := &shardHeap{reverse: opts.ReverseScan}
+	for i := range streams {
+		item, ok := <-streams[i]
+		if !ok {
+			if errs[i] != nil {
+				return errs[i]
+			}
+			continue
+		}
+		item.stream = i
+		h.items = append(h.items, item)
+	}
+	heap.Init(h)
+
+	for h.Len() > 0 {
+		head := h.items[0]
+		if err := scanner(head.key, head.value); err != nil {
+			if err == ErrScanDone {
+				break
+			}
+			return err
+		}
+
+		item, ok := <-streams[head.stream]
+		if !ok {
+			if errs[head.stream] != nil {
+				return errs[head.stream]
+			}
+			heap.Pop(h)
+			continue
+		}
+
+		item.stream = head.stream
+		h.items[0] = item
+		heap.Fix(h, 0)
+	}
+
+	return nil
+}
+
+// Close implements goukv.Close, it closes all of the shards
+func (s *sharded) Close() error {
+	var firstErr error
+	for _, shard := range s.shards {
+		if err := shard.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// shardItem a key-value pair produced by a shard stream
+type shardItem struct {
+	key, value []byte
+	stream     int
+}
+
+// shardHeap a heap of the current head of each shard stream
+type shardHeap struct {
+	items   []shardItem
+	reverse bool
+}
+
+func (h *shardHeap) Len() int {
+	return len(h.items)
+}
+
+func (h *shardHeap) Less(i, j int) bool {
+	if h.reverse {
+		return bytes.Compare(h.items[i].key, h.items[j].key) > 0
+	}
+	return bytes.Compare(h.items[i].key, h.items[j].key) < 0
+}
+
+func (h *shardHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *shardHeap) Push(x interface{}) {
+	h.items = append(h.items, x.(shardItem))
+}
+
+func (h *shardHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
@@ -0,0 +1,108 @@
+package goukv_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestShard(t *testing.T) {
+	var shards []goukv.Provider
+	for i := 0; i < 3; i++ {
+		shard, cleanup := openTempDB(t, "goleveldb", nil)
+		defer cleanup()
+		shards = append(shards, shard)
+	}
+	db := goukv.Shard(shards, nil)
+
+	var entries []*goukv.Entry
+	for i := 0; i < 100; i++ {
+		entries = append(entries, &goukv.Entry{
+			Key:   []byte(fmt.Sprintf("k%03d", i)),
+			Value: []byte(fmt.Sprintf("v%03d", i)),
+		})
+	}
+	if err := db.Batch(entries); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, shard := range shards {
+		empty := true
+		shard.Scan(goukv.ScanOpts{Scanner: func(k, v []byte) error {
+			empty = false
+			return goukv.ErrScanDone
+		}})
+		if empty {
+			t.Error("expected the keys to be spread across all of the shards")
+		}
+	}
+
+	val, err := db.Get([]byte("k042"))
+	if err != nil || string(val) != "v042" {
+		t.Errorf("expected (v042), found (%s, %v)", val, err)
+	}
+
+	for _, reverse := range []bool{false, true} {
+		var keys [][]byte
+		err := db.Scan(goukv.ScanOpts{
+			ReverseScan: reverse,
+			Scanner: func(k, v []byte) error {
+				keys = append(keys, k)
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != len(entries) {
+			t.Fatalf("expected (%d) keys, found (%d)", len(entries), len(keys))
+		}
+		for i := 1; i < len(keys); i++ {
+			cmp := bytes.Compare(keys[i-1], keys[i])
+			if (!reverse && cmp >= 0) || (reverse && cmp <= 0) {
+				t.Fatalf("expected ordered keys (reverse: %v), found (%s) before (%s)", reverse, keys[i-1], keys[i])
+			}
+		}
+	}
+
+	var keys []string
+	db.Scan(goukv.ScanOpts{
+		Offset: []byte("k010"),
+		Scanner: func(k, v []byte) error {
+			keys = append(keys, string(k))
+			if len(keys) == 3 {
+				return goukv.ErrScanDone
+			}
+			return nil
+		},
+	})
+	if fmt.Sprint(keys) != "[k011 k012 k013]" {
+		t.Errorf("expected ([k011 k012 k013]), found (%v)", keys)
+	}
+
+	var reports []int64
+	db.Scan(goukv.ScanOpts{
+		ProgressInterval: 25,
+		Progress: func(scanned int64) {
+			reports = append(reports, scanned)
+		},
+		Scanner: func(k, v []byte) error {
+			return nil
+		},
+	})
+	if fmt.Sprint(reports) != "[25 50 75 100]" {
+		t.Errorf("expected the progress of the merged scan, found (%v)", reports)
+	}
+}
+
+func TestShardWithoutProviders(t *testing.T) {
+	defer (func() {
+		if recover() == nil {
+			t.Error("expected a Shard without providers to panic")
+		}
+	})()
+
+	goukv.Shard(nil, nil)
+}
@@ -0,0 +1,164 @@
+package goukv
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats the operation counters of a provider wrapped using WithStats, they are updated atomically
+type Stats struct {
+	gets      int64
+	hits      int64
+	misses    int64
+	puts      int64
+	deletes   int64
+	scanItems int64
+}
+
+// Gets returns the number of Get calls
+func (s *Stats) Gets() int64 {
+	return atomic.LoadInt64(&s.gets)
+}
+
+// Hits returns the number of Get calls that found their key
+func (s *Stats) Hits() int64 {
+	return atomic.LoadInt64(&s.hits)
+}
+
+// Misses returns the number of Get calls that returned ErrKeyNotFound
+func (s *Stats) Misses() int64 {
+	return atomic.LoadInt64(&s.misses)
+}
+
+// HitRatio returns the ratio of the hits to the hits and the misses, 0 when there are none
+func (s *Stats) HitRatio() float64 {
+	hits, misses := s.Hits(), s.Misses()
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+// Puts returns the number of written entries (by Put and Batch)
+func (s *Stats) Puts() int64 {
+	return atomic.LoadInt64(&s.puts)
+}
+
+// Deletes returns the number of deleted keys (by Delete and Batch)
+func (s *Stats) Deletes() int64 {
+	return atomic.LoadInt64(&s.deletes)
+}
+
+// ScanItems returns the number of items passed to the scanners
+func (s *Stats) ScanItems() int64 {
+	return atomic.LoadInt64(&s.scanItems)
+}
+
+// Reset sets all the counters back to zero
+func (s *Stats) Reset() {
+	atomic.StoreInt64(&s.gets, 0)
+	atomic.StoreInt64(&s.hits, 0)
+	atomic.StoreInt64(&s.misses, 0)
+	atomic.StoreInt64(&s.puts, 0)
+	atomic.StoreInt64(&s.deletes, 0)
+	atomic.StoreInt64(&s.scanItems, 0)
+}
+
+// statsProvider a provider that counts its operations
+type statsProvider struct {
+	p     Provider
+	stats *Stats
+}
+
+// WithStats returns a provider that counts the operations it passes to p in the returned Stats,
+// only the successful writes are counted, gets are counted whatever their result
+func WithStats(p Provider) (Provider, *Stats) {
+	stats := &Stats{}
+	return &statsProvider{
+		p:     p,
+		stats: stats,
+	}, stats
+}
+
+// Open implements goukv.Open, open the provider then wrap it using WithStats instead
+func (s *statsProvider) Open(map[string]interface{}) (Provider, error) {
+	return nil, ErrNotSupported
+}
+
+// Put implements goukv.Put
+func (s *statsProvider) Put(e *Entry) error {
+	if err := s.p.Put(e); err != nil {
+		return err
+	}
+	atomic.AddInt64(&s.stats.puts, 1)
+	return nil
+}
+
+// Get implements goukv.Get
+func (s *statsProvider) Get(k []byte) ([]byte, error) {
+	atomic.AddInt64(&s.stats.gets, 1)
+
+	val, err := s.p.Get(k)
+	if err == nil {
+		atomic.AddInt64(&s.stats.hits, 1)
+	} else if err == ErrKeyNotFound {
+		atomic.AddInt64(&s.stats.misses, 1)
+	}
+
+	return val, err
+}
+
+// TTL implements goukv.TTL
+func (s *statsProvider) TTL(k []byte) (*time.Time, error) {
+	return s.p.TTL(k)
+}
+
+// Delete implements goukv.Delete
+func (s *statsProvider) Delete(k []byte) error {
+	if err := s.p.Delete(k); err != nil {
+		return err
+	}
+	atomic.AddInt64(&s.stats.deletes, 1)
+	return nil
+}
+
+// Batch implements goukv.Batch, nil values are counted as deletes
+func (s *statsProvider) Batch(entries []*Entry) error {
+	if err := s.p.Batch(entries); err != nil {
+		return err
+	}
+
+	var puts, deletes int64
+	for _, e := range entries {
+		if e.Value == nil {
+			deletes++
+		} else {
+			puts++
+		}
+	}
+
+	atomic.AddInt64(&s.stats.puts, puts)
+	atomic.AddInt64(&s.stats.deletes, deletes)
+
+	return nil
+}
+
+// Scan implements goukv.Scan
+func (s *statsProvider) Scan(opts ScanOpts) error {
+	if opts.Scanner == nil {
+		return ErrNoScanner
+	}
+
+	scanner := opts.Scanner
+	opts.Scanner = func(k, v []byte) error {
+		atomic.AddInt64(&s.stats.scanItems, 1)
+		return scanner(k, v)
+	}
+
+	return s.p.Scan(opts)
+}
+
+// Close implements goukv.Close
+func (s *statsProvider) Close() error {
+	return s.p.Close()
+}
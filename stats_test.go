@@ -0,0 +1,53 @@
+package goukv_test
+
+import (
+	"testing"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestWithStats(t *testing.T) {
+	db, cleanup := openTempDB(t, "goleveldb", nil)
+	defer cleanup()
+
+	db, stats := goukv.WithStats(db)
+
+	db.Put(&goukv.Entry{Key: []byte("k1"), Value: []byte("v")})
+	db.Batch([]*goukv.Entry{
+		{Key: []byte("k2"), Value: []byte("v")},
+		{Key: []byte("k3"), Value: []byte("v")},
+		{Key: []byte("k1"), Value: nil},
+	})
+	db.Delete([]byte("k2"))
+
+	db.Get([]byte("k1"))
+	db.Get([]byte("k2"))
+	db.Get([]byte("k3"))
+
+	db.Scan(goukv.ScanOpts{Scanner: func(k, v []byte) error { return nil }})
+
+	counters := map[string][2]int64{
+		"gets":       {stats.Gets(), 3},
+		"hits":       {stats.Hits(), 1},
+		"misses":     {stats.Misses(), 2},
+		"puts":       {stats.Puts(), 3},
+		"deletes":    {stats.Deletes(), 2},
+		"scan items": {stats.ScanItems(), 1},
+	}
+
+	for name, c := range counters {
+		if c[0] != c[1] {
+			t.Errorf("expected (%d) %s, found (%d)", c[1], name, c[0])
+		}
+	}
+
+	if ratio := stats.HitRatio(); ratio < 0.33 || ratio > 0.34 {
+		t.Errorf("expected a hit ratio of 1/3, found (%f)", ratio)
+	}
+
+	stats.Reset()
+
+	if stats.Gets() != 0 || stats.Puts() != 0 || stats.ScanItems() != 0 || stats.HitRatio() != 0 {
+		t.Error("expected the counters to be reset")
+	}
+}
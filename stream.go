@@ -0,0 +1,230 @@
+package goukv
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+// StreamPrefix the reserved prefix the chunks of the streamed values are stored under,
+// it's also the header of their manifests (see IsStream)
+var StreamPrefix = []byte("\x00goukv\x00stream\x00")
+
+// DefaultStreamChunkSize the size of the chunks of a streamed value by default
+const DefaultStreamChunkSize = 1 << 20
+
+// streamIDSize the size of the random id the chunks of a streamed value are stored under
+const streamIDSize = 16
+
+// streamManifest describes the chunks of a streamed value, it's stored under its key
+type streamManifest struct {
+	ID     []byte `msgpack:"id"`
+	Chunks uint32 `msgpack:"chunks"`
+	Size   int64  `msgpack:"size"`
+}
+
+// Streamer writes and reads values too large to be held in memory, a value is split into chunks stored as internal
+// keys (under StreamPrefix) while its key holds a small manifest pointing to them, so a Get or a Scan of the key
+// returns the manifest (see IsStream) and only GetStream reassembles the value
+type Streamer struct {
+	p Provider
+
+	// ChunkSize the maximum size of a chunk, it must fit the value size limit of the provider
+	ChunkSize int
+}
+
+// NewStreamer returns a streamer over p splitting the values into chunks of the specified size,
+// DefaultStreamChunkSize is used when it's zero
+func NewStreamer(p Provider, chunkSize int) *Streamer {
+	if chunkSize <= 0 {
+		chunkSize = DefaultStreamChunkSize
+	}
+
+	return &Streamer{
+		p:         p,
+		ChunkSize: chunkSize,
+	}
+}
+
+// IsStream whether the specified value is the manifest of a streamed value
+func IsStream(v []byte) bool {
+	return bytes.HasPrefix(v, StreamPrefix)
+}
+
+// chunkKey returns the key of the specified chunk of the specified streamed value
+func chunkKey(id []byte, i uint32) []byte {
+	k := make([]byte, len(StreamPrefix)+len(id)+4)
+	n := copy(k, StreamPrefix)
+	n += copy(k[n:], id)
+	binary.BigEndian.PutUint32(k[n:], i)
+	return k
+}
+
+// manifest returns the manifest stored under the specified key, or the value itself when it holds a plain one
+func (s *Streamer) manifest(k []byte) (*streamManifest, []byte, error) {
+	v, err := s.p.Get(k)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !IsStream(v) {
+		return nil, v, nil
+	}
+
+	var m streamManifest
+	if err := msgpack.Unmarshal(v[len(StreamPrefix):], &m); err != nil {
+		return nil, nil, err
+	}
+
+	return &m, nil, nil
+}
+
+// deleteChunks deletes the chunks of the specified streamed value, in batches of 100
+func (s *Streamer) deleteChunks(id []byte, chunks uint32) error {
+	var batch []*Entry
+	for i := uint32(0); i < chunks; i++ {
+		batch = append(batch, &Entry{Key: chunkKey(id, i)})
+		if len(batch) == 100 || i == chunks-1 {
+			if err := s.p.Batch(batch); err != nil {
+				return err
+			}
+			batch = nil
+		}
+	}
+
+	return nil
+}
+
+// PutStream writes the value read from r under the specified key, chunk by chunk, the manifest is written once every
+// chunk is so a concurrent GetStream reads either the previous value or the new one, then the chunks of the previous
+// value are deleted. the chunks and the manifest share the expiration of the ttl (zero means no expiration) so they
+// expire together, a failed write deletes the chunks it has written
+func (s *Streamer) PutStream(k []byte, r io.Reader, ttl time.Duration) error {
+	if len(k) == 0 {
+		return ErrEmptyKey
+	}
+
+	previous, _, err := s.manifest(k)
+	if err != nil && err != ErrKeyNotFound {
+		return err
+	}
+
+	var expires *time.Time
+	if ttl > 0 {
+		t := Now().Add(ttl)
+		expires = &t
+	}
+
+	m := streamManifest{ID: make([]byte, streamIDSize)}
+	if _, err := rand.Read(m.ID); err != nil {
+		return err
+	}
+
+	buf := make([]byte, s.ChunkSize)
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			err := s.p.Put(&Entry{Key: chunkKey(m.ID, m.Chunks), Value: append([]byte{}, buf[:n]...), ExpireAt: expires})
+			if err != nil {
+				s.deleteChunks(m.ID, m.Chunks)
+				return err
+			}
+			m.Chunks++
+			m.Size += int64(n)
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+
+		if readErr != nil {
+			s.deleteChunks(m.ID, m.Chunks)
+			return readErr
+		}
+	}
+
+	b, err := msgpack.Marshal(m)
+	if err != nil {
+		s.deleteChunks(m.ID, m.Chunks)
+		return err
+	}
+
+	if err := s.p.Put(&Entry{Key: k, Value: append(append([]byte{}, StreamPrefix...), b...), ExpireAt: expires}); err != nil {
+		s.deleteChunks(m.ID, m.Chunks)
+		return err
+	}
+
+	if previous != nil {
+		return s.deleteChunks(previous.ID, previous.Chunks)
+	}
+
+	return nil
+}
+
+// GetStream writes the value of the specified key to w chunk by chunk, a plain value (not written by PutStream)
+// is written as is, ErrKeyNotFound is returned when a chunk is missing (i.e: the value has been overwritten meanwhile)
+func (s *Streamer) GetStream(k []byte, w io.Writer) error {
+	m, plain, err := s.manifest(k)
+	if err != nil {
+		return err
+	}
+
+	if m == nil {
+		_, err := w.Write(plain)
+		return err
+	}
+
+	for i := uint32(0); i < m.Chunks; i++ {
+		chunk, err := s.p.Get(chunkKey(m.ID, i))
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StreamSize returns the size of the value of the specified key without reading its chunks
+func (s *Streamer) StreamSize(k []byte) (int64, error) {
+	m, plain, err := s.manifest(k)
+	if err != nil {
+		return 0, err
+	}
+
+	if m == nil {
+		return int64(len(plain)), nil
+	}
+
+	return m.Size, nil
+}
+
+// DeleteStream deletes the specified key then the chunks of its value, a plain Delete of the key would leave
+// its chunks behind (until they expire if it had a ttl)
+func (s *Streamer) DeleteStream(k []byte) error {
+	m, _, err := s.manifest(k)
+	if err == ErrKeyNotFound {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := s.p.Delete(k); err != nil {
+		return err
+	}
+
+	if m == nil {
+		return nil
+	}
+
+	return s.deleteChunks(m.ID, m.Chunks)
+}
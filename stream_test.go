@@ -0,0 +1,125 @@
+package goukv_test
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+// countChunks returns the number of live chunks stored under the stream prefix
+func countChunks(t *testing.T, db goukv.Provider) int {
+	n := 0
+	err := db.Scan(goukv.ScanOpts{Prefix: goukv.StreamPrefix, Scanner: func(k, v []byte) error {
+		n++
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestStream(t *testing.T) {
+	data := make([]byte, 5<<20+123)
+	rand.Read(data)
+
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		streamer := goukv.NewStreamer(db, 256<<10)
+
+		if err := streamer.PutStream([]byte("large"), bytes.NewReader(data), 0); err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+
+		var buf bytes.Buffer
+		if err := streamer.GetStream([]byte("large"), &buf); err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Errorf("%s: expected the streamed value to round-trip, found (%d) bytes", driver, buf.Len())
+		}
+
+		if size, err := streamer.StreamSize([]byte("large")); err != nil || size != int64(len(data)) {
+			t.Errorf("%s: expected the size (%d), found (%d, %v)", driver, len(data), size, err)
+		}
+
+		if v, err := db.Get([]byte("large")); err != nil || !goukv.IsStream(v) {
+			t.Errorf("%s: expected the key to hold the manifest, found (%d bytes, %v)", driver, len(v), err)
+		}
+
+		if n := countChunks(t, db); n != 21 {
+			t.Errorf("%s: expected (21) chunks, found (%d)", driver, n)
+		}
+
+		// the chunks are internal keys
+		db.Scan(goukv.ScanOpts{Scanner: func(k, v []byte) error {
+			if string(k) != "large" {
+				t.Errorf("%s: expected only the logical key to be scanned, found (%q)", driver, k)
+			}
+			return nil
+		}})
+
+		// overwriting deletes the previous chunks
+		small := data[:300<<10]
+		if err := streamer.PutStream([]byte("large"), bytes.NewReader(small), 0); err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+
+		buf.Reset()
+		if err := streamer.GetStream([]byte("large"), &buf); err != nil || !bytes.Equal(buf.Bytes(), small) {
+			t.Errorf("%s: expected the overwritten value, found (%d bytes, %v)", driver, buf.Len(), err)
+		}
+
+		if n := countChunks(t, db); n != 2 {
+			t.Errorf("%s: expected (2) chunks after the overwrite, found (%d)", driver, n)
+		}
+
+		if err := streamer.DeleteStream([]byte("large")); err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+
+		if err := streamer.GetStream([]byte("large"), &buf); err != goukv.ErrKeyNotFound {
+			t.Errorf("%s: expected (%v), found (%v)", driver, goukv.ErrKeyNotFound, err)
+		}
+
+		if n := countChunks(t, db); n != 0 {
+			t.Errorf("%s: expected the chunks to be deleted, found (%d)", driver, n)
+		}
+	}
+}
+
+func TestStreamTTL(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		streamer := goukv.NewStreamer(db, 1024)
+
+		if err := streamer.PutStream([]byte("k"), bytes.NewReader(make([]byte, 10000)), time.Hour); err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+
+		if n := countChunks(t, db); n != 10 {
+			t.Errorf("%s: expected (10) chunks, found (%d)", driver, n)
+		}
+
+		goukv.Now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+		var buf bytes.Buffer
+		if err := streamer.GetStream([]byte("k"), &buf); err != goukv.ErrKeyNotFound {
+			t.Errorf("%s: expected the value to expire, found (%v)", driver, err)
+		}
+
+		if n := countChunks(t, db); n != 0 {
+			t.Errorf("%s: expected the chunks to expire with the value, found (%d)", driver, n)
+		}
+
+		goukv.Now = time.Now
+	}
+}
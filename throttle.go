@@ -0,0 +1,86 @@
+package goukv
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter a token bucket refilled at a fixed rate per second, holding up to a second worth of tokens
+type RateLimiter struct {
+	lock   sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a RateLimiter of the specified rate per second, nil is returned when the rate isn't positive
+func NewRateLimiter(rate int) *RateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+
+	return &RateLimiter{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// Wait takes n tokens, blocking until they are available, n may exceed the bucket capacity
+func (l *RateLimiter) Wait(n int) {
+	l.lock.Lock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+
+	// the tokens are reserved right away, the bucket goes negative until the missing ones are refilled
+	l.tokens -= float64(n)
+	deficit := -l.tokens
+
+	l.lock.Unlock()
+
+	if deficit > 0 {
+		time.Sleep(time.Duration(deficit / l.rate * float64(time.Second)))
+	}
+}
+
+// WriteThrottle throttles the writes of a provider from its "write_rate_limit" (entries per second)
+// and "write_rate_limit_bytes" (keys + values bytes per second) options
+type WriteThrottle struct {
+	ops   *RateLimiter
+	bytes *RateLimiter
+}
+
+// NewWriteThrottle builds a WriteThrottle from the specified provider options, nil is returned when none of the limits is set
+func NewWriteThrottle(opts map[string]interface{}) *WriteThrottle {
+	ops, _ := opts["write_rate_limit"].(int)
+	bytes, _ := opts["write_rate_limit_bytes"].(int)
+
+	if ops <= 0 && bytes <= 0 {
+		return nil
+	}
+
+	return &WriteThrottle{
+		ops:   NewRateLimiter(ops),
+		bytes: NewRateLimiter(bytes),
+	}
+}
+
+// Wait blocks until the specified entries can be written
+func (t *WriteThrottle) Wait(entries ...*Entry) {
+	if t.ops != nil {
+		t.ops.Wait(len(entries))
+	}
+
+	if t.bytes != nil {
+		size := 0
+		for _, e := range entries {
+			size += len(e.Key) + len(e.Value)
+		}
+		t.bytes.Wait(size)
+	}
+}
@@ -0,0 +1,61 @@
+package goukv_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+func TestWriteRateLimit(t *testing.T) {
+	// the throttled ways of writing a new key
+	writes := map[string]func(db goukv.Provider, e *goukv.Entry) error{
+		"Put": func(db goukv.Provider, e *goukv.Entry) error {
+			return db.Put(e)
+		},
+		"PutIfChanged": func(db goukv.Provider, e *goukv.Entry) error {
+			_, err := db.(goukv.IdempotentPutter).PutIfChanged(e)
+			return err
+		},
+		"PutVersioned": func(db goukv.Provider, e *goukv.Entry) error {
+			_, err := db.(goukv.VersionedPutter).PutVersioned(e, 0)
+			return err
+		},
+	}
+
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		for name, write := range writes {
+			db, cleanup := openTempDB(t, driver, map[string]interface{}{"write_rate_limit": 200, "enable_versions": true})
+			defer cleanup()
+
+			// the bucket starts full (200 writes), the remaining 100 writes need half a second
+			start := time.Now()
+			for i := 0; i < 300; i++ {
+				if err := write(db, &goukv.Entry{Key: []byte(fmt.Sprintf("k%d", i)), Value: []byte("v")}); err != nil {
+					t.Fatalf("%s %s: %v", driver, name, err)
+				}
+			}
+			elapsed := time.Since(start)
+
+			if elapsed < 400*time.Millisecond || elapsed > 2*time.Second {
+				t.Errorf("%s %s: expected the writes to be capped near 200/s, took (%s)", driver, name, elapsed)
+			}
+		}
+	}
+}
+
+func TestRateLimiterBytes(t *testing.T) {
+	throttle := goukv.NewWriteThrottle(map[string]interface{}{"write_rate_limit_bytes": 1000})
+
+	start := time.Now()
+	throttle.Wait(&goukv.Entry{Key: []byte("k"), Value: make([]byte, 999)})
+	throttle.Wait(&goukv.Entry{Key: []byte("k"), Value: make([]byte, 499)})
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("expected the second write to wait for half a second, took (%s)", elapsed)
+	}
+
+	if goukv.NewWriteThrottle(map[string]interface{}{}) != nil {
+		t.Error("expected no throttle when no limit is set")
+	}
+}
@@ -0,0 +1,11 @@
+package goukv
+
+import "time"
+
+// DefaultTombstoneTTL how long the tombstone of a delete is kept in track_deletes mode unless "tombstone_ttl" is set
+const DefaultTombstoneTTL = time.Hour
+
+// IsTombstone whether the specified scanned value is the tombstone of a deleted key (see ScanOpts.IncludeTombstones)
+func IsTombstone(v []byte) bool {
+	return v == nil
+}
@@ -0,0 +1,15 @@
+package goukv
+
+// Txn represents a read-write transaction, its writes are only visible to the others after Commit
+type Txn interface {
+	Get([]byte) ([]byte, error)
+	Put(*Entry) error
+	Delete([]byte) error
+	Commit() error
+	Discard()
+}
+
+// Transactional an optional interface for providers supporting read-write transactions
+type Transactional interface {
+	NewTxn() (Txn, error)
+}
@@ -0,0 +1,200 @@
+package goukv
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Event represents a committed mutation delivered to the watchers, its Value is nil for deletions
+type Event struct {
+	Op    ChangeOp
+	Key   []byte
+	Value []byte
+}
+
+// Watcher an optional interface for providers that stream their mutations,
+// the returned func stops the watch and closes the channel
+type Watcher interface {
+	Watch(prefix []byte) (<-chan Event, func(), error)
+	WatchWithSnapshot(prefix []byte) (<-chan Event, func(), error)
+}
+
+// EventHub dispatches the committed changes of a provider to its watchers,
+// every watcher has its own unbounded queue so slow watchers never block the writers
+type EventHub struct {
+	lock       sync.RWMutex
+	commitLock sync.Mutex
+	watchers   map[*watcher]struct{}
+}
+
+// NewEventHub initializes a new EventHub
+func NewEventHub() *EventHub {
+	return &EventHub{
+		watchers: map[*watcher]struct{}{},
+	}
+}
+
+// Commit runs the specified write then publishes its changes if it succeeded,
+// the writes run concurrently while nobody is watching and one at a time otherwise,
+// so that the events are published in the commit order
+func (h *EventHub) Commit(changes []Change, write func() error) error {
+	h.lock.RLock()
+	if len(h.watchers) < 1 {
+		defer h.lock.RUnlock()
+		return write()
+	}
+	h.lock.RUnlock()
+
+	h.commitLock.Lock()
+	defer h.commitLock.Unlock()
+
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	if err := write(); err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		event := Event{Op: change.Op, Key: append([]byte{}, change.Key...)}
+		if change.Op == ChangePut {
+			event.Value = append([]byte{}, change.Value...)
+		}
+
+		for w := range h.watchers {
+			if bytes.HasPrefix(event.Key, w.prefix) {
+				w.push(event)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Watch streams the changes committed under the specified prefix from now on,
+// the events share their keys and values between the watchers so they must not be modified
+func (h *EventHub) Watch(prefix []byte) (<-chan Event, func()) {
+	w := h.register(prefix)
+	go w.run()
+
+	return w.out, func() { h.unregister(w) }
+}
+
+// WatchWithSnapshot emits a put event for every live key under the specified prefix read using scan
+// (which must read from a consistent snapshot), then streams the changes committed since, the watcher is
+// registered before the snapshot is read so no change is missed, but the changes committed while
+// it's being read may be delivered twice (in the snapshot and as events), the snapshot is held in memory
+func (h *EventHub) WatchWithSnapshot(prefix []byte, scan func(ScanOpts) error) (<-chan Event, func(), error) {
+	w := h.register(prefix)
+
+	var snapshot []Event
+	err := scan(ScanOpts{
+		Prefix: prefix,
+		Scanner: func(k, v []byte) error {
+			if IsInternalKey(k) {
+				return nil
+			}
+			snapshot = append(snapshot, Event{Op: ChangePut, Key: append([]byte{}, k...), Value: append([]byte{}, v...)})
+			return nil
+		},
+	})
+
+	if err != nil {
+		h.unregister(w)
+		return nil, nil, err
+	}
+
+	w.lock.Lock()
+	w.queue = append(snapshot, w.queue...)
+	w.lock.Unlock()
+
+	go w.run()
+
+	return w.out, func() { h.unregister(w) }, nil
+}
+
+func (h *EventHub) register(prefix []byte) *watcher {
+	w := &watcher{
+		prefix: append([]byte{}, prefix...),
+		out:    make(chan Event),
+		stop:   make(chan struct{}),
+	}
+	w.cond = sync.NewCond(&w.lock)
+
+	h.lock.Lock()
+	h.watchers[w] = struct{}{}
+	h.lock.Unlock()
+
+	return w
+}
+
+func (h *EventHub) unregister(w *watcher) {
+	h.lock.Lock()
+	delete(h.watchers, w)
+	h.lock.Unlock()
+
+	w.close()
+}
+
+// watcher queues the events of a single watch until they are received
+type watcher struct {
+	prefix    []byte
+	lock      sync.Mutex
+	cond      *sync.Cond
+	queue     []Event
+	done      bool
+	out       chan Event
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+func (w *watcher) push(event Event) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.done {
+		return
+	}
+
+	w.queue = append(w.queue, event)
+	w.cond.Signal()
+}
+
+func (w *watcher) close() {
+	w.closeOnce.Do(func() {
+		w.lock.Lock()
+		w.done = true
+		w.queue = nil
+		w.cond.Signal()
+		w.lock.Unlock()
+
+		close(w.stop)
+	})
+}
+
+// run delivers the queued events in order until the watcher is closed
+func (w *watcher) run() {
+	defer close(w.out)
+
+	for {
+		w.lock.Lock()
+		for len(w.queue) < 1 && !w.done {
+			w.cond.Wait()
+		}
+
+		if w.done {
+			w.lock.Unlock()
+			return
+		}
+
+		event := w.queue[0]
+		w.queue = w.queue[1:]
+		w.lock.Unlock()
+
+		select {
+		case w.out <- event:
+		case <-w.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package goukv_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alash3al/goukv"
+)
+
+// nextEvent receives the next event or fails the test after a second
+func nextEvent(t *testing.T, events <-chan goukv.Event) goukv.Event {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for an event")
+		return goukv.Event{}
+	}
+}
+
+func TestWatchWithSnapshot(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		db.Batch([]*goukv.Entry{
+			{Key: []byte("a1"), Value: []byte("v1")},
+			{Key: []byte("a2"), Value: []byte("v2")},
+			{Key: []byte("b1"), Value: []byte("v")},
+		})
+
+		events, stop, err := db.(goukv.Watcher).WatchWithSnapshot([]byte("a"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		db.Put(&goukv.Entry{Key: []byte("b2"), Value: []byte("v")})
+		db.Put(&goukv.Entry{Key: []byte("a3"), Value: []byte("v3")})
+		db.Delete([]byte("a1"))
+
+		expected := []goukv.Event{
+			{Op: goukv.ChangePut, Key: []byte("a1"), Value: []byte("v1")},
+			{Op: goukv.ChangePut, Key: []byte("a2"), Value: []byte("v2")},
+			{Op: goukv.ChangePut, Key: []byte("a3"), Value: []byte("v3")},
+			{Op: goukv.ChangeDelete, Key: []byte("a1")},
+		}
+
+		for _, e := range expected {
+			event := nextEvent(t, events)
+			if event.Op != e.Op || string(event.Key) != string(e.Key) || string(event.Value) != string(e.Value) {
+				t.Errorf("%s: expected (%v), found (%v)", driver, e, event)
+			}
+		}
+
+		stop()
+
+		if _, ok := <-events; ok {
+			t.Errorf("%s: expected the events to be closed once stopped", driver)
+		}
+	}
+}
+
+func TestWatchWithSnapshotConcurrentWrites(t *testing.T) {
+	for _, driver := range []string{"goleveldb", "badgerdb"} {
+		db, cleanup := openTempDB(t, driver, nil)
+		defer cleanup()
+
+		done := make(chan struct{})
+		go (func() {
+			defer close(done)
+			for i := 0; i < 500; i++ {
+				db.Put(&goukv.Entry{Key: []byte(fmt.Sprintf("k%d", i%50)), Value: []byte(fmt.Sprint(i))})
+			}
+			db.Put(&goukv.Entry{Key: []byte("last"), Value: []byte("v")})
+		})()
+
+		time.Sleep(time.Millisecond)
+
+		events, stop, err := db.(goukv.Watcher).WatchWithSnapshot(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// applying the snapshot then the events must end with the final state
+		view := map[string]string{}
+		for view["last"] != "v" {
+			event := nextEvent(t, events)
+			view[string(event.Key)] = string(event.Value)
+		}
+
+		stop()
+		<-done
+
+		db.Scan(goukv.ScanOpts{
+			Scanner: func(k, v []byte) error {
+				if view[string(k)] != string(v) {
+					t.Errorf("%s: expected (%s) for (%s), found (%s)", driver, v, k, view[string(k)])
+				}
+				return nil
+			},
+		})
+	}
+}